@@ -62,6 +62,9 @@ func main() {
 	}()
 	
 
+	// Start background jobs; they stop along with the server
+	srv.StartJobs()
+
 	// Wait for interrupt signal to gracefully shutdown the server
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"backend/internal/config"
+	"backend/internal/db"
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/joho/godotenv"
+)
+
+const usage = `Usage: migrate <command> [arguments]
+
+Commands:
+  up                 apply all pending migrations
+  down [-steps N]    revert the last N applied migrations (default 1)
+  status             list migrations and whether they are applied
+  create [-dir DIR] NAME
+                     write an empty NNNN_NAME.up.sql / .down.sql pair
+  seed               create the demo users if the database has none
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	command, args := os.Args[1], os.Args[2:]
+
+	switch command {
+	case "up", "down", "status", "create", "seed":
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown command %q\n\n%s", command, usage)
+		os.Exit(2)
+	}
+
+	// create only touches files, so it works without a database
+	if command == "create" {
+		flags := flag.NewFlagSet("create", flag.ExitOnError)
+		dir := flags.String("dir", db.MigrationsDir, "migrations directory")
+		flags.Parse(args)
+		if flags.NArg() != 1 {
+			log.Fatal("create requires exactly one migration name")
+		}
+
+		upPath, downPath, err := db.CreateMigration(*dir, flags.Arg(0))
+		if err != nil {
+			log.Fatalf("Failed to create migration: %v", err)
+		}
+		fmt.Printf("Created %s\nCreated %s\n", upPath, downPath)
+		return
+	}
+
+	// The .env file is optional here; the environment may provide everything
+	if err := godotenv.Load(".env"); err != nil {
+		log.Printf("No .env file loaded: %v", err)
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+
+	database, err := config.ConnectDB(cfg)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+
+	migrator, err := db.NewMigrator(database)
+	if err != nil {
+		log.Fatalf("Failed to load migrations: %v", err)
+	}
+	ctx := context.Background()
+
+	switch command {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		if err != nil {
+			log.Fatalf("Migration failed: %v", err)
+		}
+		for _, migration := range applied {
+			fmt.Printf("Applied %04d_%s\n", migration.Version, migration.Name)
+		}
+		if len(applied) == 0 {
+			fmt.Println("No pending migrations")
+		}
+
+	case "down":
+		flags := flag.NewFlagSet("down", flag.ExitOnError)
+		steps := flags.Int("steps", 1, "number of migrations to revert")
+		flags.Parse(args)
+		if *steps < 1 {
+			log.Fatal("-steps must be at least 1")
+		}
+
+		reverted, err := migrator.Down(ctx, *steps)
+		if err != nil {
+			log.Fatalf("Rollback failed: %v", err)
+		}
+		for _, migration := range reverted {
+			fmt.Printf("Reverted %04d_%s\n", migration.Version, migration.Name)
+		}
+		if len(reverted) == 0 {
+			fmt.Println("No applied migrations to revert")
+		}
+
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			log.Fatalf("Failed to read migration status: %v", err)
+		}
+		for _, status := range statuses {
+			state := "pending"
+			if status.AppliedAt != nil {
+				state = "applied " + status.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%04d  %-40s %s\n", status.Version, status.Name, state)
+		}
+
+	case "seed":
+		if err := db.Seed(database); err != nil {
+			log.Fatalf("Seeding failed: %v", err)
+		}
+	}
+}
@@ -17,12 +17,17 @@ type Config struct {
 	JWT      JWTConfig
 	CORS     CORSConfig
 	Upload   UploadConfig
+	Mail     MailConfig
 	Env      string `mapstructure:"ENV"`
 }
 
+// ServerConfig holds where the server listens and PublicBaseURL, the
+// address users reach it at, which links in mail and printed documents
+// are built from. Only development may leave it unset.
 type ServerConfig struct {
-	Host string `mapstructure:"SERVER_HOST"`
-	Port string `mapstructure:"SERVER_PORT"`
+	Host          string `mapstructure:"SERVER_HOST"`
+	Port          string `mapstructure:"SERVER_PORT"`
+	PublicBaseURL string `mapstructure:"PUBLIC_BASE_URL"`
 }
 
 type DatabaseConfig struct {
@@ -49,6 +54,29 @@ type UploadConfig struct {
 	MaxSize   int64  `mapstructure:"MAX_UPLOAD_SIZE"`
 }
 
+// MailConfig selects how outgoing mail is delivered: "smtp", "file" (an
+// .eml file per message in Directory), "memory" or "log". Only development
+// may leave it unset, which means "log".
+type MailConfig struct {
+	Transport    string `mapstructure:"MAIL_TRANSPORT"`
+	From         string `mapstructure:"MAIL_FROM"`
+	SMTPHost     string `mapstructure:"SMTP_HOST"`
+	SMTPPort     int    `mapstructure:"SMTP_PORT"`
+	SMTPUsername string `mapstructure:"SMTP_USERNAME"`
+	SMTPPassword string `mapstructure:"SMTP_PASSWORD"`
+	Directory    string `mapstructure:"MAIL_DIRECTORY"`
+}
+
+// String leaves the SMTP password out of logged configuration
+func (c MailConfig) String() string {
+	return fmt.Sprintf("{%s %s %s:%d}", c.Transport, c.From, c.SMTPHost, c.SMTPPort)
+}
+
+// IsDevelopment reports whether ENV marks a development setup
+func (c *Config) IsDevelopment() bool {
+	return c.Env == "development" || c.Env == "dev" || c.Env == "local"
+}
+
 func (c UploadConfig) String() string {
 	return fmt.Sprintf("%dM", c.MaxSize/1024/1024)
 }
@@ -114,6 +142,38 @@ func Load() (*Config, error) {
     cfg.CORS.AllowedOrigins = strings.Split(rawOrigins, ",")
 }
 
+	if cfg.Env == "" {
+		cfg.Env = viper.GetString("ENV")
+	}
+	cfg.Env = strings.ToLower(strings.TrimSpace(cfg.Env))
+
+	// Never taken from the request, whose Host header the client controls
+	cfg.Server.PublicBaseURL = strings.TrimRight(strings.TrimSpace(viper.GetString("PUBLIC_BASE_URL")), "/")
+	if cfg.Server.PublicBaseURL == "" && cfg.IsDevelopment() {
+		port := viper.GetString("SERVER_PORT")
+		if port == "" {
+			port = "50404"
+		}
+		cfg.Server.PublicBaseURL = "http://localhost:" + port
+	}
+
+	// Mail settings are read directly, like the CORS origins
+	cfg.Mail = MailConfig{
+		Transport:    strings.ToLower(viper.GetString("MAIL_TRANSPORT")),
+		From:         viper.GetString("MAIL_FROM"),
+		SMTPHost:     viper.GetString("SMTP_HOST"),
+		SMTPPort:     viper.GetInt("SMTP_PORT"),
+		SMTPUsername: viper.GetString("SMTP_USERNAME"),
+		SMTPPassword: viper.GetString("SMTP_PASSWORD"),
+		Directory:    viper.GetString("MAIL_DIRECTORY"),
+	}
+	if cfg.Mail.From == "" {
+		cfg.Mail.From = "no-reply@localhost"
+	}
+	if cfg.Mail.SMTPPort == 0 {
+		cfg.Mail.SMTPPort = 587
+	}
+
 	// ✅ Tambahkan Fallback disini, JANGAN di luar fungsi
 	if cfg.Database.Host == "" || cfg.Database.Port == "" || cfg.Database.User == "" || cfg.Database.Name == "" {
 		fmt.Println("Database config incomplete, trying direct .env file parsing")
@@ -0,0 +1,32 @@
+DROP TABLE IF EXISTS setting_changes;
+DROP TABLE IF EXISTS system_settings;
+DROP TABLE IF EXISTS activities;
+DROP TABLE IF EXISTS schedule_events;
+DROP TABLE IF EXISTS forum_messages;
+DROP TABLE IF EXISTS forum_threads;
+DROP TABLE IF EXISTS course_grades;
+DROP TABLE IF EXISTS grades;
+DROP TABLE IF EXISTS exam_answers;
+DROP TABLE IF EXISTS exam_attempts;
+DROP TABLE IF EXISTS exam_questions;
+DROP TABLE IF EXISTS exams;
+DROP TABLE IF EXISTS rubric_items;
+DROP TABLE IF EXISTS submissions;
+DROP TABLE IF EXISTS assessments;
+DROP TABLE IF EXISTS textbooks;
+DROP TABLE IF EXISTS teaching_strategies;
+DROP TABLE IF EXISTS learning_outcomes;
+DROP TABLE IF EXISTS syllabuses;
+DROP TABLE IF EXISTS attendances;
+DROP TABLE IF EXISTS materials;
+DROP TABLE IF EXISTS session_contents;
+DROP TABLE IF EXISTS sessions;
+DROP TABLE IF EXISTS enrollment_transitions;
+DROP TABLE IF EXISTS course_students;
+DROP TABLE IF EXISTS course_instructors;
+DROP TABLE IF EXISTS courses;
+DROP TABLE IF EXISTS refresh_tokens;
+DROP TABLE IF EXISTS instructor_infos;
+DROP TABLE IF EXISTS student_infos;
+DROP TABLE IF EXISTS users;
+DROP TYPE IF EXISTS user_role;
@@ -0,0 +1,508 @@
+-- Initial schema. Statements are idempotent so that databases created by the
+-- former AutoMigrate setup can adopt versioned migrations.
+
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_type WHERE typname = 'user_role') THEN
+        CREATE TYPE user_role AS ENUM ('admin', 'instructor', 'student');
+    END IF;
+END
+$$;
+
+-- Users
+
+CREATE TABLE IF NOT EXISTS users (
+    id                BIGSERIAL PRIMARY KEY,
+    username          VARCHAR(50) NOT NULL,
+    name              TEXT NOT NULL,
+    email             TEXT NOT NULL,
+    password          TEXT NOT NULL,
+    role              user_role NOT NULL,
+    gender            VARCHAR(10),
+    religion          VARCHAR(50),
+    date_of_birth     TIMESTAMPTZ,
+    place_of_birth    VARCHAR(100),
+    department        VARCHAR(100),
+    profile_photo_url VARCHAR(255),
+    created_at        TIMESTAMPTZ,
+    updated_at        TIMESTAMPTZ,
+    deleted_at        TIMESTAMPTZ
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_username ON users (username);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email ON users (email);
+CREATE INDEX IF NOT EXISTS idx_users_deleted_at ON users (deleted_at);
+
+CREATE TABLE IF NOT EXISTS student_infos (
+    id               BIGSERIAL PRIMARY KEY,
+    user_id          BIGINT NOT NULL UNIQUE REFERENCES users (id),
+    student_id       VARCHAR(50) NOT NULL UNIQUE,
+    degree           VARCHAR(50),
+    major            VARCHAR(100),
+    stream           VARCHAR(100),
+    current_semester VARCHAR(20),
+    gpa              DECIMAL(3,2),
+    skills           TEXT,
+    created_at       TIMESTAMPTZ,
+    updated_at       TIMESTAMPTZ,
+    deleted_at       TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS idx_student_infos_deleted_at ON student_infos (deleted_at);
+
+CREATE TABLE IF NOT EXISTS instructor_infos (
+    id             BIGSERIAL PRIMARY KEY,
+    user_id        BIGINT NOT NULL UNIQUE REFERENCES users (id),
+    position       VARCHAR(100),
+    department     VARCHAR(100),
+    specialization VARCHAR(100),
+    created_at     TIMESTAMPTZ,
+    updated_at     TIMESTAMPTZ,
+    deleted_at     TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS idx_instructor_infos_deleted_at ON instructor_infos (deleted_at);
+
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+    id         BIGSERIAL PRIMARY KEY,
+    user_id    BIGINT NOT NULL REFERENCES users (id),
+    token      TEXT NOT NULL,
+    expires_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_refresh_tokens_token ON refresh_tokens (token);
+
+-- Courses and enrollment
+
+CREATE TABLE IF NOT EXISTS courses (
+    id                BIGSERIAL PRIMARY KEY,
+    code              TEXT NOT NULL,
+    title             TEXT NOT NULL,
+    category          TEXT,
+    description       TEXT,
+    semester          TEXT NOT NULL,
+    year              BIGINT NOT NULL,
+    capacity          BIGINT DEFAULT 0,
+    requires_approval BOOLEAN DEFAULT false,
+    add_deadline      TIMESTAMPTZ,
+    drop_deadline     TIMESTAMPTZ,
+    withdraw_deadline TIMESTAMPTZ,
+    created_at        TIMESTAMPTZ,
+    updated_at        TIMESTAMPTZ,
+    deleted_at        TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS idx_courses_code ON courses (code);
+CREATE INDEX IF NOT EXISTS idx_courses_deleted_at ON courses (deleted_at);
+
+CREATE TABLE IF NOT EXISTS course_instructors (
+    id         BIGSERIAL PRIMARY KEY,
+    course_id  BIGINT NOT NULL REFERENCES courses (id),
+    user_id    BIGINT NOT NULL REFERENCES users (id),
+    is_main    BOOLEAN DEFAULT false,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    deleted_at TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS idx_course_instructors_course_id ON course_instructors (course_id);
+CREATE INDEX IF NOT EXISTS idx_course_instructors_user_id ON course_instructors (user_id);
+CREATE INDEX IF NOT EXISTS idx_course_instructors_deleted_at ON course_instructors (deleted_at);
+
+CREATE TABLE IF NOT EXISTS course_students (
+    id            BIGSERIAL PRIMARY KEY,
+    course_id     BIGINT NOT NULL REFERENCES courses (id),
+    user_id       BIGINT NOT NULL REFERENCES users (id),
+    enrolled_at   TIMESTAMPTZ NOT NULL,
+    status        VARCHAR(20) DEFAULT 'pending',
+    waitlisted_at TIMESTAMPTZ,
+    created_at    TIMESTAMPTZ,
+    updated_at    TIMESTAMPTZ,
+    deleted_at    TIMESTAMPTZ
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_course_students_course_user ON course_students (course_id, user_id) WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_course_students_user_id ON course_students (user_id);
+CREATE INDEX IF NOT EXISTS idx_course_students_deleted_at ON course_students (deleted_at);
+
+CREATE TABLE IF NOT EXISTS enrollment_transitions (
+    id                BIGSERIAL PRIMARY KEY,
+    course_student_id BIGINT NOT NULL,
+    course_id         BIGINT NOT NULL,
+    user_id           BIGINT NOT NULL,
+    from_status       VARCHAR(20),
+    to_status         VARCHAR(20) NOT NULL,
+    reason            TEXT,
+    changed_by        BIGINT NOT NULL,
+    changed_at        TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_enrollment_transitions_course_student_id ON enrollment_transitions (course_student_id);
+CREATE INDEX IF NOT EXISTS idx_enrollment_transitions_course_id ON enrollment_transitions (course_id);
+CREATE INDEX IF NOT EXISTS idx_enrollment_transitions_user_id ON enrollment_transitions (user_id);
+
+-- Sessions and attendance
+
+CREATE TABLE IF NOT EXISTS sessions (
+    id            BIGSERIAL PRIMARY KEY,
+    course_id     BIGINT NOT NULL REFERENCES courses (id),
+    number        BIGINT NOT NULL,
+    title         TEXT NOT NULL,
+    description   TEXT,
+    date          TIMESTAMPTZ,
+    start_time    VARCHAR(10),
+    end_time      VARCHAR(10),
+    duration      VARCHAR(10),
+    delivery_mode VARCHAR(20),
+    location      TEXT,
+    zoom_link     TEXT,
+    created_at    TIMESTAMPTZ,
+    updated_at    TIMESTAMPTZ,
+    deleted_at    TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS idx_sessions_course_id ON sessions (course_id);
+CREATE INDEX IF NOT EXISTS idx_sessions_deleted_at ON sessions (deleted_at);
+
+CREATE TABLE IF NOT EXISTS session_contents (
+    id          BIGSERIAL PRIMARY KEY,
+    session_id  BIGINT NOT NULL REFERENCES sessions (id),
+    title       TEXT NOT NULL,
+    description TEXT,
+    duration    VARCHAR(10),
+    "order"     BIGINT NOT NULL,
+    status      VARCHAR(20) DEFAULT 'not_started',
+    progress    BIGINT DEFAULT 0,
+    created_at  TIMESTAMPTZ,
+    updated_at  TIMESTAMPTZ,
+    deleted_at  TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS idx_session_contents_deleted_at ON session_contents (deleted_at);
+
+CREATE TABLE IF NOT EXISTS materials (
+    id          BIGSERIAL PRIMARY KEY,
+    session_id  BIGINT NOT NULL REFERENCES sessions (id),
+    title       TEXT NOT NULL,
+    description TEXT,
+    type        VARCHAR(20) NOT NULL,
+    url         TEXT,
+    file_path   TEXT,
+    file_size   BIGINT,
+    created_at  TIMESTAMPTZ,
+    updated_at  TIMESTAMPTZ,
+    deleted_at  TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS idx_materials_deleted_at ON materials (deleted_at);
+
+CREATE TABLE IF NOT EXISTS attendances (
+    id            BIGSERIAL PRIMARY KEY,
+    session_id    BIGINT NOT NULL REFERENCES sessions (id),
+    user_id       BIGINT NOT NULL REFERENCES users (id),
+    status        VARCHAR(20) NOT NULL,
+    check_in_time TIMESTAMPTZ,
+    comment       TEXT,
+    created_at    TIMESTAMPTZ,
+    updated_at    TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS idx_attendances_session_id ON attendances (session_id);
+CREATE INDEX IF NOT EXISTS idx_attendances_user_id ON attendances (user_id);
+
+-- Syllabus
+
+CREATE TABLE IF NOT EXISTS syllabuses (
+    id          BIGSERIAL PRIMARY KEY,
+    course_id   BIGINT NOT NULL REFERENCES courses (id),
+    description TEXT,
+    created_at  TIMESTAMPTZ,
+    updated_at  TIMESTAMPTZ,
+    deleted_at  TIMESTAMPTZ
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_syllabuses_course_id ON syllabuses (course_id);
+CREATE INDEX IF NOT EXISTS idx_syllabuses_deleted_at ON syllabuses (deleted_at);
+
+CREATE TABLE IF NOT EXISTS learning_outcomes (
+    id          BIGSERIAL PRIMARY KEY,
+    syllabus_id BIGINT NOT NULL REFERENCES syllabuses (id),
+    code        TEXT NOT NULL,
+    knowledge   TEXT NOT NULL,
+    application TEXT,
+    created_at  TIMESTAMPTZ,
+    updated_at  TIMESTAMPTZ,
+    deleted_at  TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS idx_learning_outcomes_deleted_at ON learning_outcomes (deleted_at);
+
+CREATE TABLE IF NOT EXISTS teaching_strategies (
+    id          BIGSERIAL PRIMARY KEY,
+    syllabus_id BIGINT NOT NULL REFERENCES syllabuses (id),
+    name        TEXT NOT NULL,
+    description TEXT,
+    created_at  TIMESTAMPTZ,
+    updated_at  TIMESTAMPTZ,
+    deleted_at  TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS idx_teaching_strategies_deleted_at ON teaching_strategies (deleted_at);
+
+CREATE TABLE IF NOT EXISTS textbooks (
+    id          BIGSERIAL PRIMARY KEY,
+    syllabus_id BIGINT NOT NULL REFERENCES syllabuses (id),
+    title       TEXT NOT NULL,
+    authors     TEXT,
+    year        BIGINT,
+    publisher   TEXT,
+    link        TEXT,
+    created_at  TIMESTAMPTZ,
+    updated_at  TIMESTAMPTZ,
+    deleted_at  TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS idx_textbooks_deleted_at ON textbooks (deleted_at);
+
+-- Assessments and exams
+
+CREATE TABLE IF NOT EXISTS assessments (
+    id                  BIGSERIAL PRIMARY KEY,
+    course_id           BIGINT NOT NULL REFERENCES courses (id),
+    type                VARCHAR(20) NOT NULL,
+    title               TEXT NOT NULL,
+    description         TEXT,
+    weight              DECIMAL NOT NULL,
+    due_date            TIMESTAMPTZ,
+    available_from      TIMESTAMPTZ,
+    available_to        TIMESTAMPTZ,
+    status              VARCHAR(20) DEFAULT 'not_started',
+    max_attempts        BIGINT DEFAULT 1,
+    passing_score       DECIMAL,
+    questions_count     BIGINT,
+    randomize_questions BOOLEAN DEFAULT false,
+    allowed_file_types  TEXT,
+    max_file_size       BIGINT,
+    created_at          TIMESTAMPTZ,
+    updated_at          TIMESTAMPTZ,
+    deleted_at          TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS idx_assessments_course_id ON assessments (course_id);
+CREATE INDEX IF NOT EXISTS idx_assessments_deleted_at ON assessments (deleted_at);
+
+CREATE TABLE IF NOT EXISTS submissions (
+    id              BIGSERIAL PRIMARY KEY,
+    assessment_id   BIGINT NOT NULL REFERENCES assessments (id),
+    user_id         BIGINT NOT NULL REFERENCES users (id),
+    file_path       TEXT,
+    file_name       TEXT,
+    file_size       BIGINT,
+    submission_date TIMESTAMPTZ,
+    status          VARCHAR(20) DEFAULT 'submitted',
+    score           DECIMAL,
+    feedback        TEXT,
+    graded_by       BIGINT,
+    graded_at       TIMESTAMPTZ,
+    attempt_number  BIGINT DEFAULT 1,
+    created_at      TIMESTAMPTZ,
+    updated_at      TIMESTAMPTZ,
+    deleted_at      TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS idx_submissions_assessment_user ON submissions (assessment_id, user_id);
+CREATE INDEX IF NOT EXISTS idx_submissions_deleted_at ON submissions (deleted_at);
+
+CREATE TABLE IF NOT EXISTS rubric_items (
+    id                  BIGSERIAL PRIMARY KEY,
+    assessment_id       BIGINT NOT NULL REFERENCES assessments (id),
+    learning_outcome_id BIGINT NOT NULL REFERENCES learning_outcomes (id),
+    key_indicator       TEXT NOT NULL,
+    excellent_criteria  TEXT,
+    good_criteria       TEXT,
+    average_criteria    TEXT,
+    poor_criteria       TEXT,
+    created_at          TIMESTAMPTZ,
+    updated_at          TIMESTAMPTZ,
+    deleted_at          TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS idx_rubric_items_deleted_at ON rubric_items (deleted_at);
+
+CREATE TABLE IF NOT EXISTS exams (
+    id                  BIGSERIAL PRIMARY KEY,
+    course_id           BIGINT NOT NULL REFERENCES courses (id),
+    title               TEXT NOT NULL,
+    type                VARCHAR(20) NOT NULL,
+    description         TEXT,
+    duration            VARCHAR(10) NOT NULL,
+    questions_count     BIGINT,
+    available_from      TIMESTAMPTZ,
+    available_to        TIMESTAMPTZ,
+    passing_score       DECIMAL,
+    max_attempts        BIGINT DEFAULT 1,
+    randomize_questions BOOLEAN DEFAULT false,
+    status              VARCHAR(20) DEFAULT 'not_started',
+    weight              DECIMAL NOT NULL,
+    prerequisites       TEXT,
+    created_at          TIMESTAMPTZ,
+    updated_at          TIMESTAMPTZ,
+    deleted_at          TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS idx_exams_course_id ON exams (course_id);
+CREATE INDEX IF NOT EXISTS idx_exams_deleted_at ON exams (deleted_at);
+
+CREATE TABLE IF NOT EXISTS exam_questions (
+    id             BIGSERIAL PRIMARY KEY,
+    exam_id        BIGINT NOT NULL REFERENCES exams (id),
+    type           VARCHAR(20) NOT NULL,
+    question       TEXT NOT NULL,
+    options        TEXT,
+    correct_answer TEXT,
+    points         DECIMAL NOT NULL,
+    "order"        BIGINT,
+    created_at     TIMESTAMPTZ,
+    updated_at     TIMESTAMPTZ,
+    deleted_at     TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS idx_exam_questions_exam_id ON exam_questions (exam_id);
+CREATE INDEX IF NOT EXISTS idx_exam_questions_deleted_at ON exam_questions (deleted_at);
+
+CREATE TABLE IF NOT EXISTS exam_attempts (
+    id             BIGSERIAL PRIMARY KEY,
+    exam_id        BIGINT NOT NULL REFERENCES exams (id),
+    user_id        BIGINT NOT NULL REFERENCES users (id),
+    start_time     TIMESTAMPTZ,
+    end_time       TIMESTAMPTZ,
+    score          DECIMAL,
+    status         VARCHAR(20) DEFAULT 'in_progress',
+    attempt_number BIGINT DEFAULT 1,
+    created_at     TIMESTAMPTZ,
+    updated_at     TIMESTAMPTZ,
+    deleted_at     TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS idx_exam_attempts_exam_user ON exam_attempts (exam_id, user_id);
+CREATE INDEX IF NOT EXISTS idx_exam_attempts_deleted_at ON exam_attempts (deleted_at);
+
+CREATE TABLE IF NOT EXISTS exam_answers (
+    id              BIGSERIAL PRIMARY KEY,
+    exam_attempt_id BIGINT NOT NULL REFERENCES exam_attempts (id),
+    question_id     BIGINT NOT NULL REFERENCES exam_questions (id),
+    answer          TEXT,
+    is_correct      BOOLEAN,
+    points          DECIMAL,
+    created_at      TIMESTAMPTZ,
+    updated_at      TIMESTAMPTZ,
+    deleted_at      TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS idx_exam_answers_exam_attempt_id ON exam_answers (exam_attempt_id);
+CREATE INDEX IF NOT EXISTS idx_exam_answers_deleted_at ON exam_answers (deleted_at);
+
+-- Grades
+
+-- assessment_id has no foreign key: course-level grade entries store 0
+CREATE TABLE IF NOT EXISTS grades (
+    id            BIGSERIAL PRIMARY KEY,
+    course_id     BIGINT NOT NULL REFERENCES courses (id),
+    user_id       BIGINT NOT NULL REFERENCES users (id),
+    assessment_id BIGINT,
+    title         TEXT NOT NULL,
+    weight        DECIMAL NOT NULL,
+    score         DECIMAL,
+    letter_grade  VARCHAR(5),
+    last_updated  TIMESTAMPTZ,
+    created_at    TIMESTAMPTZ,
+    updated_at    TIMESTAMPTZ,
+    deleted_at    TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS idx_grades_course_user ON grades (course_id, user_id);
+CREATE INDEX IF NOT EXISTS idx_grades_deleted_at ON grades (deleted_at);
+
+CREATE TABLE IF NOT EXISTS course_grades (
+    id            BIGSERIAL PRIMARY KEY,
+    course_id     BIGINT NOT NULL REFERENCES courses (id),
+    user_id       BIGINT NOT NULL REFERENCES users (id),
+    overall_score DECIMAL,
+    letter_grade  VARCHAR(5),
+    last_updated  TIMESTAMPTZ,
+    created_at    TIMESTAMPTZ,
+    updated_at    TIMESTAMPTZ,
+    deleted_at    TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS idx_course_grades_course_user ON course_grades (course_id, user_id);
+CREATE INDEX IF NOT EXISTS idx_course_grades_deleted_at ON course_grades (deleted_at);
+
+-- Forum
+
+CREATE TABLE IF NOT EXISTS forum_threads (
+    id             BIGSERIAL PRIMARY KEY,
+    course_id      BIGINT NOT NULL REFERENCES courses (id),
+    session_number BIGINT,
+    title          TEXT NOT NULL,
+    user_id        BIGINT NOT NULL REFERENCES users (id),
+    status         VARCHAR(20) DEFAULT 'open',
+    views          BIGINT DEFAULT 0,
+    type           VARCHAR(20) DEFAULT 'class',
+    created_at     TIMESTAMPTZ,
+    updated_at     TIMESTAMPTZ,
+    deleted_at     TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS idx_forum_threads_course_id ON forum_threads (course_id);
+CREATE INDEX IF NOT EXISTS idx_forum_threads_deleted_at ON forum_threads (deleted_at);
+
+CREATE TABLE IF NOT EXISTS forum_messages (
+    id         BIGSERIAL PRIMARY KEY,
+    thread_id  BIGINT NOT NULL REFERENCES forum_threads (id),
+    user_id    BIGINT NOT NULL REFERENCES users (id),
+    content    TEXT NOT NULL,
+    is_present BOOLEAN DEFAULT false,
+    is_passed  BOOLEAN DEFAULT false,
+    parent_id  BIGINT,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    deleted_at TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS idx_forum_messages_thread_id ON forum_messages (thread_id);
+CREATE INDEX IF NOT EXISTS idx_forum_messages_deleted_at ON forum_messages (deleted_at);
+
+-- Schedule
+
+CREATE TABLE IF NOT EXISTS schedule_events (
+    id              BIGSERIAL PRIMARY KEY,
+    course_id       BIGINT NOT NULL REFERENCES courses (id),
+    session_id      BIGINT,
+    exam_id         BIGINT,
+    title           TEXT NOT NULL,
+    type            VARCHAR(20) NOT NULL,
+    date            TIMESTAMPTZ NOT NULL,
+    start_time      VARCHAR(10) NOT NULL,
+    end_time        VARCHAR(10) NOT NULL,
+    location        TEXT,
+    instructor_id   BIGINT NOT NULL REFERENCES users (id),
+    is_onsite       BOOLEAN DEFAULT true,
+    description     TEXT,
+    recurrence_rule TEXT,
+    created_at      TIMESTAMPTZ,
+    updated_at      TIMESTAMPTZ,
+    deleted_at      TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS idx_schedule_events_course_id ON schedule_events (course_id);
+CREATE INDEX IF NOT EXISTS idx_schedule_events_deleted_at ON schedule_events (deleted_at);
+
+CREATE TABLE IF NOT EXISTS activities (
+    id          BIGSERIAL PRIMARY KEY,
+    user_id     BIGINT NOT NULL REFERENCES users (id),
+    title       TEXT NOT NULL,
+    category    TEXT,
+    date        TIMESTAMPTZ NOT NULL,
+    time        VARCHAR(10) NOT NULL,
+    duration    TEXT,
+    description TEXT,
+    created_at  TIMESTAMPTZ,
+    updated_at  TIMESTAMPTZ,
+    deleted_at  TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS idx_activities_user_id ON activities (user_id);
+CREATE INDEX IF NOT EXISTS idx_activities_deleted_at ON activities (deleted_at);
+
+-- System settings
+
+CREATE TABLE IF NOT EXISTS system_settings (
+    key        VARCHAR(100) PRIMARY KEY,
+    value      TEXT NOT NULL,
+    updated_by BIGINT,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ
+);
+
+CREATE TABLE IF NOT EXISTS setting_changes (
+    id         BIGSERIAL PRIMARY KEY,
+    key        VARCHAR(100) NOT NULL,
+    old_value  TEXT,
+    new_value  TEXT NOT NULL,
+    changed_by BIGINT NOT NULL,
+    changed_at TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_setting_changes_key ON setting_changes (key);
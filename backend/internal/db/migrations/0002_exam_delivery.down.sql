@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_exam_answers_attempt_question;
+DROP INDEX IF EXISTS idx_exam_attempts_status_deadline;
+DROP INDEX IF EXISTS idx_exam_attempts_exam_user_number;
+ALTER TABLE exam_attempts DROP COLUMN IF EXISTS deadline;
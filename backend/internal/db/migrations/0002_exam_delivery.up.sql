@@ -0,0 +1,9 @@
+-- Server-side deadline of exam attempts and idempotent answer autosave
+
+ALTER TABLE exam_attempts ADD COLUMN deadline TIMESTAMPTZ;
+UPDATE exam_attempts SET deadline = COALESCE(end_time, start_time) WHERE deadline IS NULL;
+
+CREATE UNIQUE INDEX idx_exam_attempts_exam_user_number ON exam_attempts (exam_id, user_id, attempt_number) WHERE deleted_at IS NULL;
+CREATE INDEX idx_exam_attempts_status_deadline ON exam_attempts (status, deadline);
+
+CREATE UNIQUE INDEX idx_exam_answers_attempt_question ON exam_answers (exam_attempt_id, question_id);
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_exam_attempts_grading_status;
+ALTER TABLE exam_answers DROP COLUMN IF EXISTS graded_at;
+ALTER TABLE exam_answers DROP COLUMN IF EXISTS graded_by;
+ALTER TABLE exam_answers DROP COLUMN IF EXISTS feedback;
+ALTER TABLE exam_answers DROP COLUMN IF EXISTS needs_manual_grading;
+ALTER TABLE exam_attempts DROP COLUMN IF EXISTS grading_status;
@@ -0,0 +1,22 @@
+-- Grading state of exam attempts and manual grading of answers
+
+ALTER TABLE exam_attempts ADD COLUMN grading_status VARCHAR(30);
+
+ALTER TABLE exam_answers ADD COLUMN needs_manual_grading BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE exam_answers ADD COLUMN feedback TEXT;
+ALTER TABLE exam_answers ADD COLUMN graded_by BIGINT;
+ALTER TABLE exam_answers ADD COLUMN graded_at TIMESTAMPTZ;
+
+-- Essays of attempts finished before grading was tracked still await a grade
+UPDATE exam_answers SET needs_manual_grading = true
+WHERE question_id IN (SELECT id FROM exam_questions WHERE type = 'essay')
+  AND exam_attempt_id IN (SELECT id FROM exam_attempts WHERE status <> 'in_progress');
+
+UPDATE exam_attempts SET grading_status = CASE
+    WHEN EXISTS (SELECT 1 FROM exam_answers a WHERE a.exam_attempt_id = exam_attempts.id AND a.needs_manual_grading)
+        THEN 'needs_manual_grading'
+    ELSE 'auto_graded'
+END
+WHERE status <> 'in_progress';
+
+CREATE INDEX idx_exam_attempts_grading_status ON exam_attempts (grading_status);
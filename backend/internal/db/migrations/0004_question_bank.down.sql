@@ -0,0 +1,12 @@
+ALTER TABLE exam_attempts DROP COLUMN IF EXISTS question_ids;
+ALTER TABLE exam_attempts DROP COLUMN IF EXISTS seed;
+
+DROP INDEX IF EXISTS idx_exam_questions_pool_bank_question;
+ALTER TABLE exam_questions DROP COLUMN IF EXISTS pool_id;
+ALTER TABLE exam_questions DROP COLUMN IF EXISTS bank_question_id;
+
+DROP TABLE IF EXISTS exam_question_pools;
+DROP TABLE IF EXISTS bank_question_outcomes;
+DROP TABLE IF EXISTS bank_question_tags;
+DROP TABLE IF EXISTS question_tags;
+DROP TABLE IF EXISTS bank_questions;
@@ -0,0 +1,64 @@
+-- Course question banks, exam question pools and per-attempt question drawing
+
+CREATE TABLE bank_questions (
+    id             BIGSERIAL PRIMARY KEY,
+    course_id      BIGINT NOT NULL REFERENCES courses (id),
+    type           VARCHAR(20) NOT NULL,
+    question       TEXT NOT NULL,
+    options        TEXT,
+    correct_answer TEXT,
+    points         DECIMAL NOT NULL,
+    difficulty     VARCHAR(10) DEFAULT 'medium',
+    created_by     BIGINT,
+    created_at     TIMESTAMPTZ,
+    updated_at     TIMESTAMPTZ,
+    deleted_at     TIMESTAMPTZ
+);
+CREATE INDEX idx_bank_questions_course_id ON bank_questions (course_id);
+CREATE INDEX idx_bank_questions_deleted_at ON bank_questions (deleted_at);
+
+CREATE TABLE question_tags (
+    id         BIGSERIAL PRIMARY KEY,
+    course_id  BIGINT NOT NULL REFERENCES courses (id),
+    name       VARCHAR(50) NOT NULL,
+    created_at TIMESTAMPTZ
+);
+CREATE UNIQUE INDEX idx_question_tags_course_name ON question_tags (course_id, name);
+
+CREATE TABLE bank_question_tags (
+    bank_question_id BIGINT NOT NULL REFERENCES bank_questions (id) ON DELETE CASCADE,
+    question_tag_id  BIGINT NOT NULL REFERENCES question_tags (id) ON DELETE CASCADE,
+    PRIMARY KEY (bank_question_id, question_tag_id)
+);
+CREATE INDEX idx_bank_question_tags_tag ON bank_question_tags (question_tag_id);
+
+CREATE TABLE bank_question_outcomes (
+    bank_question_id    BIGINT NOT NULL REFERENCES bank_questions (id) ON DELETE CASCADE,
+    learning_outcome_id BIGINT NOT NULL REFERENCES learning_outcomes (id) ON DELETE CASCADE,
+    PRIMARY KEY (bank_question_id, learning_outcome_id)
+);
+CREATE INDEX idx_bank_question_outcomes_outcome ON bank_question_outcomes (learning_outcome_id);
+
+CREATE TABLE exam_question_pools (
+    id                  BIGSERIAL PRIMARY KEY,
+    exam_id             BIGINT NOT NULL REFERENCES exams (id),
+    name                TEXT NOT NULL,
+    draw_count          BIGINT NOT NULL,
+    points              DECIMAL,
+    tag                 VARCHAR(50),
+    difficulty          VARCHAR(10),
+    learning_outcome_id BIGINT REFERENCES learning_outcomes (id),
+    created_at          TIMESTAMPTZ,
+    updated_at          TIMESTAMPTZ,
+    deleted_at          TIMESTAMPTZ
+);
+CREATE INDEX idx_exam_question_pools_exam_id ON exam_question_pools (exam_id);
+CREATE INDEX idx_exam_question_pools_deleted_at ON exam_question_pools (deleted_at);
+
+ALTER TABLE exam_questions ADD COLUMN bank_question_id BIGINT REFERENCES bank_questions (id);
+ALTER TABLE exam_questions ADD COLUMN pool_id BIGINT REFERENCES exam_question_pools (id);
+-- A pool draws each bank question once per exam; attempts share the copy
+CREATE UNIQUE INDEX idx_exam_questions_pool_bank_question ON exam_questions (pool_id, bank_question_id);
+
+ALTER TABLE exam_attempts ADD COLUMN seed BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE exam_attempts ADD COLUMN question_ids TEXT;
@@ -0,0 +1,9 @@
+DROP INDEX IF EXISTS idx_submissions_attempt;
+ALTER TABLE submissions DROP COLUMN IF EXISTS raw_score;
+ALTER TABLE submissions DROP COLUMN IF EXISTS late_penalty;
+ALTER TABLE submissions DROP COLUMN IF EXISTS late_days;
+ALTER TABLE submissions DROP COLUMN IF EXISTS is_late;
+ALTER TABLE submissions DROP COLUMN IF EXISTS checksum;
+ALTER TABLE submissions DROP COLUMN IF EXISTS content_type;
+ALTER TABLE assessments DROP COLUMN IF EXISTS max_late_penalty;
+ALTER TABLE assessments DROP COLUMN IF EXISTS late_penalty_per_day;
@@ -0,0 +1,17 @@
+-- Late penalties of assessments and stored files of submissions
+
+ALTER TABLE assessments ADD COLUMN late_penalty_per_day DECIMAL NOT NULL DEFAULT 0;
+ALTER TABLE assessments ADD COLUMN max_late_penalty DECIMAL NOT NULL DEFAULT 0;
+
+ALTER TABLE submissions ADD COLUMN content_type VARCHAR(100);
+ALTER TABLE submissions ADD COLUMN checksum VARCHAR(64);
+ALTER TABLE submissions ADD COLUMN is_late BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE submissions ADD COLUMN late_days BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE submissions ADD COLUMN late_penalty DECIMAL NOT NULL DEFAULT 0;
+ALTER TABLE submissions ADD COLUMN raw_score DECIMAL;
+
+-- Scores given before penalties existed were not reduced
+UPDATE submissions SET raw_score = score;
+UPDATE submissions SET is_late = true WHERE status = 'late';
+
+CREATE UNIQUE INDEX idx_submissions_attempt ON submissions (assessment_id, user_id, attempt_number);
@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS rubric_selections;
+DROP INDEX IF EXISTS idx_rubric_items_assessment_id;
+ALTER TABLE rubric_items DROP COLUMN IF EXISTS "order";
+ALTER TABLE rubric_items DROP COLUMN IF EXISTS poor_points;
+ALTER TABLE rubric_items DROP COLUMN IF EXISTS average_points;
+ALTER TABLE rubric_items DROP COLUMN IF EXISTS good_points;
+ALTER TABLE rubric_items DROP COLUMN IF EXISTS excellent_points;
@@ -0,0 +1,25 @@
+-- Points per rubric level and the levels chosen when grading submissions
+
+ALTER TABLE rubric_items ADD COLUMN excellent_points DECIMAL NOT NULL DEFAULT 4;
+ALTER TABLE rubric_items ADD COLUMN good_points DECIMAL NOT NULL DEFAULT 3;
+ALTER TABLE rubric_items ADD COLUMN average_points DECIMAL NOT NULL DEFAULT 2;
+ALTER TABLE rubric_items ADD COLUMN poor_points DECIMAL NOT NULL DEFAULT 1;
+ALTER TABLE rubric_items ADD COLUMN "order" BIGINT NOT NULL DEFAULT 0;
+CREATE INDEX IF NOT EXISTS idx_rubric_items_assessment_id ON rubric_items (assessment_id);
+
+CREATE TABLE rubric_selections (
+    id                  BIGSERIAL PRIMARY KEY,
+    submission_id       BIGINT NOT NULL REFERENCES submissions (id) ON DELETE CASCADE,
+    rubric_item_id      BIGINT NOT NULL REFERENCES rubric_items (id),
+    learning_outcome_id BIGINT NOT NULL REFERENCES learning_outcomes (id),
+    level               VARCHAR(20) NOT NULL,
+    points              DECIMAL,
+    max_points          DECIMAL,
+    criteria            TEXT,
+    comment             TEXT,
+    graded_by           BIGINT,
+    created_at          TIMESTAMPTZ,
+    updated_at          TIMESTAMPTZ
+);
+CREATE UNIQUE INDEX idx_rubric_selections_submission_item ON rubric_selections (submission_id, rubric_item_id);
+CREATE INDEX idx_rubric_selections_learning_outcome_id ON rubric_selections (learning_outcome_id);
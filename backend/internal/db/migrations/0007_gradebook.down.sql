@@ -0,0 +1,8 @@
+DROP TABLE IF EXISTS grade_scale_entries;
+DROP TABLE IF EXISTS grade_scales;
+DROP INDEX IF EXISTS idx_course_grades_course_user_unique;
+ALTER TABLE course_grades DROP COLUMN IF EXISTS counted_weight;
+ALTER TABLE course_grades DROP COLUMN IF EXISTS grade_points;
+ALTER TABLE grades DROP COLUMN IF EXISTS excused;
+ALTER TABLE grades DROP COLUMN IF EXISTS status;
+ALTER TABLE grades DROP COLUMN IF EXISTS exam_id;
@@ -0,0 +1,38 @@
+-- Weighted gradebook: one row per student and assessment or exam, one
+-- course grade per student, and letter-grade scales
+
+ALTER TABLE grades ADD COLUMN exam_id BIGINT REFERENCES exams (id);
+ALTER TABLE grades ADD COLUMN status VARCHAR(20) DEFAULT 'pending';
+ALTER TABLE grades ADD COLUMN excused BOOLEAN DEFAULT FALSE;
+
+ALTER TABLE course_grades ADD COLUMN grade_points DECIMAL DEFAULT 0;
+ALTER TABLE course_grades ADD COLUMN counted_weight DECIMAL DEFAULT 0;
+
+-- Keep the most recent course grade of each student before enforcing one
+UPDATE course_grades SET deleted_at = NOW()
+WHERE deleted_at IS NULL AND id NOT IN (
+    SELECT DISTINCT ON (course_id, user_id) id
+    FROM course_grades
+    WHERE deleted_at IS NULL
+    ORDER BY course_id, user_id, last_updated DESC NULLS LAST, id DESC
+);
+CREATE UNIQUE INDEX idx_course_grades_course_user_unique ON course_grades (course_id, user_id) WHERE deleted_at IS NULL;
+
+CREATE TABLE grade_scales (
+    id         BIGSERIAL PRIMARY KEY,
+    course_id  BIGINT REFERENCES courses (id) ON DELETE CASCADE,
+    name       TEXT NOT NULL,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ
+);
+CREATE UNIQUE INDEX idx_grade_scales_course_id ON grade_scales (course_id);
+CREATE UNIQUE INDEX idx_grade_scales_institution ON grade_scales ((course_id IS NULL)) WHERE course_id IS NULL;
+
+CREATE TABLE grade_scale_entries (
+    id             BIGSERIAL PRIMARY KEY,
+    grade_scale_id BIGINT NOT NULL REFERENCES grade_scales (id) ON DELETE CASCADE,
+    letter         VARCHAR(5) NOT NULL,
+    min_score      DECIMAL NOT NULL,
+    grade_points   DECIMAL NOT NULL
+);
+CREATE INDEX idx_grade_scale_entries_grade_scale_id ON grade_scale_entries (grade_scale_id);
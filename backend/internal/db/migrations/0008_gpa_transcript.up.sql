@@ -0,0 +1,3 @@
+-- Credit units weighting courses in semester and cumulative GPAs
+
+ALTER TABLE courses ADD COLUMN credits DECIMAL(4,1) NOT NULL DEFAULT 3;
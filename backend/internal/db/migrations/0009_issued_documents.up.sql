@@ -0,0 +1,18 @@
+-- Issued PDF documents, verifiable by the code printed on them
+
+CREATE TABLE issued_documents (
+    id            BIGSERIAL PRIMARY KEY,
+    code          VARCHAR(32) NOT NULL,
+    type          VARCHAR(20) NOT NULL,
+    user_id       BIGINT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    title         TEXT NOT NULL,
+    checksum      VARCHAR(64) NOT NULL,
+    size          BIGINT,
+    issued_by     BIGINT,
+    issued_at     TIMESTAMPTZ NOT NULL,
+    valid_until   TIMESTAMPTZ,
+    revoked_at    TIMESTAMPTZ,
+    revoke_reason TEXT
+);
+CREATE UNIQUE INDEX idx_issued_documents_code ON issued_documents (code);
+CREATE INDEX idx_issued_documents_user_id ON issued_documents (user_id);
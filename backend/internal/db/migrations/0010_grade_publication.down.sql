@@ -0,0 +1,10 @@
+DROP TABLE IF EXISTS grade_amendments;
+DROP TABLE IF EXISTS grade_moderations;
+
+ALTER TABLE courses DROP COLUMN IF EXISTS grades_release_at;
+
+ALTER TABLE course_grades DROP COLUMN IF EXISTS published_at;
+ALTER TABLE course_grades DROP COLUMN IF EXISTS publication_status;
+
+ALTER TABLE grades DROP COLUMN IF EXISTS published_at;
+ALTER TABLE grades DROP COLUMN IF EXISTS publication_status;
@@ -0,0 +1,59 @@
+-- Grade publication workflow: grades move from draft through submission and
+-- approval to publication, published grades are released to students on the
+-- course's release date and changed only through amendments
+
+ALTER TABLE grades ADD COLUMN publication_status VARCHAR(20) NOT NULL DEFAULT 'draft';
+ALTER TABLE grades ADD COLUMN published_at TIMESTAMPTZ;
+
+ALTER TABLE course_grades ADD COLUMN publication_status VARCHAR(20) NOT NULL DEFAULT 'draft';
+ALTER TABLE course_grades ADD COLUMN published_at TIMESTAMPTZ;
+
+ALTER TABLE courses ADD COLUMN grades_release_at TIMESTAMPTZ;
+
+-- Grades of completed enrollments were final before the workflow existed
+UPDATE course_grades SET publication_status = 'published', published_at = NOW()
+WHERE EXISTS (
+    SELECT 1 FROM course_students cs
+    WHERE cs.course_id = course_grades.course_id AND cs.user_id = course_grades.user_id
+      AND cs.status = 'completed' AND cs.deleted_at IS NULL
+);
+UPDATE grades SET publication_status = 'published', published_at = cg.published_at
+FROM course_grades cg
+WHERE cg.course_id = grades.course_id AND cg.user_id = grades.user_id
+  AND cg.publication_status = 'published' AND cg.deleted_at IS NULL;
+
+CREATE TABLE grade_moderations (
+    id          BIGSERIAL PRIMARY KEY,
+    course_id   BIGINT NOT NULL REFERENCES courses (id) ON DELETE CASCADE,
+    action      VARCHAR(20) NOT NULL,
+    from_status VARCHAR(20),
+    to_status   VARCHAR(20),
+    count       INTEGER,
+    actor_id    BIGINT REFERENCES users (id),
+    comment     TEXT,
+    created_at  TIMESTAMPTZ
+);
+CREATE INDEX idx_grade_moderations_course_id ON grade_moderations (course_id);
+
+CREATE TABLE grade_amendments (
+    id                BIGSERIAL PRIMARY KEY,
+    grade_id          BIGINT NOT NULL REFERENCES grades (id),
+    course_grade_id   BIGINT NOT NULL REFERENCES course_grades (id),
+    course_id         BIGINT NOT NULL REFERENCES courses (id) ON DELETE CASCADE,
+    user_id           BIGINT NOT NULL REFERENCES users (id),
+    old_status        VARCHAR(20),
+    new_status        VARCHAR(20),
+    old_score         DECIMAL,
+    new_score         DECIMAL,
+    old_letter_grade  VARCHAR(5),
+    new_letter_grade  VARCHAR(5),
+    old_overall_score DECIMAL,
+    new_overall_score DECIMAL,
+    old_course_letter VARCHAR(5),
+    new_course_letter VARCHAR(5),
+    reason            TEXT NOT NULL,
+    amended_by        BIGINT REFERENCES users (id),
+    created_at        TIMESTAMPTZ
+);
+CREATE INDEX idx_grade_amendments_grade_id ON grade_amendments (grade_id);
+CREATE INDEX idx_grade_amendments_course_id ON grade_amendments (course_id);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS regrade_events;
+DROP TABLE IF EXISTS regrade_requests;
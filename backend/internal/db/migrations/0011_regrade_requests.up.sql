@@ -0,0 +1,44 @@
+-- Regrade requests: students appeal the score of a submission or exam
+-- attempt, with the full history of every request
+
+CREATE TABLE regrade_requests (
+    id              BIGSERIAL PRIMARY KEY,
+    course_id       BIGINT NOT NULL REFERENCES courses (id) ON DELETE CASCADE,
+    user_id         BIGINT NOT NULL REFERENCES users (id),
+    item_type       VARCHAR(20) NOT NULL,
+    item_id         BIGINT NOT NULL,
+    item_title      TEXT,
+    submission_id   BIGINT REFERENCES submissions (id),
+    exam_attempt_id BIGINT REFERENCES exam_attempts (id),
+    assignee_id     BIGINT,
+    status          VARCHAR(20) NOT NULL,
+    justification   TEXT NOT NULL,
+    original_score  DECIMAL,
+    new_score       DECIMAL,
+    response        TEXT,
+    resolved_by     BIGINT,
+    resolved_at     TIMESTAMPTZ,
+    created_at      TIMESTAMPTZ,
+    updated_at      TIMESTAMPTZ,
+    CHECK ((submission_id IS NULL) <> (exam_attempt_id IS NULL))
+);
+CREATE INDEX idx_regrade_requests_course_id ON regrade_requests (course_id);
+CREATE INDEX idx_regrade_requests_user_id ON regrade_requests (user_id);
+CREATE INDEX idx_regrade_requests_assignee_id ON regrade_requests (assignee_id);
+-- At most one request per piece of work awaits a decision
+CREATE UNIQUE INDEX idx_regrade_requests_active_submission ON regrade_requests (submission_id)
+    WHERE status IN ('open', 'under_review') AND submission_id IS NOT NULL;
+CREATE UNIQUE INDEX idx_regrade_requests_active_attempt ON regrade_requests (exam_attempt_id)
+    WHERE status IN ('open', 'under_review') AND exam_attempt_id IS NOT NULL;
+
+CREATE TABLE regrade_events (
+    id          BIGSERIAL PRIMARY KEY,
+    request_id  BIGINT NOT NULL REFERENCES regrade_requests (id) ON DELETE CASCADE,
+    from_status VARCHAR(20),
+    to_status   VARCHAR(20) NOT NULL,
+    actor_id    BIGINT,
+    note        TEXT,
+    score       DECIMAL,
+    created_at  TIMESTAMPTZ
+);
+CREATE INDEX idx_regrade_events_request_id ON regrade_events (request_id);
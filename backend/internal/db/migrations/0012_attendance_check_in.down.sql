@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS attendance_changes;
+DROP TABLE IF EXISTS attendance_windows;
+
+ALTER TABLE attendances DROP COLUMN IF EXISTS recorded_by;
+ALTER TABLE attendances DROP COLUMN IF EXISTS source;
+
+DROP INDEX IF EXISTS idx_attendances_session_user;
@@ -0,0 +1,44 @@
+-- Attendance check-in: QR check-in windows per session, one attendance
+-- record per student and session, and the history of status changes
+
+-- Keep the latest record where a student was recorded twice for a session
+DELETE FROM attendances a
+USING attendances b
+WHERE a.session_id = b.session_id AND a.user_id = b.user_id AND a.id < b.id;
+
+CREATE UNIQUE INDEX idx_attendances_session_user ON attendances (session_id, user_id);
+
+ALTER TABLE attendances ADD COLUMN source VARCHAR(20);
+ALTER TABLE attendances ADD COLUMN recorded_by BIGINT;
+
+UPDATE attendances SET source = 'manual';
+
+CREATE TABLE attendance_windows (
+    id         BIGSERIAL PRIMARY KEY,
+    session_id BIGINT NOT NULL REFERENCES sessions (id) ON DELETE CASCADE,
+    course_id  BIGINT NOT NULL REFERENCES courses (id) ON DELETE CASCADE,
+    secret     VARCHAR(64) NOT NULL,
+    opened_by  BIGINT NOT NULL,
+    opened_at  TIMESTAMPTZ NOT NULL,
+    late_after TIMESTAMPTZ NOT NULL,
+    closes_at  TIMESTAMPTZ NOT NULL,
+    closed_at  TIMESTAMPTZ,
+    closed_by  BIGINT
+);
+CREATE INDEX idx_attendance_windows_session_id ON attendance_windows (session_id);
+CREATE INDEX idx_attendance_windows_course_id ON attendance_windows (course_id);
+
+CREATE TABLE attendance_changes (
+    id            BIGSERIAL PRIMARY KEY,
+    attendance_id BIGINT NOT NULL REFERENCES attendances (id) ON DELETE CASCADE,
+    session_id    BIGINT NOT NULL REFERENCES sessions (id) ON DELETE CASCADE,
+    user_id       BIGINT NOT NULL,
+    old_status    VARCHAR(20),
+    new_status    VARCHAR(20) NOT NULL,
+    source        VARCHAR(20) NOT NULL,
+    reason        TEXT,
+    changed_by    BIGINT,
+    changed_at    TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX idx_attendance_changes_attendance_id ON attendance_changes (attendance_id);
+CREATE INDEX idx_attendance_changes_session_id ON attendance_changes (session_id);
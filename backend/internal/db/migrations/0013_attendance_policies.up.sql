@@ -0,0 +1,16 @@
+-- Attendance policies: the attendance rate a course requires and the exam
+-- types it gates; courses without one follow the institution's default
+
+CREATE TABLE attendance_policies (
+    id               BIGSERIAL PRIMARY KEY,
+    course_id        BIGINT NOT NULL REFERENCES courses (id) ON DELETE CASCADE,
+    minimum_rate     DECIMAL NOT NULL,
+    warning_margin   DECIMAL NOT NULL,
+    late_credit      DECIMAL NOT NULL,
+    excused_mode     VARCHAR(20) NOT NULL,
+    gated_exam_types TEXT,
+    updated_by       BIGINT,
+    created_at       TIMESTAMPTZ,
+    updated_at       TIMESTAMPTZ
+);
+CREATE UNIQUE INDEX idx_attendance_policies_course_id ON attendance_policies (course_id);
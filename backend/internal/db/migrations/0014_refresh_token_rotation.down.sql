@@ -0,0 +1,19 @@
+-- Stored hashes cannot be turned back into tokens, so every session is
+-- signed out
+DELETE FROM refresh_tokens;
+
+DROP INDEX IF EXISTS idx_refresh_tokens_user_id;
+DROP INDEX IF EXISTS idx_refresh_tokens_family_id;
+DROP INDEX IF EXISTS idx_refresh_tokens_token_hash;
+
+ALTER TABLE refresh_tokens ADD COLUMN token TEXT NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_refresh_tokens_token ON refresh_tokens (token);
+
+ALTER TABLE refresh_tokens DROP COLUMN IF EXISTS replaced_by_id;
+ALTER TABLE refresh_tokens DROP COLUMN IF EXISTS rotated_at;
+ALTER TABLE refresh_tokens DROP COLUMN IF EXISTS last_used_at;
+ALTER TABLE refresh_tokens DROP COLUMN IF EXISTS authenticated_at;
+ALTER TABLE refresh_tokens DROP COLUMN IF EXISTS ip_address;
+ALTER TABLE refresh_tokens DROP COLUMN IF EXISTS user_agent;
+ALTER TABLE refresh_tokens DROP COLUMN IF EXISTS family_id;
+ALTER TABLE refresh_tokens DROP COLUMN IF EXISTS token_hash;
@@ -0,0 +1,31 @@
+-- Refresh token rotation: tokens are stored as SHA-256 hashes and grouped
+-- into families, one per signed-in session, with the client they were
+-- issued to
+
+ALTER TABLE refresh_tokens ADD COLUMN token_hash VARCHAR(64);
+ALTER TABLE refresh_tokens ADD COLUMN family_id VARCHAR(64);
+ALTER TABLE refresh_tokens ADD COLUMN user_agent TEXT;
+ALTER TABLE refresh_tokens ADD COLUMN ip_address VARCHAR(64);
+ALTER TABLE refresh_tokens ADD COLUMN authenticated_at TIMESTAMPTZ;
+ALTER TABLE refresh_tokens ADD COLUMN last_used_at TIMESTAMPTZ;
+ALTER TABLE refresh_tokens ADD COLUMN rotated_at TIMESTAMPTZ;
+ALTER TABLE refresh_tokens ADD COLUMN replaced_by_id BIGINT;
+
+-- Existing tokens keep working, each as a session of its own
+UPDATE refresh_tokens SET
+    token_hash = encode(sha256(convert_to(token, 'UTF8')), 'hex'),
+    family_id = md5(id::text || random()::text),
+    authenticated_at = COALESCE(created_at, NOW()),
+    last_used_at = COALESCE(created_at, NOW());
+
+ALTER TABLE refresh_tokens ALTER COLUMN token_hash SET NOT NULL;
+ALTER TABLE refresh_tokens ALTER COLUMN family_id SET NOT NULL;
+ALTER TABLE refresh_tokens ALTER COLUMN authenticated_at SET NOT NULL;
+ALTER TABLE refresh_tokens ALTER COLUMN last_used_at SET NOT NULL;
+
+DROP INDEX IF EXISTS idx_refresh_tokens_token;
+ALTER TABLE refresh_tokens DROP COLUMN token;
+
+CREATE UNIQUE INDEX idx_refresh_tokens_token_hash ON refresh_tokens (token_hash);
+CREATE INDEX idx_refresh_tokens_family_id ON refresh_tokens (family_id);
+CREATE INDEX idx_refresh_tokens_user_id ON refresh_tokens (user_id);
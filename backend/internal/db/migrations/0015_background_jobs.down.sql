@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_attendance_windows_unclosed;
+DROP INDEX IF EXISTS idx_refresh_tokens_expires_at;
@@ -0,0 +1,5 @@
+-- Background jobs: indexes for the janitor's periodic sweeps over expired
+-- refresh tokens and check-in windows left open
+
+CREATE INDEX idx_refresh_tokens_expires_at ON refresh_tokens (expires_at);
+CREATE INDEX idx_attendance_windows_unclosed ON attendance_windows (closes_at) WHERE closed_at IS NULL;
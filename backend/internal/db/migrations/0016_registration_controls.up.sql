@@ -0,0 +1,21 @@
+-- Self-registration controls: users verify their email address before
+-- signing in, and instructor and admin accounts come from invitations
+
+ALTER TABLE users ADD COLUMN email_verified_at TIMESTAMPTZ;
+
+-- Existing accounts keep signing in as before
+UPDATE users SET email_verified_at = COALESCE(created_at, NOW());
+
+CREATE TABLE invitations (
+    id          BIGSERIAL PRIMARY KEY,
+    email       TEXT NOT NULL,
+    role        user_role NOT NULL,
+    token_hash  VARCHAR(64) NOT NULL,
+    invited_by  BIGINT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    expires_at  TIMESTAMPTZ NOT NULL,
+    accepted_at TIMESTAMPTZ,
+    accepted_by BIGINT REFERENCES users (id) ON DELETE SET NULL,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE UNIQUE INDEX idx_invitations_token_hash ON invitations (token_hash);
+CREATE INDEX idx_invitations_email ON invitations (email);
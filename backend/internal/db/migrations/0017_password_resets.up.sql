@@ -0,0 +1,23 @@
+-- Password reset: single-use tokens stored as SHA-256 hashes, and a log of
+-- reset requests to rate limit them per address and per client
+
+CREATE TABLE password_reset_tokens (
+    id           BIGSERIAL PRIMARY KEY,
+    user_id      BIGINT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    token_hash   VARCHAR(64) NOT NULL,
+    requested_ip VARCHAR(64),
+    expires_at   TIMESTAMPTZ NOT NULL,
+    used_at      TIMESTAMPTZ,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE UNIQUE INDEX idx_password_reset_tokens_token_hash ON password_reset_tokens (token_hash);
+CREATE INDEX idx_password_reset_tokens_user_id ON password_reset_tokens (user_id);
+
+CREATE TABLE password_reset_attempts (
+    id         BIGSERIAL PRIMARY KEY,
+    email      TEXT NOT NULL,
+    ip_address VARCHAR(64) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX idx_password_reset_attempts_email ON password_reset_attempts (email, created_at);
+CREATE INDEX idx_password_reset_attempts_ip_address ON password_reset_attempts (ip_address, created_at);
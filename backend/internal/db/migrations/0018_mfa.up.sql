@@ -0,0 +1,34 @@
+-- Two-factor authentication: TOTP authenticators with encrypted secrets,
+-- hashed single-use recovery codes, and the challenges of two-step sign-ins
+
+CREATE TABLE mfa_enrollments (
+    id           BIGSERIAL PRIMARY KEY,
+    user_id      BIGINT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    secret       TEXT NOT NULL,
+    confirmed_at TIMESTAMPTZ,
+    last_step    BIGINT NOT NULL DEFAULT 0,
+    created_at   TIMESTAMPTZ,
+    updated_at   TIMESTAMPTZ
+);
+CREATE UNIQUE INDEX idx_mfa_enrollments_user_id ON mfa_enrollments (user_id);
+
+CREATE TABLE mfa_recovery_codes (
+    id         BIGSERIAL PRIMARY KEY,
+    user_id    BIGINT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    code_hash  VARCHAR(64) NOT NULL,
+    used_at    TIMESTAMPTZ,
+    created_at TIMESTAMPTZ
+);
+CREATE INDEX idx_mfa_recovery_codes_user_id ON mfa_recovery_codes (user_id);
+
+CREATE TABLE mfa_challenges (
+    id         BIGSERIAL PRIMARY KEY,
+    user_id    BIGINT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    token_hash VARCHAR(64) NOT NULL,
+    attempts   INTEGER NOT NULL DEFAULT 0,
+    expires_at TIMESTAMPTZ NOT NULL,
+    used_at    TIMESTAMPTZ,
+    created_at TIMESTAMPTZ
+);
+CREATE UNIQUE INDEX idx_mfa_challenges_token_hash ON mfa_challenges (token_hash);
+CREATE INDEX idx_mfa_challenges_user_id ON mfa_challenges (user_id);
@@ -0,0 +1 @@
+ALTER TABLE exam_attempts DROP COLUMN IF EXISTS score_override;
@@ -0,0 +1,11 @@
+-- Exam scores set by accepted regrade requests, kept when answers are
+-- graded again later
+
+ALTER TABLE exam_attempts ADD COLUMN score_override DECIMAL;
+
+UPDATE exam_attempts SET score_override = (
+    SELECT r.new_score FROM regrade_requests r
+    WHERE r.exam_attempt_id = exam_attempts.id AND r.status = 'accepted'
+    ORDER BY r.resolved_at DESC
+    LIMIT 1
+);
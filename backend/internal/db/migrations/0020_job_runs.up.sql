@@ -0,0 +1,8 @@
+-- The last scheduled time each background job ran for, so that a replica
+-- reaching a tick after another has finished it does not run it again
+
+CREATE TABLE job_runs (
+    name       VARCHAR(100) PRIMARY KEY,
+    last_run   TIMESTAMPTZ NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
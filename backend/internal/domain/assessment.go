@@ -25,6 +25,8 @@ type Assessment struct {
 	RandomizeQuestions bool        `json:"randomizeQuestions" gorm:"default:false"`
 	AllowedFileTypes string        `json:"allowedFileTypes"`
 	MaxFileSize     int64          `json:"maxFileSize"`
+	LatePenaltyPerDay float64      `json:"latePenaltyPerDay"` // percent of the score deducted per started day past DueDate
+	MaxLatePenalty  float64        `json:"maxLatePenalty"` // cap of the late penalty in percent; 0 means no cap
 	Submissions     []Submission   `json:"submissions,omitempty" gorm:"foreignKey:AssessmentID"`
 	RubricItems     []RubricItem   `json:"rubricItems,omitempty" gorm:"foreignKey:AssessmentID"`
 	CreatedAt       time.Time      `json:"createdAt"`
@@ -42,13 +44,21 @@ type Submission struct {
 	FilePath        string         `json:"filePath"`
 	FileName        string         `json:"fileName"`
 	FileSize        int64          `json:"fileSize"`
+	ContentType     string         `json:"contentType" gorm:"type:varchar(100)"` // detected from the file's content
+	Checksum        string         `json:"checksum" gorm:"type:varchar(64)"` // hex SHA-256 of the file
 	SubmissionDate  time.Time      `json:"submissionDate"`
 	Status          string         `json:"status" gorm:"type:varchar(20);default:'submitted'"` // submitted, graded, late, failed
+	IsLate          bool           `json:"isLate" gorm:"default:false"`
+	LateDays        int            `json:"lateDays"` // started days past the due date
+	LatePenalty     float64        `json:"latePenalty"` // percent deducted from the raw score
+	RawScore        float64        `json:"rawScore"` // score before the late penalty
 	Score           float64        `json:"score"`
 	Feedback        string         `json:"feedback"`
 	GradedBy        uint           `json:"gradedBy"`
 	GradedAt        *time.Time     `json:"gradedAt"`
 	AttemptNumber   int            `json:"attemptNumber" gorm:"default:1"`
+	RubricSelections []RubricSelection `json:"rubricSelections,omitempty" gorm:"foreignKey:SubmissionID"`
+	ResultsHidden   bool           `json:"resultsHidden,omitempty" gorm:"-"` // set when shown to the student before its grades are released
 	CreatedAt       time.Time      `json:"createdAt"`
 	UpdatedAt       time.Time      `json:"updatedAt"`
 	DeletedAt       gorm.DeletedAt `json:"-" gorm:"index"`
@@ -66,6 +76,11 @@ type RubricItem struct {
 	GoodCriteria    string         `json:"goodCriteria"`
 	AverageCriteria string         `json:"averageCriteria"`
 	PoorCriteria    string         `json:"poorCriteria"`
+	ExcellentPoints float64        `json:"excellentPoints"`
+	GoodPoints      float64        `json:"goodPoints"`
+	AveragePoints   float64        `json:"averagePoints"`
+	PoorPoints      float64        `json:"poorPoints"`
+	Order           int            `json:"order"`
 	CreatedAt       time.Time      `json:"createdAt"`
 	UpdatedAt       time.Time      `json:"updatedAt"`
 	DeletedAt       gorm.DeletedAt `json:"-" gorm:"index"`
@@ -101,12 +116,14 @@ type ExamQuestion struct {
 	ID              uint           `json:"id" gorm:"primaryKey"`
 	ExamID          uint           `json:"examId" gorm:"not null"`
 	Exam            Exam           `json:"-" gorm:"foreignKey:ExamID"`
-	Type            string         `json:"type" gorm:"type:varchar(20);not null"` // multiple_choice, true_false, essay
+	Type            string         `json:"type" gorm:"type:varchar(20);not null"` // multiple_choice, multi_select, true_false, numeric, short_answer, essay
 	Question        string         `json:"question" gorm:"not null"`
 	Options         string         `json:"options"`
 	CorrectAnswer   string         `json:"correctAnswer"`
 	Points          float64        `json:"points" gorm:"not null"`
 	Order           int            `json:"order"`
+	BankQuestionID  *uint          `json:"bankQuestionId,omitempty" gorm:"uniqueIndex:idx_exam_questions_pool_bank_question"` // set when copied from the question bank
+	PoolID          *uint          `json:"poolId,omitempty" gorm:"uniqueIndex:idx_exam_questions_pool_bank_question"` // set when drawn by a question pool
 	CreatedAt       time.Time      `json:"createdAt"`
 	UpdatedAt       time.Time      `json:"updatedAt"`
 	DeletedAt       gorm.DeletedAt `json:"-" gorm:"index"`
@@ -120,10 +137,15 @@ type ExamAttempt struct {
 	UserID          uint           `json:"userId" gorm:"not null"`
 	User            User           `json:"user" gorm:"foreignKey:UserID"`
 	StartTime       time.Time      `json:"startTime"`
+	Deadline        time.Time      `json:"deadline"` // server-side end of the attempt's time limit
 	EndTime         *time.Time     `json:"endTime"`
 	Score           float64        `json:"score"`
+	ScoreOverride   *float64       `json:"scoreOverride"` // set by an accepted regrade; the score stays at it when answers are graded again
 	Status          string         `json:"status" gorm:"type:varchar(20);default:'in_progress'"` // in_progress, completed, timed_out
+	GradingStatus   string         `json:"gradingStatus" gorm:"type:varchar(30)"` // auto_graded, needs_manual_grading, graded
 	AttemptNumber   int            `json:"attemptNumber" gorm:"default:1"`
+	Seed            int64          `json:"seed"` // drives question drawing and shuffling of this attempt
+	QuestionIDs     string         `json:"questionIds" gorm:"type:text"` // JSON array of the drawn questions in delivery order
 	Answers         []ExamAnswer   `json:"answers,omitempty" gorm:"foreignKey:ExamAttemptID"`
 	CreatedAt       time.Time      `json:"createdAt"`
 	UpdatedAt       time.Time      `json:"updatedAt"`
@@ -133,13 +155,17 @@ type ExamAttempt struct {
 // ExamAnswer represents a student's answer to an exam question
 type ExamAnswer struct {
 	ID              uint           `json:"id" gorm:"primaryKey"`
-	ExamAttemptID   uint           `json:"examAttemptId" gorm:"not null"`
+	ExamAttemptID   uint           `json:"examAttemptId" gorm:"not null;uniqueIndex:idx_exam_answers_attempt_question"`
 	ExamAttempt     ExamAttempt    `json:"-" gorm:"foreignKey:ExamAttemptID"`
-	QuestionID      uint           `json:"questionId" gorm:"not null"`
+	QuestionID      uint           `json:"questionId" gorm:"not null;uniqueIndex:idx_exam_answers_attempt_question"`
 	Question        ExamQuestion   `json:"question" gorm:"foreignKey:QuestionID"`
 	Answer          string         `json:"answer"`
 	IsCorrect       bool           `json:"isCorrect"`
 	Points          float64        `json:"points"`
+	NeedsManualGrading bool        `json:"needsManualGrading" gorm:"default:false"`
+	Feedback        string         `json:"feedback"`
+	GradedBy        *uint          `json:"gradedBy"`
+	GradedAt        *time.Time     `json:"gradedAt"`
 	CreatedAt       time.Time      `json:"createdAt"`
 	UpdatedAt       time.Time      `json:"updatedAt"`
 	DeletedAt       gorm.DeletedAt `json:"-" gorm:"index"`
@@ -152,12 +178,17 @@ type Grade struct {
 	Course          Course         `json:"-" gorm:"foreignKey:CourseID"`
 	UserID          uint           `json:"userId" gorm:"not null"`
 	User            User           `json:"user" gorm:"foreignKey:UserID"`
-	AssessmentID    uint           `json:"assessmentId"`
-	Assessment      Assessment     `json:"assessment" gorm:"foreignKey:AssessmentID"`
+	AssessmentID    *uint          `json:"assessmentId"`
+	Assessment      *Assessment    `json:"assessment,omitempty" gorm:"foreignKey:AssessmentID"`
+	ExamID          *uint          `json:"examId"`
 	Title           string         `json:"title" gorm:"not null"`
 	Weight          float64        `json:"weight" gorm:"not null"`
+	Status          string         `json:"status" gorm:"type:varchar(20);default:'pending'"` // graded, pending, missing, excused
+	Excused         bool           `json:"excused" gorm:"default:false"` // set by staff, kept across recalculations
 	Score           float64        `json:"score"`
 	LetterGrade     string         `json:"letterGrade" gorm:"type:varchar(5)"`
+	PublicationStatus string       `json:"publicationStatus" gorm:"type:varchar(20);default:'draft'"` // draft, submitted, approved, published
+	PublishedAt     *time.Time     `json:"publishedAt"`
 	LastUpdated     time.Time      `json:"lastUpdated"`
 	CreatedAt       time.Time      `json:"createdAt"`
 	UpdatedAt       time.Time      `json:"updatedAt"`
@@ -173,6 +204,10 @@ type CourseGrade struct {
 	User            User           `json:"user" gorm:"foreignKey:UserID"`
 	OverallScore    float64        `json:"overallScore"`
 	LetterGrade     string         `json:"letterGrade" gorm:"type:varchar(5)"`
+	GradePoints     float64        `json:"gradePoints"`
+	CountedWeight   float64        `json:"countedWeight"` // weight of the items the overall score is based on
+	PublicationStatus string       `json:"publicationStatus" gorm:"type:varchar(20);default:'draft'"` // draft, submitted, approved, published
+	PublishedAt     *time.Time     `json:"publishedAt"`
 	LastUpdated     time.Time      `json:"lastUpdated"`
 	CreatedAt       time.Time      `json:"createdAt"`
 	UpdatedAt       time.Time      `json:"updatedAt"`
@@ -195,6 +230,8 @@ type CreateAssessmentRequest struct {
 	RandomizeQuestions bool   `json:"randomizeQuestions"`
 	AllowedFileTypes string   `json:"allowedFileTypes"`
 	MaxFileSize     int64     `json:"maxFileSize" validate:"min=0"`
+	LatePenaltyPerDay float64 `json:"latePenaltyPerDay" validate:"min=0,max=100"`
+	MaxLatePenalty  float64   `json:"maxLatePenalty" validate:"min=0,max=100"`
 }
 
 // SubmitAssessmentRequest represents a request to submit an assessment
@@ -204,9 +241,12 @@ type SubmitAssessmentRequest struct {
 	AttemptNumber   int       `json:"attemptNumber" validate:"min=1"`
 }
 
-// GradeSubmissionRequest represents a request to grade a submission
+// GradeSubmissionRequest represents a request to grade a submission.
+// Submissions of assessments with a rubric are graded by choosing a level
+// for every rubric item; Score is then computed and ignored.
 type GradeSubmissionRequest struct {
-	SubmissionID    uint      `json:"submissionId" validate:"required"`
-	Score           float64   `json:"score" validate:"required,min=0,max=100"`
+	SubmissionID    uint      `json:"submissionId"`
+	Score           float64   `json:"score" validate:"min=0,max=100"`
 	Feedback        string    `json:"feedback"`
+	Rubric          []RubricSelectionRequest `json:"rubric" validate:"dive"`
 }
\ No newline at end of file
@@ -0,0 +1,299 @@
+package domain
+
+import (
+	"encoding/json"
+	"math"
+	"time"
+)
+
+// Attendance statuses
+const (
+	AttendancePresent = "present"
+	AttendanceAbsent  = "absent"
+	AttendanceLate    = "late"
+	AttendanceExcused = "excused"
+)
+
+// Attendance sources: how a record was last set
+const (
+	AttendanceSourceCheckIn = "check_in" // the student scanned the session's QR code
+	AttendanceSourceManual  = "manual"   // course staff set the status
+	AttendanceSourceClosing = "closing"  // marked absent when the check-in window closed
+)
+
+// AttendanceWindow is a check-in window opened by course staff for a
+// session. While it is open, students check in with a token that rotates
+// every 30 seconds and is signed with the window's secret; those who check
+// in after LateAfter are marked late.
+type AttendanceWindow struct {
+	ID        uint       `json:"id" gorm:"primaryKey"`
+	SessionID uint       `json:"sessionId" gorm:"not null;index"`
+	CourseID  uint       `json:"courseId" gorm:"not null;index"`
+	Secret    string     `json:"-" gorm:"type:varchar(64);not null"`
+	OpenedBy  uint       `json:"openedBy" gorm:"not null"`
+	OpenedAt  time.Time  `json:"openedAt" gorm:"not null"`
+	LateAfter time.Time  `json:"lateAfter" gorm:"not null"`
+	ClosesAt  time.Time  `json:"closesAt" gorm:"not null"`
+	ClosedAt  *time.Time `json:"closedAt"`
+	ClosedBy  *uint      `json:"closedBy"`
+}
+
+// OpenAt reports whether students can check in at the given time
+func (w *AttendanceWindow) OpenAt(now time.Time) bool {
+	return w.ClosedAt == nil && !now.Before(w.OpenedAt) && now.Before(w.ClosesAt)
+}
+
+// AttendanceChange records a change of a student's attendance status for a
+// session, with the reason given by course staff for manual overrides
+type AttendanceChange struct {
+	ID           uint      `json:"id" gorm:"primaryKey"`
+	AttendanceID uint      `json:"attendanceId" gorm:"not null;index"`
+	SessionID    uint      `json:"sessionId" gorm:"not null;index"`
+	UserID       uint      `json:"userId" gorm:"not null"`
+	OldStatus    string    `json:"oldStatus" gorm:"type:varchar(20)"`
+	NewStatus    string    `json:"newStatus" gorm:"type:varchar(20);not null"`
+	Source       string    `json:"source" gorm:"type:varchar(20);not null"`
+	Reason       string    `json:"reason" gorm:"type:text"`
+	ChangedBy    uint      `json:"changedBy"`
+	ChangedAt    time.Time `json:"changedAt" gorm:"not null"`
+}
+
+// OpenCheckInRequest opens a check-in window for a session. Durations are
+// in minutes; the late threshold defaults to the attendance_late_minutes
+// setting.
+type OpenCheckInRequest struct {
+	DurationMinutes  int  `json:"durationMinutes" validate:"omitempty,min=1,max=240"`
+	LateAfterMinutes *int `json:"lateAfterMinutes" validate:"omitempty,min=0,max=240"`
+}
+
+// CheckInToken is the current token of an open check-in window, to be shown
+// as a QR code. A token stays valid for one period after it is replaced, so
+// scans made just before the code rotates still count.
+//
+// QRPayload is a link to the check-in endpoint that only carries the token:
+// opening it in a browser does not check anyone in. The app that scans it
+// POSTs it, or the token taken from it, to /api/v1/attendance/check-in with
+// the student's bearer token.
+type CheckInToken struct {
+	WindowID  uint      `json:"windowId"`
+	Token     string    `json:"token"`
+	QRPayload string    `json:"qrPayload"`
+	ExpiresAt time.Time `json:"expiresAt"`
+	LateAfter time.Time `json:"lateAfter"`
+	ClosesAt  time.Time `json:"closesAt"`
+}
+
+// CheckInRequest checks the current student in with a scanned token
+type CheckInRequest struct {
+	Token string `json:"token" validate:"required"`
+}
+
+// AttendanceOverride sets one student's status for a session; Reason
+// falls back to the reason of the whole request
+type AttendanceOverride struct {
+	UserID  uint   `json:"userId" validate:"required"`
+	Status  string `json:"status" validate:"required,oneof=present absent late excused"`
+	Reason  string `json:"reason" validate:"max=1000"`
+	Comment string `json:"comment" validate:"max=1000"`
+}
+
+// OverrideAttendanceRequest sets the statuses of students for a session in bulk
+type OverrideAttendanceRequest struct {
+	Records []AttendanceOverride `json:"records" validate:"required,min=1,max=1000,dive"`
+	Reason  string               `json:"reason" validate:"max=1000"`
+}
+
+// SessionAttendance is a student's attendance for one session; Status is
+// empty while nothing has been recorded
+type SessionAttendance struct {
+	SessionID   uint       `json:"sessionId"`
+	CourseID    uint       `json:"courseId"`
+	Number      int        `json:"number"`
+	Title       string     `json:"title"`
+	Date        time.Time  `json:"date"`
+	Status      string     `json:"status"`
+	CheckInTime *time.Time `json:"checkInTime"`
+	Comment     string     `json:"comment"`
+	Source      string     `json:"source"`
+}
+
+// AttendanceSummary counts a student's attendance records in a course
+type AttendanceSummary struct {
+	UserID   uint         `json:"userId"`
+	User     UserResponse `json:"user"`
+	Present  int          `json:"present"`
+	Late     int          `json:"late"`
+	Absent   int          `json:"absent"`
+	Excused  int          `json:"excused"`
+	Recorded int          `json:"recorded"`
+}
+
+// Add counts one record in the summary
+func (s *AttendanceSummary) Add(status string) {
+	switch status {
+	case AttendancePresent:
+		s.Present++
+	case AttendanceLate:
+		s.Late++
+	case AttendanceAbsent:
+		s.Absent++
+	case AttendanceExcused:
+		s.Excused++
+	}
+	s.Recorded++
+}
+
+// How excused absences count toward an attendance rate
+const (
+	ExcusedExclude  = "exclude"  // left out of the rate
+	ExcusedAttended = "attended" // counted as attended
+	ExcusedAbsent   = "absent"   // counted as missed
+)
+
+// Attendance standings of a student in a course
+const (
+	StandingGood       = "good"
+	StandingAtRisk     = "at_risk"
+	StandingIneligible = "ineligible"
+)
+
+// AttendancePolicy sets the attendance a course requires. Students whose
+// rate is below MinimumRate may not start exams of the gated types; those
+// within WarningMargin points above it are at risk. A late mark counts as
+// LateCredit of an attended session.
+type AttendancePolicy struct {
+	ID             uint      `json:"id" gorm:"primaryKey"`
+	CourseID       uint      `json:"courseId" gorm:"not null;uniqueIndex"`
+	MinimumRate    float64   `json:"minimumRate" gorm:"not null"`
+	WarningMargin  float64   `json:"warningMargin" gorm:"not null"`
+	LateCredit     float64   `json:"lateCredit" gorm:"not null"`
+	ExcusedMode    string    `json:"excusedMode" gorm:"type:varchar(20);not null"` // exclude, attended, absent
+	GatedExamTypes string    `json:"-" gorm:"type:text"`                           // JSON array of exam types
+	UpdatedBy      uint      `json:"updatedBy"`
+	CreatedAt      time.Time `json:"createdAt"`
+	UpdatedAt      time.Time `json:"updatedAt"`
+}
+
+// DefaultAttendancePolicy is the institution's rule for courses without a
+// policy of their own: 75% attendance to sit final exams
+func DefaultAttendancePolicy(courseID uint) AttendancePolicy {
+	policy := AttendancePolicy{
+		CourseID:      courseID,
+		MinimumRate:   75,
+		WarningMargin: 10,
+		LateCredit:    1,
+		ExcusedMode:   ExcusedExclude,
+	}
+	policy.SetGatedExamTypes([]string{"final"})
+	return policy
+}
+
+// GatedExamTypeList returns the exam types that require the minimum attendance
+func (p *AttendancePolicy) GatedExamTypeList() []string {
+	types := []string{}
+	if p.GatedExamTypes == "" || json.Unmarshal([]byte(p.GatedExamTypes), &types) != nil {
+		return []string{}
+	}
+	return types
+}
+
+// SetGatedExamTypes stores the exam types that require the minimum attendance
+func (p *AttendancePolicy) SetGatedExamTypes(types []string) {
+	if types == nil {
+		types = []string{}
+	}
+	encoded, _ := json.Marshal(types)
+	p.GatedExamTypes = string(encoded)
+}
+
+// Gates reports whether starting an exam of the given type requires the
+// minimum attendance
+func (p *AttendancePolicy) Gates(examType string) bool {
+	if p.MinimumRate <= 0 {
+		return false
+	}
+	for _, gated := range p.GatedExamTypeList() {
+		if gated == examType {
+			return true
+		}
+	}
+	return false
+}
+
+// Rate returns the attendance rate in percent for a summary of a student's
+// records. Sessions without a record do not count; a student with nothing
+// that counts has a rate of 100.
+func (p *AttendancePolicy) Rate(summary AttendanceSummary) float64 {
+	attended := float64(summary.Present) + float64(summary.Late)*p.LateCredit
+	counted := float64(summary.Present + summary.Late + summary.Absent)
+	switch p.ExcusedMode {
+	case ExcusedAttended:
+		attended += float64(summary.Excused)
+		counted += float64(summary.Excused)
+	case ExcusedAbsent:
+		counted += float64(summary.Excused)
+	}
+	if counted == 0 {
+		return 100
+	}
+	return math.Round(attended/counted*10000) / 100
+}
+
+// Standing classifies an attendance rate under the policy
+func (p *AttendancePolicy) Standing(rate float64) string {
+	switch {
+	case rate < p.MinimumRate:
+		return StandingIneligible
+	case rate < p.MinimumRate+p.WarningMargin:
+		return StandingAtRisk
+	default:
+		return StandingGood
+	}
+}
+
+// ToResponse converts the policy to its API format
+func (p *AttendancePolicy) ToResponse() AttendancePolicyResponse {
+	return AttendancePolicyResponse{
+		CourseID:       p.CourseID,
+		MinimumRate:    p.MinimumRate,
+		WarningMargin:  p.WarningMargin,
+		LateCredit:     p.LateCredit,
+		ExcusedMode:    p.ExcusedMode,
+		GatedExamTypes: p.GatedExamTypeList(),
+		Default:        p.ID == 0,
+		UpdatedBy:      p.UpdatedBy,
+		UpdatedAt:      p.UpdatedAt,
+	}
+}
+
+// AttendancePolicyResponse is the API format of an attendance policy;
+// Default is set for courses following the institution's rule
+type AttendancePolicyResponse struct {
+	CourseID       uint      `json:"courseId"`
+	MinimumRate    float64   `json:"minimumRate"`
+	WarningMargin  float64   `json:"warningMargin"`
+	LateCredit     float64   `json:"lateCredit"`
+	ExcusedMode    string    `json:"excusedMode"`
+	GatedExamTypes []string  `json:"gatedExamTypes"`
+	Default        bool      `json:"default"`
+	UpdatedBy      uint      `json:"updatedBy"`
+	UpdatedAt      time.Time `json:"updatedAt"`
+}
+
+// AttendancePolicyRequest sets a course's attendance policy
+type AttendancePolicyRequest struct {
+	MinimumRate    float64  `json:"minimumRate" validate:"min=0,max=100"`
+	WarningMargin  float64  `json:"warningMargin" validate:"min=0,max=100"`
+	LateCredit     float64  `json:"lateCredit" validate:"min=0,max=1"`
+	ExcusedMode    string   `json:"excusedMode" validate:"required,oneof=exclude attended absent"`
+	GatedExamTypes []string `json:"gatedExamTypes" validate:"dive,oneof=quiz midterm final practice"`
+}
+
+// AttendanceStanding is a student's attendance rate in a course measured
+// against the course's policy
+type AttendanceStanding struct {
+	AttendanceSummary
+	Rate         float64 `json:"rate"`
+	RequiredRate float64 `json:"requiredRate"`
+	Standing     string  `json:"standing"` // good, at_risk, ineligible
+}
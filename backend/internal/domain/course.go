@@ -15,6 +15,13 @@ type Course struct {
 	Description    string         `json:"description"`
 	Semester       string         `json:"semester" gorm:"not null"`
 	Year           int            `json:"year" gorm:"not null"`
+	Credits        float64        `json:"credits" gorm:"type:decimal(4,1);default:3"` // credit units weighting the course in GPAs
+	Capacity       int            `json:"capacity" gorm:"default:0"` // 0 means unlimited
+	RequiresApproval bool         `json:"requiresApproval" gorm:"default:false"`
+	AddDeadline    *time.Time     `json:"addDeadline"`
+	DropDeadline   *time.Time     `json:"dropDeadline"`
+	WithdrawDeadline *time.Time   `json:"withdrawDeadline"`
+	GradesReleaseAt *time.Time    `json:"gradesReleaseAt"` // published grades stay hidden from students until then
 	CreatedAt      time.Time      `json:"createdAt"`
 	UpdatedAt      time.Time      `json:"updatedAt"`
 	DeletedAt      gorm.DeletedAt `json:"-" gorm:"index"`
@@ -46,12 +53,55 @@ type CourseStudent struct {
 	UserID       uint           `json:"userId" gorm:"not null"`
 	User         User           `json:"user" gorm:"foreignKey:UserID"`
 	EnrolledAt   time.Time      `json:"enrolledAt" gorm:"not null"`
-	Status       string         `json:"status" gorm:"type:varchar(20);default:'active'"`
+	Status       string         `json:"status" gorm:"type:varchar(20);default:'pending'"` // pending, enrolled, waitlisted, dropped, withdrawn, completed
+	WaitlistedAt *time.Time     `json:"waitlistedAt"`
 	CreatedAt    time.Time      `json:"createdAt"`
 	UpdatedAt    time.Time      `json:"updatedAt"`
 	DeletedAt    gorm.DeletedAt `json:"-" gorm:"index"`
 }
 
+// CourseDetailResponse is the course detail format with its instructors
+type CourseDetailResponse struct {
+	Course
+	Instructors []CourseInstructorResponse `json:"instructors"`
+}
+
+// CourseInstructorResponse represents an instructor assigned to a course
+type CourseInstructorResponse struct {
+	UserID uint         `json:"userId"`
+	IsMain bool         `json:"isMain"`
+	User   UserResponse `json:"user"`
+}
+
+// CourseStudentResponse represents a student enrolled in a course
+type CourseStudentResponse struct {
+	UserID       uint         `json:"userId"`
+	Status       string       `json:"status"`
+	EnrolledAt   time.Time    `json:"enrolledAt"`
+	WaitlistedAt *time.Time   `json:"waitlistedAt,omitempty"`
+	User         UserResponse `json:"user"`
+}
+
+// ToCourseInstructorResponse converts a CourseInstructor to CourseInstructorResponse
+func (ci *CourseInstructor) ToCourseInstructorResponse() CourseInstructorResponse {
+	return CourseInstructorResponse{
+		UserID: ci.UserID,
+		IsMain: ci.IsMain,
+		User:   ci.User.ToUserResponse(),
+	}
+}
+
+// ToCourseStudentResponse converts a CourseStudent to CourseStudentResponse
+func (cs *CourseStudent) ToCourseStudentResponse() CourseStudentResponse {
+	return CourseStudentResponse{
+		UserID:       cs.UserID,
+		Status:       cs.Status,
+		EnrolledAt:   cs.EnrolledAt,
+		WaitlistedAt: cs.WaitlistedAt,
+		User:         cs.User.ToUserResponse(),
+	}
+}
+
 // Session represents a course session
 type Session struct {
 	ID            uint           `json:"id" gorm:"primaryKey"`
@@ -109,13 +159,15 @@ type Material struct {
 // Attendance represents a student's attendance for a session
 type Attendance struct {
 	ID        uint      `json:"id" gorm:"primaryKey"`
-	SessionID uint      `json:"sessionId" gorm:"not null"`
+	SessionID uint      `json:"sessionId" gorm:"not null;uniqueIndex:idx_attendances_session_user"`
 	Session   Session   `json:"-" gorm:"foreignKey:SessionID"`
-	UserID    uint      `json:"userId" gorm:"not null"`
+	UserID    uint      `json:"userId" gorm:"not null;uniqueIndex:idx_attendances_session_user"`
 	User      User      `json:"user" gorm:"foreignKey:UserID"`
 	Status    string    `json:"status" gorm:"type:varchar(20);not null"` // present, absent, late, excused
 	CheckInTime *time.Time `json:"checkInTime"`
 	Comment   string    `json:"comment"`
+	Source    string    `json:"source" gorm:"type:varchar(20)"` // check_in, manual, closing
+	RecordedBy *uint    `json:"recordedBy"`
 	CreatedAt time.Time `json:"createdAt"`
 	UpdatedAt time.Time `json:"updatedAt"`
 }
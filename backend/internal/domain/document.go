@@ -0,0 +1,52 @@
+package domain
+
+import "time"
+
+// Types of issued documents
+const (
+	DocumentTypeTranscript  = "transcript"
+	DocumentTypeGradeReport = "grade_report"
+	DocumentTypeIDCard      = "id_card"
+)
+
+// IssuedDocument records a generated PDF so that it can be verified later.
+// The verification code is printed on the document; the checksum is the
+// SHA-256 of the exact bytes issued, so any alteration is detected.
+type IssuedDocument struct {
+	ID           uint       `json:"id" gorm:"primaryKey"`
+	Code         string     `json:"code" gorm:"type:varchar(32);uniqueIndex;not null"`
+	Type         string     `json:"type" gorm:"type:varchar(20);not null"` // transcript, grade_report, id_card
+	UserID       uint       `json:"userId" gorm:"not null;index"`
+	User         User       `json:"-" gorm:"foreignKey:UserID"`
+	Title        string     `json:"title" gorm:"not null"`
+	Checksum     string     `json:"checksum" gorm:"type:varchar(64);not null"`
+	Size         int64      `json:"size"`
+	IssuedBy     uint       `json:"issuedBy"`
+	IssuedAt     time.Time  `json:"issuedAt" gorm:"not null"`
+	ValidUntil   *time.Time `json:"validUntil"`
+	RevokedAt    *time.Time `json:"revokedAt"`
+	RevokeReason string     `json:"revokeReason"`
+}
+
+// DocumentVerification is the public answer to a verification request. It
+// deliberately reveals no more than what is printed on the document.
+type DocumentVerification struct {
+	Code        string     `json:"code"`
+	Valid       bool       `json:"valid"`
+	Status      string     `json:"status"` // valid, revoked, expired
+	Type        string     `json:"type"`
+	Title       string     `json:"title"`
+	HolderName  string     `json:"holderName"`
+	Institution string     `json:"institution"`
+	IssuedAt    time.Time  `json:"issuedAt"`
+	ValidUntil  *time.Time `json:"validUntil,omitempty"`
+	RevokedAt   *time.Time `json:"revokedAt,omitempty"`
+	Checksum    string     `json:"checksum"`
+	// Unaltered is set when a file was uploaded for comparison
+	Unaltered *bool `json:"unaltered,omitempty"`
+}
+
+// RevokeDocumentRequest represents a request to revoke an issued document
+type RevokeDocumentRequest struct {
+	Reason string `json:"reason" validate:"required,max=500"`
+}
@@ -0,0 +1,60 @@
+package domain
+
+import (
+	"time"
+)
+
+// Enrollment statuses of a CourseStudent
+const (
+	EnrollmentStatusPending    = "pending"
+	EnrollmentStatusEnrolled   = "enrolled"
+	EnrollmentStatusWaitlisted = "waitlisted"
+	EnrollmentStatusDropped    = "dropped"
+	EnrollmentStatusWithdrawn  = "withdrawn"
+	EnrollmentStatusCompleted  = "completed"
+)
+
+// enrollmentTransitions lists the statuses reachable from each status.
+// The empty status is the starting point of a new enrollment.
+var enrollmentTransitions = map[string][]string{
+	"":                         {EnrollmentStatusPending},
+	EnrollmentStatusPending:    {EnrollmentStatusEnrolled, EnrollmentStatusWaitlisted, EnrollmentStatusDropped},
+	EnrollmentStatusWaitlisted: {EnrollmentStatusEnrolled, EnrollmentStatusDropped},
+	EnrollmentStatusEnrolled:   {EnrollmentStatusDropped, EnrollmentStatusWithdrawn, EnrollmentStatusCompleted},
+	EnrollmentStatusDropped:    {EnrollmentStatusPending},
+	EnrollmentStatusWithdrawn:  {EnrollmentStatusPending},
+}
+
+// CanTransitionEnrollment reports whether an enrollment may move from one status to another
+func CanTransitionEnrollment(from, to string) bool {
+	for _, next := range enrollmentTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// EnrollmentTransition records a single status change of an enrollment
+type EnrollmentTransition struct {
+	ID              uint      `json:"id" gorm:"primaryKey"`
+	CourseStudentID uint      `json:"courseStudentId" gorm:"not null;index"`
+	CourseID        uint      `json:"courseId" gorm:"not null;index"`
+	UserID          uint      `json:"userId" gorm:"not null;index"`
+	FromStatus      string    `json:"fromStatus" gorm:"type:varchar(20)"`
+	ToStatus        string    `json:"toStatus" gorm:"type:varchar(20);not null"`
+	Reason          string    `json:"reason"`
+	ChangedBy       uint      `json:"changedBy" gorm:"not null"`
+	ChangedAt       time.Time `json:"changedAt" gorm:"not null"`
+}
+
+// EnrollmentRequest represents a request to enroll a student in a course
+type EnrollmentRequest struct {
+	UserID uint   `json:"userId" validate:"required"`
+	Reason string `json:"reason"`
+}
+
+// EnrollmentActionRequest represents a request to change an existing enrollment
+type EnrollmentActionRequest struct {
+	Reason string `json:"reason"`
+}
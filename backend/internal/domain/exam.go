@@ -0,0 +1,214 @@
+package domain
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Exam attempt statuses
+const (
+	ExamAttemptStatusInProgress = "in_progress"
+	ExamAttemptStatusCompleted  = "completed"
+	ExamAttemptStatusTimedOut   = "timed_out"
+)
+
+// Exam attempt grading statuses
+const (
+	GradingStatusAutoGraded         = "auto_graded"
+	GradingStatusNeedsManualGrading = "needs_manual_grading"
+	GradingStatusGraded             = "graded"
+)
+
+// Exam question types
+const (
+	QuestionTypeMultipleChoice = "multiple_choice"
+	QuestionTypeMultiSelect    = "multi_select"
+	QuestionTypeTrueFalse      = "true_false"
+	QuestionTypeNumeric        = "numeric"
+	QuestionTypeShortAnswer    = "short_answer"
+	QuestionTypeEssay          = "essay"
+)
+
+// ParseExamDuration parses an exam's Duration, given either as minutes
+// ("90"), as hours and minutes ("1:30") or as a Go duration ("1h30m")
+func ParseExamDuration(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+
+	var duration time.Duration
+	if minutes, err := strconv.Atoi(value); err == nil {
+		duration = time.Duration(minutes) * time.Minute
+	} else if parts := strings.Split(value, ":"); len(parts) == 2 {
+		hours, errH := strconv.Atoi(parts[0])
+		minutes, errM := strconv.Atoi(parts[1])
+		if errH != nil || errM != nil || minutes >= 60 {
+			return 0, fmt.Errorf("invalid duration %q", value)
+		}
+		duration = time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute
+	} else if parsed, err := time.ParseDuration(value); err == nil {
+		duration = parsed
+	} else {
+		return 0, fmt.Errorf("invalid duration %q", value)
+	}
+
+	if duration <= 0 {
+		return 0, fmt.Errorf("duration must be positive")
+	}
+	return duration, nil
+}
+
+// IsFinished reports whether the attempt has been submitted or timed out
+func (a *ExamAttempt) IsFinished() bool {
+	return a.Status != ExamAttemptStatusInProgress
+}
+
+// CreateExamRequest represents a request to create or update an exam
+type CreateExamRequest struct {
+	CourseID           uint      `json:"courseId" validate:"required"`
+	Title              string    `json:"title" validate:"required"`
+	Type               string    `json:"type" validate:"required,oneof=quiz midterm final practice"`
+	Description        string    `json:"description"`
+	Duration           string    `json:"duration" validate:"required,max=10"`
+	AvailableFrom      time.Time `json:"availableFrom"`
+	AvailableTo        time.Time `json:"availableTo"`
+	PassingScore       float64   `json:"passingScore" validate:"min=0,max=100"`
+	MaxAttempts        int       `json:"maxAttempts" validate:"min=0"`
+	RandomizeQuestions bool      `json:"randomizeQuestions"`
+	Weight             float64   `json:"weight" validate:"min=0,max=100"`
+	Prerequisites      string    `json:"prerequisites"`
+}
+
+// ExamQuestionRequest represents a request to add or update an exam question
+type ExamQuestionRequest struct {
+	Type          string  `json:"type" validate:"required,oneof=multiple_choice multi_select true_false numeric short_answer essay"`
+	Question      string  `json:"question" validate:"required"`
+	Options       string  `json:"options"`
+	CorrectAnswer string  `json:"correctAnswer"`
+	Points        float64 `json:"points" validate:"gt=0"`
+	Order         int     `json:"order"`
+}
+
+// GradeExamAnswerRequest represents a manual grade for one answer
+type GradeExamAnswerRequest struct {
+	Points   float64 `json:"points" validate:"min=0"`
+	Feedback string  `json:"feedback"`
+}
+
+// QuestionImportItem reports the outcome of importing one item of a question file
+type QuestionImportItem struct {
+	Item       int      `json:"item"`
+	Identifier string   `json:"identifier,omitempty"`
+	Title      string   `json:"title,omitempty"`
+	Type       string   `json:"type,omitempty"`
+	Status     string   `json:"status"` // valid, invalid, imported
+	Errors     []string `json:"errors,omitempty"`
+}
+
+// QuestionImportResult is the response of a question import
+type QuestionImportResult struct {
+	Format     string               `json:"format"`
+	DryRun     bool                 `json:"dryRun"`
+	TotalItems int                  `json:"totalItems"`
+	ValidItems int                  `json:"validItems"`
+	Imported   int                  `json:"imported"`
+	Items      []QuestionImportItem `json:"items"`
+}
+
+// SaveExamAnswerRequest represents an autosaved answer to one question
+type SaveExamAnswerRequest struct {
+	Answer string `json:"answer"`
+}
+
+// ExamQuestionResponse is a question as shown to students, without its correct answer
+type ExamQuestionResponse struct {
+	ID       uint    `json:"id"`
+	Type     string  `json:"type"`
+	Question string  `json:"question"`
+	Options  string  `json:"options"`
+	Points   float64 `json:"points"`
+	Order    int     `json:"order"`
+}
+
+// ExamAnswerResponse is a saved answer as shown to students
+type ExamAnswerResponse struct {
+	QuestionID uint      `json:"questionId"`
+	Answer     string    `json:"answer"`
+	SavedAt    time.Time `json:"savedAt"`
+	Points     *float64  `json:"points,omitempty"`
+	Feedback   string    `json:"feedback,omitempty"`
+}
+
+// ExamAttemptResponse is an attempt as shown to the student taking it.
+// RemainingSeconds is counted by the server; the score is only set once
+// the attempt is finished and its grades are released, until then
+// ResultsHidden is set.
+type ExamAttemptResponse struct {
+	ID               uint                   `json:"id"`
+	ExamID           uint                   `json:"examId"`
+	AttemptNumber    int                    `json:"attemptNumber"`
+	Status           string                 `json:"status"`
+	StartTime        time.Time              `json:"startTime"`
+	Deadline         time.Time              `json:"deadline"`
+	EndTime          *time.Time             `json:"endTime"`
+	RemainingSeconds int64                  `json:"remainingSeconds"`
+	Score            *float64               `json:"score,omitempty"`
+	GradingStatus    string                 `json:"gradingStatus,omitempty"`
+	ResultsHidden    bool                   `json:"resultsHidden,omitempty"`
+	Questions        []ExamQuestionResponse `json:"questions,omitempty"`
+	Answers          []ExamAnswerResponse   `json:"answers"`
+}
+
+// ToExamQuestionResponse converts a question for delivery to students
+func (q *ExamQuestion) ToExamQuestionResponse() ExamQuestionResponse {
+	return ExamQuestionResponse{
+		ID:       q.ID,
+		Type:     q.Type,
+		Question: q.Question,
+		Options:  q.Options,
+		Points:   q.Points,
+		Order:    q.Order,
+	}
+}
+
+// ToExamAttemptResponse converts an attempt with its answers for the student
+// taking it; questions are added by the caller when needed. Scores and
+// feedback are left out unless released is set.
+func (a *ExamAttempt) ToExamAttemptResponse(now time.Time, released bool) ExamAttemptResponse {
+	response := ExamAttemptResponse{
+		ID:            a.ID,
+		ExamID:        a.ExamID,
+		AttemptNumber: a.AttemptNumber,
+		Status:        a.Status,
+		StartTime:     a.StartTime,
+		Deadline:      a.Deadline,
+		EndTime:       a.EndTime,
+		Answers:       make([]ExamAnswerResponse, 0, len(a.Answers)),
+	}
+	showResults := a.IsFinished() && released
+	if a.IsFinished() {
+		response.GradingStatus = a.GradingStatus
+		if released {
+			score := a.Score
+			response.Score = &score
+		} else {
+			response.ResultsHidden = true
+		}
+	} else if remaining := a.Deadline.Sub(now); remaining > 0 {
+		response.RemainingSeconds = int64(remaining / time.Second)
+	}
+	for _, answer := range a.Answers {
+		answerResponse := ExamAnswerResponse{
+			QuestionID: answer.QuestionID,
+			Answer:     answer.Answer,
+			SavedAt:    answer.UpdatedAt,
+		}
+		if showResults && !answer.NeedsManualGrading {
+			points := answer.Points
+			answerResponse.Points = &points
+			answerResponse.Feedback = answer.Feedback
+		}
+		response.Answers = append(response.Answers, answerResponse)
+	}
+	return response
+}
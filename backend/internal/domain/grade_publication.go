@@ -0,0 +1,91 @@
+package domain
+
+import "time"
+
+// Grade publication statuses. Grades are drafts while the gradebook
+// computes them, are submitted by course staff, approved by an admin and
+// then published. Only published grades are shown to students, once the
+// course's release date has passed, and count toward GPAs. Grades past the
+// draft stage are no longer recalculated; published grades change only
+// through amendments.
+const (
+	PublicationDraft     = "draft"
+	PublicationSubmitted = "submitted"
+	PublicationApproved  = "approved"
+	PublicationPublished = "published"
+)
+
+// Grade moderation actions
+const (
+	ModerationSubmit  = "submit"
+	ModerationApprove = "approve"
+	ModerationReturn  = "return"
+	ModerationPublish = "publish"
+)
+
+// GradeModeration records a step of a course's grade publication workflow
+type GradeModeration struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	CourseID   uint      `json:"courseId" gorm:"not null;index"`
+	Action     string    `json:"action" gorm:"type:varchar(20);not null"` // submit, approve, return, publish
+	FromStatus string    `json:"fromStatus" gorm:"type:varchar(20)"`
+	ToStatus   string    `json:"toStatus" gorm:"type:varchar(20)"`
+	Count      int       `json:"count"` // number of students whose grades moved
+	ActorID    uint      `json:"actorId"`
+	Actor      User      `json:"actor" gorm:"foreignKey:ActorID"`
+	Comment    string    `json:"comment"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+// GradeAmendment records a change to a published grade. It keeps the
+// grade item's and the course grade's values before and after the change.
+type GradeAmendment struct {
+	ID              uint      `json:"id" gorm:"primaryKey"`
+	GradeID         uint      `json:"gradeId" gorm:"not null;index"`
+	Grade           *Grade    `json:"grade,omitempty" gorm:"foreignKey:GradeID"`
+	CourseGradeID   uint      `json:"courseGradeId" gorm:"not null"`
+	CourseID        uint      `json:"courseId" gorm:"not null;index"`
+	UserID          uint      `json:"userId" gorm:"not null"`
+	OldStatus       string    `json:"oldStatus" gorm:"type:varchar(20)"`
+	NewStatus       string    `json:"newStatus" gorm:"type:varchar(20)"`
+	OldScore        float64   `json:"oldScore"`
+	NewScore        float64   `json:"newScore"`
+	OldLetterGrade  string    `json:"oldLetterGrade" gorm:"type:varchar(5)"`
+	NewLetterGrade  string    `json:"newLetterGrade" gorm:"type:varchar(5)"`
+	OldOverallScore float64   `json:"oldOverallScore"`
+	NewOverallScore float64   `json:"newOverallScore"`
+	OldCourseLetter string    `json:"oldCourseLetter" gorm:"type:varchar(5)"`
+	NewCourseLetter string    `json:"newCourseLetter" gorm:"type:varchar(5)"`
+	Reason          string    `json:"reason" gorm:"not null"`
+	AmendedBy       uint      `json:"amendedBy"`
+	Amender         User      `json:"amender" gorm:"foreignKey:AmendedBy"`
+	CreatedAt       time.Time `json:"createdAt"`
+}
+
+// GradeModerationRequest moves a course's grades to the next stage of the
+// workflow. UserIDs narrows it to some students; all are moved otherwise.
+type GradeModerationRequest struct {
+	UserIDs []uint `json:"userIds"`
+	Comment string `json:"comment" validate:"max=1000"`
+}
+
+// AmendGradeRequest changes a published grade item. Score sets a new
+// score and Excused excuses the student or lifts it; with neither, the
+// item is recomputed from the student's current results, e.g. after a
+// regrade.
+type AmendGradeRequest struct {
+	Type    string   `json:"type" validate:"required,oneof=assessment exam"`
+	ID      uint     `json:"id" validate:"required"`
+	Score   *float64 `json:"score" validate:"omitempty,min=0,max=100"`
+	Excused *bool    `json:"excused"`
+	Reason  string   `json:"reason" validate:"required,max=1000"`
+}
+
+// GradePublication summarizes where a course's grades are in the workflow
+type GradePublication struct {
+	CourseID  uint              `json:"courseId"`
+	ReleaseAt *time.Time        `json:"releaseAt"`
+	Released  bool              `json:"released"` // the release date has passed or there is none
+	Counts    map[string]int    `json:"counts"`   // students per publication status
+	History   []GradeModeration `json:"history"`
+}
@@ -0,0 +1,125 @@
+package domain
+
+import (
+	"sort"
+	"time"
+)
+
+// Gradebook item statuses
+const (
+	GradeStatusGraded  = "graded"
+	GradeStatusPending = "pending" // not graded yet and not overdue; left out of the total
+	GradeStatusMissing = "missing" // overdue without a result; counts as zero
+	GradeStatusExcused = "excused" // left out of the total, its weight spread over the rest
+)
+
+// Gradebook item types
+const (
+	GradeItemAssessment = "assessment"
+	GradeItemExam       = "exam"
+)
+
+// GradeScale maps course scores to letter grades. A scale without a course
+// is the institution's scale, used by courses without their own.
+type GradeScale struct {
+	ID        uint              `json:"id" gorm:"primaryKey"`
+	CourseID  *uint             `json:"courseId"`
+	Name      string            `json:"name" gorm:"not null"`
+	Entries   []GradeScaleEntry `json:"entries" gorm:"foreignKey:GradeScaleID"`
+	CreatedAt time.Time         `json:"createdAt"`
+	UpdatedAt time.Time         `json:"updatedAt"`
+}
+
+// GradeScaleEntry is the lowest score that earns a letter grade
+type GradeScaleEntry struct {
+	ID           uint    `json:"id" gorm:"primaryKey"`
+	GradeScaleID uint    `json:"-" gorm:"not null"`
+	Letter       string  `json:"letter" gorm:"type:varchar(5);not null"`
+	MinScore     float64 `json:"minScore"`
+	GradePoints  float64 `json:"gradePoints"`
+}
+
+// DefaultGradeScale is used until an institution scale is configured
+func DefaultGradeScale() GradeScale {
+	return GradeScale{
+		Name: "Default",
+		Entries: []GradeScaleEntry{
+			{Letter: "A", MinScore: 93, GradePoints: 4.0},
+			{Letter: "A-", MinScore: 90, GradePoints: 3.7},
+			{Letter: "B+", MinScore: 87, GradePoints: 3.3},
+			{Letter: "B", MinScore: 83, GradePoints: 3.0},
+			{Letter: "B-", MinScore: 80, GradePoints: 2.7},
+			{Letter: "C+", MinScore: 77, GradePoints: 2.3},
+			{Letter: "C", MinScore: 73, GradePoints: 2.0},
+			{Letter: "C-", MinScore: 70, GradePoints: 1.7},
+			{Letter: "D+", MinScore: 67, GradePoints: 1.3},
+			{Letter: "D", MinScore: 60, GradePoints: 1.0},
+			{Letter: "F", MinScore: 0, GradePoints: 0},
+		},
+	}
+}
+
+// SortEntries orders the entries from the highest minimum score down
+func (s *GradeScale) SortEntries() {
+	sort.SliceStable(s.Entries, func(i, j int) bool {
+		return s.Entries[i].MinScore > s.Entries[j].MinScore
+	})
+}
+
+// Grade returns the letter and grade points a score earns. Entries must be
+// sorted; scores below every entry earn nothing.
+func (s *GradeScale) Grade(score float64) (letter string, gradePoints float64) {
+	for _, entry := range s.Entries {
+		if score >= entry.MinScore {
+			return entry.Letter, entry.GradePoints
+		}
+	}
+	return "", 0
+}
+
+// GradeScaleRequest represents a request to set a grade scale
+type GradeScaleRequest struct {
+	Name    string                   `json:"name" validate:"required,max=100"`
+	Entries []GradeScaleEntryRequest `json:"entries" validate:"required,min=1,dive"`
+}
+
+// GradeScaleEntryRequest is one letter grade of a grade scale request
+type GradeScaleEntryRequest struct {
+	Letter      string  `json:"letter" validate:"required,max=5"`
+	MinScore    float64 `json:"minScore" validate:"min=0,max=100"`
+	GradePoints float64 `json:"gradePoints" validate:"min=0,max=10"`
+}
+
+// GradebookItem is an assessment or exam that counts toward a course grade
+type GradebookItem struct {
+	Type    string     `json:"type"` // assessment, exam
+	ID      uint       `json:"id"`
+	Title   string     `json:"title"`
+	Weight  float64    `json:"weight"`
+	DueDate *time.Time `json:"dueDate"` // after it, items without a result are missing
+}
+
+// GradebookWeightsRequest sets the weights of all items of a course at once
+type GradebookWeightsRequest struct {
+	Items []GradebookWeight `json:"items" validate:"required,min=1,dive"`
+}
+
+// GradebookWeight is the weight of one gradebook item, in percent
+type GradebookWeight struct {
+	Type   string  `json:"type" validate:"required,oneof=assessment exam"`
+	ID     uint    `json:"id" validate:"required"`
+	Weight float64 `json:"weight" validate:"min=0,max=100"`
+}
+
+// ExcuseGradeRequest excuses a student from a gradebook item, or lifts it
+type ExcuseGradeRequest struct {
+	Type    string `json:"type" validate:"required,oneof=assessment exam"`
+	ID      uint   `json:"id" validate:"required"`
+	Excused bool   `json:"excused"`
+}
+
+// StudentGradebook is a student's course grade with the items it is based on
+type StudentGradebook struct {
+	CourseGrade CourseGrade `json:"courseGrade"`
+	Items       []Grade     `json:"items"`
+}
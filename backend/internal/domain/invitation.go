@@ -0,0 +1,40 @@
+package domain
+
+import (
+	"time"
+)
+
+// Invitation lets the holder of its token create an account with the given
+// role and email address. Only a SHA-256 hash of the token is stored, and an
+// invitation can be accepted once.
+type Invitation struct {
+	ID         uint       `json:"id" gorm:"primaryKey"`
+	Email      string     `json:"email" gorm:"not null;index"`
+	Role       string     `json:"role" gorm:"type:user_role;not null"`
+	TokenHash  string     `json:"-" gorm:"type:varchar(64);uniqueIndex;not null"`
+	InvitedBy  uint       `json:"invited_by" gorm:"not null"`
+	ExpiresAt  time.Time  `json:"expires_at" gorm:"not null"`
+	AcceptedAt *time.Time `json:"accepted_at"`
+	AcceptedBy *uint      `json:"accepted_by"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// Pending reports whether the invitation can still be accepted
+func (i *Invitation) Pending(now time.Time) bool {
+	return i.AcceptedAt == nil && now.Before(i.ExpiresAt)
+}
+
+// CreateInvitationRequest is the payload to invite someone
+type CreateInvitationRequest struct {
+	Email     string `json:"email" validate:"required,email"`
+	Role      string `json:"role" validate:"required,oneof=student instructor admin"`
+	ExpiresIn int    `json:"expires_in_days" validate:"omitempty,min=1,max=30"`
+}
+
+// AcceptInvitationRequest is the payload to create an account from an invitation
+type AcceptInvitationRequest struct {
+	Token    string `json:"token" validate:"required"`
+	Username string `json:"username" validate:"required"`
+	Name     string `json:"name" validate:"required"`
+	Password string `json:"password" validate:"required,min=6"`
+}
@@ -0,0 +1,89 @@
+package domain
+
+import (
+	"time"
+)
+
+// MFAEnrollment is a user's TOTP authenticator. It protects sign-ins once
+// confirmed with a first code. Secret is encrypted at rest, and LastStep is
+// the time step of the last accepted code, which cannot be used again.
+type MFAEnrollment struct {
+	ID          uint       `json:"id" gorm:"primaryKey"`
+	UserID      uint       `json:"user_id" gorm:"not null;uniqueIndex"`
+	Secret      string     `json:"-" gorm:"type:text;not null"`
+	ConfirmedAt *time.Time `json:"confirmed_at"`
+	LastStep    int64      `json:"-" gorm:"not null;default:0"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// MFARecoveryCode is a single-use code that stands in for an authenticator
+// code when the device is lost. Only a SHA-256 hash of the code is stored.
+type MFARecoveryCode struct {
+	ID        uint       `json:"id" gorm:"primaryKey"`
+	UserID    uint       `json:"user_id" gorm:"not null;index"`
+	CodeHash  string     `json:"-" gorm:"type:varchar(64);not null"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// MFAChallenge is the second step of a sign-in whose password was correct.
+// Its token is handed out instead of access and refresh tokens; only a
+// SHA-256 hash of it is stored, and it allows a few code attempts.
+type MFAChallenge struct {
+	ID        uint       `json:"id" gorm:"primaryKey"`
+	UserID    uint       `json:"user_id" gorm:"not null;index"`
+	TokenHash string     `json:"-" gorm:"type:varchar(64);uniqueIndex;not null"`
+	Attempts  int        `json:"attempts" gorm:"not null;default:0"`
+	ExpiresAt time.Time  `json:"expires_at" gorm:"not null"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// Open reports whether the challenge can still be completed
+func (c *MFAChallenge) Open(now time.Time) bool {
+	return c.UsedAt == nil && now.Before(c.ExpiresAt)
+}
+
+// MFASetup is what an authenticator app needs to add an account; the
+// provisioning URI is meant to be shown as a QR code
+type MFASetup struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+	Issuer          string `json:"issuer"`
+	Account         string `json:"account"`
+}
+
+// MFAStatus describes a user's two-factor authentication
+type MFAStatus struct {
+	Enabled           bool       `json:"enabled"`
+	Pending           bool       `json:"pending"`
+	Required          bool       `json:"required"`
+	ConfirmedAt       *time.Time `json:"confirmed_at"`
+	RecoveryCodesLeft int64      `json:"recovery_codes_left"`
+}
+
+// MFACodeRequest carries an authenticator code
+type MFACodeRequest struct {
+	Code string `json:"code" validate:"required"`
+}
+
+// MFAVerifyRequest completes a sign-in with an authenticator code or a
+// recovery code
+type MFAVerifyRequest struct {
+	MFAToken     string `json:"mfa_token" validate:"required"`
+	Code         string `json:"code" validate:"required_without=RecoveryCode"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+// MFAEnrollRequest sets up an authenticator during a sign-in that requires one
+type MFAEnrollRequest struct {
+	MFAToken string `json:"mfa_token" validate:"required"`
+	Code     string `json:"code"`
+}
+
+// DisableMFARequest is the payload to turn two-factor authentication off
+type DisableMFARequest struct {
+	Password string `json:"password" validate:"required"`
+	Code     string `json:"code" validate:"required"`
+}
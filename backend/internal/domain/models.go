@@ -12,18 +12,54 @@ func (u *User) ToUserResponse() UserResponse {
 		Name:      u.Name,
 		Email:     u.Email,
 		Role:      u.Role,
+		Verified:  u.EmailVerifiedAt != nil,
 		CreatedAt: u.CreatedAt,
 		UpdatedAt: u.UpdatedAt,
 	}
 }
 
-// RefreshToken represents a refresh token for JWT authentication
+// RefreshToken represents a refresh token for JWT authentication. Only a
+// SHA-256 hash of the token is stored. Each refresh replaces the token with
+// a new one of the same family, which stands for one signed-in session; a
+// replaced token that is presented again gives the whole family away.
 type RefreshToken struct {
-	ID        uint      `json:"id" gorm:"primaryKey"`
-	UserID    uint      `json:"user_id" gorm:"not null"`
-	Token     string    `json:"token" gorm:"uniqueIndex;not null"`
-	ExpiresAt time.Time `json:"expires_at"`
-	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+	ID              uint       `json:"id" gorm:"primaryKey"`
+	UserID          uint       `json:"user_id" gorm:"not null;index"`
+	TokenHash       string     `json:"-" gorm:"type:varchar(64);uniqueIndex;not null"`
+	FamilyID        string     `json:"family_id" gorm:"type:varchar(64);not null;index"`
+	UserAgent       string     `json:"user_agent"`
+	IPAddress       string     `json:"ip_address" gorm:"type:varchar(64)"`
+	AuthenticatedAt time.Time  `json:"authenticated_at" gorm:"not null"`
+	LastUsedAt      time.Time  `json:"last_used_at" gorm:"not null"`
+	ExpiresAt       time.Time  `json:"expires_at"`
+	RotatedAt       *time.Time `json:"rotated_at"`
+	ReplacedByID    *uint      `json:"replaced_by_id"`
+	CreatedAt       time.Time  `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// AuthSession is a signed-in session of a user: the current token of a
+// refresh token family
+type AuthSession struct {
+	ID              string    `json:"id"`
+	UserAgent       string    `json:"user_agent"`
+	IPAddress       string    `json:"ip_address"`
+	AuthenticatedAt time.Time `json:"authenticated_at"`
+	LastUsedAt      time.Time `json:"last_used_at"`
+	ExpiresAt       time.Time `json:"expires_at"`
+	Current         bool      `json:"current"`
+}
+
+// ToAuthSession describes the session a refresh token belongs to
+func (t *RefreshToken) ToAuthSession(currentFamilyID string) AuthSession {
+	return AuthSession{
+		ID:              t.FamilyID,
+		UserAgent:       t.UserAgent,
+		IPAddress:       t.IPAddress,
+		AuthenticatedAt: t.AuthenticatedAt,
+		LastUsedAt:      t.LastUsedAt,
+		ExpiresAt:       t.ExpiresAt,
+		Current:         t.FamilyID == currentFamilyID,
+	}
 }
 
 // UpdateUserRequest represents a request to update a user
@@ -0,0 +1,58 @@
+package domain
+
+import "time"
+
+// RubricLevelShare is how many of the assessed students attained a rubric level
+type RubricLevelShare struct {
+	Level    string  `json:"level"`
+	Students int     `json:"students"`
+	Percent  float64 `json:"percent"`
+}
+
+// OutcomeAttainment summarizes how the students of a course attained one
+// learning outcome. A student's level is the rounded mean of the levels they
+// received for the outcome; MeanAttainment is the mean share of the rubric
+// points the students earned, in percent.
+type OutcomeAttainment struct {
+	LearningOutcomeID uint               `json:"learningOutcomeId"`
+	Code              string             `json:"code"`
+	Description       string             `json:"description"`
+	StudentsAssessed  int                `json:"studentsAssessed"`
+	MeanAttainment    *float64           `json:"meanAttainment"`
+	Levels            []RubricLevelShare `json:"levels"`
+}
+
+// CourseOutcomeReport is the learning outcome attainment of a course
+type CourseOutcomeReport struct {
+	CourseID    uint                `json:"courseId"`
+	CourseCode  string              `json:"courseCode"`
+	CourseTitle string              `json:"courseTitle"`
+	Outcomes    []OutcomeAttainment `json:"outcomes"`
+	GeneratedAt time.Time           `json:"generatedAt"`
+}
+
+// StudentOutcome is a student's attainment of one learning outcome
+type StudentOutcome struct {
+	LearningOutcomeID uint     `json:"learningOutcomeId"`
+	Code              string   `json:"code"`
+	Description       string   `json:"description"`
+	AssessedItems     int      `json:"assessedItems"`
+	Attainment        *float64 `json:"attainment"`
+	Level             string   `json:"level,omitempty"`
+}
+
+// StudentCourseOutcomes are a student's outcome attainments in one course
+type StudentCourseOutcomes struct {
+	CourseID    uint             `json:"courseId"`
+	CourseCode  string           `json:"courseCode"`
+	CourseTitle string           `json:"courseTitle"`
+	Outcomes    []StudentOutcome `json:"outcomes"`
+}
+
+// StudentOutcomeProfile is a student's learning outcome attainment across courses
+type StudentOutcomeProfile struct {
+	UserID      uint                    `json:"userId"`
+	Name        string                  `json:"name"`
+	Courses     []StudentCourseOutcomes `json:"courses"`
+	GeneratedAt time.Time               `json:"generatedAt"`
+}
@@ -0,0 +1,44 @@
+package domain
+
+import (
+	"time"
+)
+
+// PasswordResetToken lets the holder of its token set a new password for a
+// user. Only a SHA-256 hash of the token is stored; a token works once and
+// until it expires.
+type PasswordResetToken struct {
+	ID          uint       `json:"id" gorm:"primaryKey"`
+	UserID      uint       `json:"user_id" gorm:"not null;index"`
+	TokenHash   string     `json:"-" gorm:"type:varchar(64);uniqueIndex;not null"`
+	RequestedIP string     `json:"requested_ip" gorm:"type:varchar(64)"`
+	ExpiresAt   time.Time  `json:"expires_at" gorm:"not null"`
+	UsedAt      *time.Time `json:"used_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// Usable reports whether the token can still reset a password
+func (t *PasswordResetToken) Usable(now time.Time) bool {
+	return t.UsedAt == nil && now.Before(t.ExpiresAt)
+}
+
+// PasswordResetAttempt records a request for a reset link, whether or not
+// the address belongs to anyone, so that requests can be rate limited per
+// address and per client
+type PasswordResetAttempt struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	Email     string    `json:"email" gorm:"not null;index"`
+	IPAddress string    `json:"ip_address" gorm:"type:varchar(64);not null;index"`
+	CreatedAt time.Time `json:"created_at" gorm:"not null"`
+}
+
+// ForgotPasswordRequest is the payload to request a password reset link
+type ForgotPasswordRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+// ResetPasswordRequest is the payload to set a new password with a reset token
+type ResetPasswordRequest struct {
+	Token       string `json:"token" validate:"required"`
+	NewPassword string `json:"new_password" validate:"required,min=6"`
+}
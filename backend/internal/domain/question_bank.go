@@ -0,0 +1,114 @@
+package domain
+
+import (
+	"encoding/json"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Question difficulties
+const (
+	DifficultyEasy   = "easy"
+	DifficultyMedium = "medium"
+	DifficultyHard   = "hard"
+)
+
+// BankQuestion is a reusable question in a course's question bank. Exams draw
+// bank questions through question pools.
+type BankQuestion struct {
+	ID               uint              `json:"id" gorm:"primaryKey"`
+	CourseID         uint              `json:"courseId" gorm:"not null;index"`
+	Course           Course            `json:"-" gorm:"foreignKey:CourseID"`
+	Type             string            `json:"type" gorm:"type:varchar(20);not null"`
+	Question         string            `json:"question" gorm:"not null"`
+	Options          string            `json:"options"`
+	CorrectAnswer    string            `json:"correctAnswer"`
+	Points           float64           `json:"points" gorm:"not null"`
+	Difficulty       string            `json:"difficulty" gorm:"type:varchar(10);default:'medium'"` // easy, medium, hard
+	Tags             []QuestionTag     `json:"tags" gorm:"many2many:bank_question_tags"`
+	LearningOutcomes []LearningOutcome `json:"learningOutcomes" gorm:"many2many:bank_question_outcomes"`
+	CreatedBy        uint              `json:"createdBy"`
+	CreatedAt        time.Time         `json:"createdAt"`
+	UpdatedAt        time.Time         `json:"updatedAt"`
+	DeletedAt        gorm.DeletedAt    `json:"-" gorm:"index"`
+}
+
+// QuestionTag is a tag of a course's bank questions
+type QuestionTag struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	CourseID  uint      `json:"courseId" gorm:"not null;uniqueIndex:idx_question_tags_course_name"`
+	Name      string    `json:"name" gorm:"size:50;not null;uniqueIndex:idx_question_tags_course_name"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// ExamQuestionPool draws DrawCount random questions from the bank questions
+// of the exam's course that match all of its criteria; empty criteria match
+// every bank question
+type ExamQuestionPool struct {
+	ID                uint           `json:"id" gorm:"primaryKey"`
+	ExamID            uint           `json:"examId" gorm:"not null;index"`
+	Exam              Exam           `json:"-" gorm:"foreignKey:ExamID"`
+	Name              string         `json:"name" gorm:"not null"`
+	DrawCount         int            `json:"drawCount" gorm:"not null"`
+	Points            float64        `json:"points"` // points per drawn question; 0 keeps the bank question's points
+	Tag               string         `json:"tag" gorm:"size:50"`
+	Difficulty        string         `json:"difficulty" gorm:"type:varchar(10)"`
+	LearningOutcomeID *uint          `json:"learningOutcomeId"`
+	CreatedAt         time.Time      `json:"createdAt"`
+	UpdatedAt         time.Time      `json:"updatedAt"`
+	DeletedAt         gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+// ToExamQuestion copies a bank question into a question of the given exam
+func (q *BankQuestion) ToExamQuestion(examID uint) ExamQuestion {
+	bankQuestionID := q.ID
+	return ExamQuestion{
+		ExamID:         examID,
+		BankQuestionID: &bankQuestionID,
+		Type:           q.Type,
+		Question:       q.Question,
+		Options:        q.Options,
+		CorrectAnswer:  q.CorrectAnswer,
+		Points:         q.Points,
+	}
+}
+
+// QuestionIDList returns the questions drawn for the attempt in delivery
+// order. It is empty for attempts started before questions were drawn per
+// attempt, which use all of the exam's fixed questions.
+func (a *ExamAttempt) QuestionIDList() []uint {
+	var ids []uint
+	if a.QuestionIDs == "" || json.Unmarshal([]byte(a.QuestionIDs), &ids) != nil {
+		return nil
+	}
+	return ids
+}
+
+// SetQuestionIDList stores the questions drawn for the attempt
+func (a *ExamAttempt) SetQuestionIDList(ids []uint) {
+	encoded, _ := json.Marshal(ids)
+	a.QuestionIDs = string(encoded)
+}
+
+// BankQuestionRequest represents a request to create or update a bank question
+type BankQuestionRequest struct {
+	Type               string   `json:"type" validate:"required,oneof=multiple_choice multi_select true_false numeric short_answer essay"`
+	Question           string   `json:"question" validate:"required"`
+	Options            string   `json:"options"`
+	CorrectAnswer      string   `json:"correctAnswer"`
+	Points             float64  `json:"points" validate:"gt=0"`
+	Difficulty         string   `json:"difficulty" validate:"omitempty,oneof=easy medium hard"`
+	Tags               []string `json:"tags" validate:"dive,required,max=50"`
+	LearningOutcomeIDs []uint   `json:"learningOutcomeIds"`
+}
+
+// QuestionPoolRequest represents a request to create or update a question pool
+type QuestionPoolRequest struct {
+	Name              string  `json:"name" validate:"required"`
+	DrawCount         int     `json:"drawCount" validate:"min=1"`
+	Points            float64 `json:"points" validate:"min=0"`
+	Tag               string  `json:"tag" validate:"max=50"`
+	Difficulty        string  `json:"difficulty" validate:"omitempty,oneof=easy medium hard"`
+	LearningOutcomeID *uint   `json:"learningOutcomeId"`
+}
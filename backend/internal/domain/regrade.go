@@ -0,0 +1,72 @@
+package domain
+
+import "time"
+
+// Regrade request statuses
+const (
+	RegradeStatusOpen        = "open"
+	RegradeStatusUnderReview = "under_review"
+	RegradeStatusAccepted    = "accepted"
+	RegradeStatusRejected    = "rejected"
+)
+
+// RegradeRequest is a student's appeal against the score of a submission
+// or an exam attempt. It is routed to the instructor who graded the work,
+// falling back to the course's main instructor.
+type RegradeRequest struct {
+	ID            uint           `json:"id" gorm:"primaryKey"`
+	CourseID      uint           `json:"courseId" gorm:"not null;index"`
+	UserID        uint           `json:"userId" gorm:"not null;index"`
+	User          User           `json:"user" gorm:"foreignKey:UserID"`
+	ItemType      string         `json:"itemType" gorm:"type:varchar(20);not null"` // assessment, exam
+	ItemID        uint           `json:"itemId" gorm:"not null"`                    // the assessment or exam
+	ItemTitle     string         `json:"itemTitle"`
+	SubmissionID  *uint          `json:"submissionId"`
+	ExamAttemptID *uint          `json:"examAttemptId"`
+	AssigneeID    uint           `json:"assigneeId" gorm:"index"`
+	Assignee      User           `json:"assignee" gorm:"foreignKey:AssigneeID"`
+	Status        string         `json:"status" gorm:"type:varchar(20);not null"` // open, under_review, accepted, rejected
+	Justification string         `json:"justification" gorm:"type:text;not null"`
+	OriginalScore float64        `json:"originalScore"`
+	NewScore      *float64       `json:"newScore"`
+	Response      string         `json:"response" gorm:"type:text"`
+	ResolvedBy    *uint          `json:"resolvedBy"`
+	ResolvedAt    *time.Time     `json:"resolvedAt"`
+	Events        []RegradeEvent `json:"events,omitempty" gorm:"foreignKey:RequestID"`
+	CreatedAt     time.Time      `json:"createdAt"`
+	UpdatedAt     time.Time      `json:"updatedAt"`
+}
+
+// Active reports whether the request still awaits a decision
+func (r *RegradeRequest) Active() bool {
+	return r.Status == RegradeStatusOpen || r.Status == RegradeStatusUnderReview
+}
+
+// RegradeEvent records a step in the history of a regrade request
+type RegradeEvent struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	RequestID  uint      `json:"requestId" gorm:"not null;index"`
+	FromStatus string    `json:"fromStatus" gorm:"type:varchar(20)"`
+	ToStatus   string    `json:"toStatus" gorm:"type:varchar(20);not null"`
+	ActorID    uint      `json:"actorId"`
+	Actor      User      `json:"actor" gorm:"foreignKey:ActorID"`
+	Note       string    `json:"note" gorm:"type:text"`
+	Score      *float64  `json:"score"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+// CreateRegradeRequest appeals the score of a submission or of an exam
+// attempt; exactly one of them must be given
+type CreateRegradeRequest struct {
+	SubmissionID  *uint  `json:"submissionId"`
+	ExamAttemptID *uint  `json:"examAttemptId"`
+	Justification string `json:"justification" validate:"required,max=5000"`
+}
+
+// RegradeDecisionRequest accepts or rejects a regrade request. Accepting
+// requires the new score: the raw score before any late penalty for
+// submissions, the attempt's score for exams.
+type RegradeDecisionRequest struct {
+	Score    *float64 `json:"score" validate:"omitempty,min=0,max=100"`
+	Response string   `json:"response" validate:"max=5000"`
+}
@@ -0,0 +1,46 @@
+package domain
+
+import (
+	"time"
+)
+
+// RosterImportRow is a single user parsed from a roster import file.
+// The validate tags mirror the admin create-user request.
+type RosterImportRow struct {
+	Username        string     `json:"username" validate:"required,max=50"`
+	Name            string     `json:"name" validate:"required"`
+	Email           string     `json:"email" validate:"required,email"`
+	Password        string     `json:"-" validate:"required,min=6,max=72"`
+	Role            string     `json:"role" validate:"required,oneof=student instructor admin"`
+	Gender          string     `json:"gender,omitempty" validate:"max=10"`
+	Religion        string     `json:"religion,omitempty" validate:"max=50"`
+	DateOfBirth     *time.Time `json:"date_of_birth,omitempty"`
+	PlaceOfBirth    string     `json:"place_of_birth,omitempty" validate:"max=100"`
+	Department      string     `json:"department,omitempty" validate:"max=100"`
+	StudentID       string     `json:"student_id,omitempty" validate:"required_if=Role student,max=50"`
+	Degree          string     `json:"degree,omitempty" validate:"max=50"`
+	Major           string     `json:"major,omitempty" validate:"max=100"`
+	Stream          string     `json:"stream,omitempty" validate:"max=100"`
+	CurrentSemester string     `json:"current_semester,omitempty" validate:"max=20"`
+	Position        string     `json:"position,omitempty" validate:"max=100"`
+	Specialization  string     `json:"specialization,omitempty" validate:"max=100"`
+	CourseCodes     []string   `json:"course_codes,omitempty"`
+}
+
+// RosterRowResult reports the outcome of importing one row
+type RosterRowResult struct {
+	Row      int      `json:"row"`
+	Username string   `json:"username"`
+	Status   string   `json:"status"` // valid, invalid, created
+	Errors   []string `json:"errors,omitempty"`
+}
+
+// RosterImportResult is the response of a roster import
+type RosterImportResult struct {
+	DryRun    bool              `json:"dry_run"`
+	TotalRows int               `json:"total_rows"`
+	ValidRows int               `json:"valid_rows"`
+	Created   int               `json:"created"`
+	Enrolled  int               `json:"enrolled"`
+	Rows      []RosterRowResult `json:"rows"`
+}
@@ -0,0 +1,83 @@
+package domain
+
+import (
+	"time"
+)
+
+// Rubric levels, from highest to lowest
+const (
+	RubricLevelExcellent = "excellent"
+	RubricLevelGood      = "good"
+	RubricLevelAverage   = "average"
+	RubricLevelPoor      = "poor"
+)
+
+// RubricLevels lists the rubric levels from highest to lowest
+var RubricLevels = []string{RubricLevelExcellent, RubricLevelGood, RubricLevelAverage, RubricLevelPoor}
+
+// RubricSelection is the level an instructor chose for one rubric item when
+// grading a submission. The points and criteria are copied from the rubric
+// item so that later rubric changes do not alter given grades.
+type RubricSelection struct {
+	ID                uint      `json:"id" gorm:"primaryKey"`
+	SubmissionID      uint      `json:"submissionId" gorm:"not null;uniqueIndex:idx_rubric_selections_submission_item"`
+	RubricItemID      uint      `json:"rubricItemId" gorm:"not null;uniqueIndex:idx_rubric_selections_submission_item"`
+	LearningOutcomeID uint      `json:"learningOutcomeId" gorm:"not null;index"`
+	Level             string    `json:"level" gorm:"type:varchar(20);not null"`
+	Points            float64   `json:"points"`
+	MaxPoints         float64   `json:"maxPoints"`
+	Criteria          string    `json:"criteria"`
+	Comment           string    `json:"comment"`
+	GradedBy          uint      `json:"gradedBy"`
+	CreatedAt         time.Time `json:"createdAt"`
+	UpdatedAt         time.Time `json:"updatedAt"`
+}
+
+// LevelPoints returns the points and criteria of a rubric level
+func (r *RubricItem) LevelPoints(level string) (points float64, criteria string, ok bool) {
+	switch level {
+	case RubricLevelExcellent:
+		return r.ExcellentPoints, r.ExcellentCriteria, true
+	case RubricLevelGood:
+		return r.GoodPoints, r.GoodCriteria, true
+	case RubricLevelAverage:
+		return r.AveragePoints, r.AverageCriteria, true
+	case RubricLevelPoor:
+		return r.PoorPoints, r.PoorCriteria, true
+	}
+	return 0, "", false
+}
+
+// MaxPoints returns the points of the item's best level
+func (r *RubricItem) MaxPoints() float64 {
+	max := r.ExcellentPoints
+	for _, points := range []float64{r.GoodPoints, r.AveragePoints, r.PoorPoints} {
+		if points > max {
+			max = points
+		}
+	}
+	return max
+}
+
+// RubricItemRequest represents a request to create or update a rubric item.
+// Levels without points get 4, 3, 2 and 1 points from excellent to poor.
+type RubricItemRequest struct {
+	LearningOutcomeID uint     `json:"learningOutcomeId" validate:"required"`
+	KeyIndicator      string   `json:"keyIndicator" validate:"required"`
+	ExcellentCriteria string   `json:"excellentCriteria"`
+	GoodCriteria      string   `json:"goodCriteria"`
+	AverageCriteria   string   `json:"averageCriteria"`
+	PoorCriteria      string   `json:"poorCriteria"`
+	ExcellentPoints   *float64 `json:"excellentPoints" validate:"omitempty,min=0"`
+	GoodPoints        *float64 `json:"goodPoints" validate:"omitempty,min=0"`
+	AveragePoints     *float64 `json:"averagePoints" validate:"omitempty,min=0"`
+	PoorPoints        *float64 `json:"poorPoints" validate:"omitempty,min=0"`
+	Order             int      `json:"order"`
+}
+
+// RubricSelectionRequest chooses the level of one rubric item when grading
+type RubricSelectionRequest struct {
+	RubricItemID uint   `json:"rubricItemId" validate:"required"`
+	Level        string `json:"level" validate:"required,oneof=excellent good average poor"`
+	Comment      string `json:"comment"`
+}
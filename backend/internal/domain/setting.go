@@ -0,0 +1,119 @@
+package domain
+
+import (
+	"strings"
+	"time"
+)
+
+// Setting keys of the system settings store
+const (
+	SettingSystemName       = "system_name"
+	SettingInstitutionName  = "institution_name"
+	SettingAdminEmail       = "admin_email"
+	SettingMaintenanceMode  = "maintenance_mode"
+	SettingMaintenanceMsg   = "maintenance_message"
+	SettingMaintenanceStart = "maintenance_start"
+	SettingMaintenanceEnd   = "maintenance_end"
+	SettingAcademicYear     = "academic_year"
+	SettingSemester         = "semester"
+	SettingMaxFileSize      = "max_file_size"
+	SettingAllowedFileTypes = "allowed_file_types"
+	SettingGPARepeatPolicy  = "gpa_repeat_policy"
+	SettingRegradeWindow    = "regrade_window_days"
+	SettingAttendanceLate   = "attendance_late_minutes"
+	SettingRegistration     = "registration_enabled"
+	SettingRegistrationMail = "registration_email_domains"
+	SettingMFARequired      = "mfa_required_roles"
+)
+
+// SystemSetting is a single persisted setting; Value holds the JSON encoded value
+type SystemSetting struct {
+	Key       string    `json:"key" gorm:"primaryKey;size:100"`
+	Value     string    `json:"value" gorm:"type:text;not null"`
+	UpdatedBy uint      `json:"updatedBy"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// SettingChange records a change of a setting
+type SettingChange struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	Key       string    `json:"key" gorm:"size:100;not null;index"`
+	OldValue  string    `json:"oldValue" gorm:"type:text"`
+	NewValue  string    `json:"newValue" gorm:"type:text;not null"`
+	ChangedBy uint      `json:"changedBy" gorm:"not null"`
+	ChangedAt time.Time `json:"changedAt" gorm:"not null"`
+}
+
+// SystemSettings is the typed view of all system settings
+type SystemSettings struct {
+	SystemName       string     `json:"system_name"`
+	InstitutionName  string     `json:"institution_name"`
+	AdminEmail       string     `json:"admin_email"`
+	MaintenanceMode  bool       `json:"maintenance_mode"`
+	MaintenanceMsg   string     `json:"maintenance_message"`
+	MaintenanceStart *time.Time `json:"maintenance_start"`
+	MaintenanceEnd   *time.Time `json:"maintenance_end"`
+	AcademicYear     string     `json:"academic_year"`
+	Semester         string     `json:"semester"`
+	MaxFileSize      int64      `json:"max_file_size"`
+	AllowedFileTypes []string   `json:"allowed_file_types"`
+	GPARepeatPolicy  string     `json:"gpa_repeat_policy"`
+	RegradeWindow    int        `json:"regrade_window_days"`        // days after grading to request a regrade; 0 disables requests
+	AttendanceLate   int        `json:"attendance_late_minutes"`    // minutes after a check-in window opens before students count as late
+	Registration     bool       `json:"registration_enabled"`       // whether anyone may sign up as a student
+	RegistrationMail []string   `json:"registration_email_domains"` // email domains allowed to sign up; empty allows any
+	MFARequired      []string   `json:"mfa_required_roles"`         // roles that must sign in with an authenticator code
+}
+
+// MaintenanceActive reports whether the system is in maintenance at the given time.
+// maintenance_mode switches it on immediately; otherwise a window scheduled from
+// maintenance_start lasts until maintenance_end, or is open-ended without one.
+func (s SystemSettings) MaintenanceActive(now time.Time) bool {
+	if s.MaintenanceMode {
+		return true
+	}
+	if s.MaintenanceStart == nil || now.Before(*s.MaintenanceStart) {
+		return false
+	}
+	return s.MaintenanceEnd == nil || now.Before(*s.MaintenanceEnd)
+}
+
+// IsFileTypeAllowed reports whether a file extension such as ".pdf" is allowed for uploads
+func (s SystemSettings) IsFileTypeAllowed(ext string) bool {
+	for _, allowed := range s.AllowedFileTypes {
+		if allowed == ext {
+			return true
+		}
+	}
+	return false
+}
+
+// IsRegistrationEmailAllowed reports whether an email address may be used to
+// sign up. Subdomains of an allowed domain are allowed too.
+func (s SystemSettings) IsRegistrationEmailAllowed(email string) bool {
+	if len(s.RegistrationMail) == 0 {
+		return true
+	}
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return false
+	}
+	domain := strings.ToLower(email[at+1:])
+	for _, allowed := range s.RegistrationMail {
+		if domain == allowed || strings.HasSuffix(domain, "."+allowed) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsMFARequired reports whether users of a role must use two-factor authentication
+func (s SystemSettings) IsMFARequired(role string) bool {
+	for _, required := range s.MFARequired {
+		if required == role {
+			return true
+		}
+	}
+	return false
+}
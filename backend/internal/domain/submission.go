@@ -0,0 +1,70 @@
+package domain
+
+import (
+	"math"
+	"strings"
+	"time"
+)
+
+// Submission statuses
+const (
+	SubmissionStatusSubmitted = "submitted"
+	SubmissionStatusLate      = "late"
+	SubmissionStatusGraded    = "graded"
+)
+
+// FileTypeList returns the assessment's allowed file extensions. An empty
+// list leaves the choice to the system settings.
+func (a *Assessment) FileTypeList() []string {
+	return ParseFileTypes(a.AllowedFileTypes)
+}
+
+// ParseFileTypes splits a comma separated list of file types into
+// lowercase extensions such as ".pdf"
+func ParseFileTypes(list string) []string {
+	var types []string
+	for _, t := range strings.Split(list, ",") {
+		t = strings.ToLower(strings.TrimSpace(t))
+		if t == "" {
+			continue
+		}
+		if !strings.HasPrefix(t, ".") {
+			t = "." + t
+		}
+		types = append(types, t)
+	}
+	return types
+}
+
+// LatePenalty returns how many started days a submission made at the given
+// time is past the due date, and the percentage deducted for it
+func (a *Assessment) LatePenalty(submittedAt time.Time) (days int, penalty float64) {
+	if a.DueDate.IsZero() || !submittedAt.After(a.DueDate) {
+		return 0, 0
+	}
+	days = int(math.Ceil(submittedAt.Sub(a.DueDate).Hours() / 24))
+	penalty = float64(days) * a.LatePenaltyPerDay
+	if a.MaxLatePenalty > 0 && penalty > a.MaxLatePenalty {
+		penalty = a.MaxLatePenalty
+	}
+	if penalty > 100 {
+		penalty = 100
+	}
+	return days, penalty
+}
+
+// HideResults clears the score, feedback and rubric levels of a submission
+// shown to its student before the assessment's grades are released
+func (s *Submission) HideResults() {
+	s.RawScore = 0
+	s.Score = 0
+	s.Feedback = ""
+	s.RubricSelections = nil
+	s.ResultsHidden = true
+}
+
+// ApplyGrade sets the submission's raw score and its score after the late penalty
+func (s *Submission) ApplyGrade(rawScore float64) {
+	s.RawScore = rawScore
+	s.Score = math.Round(rawScore*(100-s.LatePenalty)) / 100
+}
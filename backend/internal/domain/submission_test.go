@@ -0,0 +1,91 @@
+package domain
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestLatePenalty(t *testing.T) {
+	due := time.Date(2024, 3, 1, 23, 59, 0, 0, time.UTC)
+	tests := []struct {
+		name        string
+		assessment  Assessment
+		submittedAt time.Time
+		days        int
+		penalty     float64
+	}{
+		{"on time", Assessment{DueDate: due, LatePenaltyPerDay: 10}, due.Add(-time.Hour), 0, 0},
+		{"exactly at the due date", Assessment{DueDate: due, LatePenaltyPerDay: 10}, due, 0, 0},
+		{"a minute late starts a day", Assessment{DueDate: due, LatePenaltyPerDay: 10}, due.Add(time.Minute), 1, 10},
+		{"one full day", Assessment{DueDate: due, LatePenaltyPerDay: 10}, due.Add(24 * time.Hour), 1, 10},
+		{"into the second day", Assessment{DueDate: due, LatePenaltyPerDay: 10}, due.Add(25 * time.Hour), 2, 20},
+		{"no penalty per day", Assessment{DueDate: due}, due.Add(72 * time.Hour), 3, 0},
+		{"capped", Assessment{DueDate: due, LatePenaltyPerDay: 10, MaxLatePenalty: 25}, due.Add(5 * 24 * time.Hour), 5, 25},
+		{"under the cap", Assessment{DueDate: due, LatePenaltyPerDay: 10, MaxLatePenalty: 25}, due.Add(time.Hour), 1, 10},
+		{"never above 100", Assessment{DueDate: due, LatePenaltyPerDay: 30}, due.Add(10 * 24 * time.Hour), 10, 100},
+		{"cap above 100", Assessment{DueDate: due, LatePenaltyPerDay: 50, MaxLatePenalty: 150}, due.Add(4 * 24 * time.Hour), 4, 100},
+		{"no due date", Assessment{LatePenaltyPerDay: 10}, due, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			days, penalty := tt.assessment.LatePenalty(tt.submittedAt)
+			if days != tt.days || penalty != tt.penalty {
+				t.Errorf("LatePenalty = %d days, %v%%, want %d days, %v%%", days, penalty, tt.days, tt.penalty)
+			}
+		})
+	}
+}
+
+func TestApplyGrade(t *testing.T) {
+	tests := []struct {
+		name        string
+		latePenalty float64
+		rawScore    float64
+		score       float64
+	}{
+		{"on time", 0, 87.5, 87.5},
+		{"ten percent off", 10, 80, 72},
+		{"rounded to cents", 15, 33.33, 28.33},
+		{"capped penalty", 25, 100, 75},
+		{"full penalty", 100, 95, 0},
+		{"zero score", 20, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := Submission{LatePenalty: tt.latePenalty}
+			s.ApplyGrade(tt.rawScore)
+			if s.RawScore != tt.rawScore || s.Score != tt.score {
+				t.Errorf("ApplyGrade(%v) with %v%% penalty = raw %v, score %v, want score %v",
+					tt.rawScore, tt.latePenalty, s.RawScore, s.Score, tt.score)
+			}
+		})
+	}
+}
+
+func TestLatePenaltyAppliedToGrade(t *testing.T) {
+	due := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	a := Assessment{DueDate: due, LatePenaltyPerDay: 20, MaxLatePenalty: 30}
+	s := Submission{}
+	s.LateDays, s.LatePenalty = a.LatePenalty(due.Add(3 * 24 * time.Hour))
+	s.ApplyGrade(90)
+	if s.LateDays != 3 || s.LatePenalty != 30 || s.Score != 63 {
+		t.Errorf("3 days late = %d days, %v%%, score %v, want 3 days, 30%%, score 63", s.LateDays, s.LatePenalty, s.Score)
+	}
+}
+
+func TestParseFileTypes(t *testing.T) {
+	tests := []struct {
+		list string
+		want []string
+	}{
+		{"", nil},
+		{"pdf", []string{".pdf"}},
+		{" .PDF, docx ,,zip", []string{".pdf", ".docx", ".zip"}},
+	}
+	for _, tt := range tests {
+		if got := ParseFileTypes(tt.list); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseFileTypes(%q) = %v, want %v", tt.list, got, tt.want)
+		}
+	}
+}
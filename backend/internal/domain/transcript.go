@@ -0,0 +1,50 @@
+package domain
+
+import "time"
+
+// Repeat policies deciding which attempt of a repeated course counts
+// toward the cumulative GPA
+const (
+	RepeatPolicyBest   = "best"   // the attempt with the most grade points
+	RepeatPolicyLatest = "latest" // the most recent attempt
+)
+
+// Transcript lists a student's courses per semester with their GPAs.
+// Courses count toward the GPAs once they have a letter grade and credits;
+// withdrawn courses are listed but never count.
+type Transcript struct {
+	UserID           uint                 `json:"userId"`
+	StudentID        string               `json:"studentId"`
+	Name             string               `json:"name"`
+	Major            string               `json:"major"`
+	RepeatPolicy     string               `json:"repeatPolicy"`
+	Semesters        []TranscriptSemester `json:"semesters"`
+	CumulativeGPA    float64              `json:"cumulativeGpa"`
+	AttemptedCredits float64              `json:"attemptedCredits"` // credits of the courses the cumulative GPA is based on
+	EarnedCredits    float64              `json:"earnedCredits"`    // of those, the credits with a passing grade
+	GeneratedAt      time.Time            `json:"generatedAt"`
+}
+
+// TranscriptSemester is one semester of a transcript. Its GPA includes
+// every graded course of the semester, repeated or not.
+type TranscriptSemester struct {
+	Semester string             `json:"semester"`
+	Year     int                `json:"year"`
+	Courses  []TranscriptCourse `json:"courses"`
+	Credits  float64            `json:"credits"`
+	GPA      float64            `json:"gpa"`
+}
+
+// TranscriptCourse is one course taken by a student
+type TranscriptCourse struct {
+	CourseID     uint    `json:"courseId"`
+	Code         string  `json:"code"`
+	Title        string  `json:"title"`
+	Credits      float64 `json:"credits"`
+	Status       string  `json:"status"` // enrollment status: enrolled, completed, withdrawn
+	OverallScore float64 `json:"overallScore"`
+	LetterGrade  string  `json:"letterGrade"`
+	GradePoints  float64 `json:"gradePoints"`
+	Repeated     bool    `json:"repeated"`   // the course code was taken more than once
+	Cumulative   bool    `json:"cumulative"` // counts toward the cumulative GPA
+}
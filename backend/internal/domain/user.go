@@ -21,6 +21,7 @@ type User struct {
 	PlaceOfBirth    string         `json:"place_of_birth" gorm:"size:100"`
 	Department      string         `json:"department" gorm:"size:100"`
 	ProfilePhotoURL string         `json:"profile_photo_url" gorm:"size:255"`
+	EmailVerifiedAt *time.Time     `json:"email_verified_at"`
 	CreatedAt       time.Time      `json:"created_at"`
 	UpdatedAt       time.Time      `json:"updated_at"`
 	DeletedAt       gorm.DeletedAt `gorm:"index"`
@@ -52,6 +53,7 @@ type UserResponse struct {
 	Name      string    `json:"name"`
 	Email     string    `json:"email"`
 	Role      string    `json:"role"`
+	Verified  bool      `json:"email_verified"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
@@ -3,6 +3,10 @@ package handler
 import (
 	"backend/internal/domain"
 	"backend/internal/repository"
+	"backend/internal/service"
+	"backend/pkg/middleware"
+	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 	"time"
@@ -15,6 +19,8 @@ type AdminHandler struct {
 	userRepo       *repository.UserRepository
 	courseRepo     *repository.CourseRepository
 	assessmentRepo *repository.AssessmentRepository
+	enrollments    *service.EnrollmentManager
+	settings       *service.SettingsStore
 }
 
 // NewAdminHandler creates a new admin handler
@@ -22,11 +28,15 @@ func NewAdminHandler(
 	userRepo *repository.UserRepository,
 	courseRepo *repository.CourseRepository,
 	assessmentRepo *repository.AssessmentRepository,
+	enrollments *service.EnrollmentManager,
+	settings *service.SettingsStore,
 ) *AdminHandler {
 	return &AdminHandler{
 		userRepo:       userRepo,
 		courseRepo:     courseRepo,
 		assessmentRepo: assessmentRepo,
+		enrollments:    enrollments,
+		settings:       settings,
 	}
 }
 
@@ -129,7 +139,8 @@ func (h *AdminHandler) CreateUser(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusConflict, "Email already exists")
 	}
 	
-	// Create new user
+	// Create new user; accounts created by an admin need no email verification
+	verifiedAt := time.Now()
 	user := &domain.User{
 		Username:     createReq.Username,
 		Name:         createReq.Name,
@@ -142,6 +153,8 @@ func (h *AdminHandler) CreateUser(c echo.Context) error {
 		Department:   createReq.Department,
 		CreatedAt:    time.Now(),
 		UpdatedAt:    time.Now(),
+
+		EmailVerifiedAt: &verifiedAt,
 	}
 	
 	// Set password
@@ -435,33 +448,71 @@ func (h *AdminHandler) DeleteCourse(c echo.Context) error {
 
 // GetSystemSettings returns system settings
 func (h *AdminHandler) GetSystemSettings(c echo.Context) error {
-	// In a real application, these would come from a database
-	settings := map[string]interface{}{
-		"system_name":        "Learning Management System",
-		"institution_name":   "Your University",
-		"admin_email":        "admin@example.com",
-		"maintenance_mode":   false,
-		"academic_year":      "2024-2025",
-		"semester":           "Spring",
-		"max_file_size":      5242880, // 5MB
-		"allowed_file_types": []string{".pdf", ".doc", ".docx", ".jpg", ".png"},
-	}
-	
-	return c.JSON(http.StatusOK, settings)
+	if err := h.settings.Reload(); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to load settings")
+	}
+	
+	return c.JSON(http.StatusOK, h.settings.Current())
 }
 
 // UpdateSystemSettings updates system settings
 func (h *AdminHandler) UpdateSystemSettings(c echo.Context) error {
-	// Parse request
-	var settings map[string]interface{}
+	// Get admin ID from token
+	adminID, err := middleware.GetUserIDFromToken(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Invalid token")
+	}
 	
-	if err := c.Bind(&settings); err != nil {
+	// Parse request - only the keys present are changed
+	var changes map[string]json.RawMessage
+	if err := c.Bind(&changes); err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
 	}
+	if len(changes) == 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "No settings provided")
+	}
 	
-	// In a real application, you would save these settings to a database
+	settings, err := h.settings.Update(changes, adminID)
+	if err != nil {
+		var validationErr *service.SettingValidationError
+		if errors.As(err, &validationErr) {
+			return c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"message": "Invalid settings",
+				"errors":  validationErr.Errors,
+			})
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to update settings")
+	}
 	
-	return c.JSON(http.StatusOK, map[string]string{
-		"message": "Settings updated successfully",
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message":  "Settings updated successfully",
+		"settings": settings,
+	})
+}
+
+// GetSettingsHistory returns the change history of the system settings
+func (h *AdminHandler) GetSettingsHistory(c echo.Context) error {
+	// Parse pagination parameters
+	limit, _ := strconv.Atoi(c.QueryParam("limit"))
+	page, _ := strconv.Atoi(c.QueryParam("page"))
+	
+	if limit <= 0 {
+		limit = 20
+	}
+	if page <= 0 {
+		page = 1
+	}
+	
+	offset := (page - 1) * limit
+	
+	changes, err := h.settings.History(c.QueryParam("key"), limit, offset)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to get settings history")
+	}
+	
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"changes": changes,
+		"page":    page,
+		"limit":   limit,
 	})
-}
\ No newline at end of file
+}
@@ -0,0 +1,387 @@
+package handler
+
+import (
+	"backend/internal/domain"
+	"backend/internal/repository"
+	"backend/internal/service"
+	"backend/pkg/middleware"
+	"backend/pkg/spreadsheet"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+// rosterColumns maps normalized header names to roster fields
+var rosterColumns = map[string]string{
+	"username":         "username",
+	"name":             "name",
+	"full_name":        "name",
+	"email":            "email",
+	"password":         "password",
+	"role":             "role",
+	"gender":           "gender",
+	"religion":         "religion",
+	"date_of_birth":    "date_of_birth",
+	"dob":              "date_of_birth",
+	"place_of_birth":   "place_of_birth",
+	"department":       "department",
+	"student_id":       "student_id",
+	"nim":              "student_id",
+	"degree":           "degree",
+	"major":            "major",
+	"stream":           "stream",
+	"current_semester": "current_semester",
+	"semester":         "current_semester",
+	"position":         "position",
+	"specialization":   "specialization",
+	"course_codes":     "course_codes",
+	"courses":          "course_codes",
+}
+
+var requiredRosterColumns = []string{"username", "name", "email", "password", "role"}
+
+// ImportUsers creates users in bulk from an uploaded CSV or XLSX roster.
+// Every row is validated first; with dry_run=true only the report is returned.
+// Otherwise the whole roster is created in one transaction, so a file with
+// any invalid row creates nothing.
+func (h *AdminHandler) ImportUsers(c echo.Context) error {
+	adminID, err := middleware.GetUserIDFromToken(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Invalid token")
+	}
+
+	dryRun, _ := strconv.ParseBool(c.FormValue("dry_run"))
+	if !dryRun {
+		dryRun, _ = strconv.ParseBool(c.QueryParam("dry_run"))
+	}
+
+	// Get file from request
+	file, err := c.FormFile("file")
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "No file uploaded")
+	}
+	src, err := file.Open()
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to open uploaded file")
+	}
+	defer src.Close()
+
+	data, err := io.ReadAll(src)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to read uploaded file")
+	}
+
+	records, err := spreadsheet.Read(file.Filename, data)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	if len(records) < 2 {
+		return echo.NewHTTPError(http.StatusBadRequest, "File has no data rows")
+	}
+
+	columns, err := parseRosterHeader(records[0])
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	result := domain.RosterImportResult{DryRun: dryRun}
+	rows := make([]domain.RosterImportRow, 0, len(records)-1)
+	courses := make(map[string]*domain.Course)
+	seenUsernames := make(map[string]int)
+	seenEmails := make(map[string]int)
+	seenStudentIDs := make(map[string]int)
+
+	for i, record := range records[1:] {
+		rowNumber := i + 2 // 1-based, after the header
+		if isBlankRecord(record) {
+			continue
+		}
+
+		row, errs := parseRosterRecord(record, columns)
+		errs = append(errs, h.validateRosterRow(c, row, rowNumber, courses, seenUsernames, seenEmails, seenStudentIDs)...)
+
+		rowResult := domain.RosterRowResult{
+			Row:      rowNumber,
+			Username: row.Username,
+			Status:   "valid",
+			Errors:   errs,
+		}
+		if len(errs) > 0 {
+			rowResult.Status = "invalid"
+		} else {
+			result.ValidRows++
+		}
+		result.Rows = append(result.Rows, rowResult)
+		rows = append(rows, row)
+	}
+	result.TotalRows = len(result.Rows)
+
+	if dryRun {
+		return c.JSON(http.StatusOK, result)
+	}
+	if result.ValidRows != result.TotalRows {
+		return c.JSON(http.StatusUnprocessableEntity, result)
+	}
+
+	err = h.userRepo.Transaction(func(tx *gorm.DB) error {
+		userRepo := repository.NewUserRepository(tx)
+		courseRepo := repository.NewCourseRepository(tx)
+		enrollmentRepo := repository.NewEnrollmentRepository(tx)
+
+		for i, row := range rows {
+			user, err := newRosterUser(row)
+			if err != nil {
+				return fmt.Errorf("row %d: failed to hash password: %w", result.Rows[i].Row, err)
+			}
+			if err := userRepo.Create(user); err != nil {
+				return fmt.Errorf("row %d: failed to create user: %w", result.Rows[i].Row, err)
+			}
+
+			for _, code := range row.CourseCodes {
+				course := courses[code]
+				if row.Role == "instructor" {
+					err = courseRepo.AddInstructor(&domain.CourseInstructor{CourseID: course.ID, UserID: user.ID})
+				} else {
+					_, err = h.enrollments.EnrollTx(enrollmentRepo, service.EnrollmentAction{
+						CourseID: course.ID,
+						UserID:   user.ID,
+						ActorID:  adminID,
+						Reason:   "roster import",
+						Override: true,
+						Approved: true,
+					})
+				}
+				if err != nil {
+					return fmt.Errorf("row %d: failed to add %s to %s: %w", result.Rows[i].Row, row.Username, code, err)
+				}
+				result.Enrolled++
+			}
+
+			result.Rows[i].Status = "created"
+			result.Created++
+		}
+		return nil
+	})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Import rolled back: "+err.Error())
+	}
+
+	return c.JSON(http.StatusCreated, result)
+}
+
+// parseRosterHeader maps each column index to a roster field
+func parseRosterHeader(header []string) (map[int]string, error) {
+	columns := make(map[int]string)
+	present := make(map[string]bool)
+	for i, name := range header {
+		key := strings.ToLower(strings.TrimSpace(name))
+		key = strings.NewReplacer(" ", "_", "-", "_").Replace(key)
+		if field, ok := rosterColumns[key]; ok {
+			columns[i] = field
+			present[field] = true
+		}
+	}
+
+	var missing []string
+	for _, field := range requiredRosterColumns {
+		if !present[field] {
+			missing = append(missing, field)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("missing required columns: %s", strings.Join(missing, ", "))
+	}
+	return columns, nil
+}
+
+// parseRosterRecord converts a file row into a RosterImportRow
+func parseRosterRecord(record []string, columns map[int]string) (domain.RosterImportRow, []string) {
+	var row domain.RosterImportRow
+	var errs []string
+
+	for i, value := range record {
+		field, ok := columns[i]
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+
+		switch field {
+		case "username":
+			row.Username = value
+		case "name":
+			row.Name = value
+		case "email":
+			row.Email = strings.ToLower(value)
+		case "password":
+			row.Password = value
+		case "role":
+			row.Role = strings.ToLower(value)
+		case "gender":
+			row.Gender = value
+		case "religion":
+			row.Religion = value
+		case "date_of_birth":
+			dob, err := spreadsheet.ParseDate(value)
+			if err != nil {
+				errs = append(errs, "date_of_birth: "+err.Error())
+				continue
+			}
+			row.DateOfBirth = &dob
+		case "place_of_birth":
+			row.PlaceOfBirth = value
+		case "department":
+			row.Department = value
+		case "student_id":
+			row.StudentID = value
+		case "degree":
+			row.Degree = value
+		case "major":
+			row.Major = value
+		case "stream":
+			row.Stream = value
+		case "current_semester":
+			row.CurrentSemester = value
+		case "position":
+			row.Position = value
+		case "specialization":
+			row.Specialization = value
+		case "course_codes":
+			for _, code := range strings.FieldsFunc(value, func(r rune) bool { return r == ';' || r == ',' || r == '|' }) {
+				if code = strings.TrimSpace(code); code != "" {
+					row.CourseCodes = append(row.CourseCodes, code)
+				}
+			}
+		}
+	}
+	return row, errs
+}
+
+// validateRosterRow applies the validator tags and checks the row against
+// the database and the rows before it
+func (h *AdminHandler) validateRosterRow(
+	c echo.Context,
+	row domain.RosterImportRow,
+	rowNumber int,
+	courses map[string]*domain.Course,
+	seenUsernames, seenEmails, seenStudentIDs map[string]int,
+) []string {
+	var errs []string
+
+	if err := c.Validate(&row); err != nil {
+		var validationErrs validator.ValidationErrors
+		if errors.As(err, &validationErrs) {
+			for _, fe := range validationErrs {
+				errs = append(errs, fmt.Sprintf("%s: failed '%s' validation", fe.Field(), fe.Tag()))
+			}
+		} else {
+			errs = append(errs, err.Error())
+		}
+	}
+
+	if row.Username != "" {
+		if first, ok := seenUsernames[row.Username]; ok {
+			errs = append(errs, fmt.Sprintf("Username: duplicates row %d", first))
+		} else if _, err := h.userRepo.GetByUsername(row.Username); err == nil {
+			errs = append(errs, "Username: already exists")
+		}
+		seenUsernames[row.Username] = rowNumber
+	}
+	if row.Email != "" {
+		if first, ok := seenEmails[row.Email]; ok {
+			errs = append(errs, fmt.Sprintf("Email: duplicates row %d", first))
+		} else if _, err := h.userRepo.GetByEmail(row.Email); err == nil {
+			errs = append(errs, "Email: already exists")
+		}
+		seenEmails[row.Email] = rowNumber
+	}
+	if row.StudentID != "" {
+		if row.Role != "student" {
+			errs = append(errs, "StudentID: only allowed for students")
+		} else if first, ok := seenStudentIDs[row.StudentID]; ok {
+			errs = append(errs, fmt.Sprintf("StudentID: duplicates row %d", first))
+		} else if exists, err := h.userRepo.StudentIDExists(row.StudentID); err == nil && exists {
+			errs = append(errs, "StudentID: already exists")
+		}
+		seenStudentIDs[row.StudentID] = rowNumber
+	}
+
+	if len(row.CourseCodes) > 0 && row.Role == "admin" {
+		errs = append(errs, "CourseCodes: admins cannot be enrolled in courses")
+	}
+	for _, code := range row.CourseCodes {
+		if _, ok := courses[code]; ok {
+			continue
+		}
+		course, err := h.courseRepo.GetByCode(code)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("CourseCodes: course %s not found", code))
+			continue
+		}
+		courses[code] = course
+	}
+
+	return errs
+}
+
+// newRosterUser builds the user with its student or instructor profile
+func newRosterUser(row domain.RosterImportRow) (*domain.User, error) {
+	// Accounts created by an admin need no email verification
+	verifiedAt := time.Now()
+	user := &domain.User{
+		Username:     row.Username,
+		Name:         row.Name,
+		Email:        row.Email,
+		Role:         row.Role,
+		Gender:       row.Gender,
+		Religion:     row.Religion,
+		DateOfBirth:  row.DateOfBirth,
+		PlaceOfBirth: row.PlaceOfBirth,
+		Department:   row.Department,
+		CreatedAt:    time.Now(),
+		UpdatedAt:    time.Now(),
+
+		EmailVerifiedAt: &verifiedAt,
+	}
+	if err := user.SetPassword(row.Password); err != nil {
+		return nil, err
+	}
+
+	switch row.Role {
+	case "student":
+		user.StudentInfo = domain.StudentInfo{
+			StudentID:       row.StudentID,
+			Degree:          row.Degree,
+			Major:           row.Major,
+			Stream:          row.Stream,
+			CurrentSemester: row.CurrentSemester,
+		}
+	case "instructor":
+		user.InstructorInfo = domain.InstructorInfo{
+			Position:       row.Position,
+			Department:     row.Department,
+			Specialization: row.Specialization,
+		}
+	}
+	return user, nil
+}
+
+func isBlankRecord(record []string) bool {
+	for _, value := range record {
+		if strings.TrimSpace(value) != "" {
+			return false
+		}
+	}
+	return true
+}
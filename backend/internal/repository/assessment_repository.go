@@ -2,9 +2,19 @@ package repository
 
 import (
 	"backend/internal/domain"
+	"errors"
+
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// AssessmentFilter narrows an assessment listing; zero values are ignored
+type AssessmentFilter struct {
+	CourseID     uint
+	StudentID    uint // only assessments of courses the student is enrolled in
+	InstructorID uint // only assessments of courses the instructor teaches
+}
+
 // AssessmentRepository handles database operations for assessments
 type AssessmentRepository struct {
 	db *gorm.DB
@@ -15,6 +25,13 @@ func NewAssessmentRepository(db *gorm.DB) *AssessmentRepository {
 	return &AssessmentRepository{db}
 }
 
+// Transaction runs fn inside a database transaction with a repository bound to it
+func (r *AssessmentRepository) Transaction(fn func(tx *AssessmentRepository) error) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return fn(&AssessmentRepository{tx})
+	})
+}
+
 // GetByID retrieves an assessment by ID
 func (r *AssessmentRepository) GetByID(id uint) (*domain.Assessment, error) {
 	var assessment domain.Assessment
@@ -55,4 +72,167 @@ func (r *AssessmentRepository) CountAssessments() (int64, error) {
 		return 0, err
 	}
 	return count, nil
-}
\ No newline at end of file
+}
+
+// FindAssessments retrieves the assessments matching the filter, soonest due first
+func (r *AssessmentRepository) FindAssessments(filter AssessmentFilter) ([]domain.Assessment, error) {
+	query := r.db.Model(&domain.Assessment{})
+	if filter.CourseID != 0 {
+		query = query.Where("course_id = ?", filter.CourseID)
+	}
+	if filter.StudentID != 0 {
+		query = query.Where("EXISTS (SELECT 1 FROM course_students cs WHERE cs.course_id = assessments.course_id AND cs.user_id = ? AND cs.status IN ? AND cs.deleted_at IS NULL)",
+			filter.StudentID, []string{domain.EnrollmentStatusEnrolled, domain.EnrollmentStatusCompleted})
+	}
+	if filter.InstructorID != 0 {
+		query = query.Where("EXISTS (SELECT 1 FROM course_instructors ci WHERE ci.course_id = assessments.course_id AND ci.user_id = ? AND ci.deleted_at IS NULL)",
+			filter.InstructorID)
+	}
+
+	var assessments []domain.Assessment
+	if err := query.Order("due_date ASC, id ASC").Find(&assessments).Error; err != nil {
+		return nil, err
+	}
+	return assessments, nil
+}
+
+// LockStudentAssessment serializes submissions of one student to an
+// assessment until the transaction ends
+func (r *AssessmentRepository) LockStudentAssessment(assessmentID, userID uint) error {
+	// The first key keeps these locks apart from the exam attempt locks
+	return r.db.Exec("SELECT pg_advisory_xact_lock(?, ?)", int32(assessmentID)|-1<<31, int32(userID)).Error
+}
+
+// GetSubmission retrieves a submission with its rubric selections
+func (r *AssessmentRepository) GetSubmission(id uint) (*domain.Submission, error) {
+	var submission domain.Submission
+	if err := r.db.Preload("RubricSelections").First(&submission, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("submission not found")
+		}
+		return nil, err
+	}
+	return &submission, nil
+}
+
+// GetUserSubmissions retrieves a student's submissions to an assessment, oldest first
+func (r *AssessmentRepository) GetUserSubmissions(assessmentID, userID uint) ([]domain.Submission, error) {
+	var submissions []domain.Submission
+	err := r.db.Where("assessment_id = ? AND user_id = ?", assessmentID, userID).
+		Order("attempt_number ASC").
+		Find(&submissions).Error
+	if err != nil {
+		return nil, err
+	}
+	return submissions, nil
+}
+
+// GetAssessmentSubmissions retrieves every submission to an assessment with
+// the students who made them
+func (r *AssessmentRepository) GetAssessmentSubmissions(assessmentID uint) ([]domain.Submission, error) {
+	var submissions []domain.Submission
+	err := r.db.Preload("User").
+		Where("assessment_id = ?", assessmentID).
+		Order("user_id ASC, attempt_number ASC").
+		Find(&submissions).Error
+	if err != nil {
+		return nil, err
+	}
+	return submissions, nil
+}
+
+// LastAttemptNumber returns the highest attempt number of a student's
+// submissions, counting deleted ones so attempt numbers are never reused
+func (r *AssessmentRepository) LastAttemptNumber(assessmentID, userID uint) (int, error) {
+	var last int
+	err := r.db.Unscoped().Model(&domain.Submission{}).
+		Where("assessment_id = ? AND user_id = ?", assessmentID, userID).
+		Select("COALESCE(MAX(attempt_number), 0)").
+		Scan(&last).Error
+	return last, err
+}
+
+// CreateSubmission creates a new submission
+func (r *AssessmentRepository) CreateSubmission(submission *domain.Submission) error {
+	return r.db.Omit(clause.Associations).Create(submission).Error
+}
+
+// DeleteSubmission removes a submission for good, freeing its attempt number
+func (r *AssessmentRepository) DeleteSubmission(id uint) error {
+	return r.db.Unscoped().Delete(&domain.Submission{}, id).Error
+}
+
+// SaveSubmission updates a submission
+func (r *AssessmentRepository) SaveSubmission(submission *domain.Submission) error {
+	return r.db.Omit(clause.Associations).Save(submission).Error
+}
+
+// GetRubricItems retrieves the rubric of an assessment in order
+func (r *AssessmentRepository) GetRubricItems(assessmentID uint) ([]domain.RubricItem, error) {
+	var items []domain.RubricItem
+	err := r.db.Preload("LearningOutcome").
+		Where("assessment_id = ?", assessmentID).
+		Order(`"order" ASC, id ASC`).
+		Find(&items).Error
+	if err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// GetRubricItem retrieves a rubric item of an assessment
+func (r *AssessmentRepository) GetRubricItem(assessmentID, itemID uint) (*domain.RubricItem, error) {
+	var item domain.RubricItem
+	err := r.db.Preload("LearningOutcome").
+		Where("assessment_id = ?", assessmentID).
+		First(&item, itemID).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("rubric item not found")
+		}
+		return nil, err
+	}
+	return &item, nil
+}
+
+// CreateRubricItem creates a new rubric item
+func (r *AssessmentRepository) CreateRubricItem(item *domain.RubricItem) error {
+	return r.db.Omit(clause.Associations).Create(item).Error
+}
+
+// UpdateRubricItem updates a rubric item
+func (r *AssessmentRepository) UpdateRubricItem(item *domain.RubricItem) error {
+	return r.db.Omit(clause.Associations).Save(item).Error
+}
+
+// DeleteRubricItem deletes a rubric item of an assessment. Selections made
+// with it are kept.
+func (r *AssessmentRepository) DeleteRubricItem(assessmentID, itemID uint) error {
+	return r.db.Where("assessment_id = ?", assessmentID).Delete(&domain.RubricItem{}, itemID).Error
+}
+
+// GetCourseLearningOutcome retrieves a learning outcome of the course's syllabus
+func (r *AssessmentRepository) GetCourseLearningOutcome(courseID, outcomeID uint) (*domain.LearningOutcome, error) {
+	var outcome domain.LearningOutcome
+	err := r.db.Joins("JOIN syllabuses ON syllabuses.id = learning_outcomes.syllabus_id AND syllabuses.deleted_at IS NULL").
+		Where("syllabuses.course_id = ?", courseID).
+		First(&outcome, outcomeID).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("learning outcome not found")
+		}
+		return nil, err
+	}
+	return &outcome, nil
+}
+
+// ReplaceRubricSelections replaces the rubric selections of a submission
+func (r *AssessmentRepository) ReplaceRubricSelections(submissionID uint, selections []domain.RubricSelection) error {
+	if err := r.db.Where("submission_id = ?", submissionID).Delete(&domain.RubricSelection{}).Error; err != nil {
+		return err
+	}
+	if len(selections) == 0 {
+		return nil
+	}
+	return r.db.Create(&selections).Error
+}
@@ -0,0 +1,200 @@
+package repository
+
+import (
+	"backend/internal/domain"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// AttendanceRepository handles database operations for attendance records
+// and check-in windows
+type AttendanceRepository struct {
+	db *gorm.DB
+}
+
+// NewAttendanceRepository creates a new attendance repository
+func NewAttendanceRepository(db *gorm.DB) *AttendanceRepository {
+	return &AttendanceRepository{db}
+}
+
+// Transaction runs fn inside a database transaction with a repository bound to it
+func (r *AttendanceRepository) Transaction(fn func(tx *AttendanceRepository) error) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return fn(&AttendanceRepository{tx})
+	})
+}
+
+// LockSession serializes check-ins, overrides and window changes of a
+// session until the transaction ends
+func (r *AttendanceRepository) LockSession(sessionID uint) error {
+	return r.db.Exec("SELECT pg_advisory_xact_lock(hashtext('attendance'), ?)", sessionID).Error
+}
+
+// CreateWindow creates a check-in window
+func (r *AttendanceRepository) CreateWindow(window *domain.AttendanceWindow) error {
+	return r.db.Create(window).Error
+}
+
+// SaveWindow updates a check-in window
+func (r *AttendanceRepository) SaveWindow(window *domain.AttendanceWindow) error {
+	return r.db.Save(window).Error
+}
+
+// GetWindow retrieves a check-in window by ID
+func (r *AttendanceRepository) GetWindow(id uint) (*domain.AttendanceWindow, error) {
+	var window domain.AttendanceWindow
+	if err := r.db.First(&window, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("check-in window not found")
+		}
+		return nil, err
+	}
+	return &window, nil
+}
+
+// GetUnclosedWindow retrieves the latest window of a session that has not
+// been closed, whether or not its time is up
+func (r *AttendanceRepository) GetUnclosedWindow(sessionID uint) (*domain.AttendanceWindow, error) {
+	var window domain.AttendanceWindow
+	err := r.db.Where("session_id = ? AND closed_at IS NULL", sessionID).
+		Order("opened_at DESC, id DESC").
+		First(&window).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("check-in window not found")
+		}
+		return nil, err
+	}
+	return &window, nil
+}
+
+// GetExpiredWindows retrieves the windows whose time is up but that nobody
+// has closed, oldest first
+func (r *AttendanceRepository) GetExpiredWindows(now time.Time, limit int) ([]domain.AttendanceWindow, error) {
+	var windows []domain.AttendanceWindow
+	err := r.db.Where("closed_at IS NULL AND closes_at <= ?", now).
+		Order("closes_at, id").
+		Limit(limit).
+		Find(&windows).Error
+	if err != nil {
+		return nil, err
+	}
+	return windows, nil
+}
+
+// GetRecord retrieves a student's attendance record for a session
+func (r *AttendanceRepository) GetRecord(sessionID, userID uint) (*domain.Attendance, error) {
+	var record domain.Attendance
+	if err := r.db.Where("session_id = ? AND user_id = ?", sessionID, userID).First(&record).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("attendance not found")
+		}
+		return nil, err
+	}
+	return &record, nil
+}
+
+// SaveRecord creates or updates an attendance record
+func (r *AttendanceRepository) SaveRecord(record *domain.Attendance) error {
+	return r.db.Omit(clause.Associations).Save(record).Error
+}
+
+// AddChange records a change of an attendance status
+func (r *AttendanceRepository) AddChange(change *domain.AttendanceChange) error {
+	return r.db.Create(change).Error
+}
+
+// GetSessionRecords retrieves the attendance records of a session with their students
+func (r *AttendanceRepository) GetSessionRecords(sessionID uint) ([]domain.Attendance, error) {
+	var records []domain.Attendance
+	err := r.db.Preload("User").
+		Where("session_id = ?", sessionID).
+		Order("user_id ASC").
+		Find(&records).Error
+	if err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// GetChanges retrieves the history of the attendance records of a session,
+// newest first
+func (r *AttendanceRepository) GetChanges(sessionID uint) ([]domain.AttendanceChange, error) {
+	var changes []domain.AttendanceChange
+	err := r.db.Where("session_id = ?", sessionID).
+		Order("changed_at DESC, id DESC").
+		Find(&changes).Error
+	if err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
+
+// GetCourseRecords retrieves the attendance records for the sessions of a
+// course with their students. userID narrows them to one student when not zero.
+func (r *AttendanceRepository) GetCourseRecords(courseID, userID uint) ([]domain.Attendance, error) {
+	query := r.db.Preload("User").
+		Joins("JOIN sessions ON sessions.id = attendances.session_id AND sessions.deleted_at IS NULL").
+		Where("sessions.course_id = ?", courseID)
+	if userID != 0 {
+		query = query.Where("attendances.user_id = ?", userID)
+	}
+
+	var records []domain.Attendance
+	if err := query.Order("attendances.user_id ASC, sessions.number ASC").Find(&records).Error; err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// GetUserRecords retrieves a student's attendance records across courses
+// with their sessions, most recent session first
+func (r *AttendanceRepository) GetUserRecords(userID uint) ([]domain.Attendance, error) {
+	var records []domain.Attendance
+	err := r.db.Preload("Session").
+		Joins("JOIN sessions ON sessions.id = attendances.session_id AND sessions.deleted_at IS NULL").
+		Where("attendances.user_id = ?", userID).
+		Order("sessions.date DESC, sessions.id DESC").
+		Find(&records).Error
+	if err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// GetUnrecordedStudentIDs retrieves the students enrolled in a course who
+// have no attendance record for the session yet
+func (r *AttendanceRepository) GetUnrecordedStudentIDs(courseID, sessionID uint) ([]uint, error) {
+	var ids []uint
+	err := r.db.Model(&domain.CourseStudent{}).
+		Where("course_id = ? AND status = ?", courseID, domain.EnrollmentStatusEnrolled).
+		Where("NOT EXISTS (SELECT 1 FROM attendances a WHERE a.session_id = ? AND a.user_id = course_students.user_id)", sessionID).
+		Order("user_id ASC").
+		Pluck("user_id", &ids).Error
+	return ids, err
+}
+
+// GetPolicy retrieves the attendance policy of a course
+func (r *AttendanceRepository) GetPolicy(courseID uint) (*domain.AttendancePolicy, error) {
+	var policy domain.AttendancePolicy
+	if err := r.db.Where("course_id = ?", courseID).First(&policy).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("attendance policy not found")
+		}
+		return nil, err
+	}
+	return &policy, nil
+}
+
+// SavePolicy creates or updates the attendance policy of a course
+func (r *AttendanceRepository) SavePolicy(policy *domain.AttendancePolicy) error {
+	return r.db.Save(policy).Error
+}
+
+// DeletePolicy removes the attendance policy of a course
+func (r *AttendanceRepository) DeletePolicy(courseID uint) error {
+	return r.db.Where("course_id = ?", courseID).Delete(&domain.AttendancePolicy{}).Error
+}
@@ -13,6 +13,17 @@ type CourseRepository struct {
 	db *gorm.DB
 }
 
+// CourseFilter narrows down a course listing.
+// StudentID and InstructorID restrict the result to courses the given user
+// is enrolled in or teaches; zero values are ignored.
+type CourseFilter struct {
+	Semester     string
+	Year         int
+	Category     string
+	StudentID    uint
+	InstructorID uint
+}
+
 // NewCourseRepository creates a new course repository
 func NewCourseRepository(db *gorm.DB) *CourseRepository {
 	return &CourseRepository{db}
@@ -30,6 +41,36 @@ func (r *CourseRepository) GetByID(id uint) (*domain.Course, error) {
 	return &course, nil
 }
 
+// GetByIDWithDetails retrieves a course with its sessions and instructors preloaded
+func (r *CourseRepository) GetByIDWithDetails(id uint) (*domain.Course, error) {
+	var course domain.Course
+	err := r.db.
+		Preload("Sessions", func(db *gorm.DB) *gorm.DB {
+			return db.Order("number ASC")
+		}).
+		Preload("CourseInstructors.User").
+		First(&course, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("course not found")
+		}
+		return nil, err
+	}
+	return &course, nil
+}
+
+// GetByCode retrieves the most recent offering of a course by its code
+func (r *CourseRepository) GetByCode(code string) (*domain.Course, error) {
+	var course domain.Course
+	if err := r.db.Where("code = ?", code).Order("year DESC, id DESC").First(&course).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("course not found")
+		}
+		return nil, err
+	}
+	return &course, nil
+}
+
 // Create creates a new course
 func (r *CourseRepository) Create(course *domain.Course) error {
 	return r.db.Create(course).Error
@@ -61,4 +102,127 @@ func (r *CourseRepository) CountCourses() (int64, error) {
 		return 0, err
 	}
 	return count, nil
-}
\ No newline at end of file
+}
+
+// FindCourses retrieves courses matching the filter with pagination and
+// returns the total number of matches
+func (r *CourseRepository) FindCourses(filter CourseFilter, limit, offset int) ([]domain.Course, int64, error) {
+	query := r.filteredQuery(filter)
+
+	var count int64
+	if err := query.Count(&count).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var courses []domain.Course
+	if err := query.Order("courses.year DESC, courses.code ASC").Limit(limit).Offset(offset).Find(&courses).Error; err != nil {
+		return nil, 0, err
+	}
+	return courses, count, nil
+}
+
+func (r *CourseRepository) filteredQuery(filter CourseFilter) *gorm.DB {
+	query := r.db.Model(&domain.Course{})
+
+	if filter.Semester != "" {
+		query = query.Where("courses.semester = ?", filter.Semester)
+	}
+	if filter.Year > 0 {
+		query = query.Where("courses.year = ?", filter.Year)
+	}
+	if filter.Category != "" {
+		query = query.Where("courses.category = ?", filter.Category)
+	}
+	if filter.StudentID > 0 {
+		query = query.Where(
+			"EXISTS (SELECT 1 FROM course_students cs WHERE cs.course_id = courses.id AND cs.user_id = ? AND cs.status IN ? AND cs.deleted_at IS NULL)",
+			filter.StudentID, []string{domain.EnrollmentStatusEnrolled, domain.EnrollmentStatusCompleted},
+		)
+	}
+	if filter.InstructorID > 0 {
+		query = query.Where(
+			"EXISTS (SELECT 1 FROM course_instructors ci WHERE ci.course_id = courses.id AND ci.user_id = ? AND ci.deleted_at IS NULL)",
+			filter.InstructorID,
+		)
+	}
+	return query
+}
+
+// IsStudentEnrolled reports whether the user is enrolled in or has completed the course
+func (r *CourseRepository) IsStudentEnrolled(courseID, userID uint) (bool, error) {
+	var count int64
+	err := r.db.Model(&domain.CourseStudent{}).
+		Where("course_id = ? AND user_id = ? AND status IN ?", courseID, userID,
+			[]string{domain.EnrollmentStatusEnrolled, domain.EnrollmentStatusCompleted}).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// IsInstructor reports whether the user teaches the course
+func (r *CourseRepository) IsInstructor(courseID, userID uint) (bool, error) {
+	var count int64
+	err := r.db.Model(&domain.CourseInstructor{}).
+		Where("course_id = ? AND user_id = ?", courseID, userID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// GetInstructors retrieves the instructors of a course
+func (r *CourseRepository) GetInstructors(courseID uint) ([]domain.CourseInstructor, error) {
+	var instructors []domain.CourseInstructor
+	err := r.db.Preload("User").
+		Where("course_id = ?", courseID).
+		Order("is_main DESC, id ASC").
+		Find(&instructors).Error
+	if err != nil {
+		return nil, err
+	}
+	return instructors, nil
+}
+
+// GetInstructor retrieves a single instructor assignment of a course
+func (r *CourseRepository) GetInstructor(courseID, userID uint) (*domain.CourseInstructor, error) {
+	var instructor domain.CourseInstructor
+	if err := r.db.Where("course_id = ? AND user_id = ?", courseID, userID).First(&instructor).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("course instructor not found")
+		}
+		return nil, err
+	}
+	return &instructor, nil
+}
+
+// AddInstructor assigns an instructor to a course
+func (r *CourseRepository) AddInstructor(instructor *domain.CourseInstructor) error {
+	return r.db.Create(instructor).Error
+}
+
+// RemoveInstructor removes an instructor from a course
+func (r *CourseRepository) RemoveInstructor(courseID, userID uint) error {
+	return r.db.Where("course_id = ? AND user_id = ?", courseID, userID).Delete(&domain.CourseInstructor{}).Error
+}
+
+// GetStudents retrieves the enrollments of a course
+func (r *CourseRepository) GetStudents(courseID uint) ([]domain.CourseStudent, error) {
+	var students []domain.CourseStudent
+	err := r.db.Preload("User").
+		Where("course_id = ?", courseID).
+		Order("enrolled_at ASC").
+		Find(&students).Error
+	if err != nil {
+		return nil, err
+	}
+	return students, nil
+}
+
+// GetEnrollment retrieves a student's enrollment in a course
+func (r *CourseRepository) GetEnrollment(courseID, userID uint) (*domain.CourseStudent, error) {
+	var enrollment domain.CourseStudent
+	if err := r.db.Where("course_id = ? AND user_id = ?", courseID, userID).First(&enrollment).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("enrollment not found")
+		}
+		return nil, err
+	}
+	return &enrollment, nil
+}
@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"backend/internal/domain"
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+// DocumentRepository handles database operations for issued documents
+type DocumentRepository struct {
+	db *gorm.DB
+}
+
+// NewDocumentRepository creates a new document repository
+func NewDocumentRepository(db *gorm.DB) *DocumentRepository {
+	return &DocumentRepository{db}
+}
+
+// Create records an issued document
+func (r *DocumentRepository) Create(document *domain.IssuedDocument) error {
+	return r.db.Omit("User").Create(document).Error
+}
+
+// GetByCode retrieves an issued document with its holder by verification code
+func (r *DocumentRepository) GetByCode(code string) (*domain.IssuedDocument, error) {
+	var document domain.IssuedDocument
+	if err := r.db.Preload("User").Where("code = ?", code).First(&document).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("document not found")
+		}
+		return nil, err
+	}
+	return &document, nil
+}
+
+// GetUserDocuments retrieves the documents issued to a user, newest first
+func (r *DocumentRepository) GetUserDocuments(userID uint) ([]domain.IssuedDocument, error) {
+	var documents []domain.IssuedDocument
+	err := r.db.Where("user_id = ?", userID).Order("issued_at DESC").Find(&documents).Error
+	if err != nil {
+		return nil, err
+	}
+	return documents, nil
+}
+
+// Save updates an issued document
+func (r *DocumentRepository) Save(document *domain.IssuedDocument) error {
+	return r.db.Omit("User").Save(document).Error
+}
@@ -0,0 +1,126 @@
+package repository
+
+import (
+	"backend/internal/domain"
+	"errors"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// EnrollmentRepository handles database operations for course enrollments
+type EnrollmentRepository struct {
+	db *gorm.DB
+}
+
+// NewEnrollmentRepository creates a new enrollment repository
+func NewEnrollmentRepository(db *gorm.DB) *EnrollmentRepository {
+	return &EnrollmentRepository{db}
+}
+
+// Transaction runs fn inside a database transaction with a repository bound to it
+func (r *EnrollmentRepository) Transaction(fn func(tx *EnrollmentRepository) error) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return fn(&EnrollmentRepository{tx})
+	})
+}
+
+// LockCourse retrieves a course and locks its row until the transaction ends,
+// serializing seat allocation for that course
+func (r *EnrollmentRepository) LockCourse(courseID uint) (*domain.Course, error) {
+	var course domain.Course
+	err := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).First(&course, courseID).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("course not found")
+		}
+		return nil, err
+	}
+	return &course, nil
+}
+
+// GetEnrollment retrieves a student's enrollment in a course
+func (r *EnrollmentRepository) GetEnrollment(courseID, userID uint) (*domain.CourseStudent, error) {
+	var enrollment domain.CourseStudent
+	if err := r.db.Where("course_id = ? AND user_id = ?", courseID, userID).First(&enrollment).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("enrollment not found")
+		}
+		return nil, err
+	}
+	return &enrollment, nil
+}
+
+// Save creates or updates an enrollment
+func (r *EnrollmentRepository) Save(enrollment *domain.CourseStudent) error {
+	return r.db.Save(enrollment).Error
+}
+
+// CountByStatus counts the enrollments of a course with the given status
+func (r *EnrollmentRepository) CountByStatus(courseID uint, status string) (int64, error) {
+	var count int64
+	err := r.db.Model(&domain.CourseStudent{}).
+		Where("course_id = ? AND status = ?", courseID, status).
+		Count(&count).Error
+	return count, err
+}
+
+// GetWaitlist retrieves the waitlisted enrollments of a course in FIFO order
+func (r *EnrollmentRepository) GetWaitlist(courseID uint) ([]domain.CourseStudent, error) {
+	var waitlist []domain.CourseStudent
+	err := r.db.Preload("User").
+		Where("course_id = ? AND status = ?", courseID, domain.EnrollmentStatusWaitlisted).
+		Order("waitlisted_at ASC, id ASC").
+		Find(&waitlist).Error
+	if err != nil {
+		return nil, err
+	}
+	return waitlist, nil
+}
+
+// NextWaitlisted retrieves the enrollment at the head of a course's waitlist
+func (r *EnrollmentRepository) NextWaitlisted(courseID uint) (*domain.CourseStudent, error) {
+	var enrollment domain.CourseStudent
+	err := r.db.
+		Where("course_id = ? AND status = ?", courseID, domain.EnrollmentStatusWaitlisted).
+		Order("waitlisted_at ASC, id ASC").
+		First(&enrollment).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &enrollment, nil
+}
+
+// CreateTransition records an enrollment status change
+func (r *EnrollmentRepository) CreateTransition(transition *domain.EnrollmentTransition) error {
+	return r.db.Create(transition).Error
+}
+
+// GetHistory retrieves the status changes of a student's enrollment in a course
+func (r *EnrollmentRepository) GetHistory(courseID, userID uint) ([]domain.EnrollmentTransition, error) {
+	var history []domain.EnrollmentTransition
+	err := r.db.
+		Where("course_id = ? AND user_id = ?", courseID, userID).
+		Order("changed_at ASC, id ASC").
+		Find(&history).Error
+	if err != nil {
+		return nil, err
+	}
+	return history, nil
+}
+
+// GetUserHistory retrieves every enrollment status change of a student across all courses
+func (r *EnrollmentRepository) GetUserHistory(userID uint) ([]domain.EnrollmentTransition, error) {
+	var history []domain.EnrollmentTransition
+	err := r.db.
+		Where("user_id = ?", userID).
+		Order("changed_at ASC, id ASC").
+		Find(&history).Error
+	if err != nil {
+		return nil, err
+	}
+	return history, nil
+}
@@ -0,0 +1,373 @@
+package repository
+
+import (
+	"backend/internal/domain"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ExamFilter narrows an exam listing; zero values are ignored
+type ExamFilter struct {
+	CourseID     uint
+	StudentID    uint // only exams of courses the student is enrolled in
+	InstructorID uint // only exams of courses the instructor teaches
+}
+
+// ExamRepository handles database operations for exams, questions and attempts
+type ExamRepository struct {
+	db *gorm.DB
+}
+
+// NewExamRepository creates a new exam repository
+func NewExamRepository(db *gorm.DB) *ExamRepository {
+	return &ExamRepository{db}
+}
+
+// Transaction runs fn inside a database transaction with a repository bound to it
+func (r *ExamRepository) Transaction(fn func(tx *ExamRepository) error) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return fn(&ExamRepository{tx})
+	})
+}
+
+// GetByID retrieves an exam by ID
+func (r *ExamRepository) GetByID(id uint) (*domain.Exam, error) {
+	var exam domain.Exam
+	if err := r.db.First(&exam, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("exam not found")
+		}
+		return nil, err
+	}
+	return &exam, nil
+}
+
+// FindExams retrieves the exams matching the filter, soonest available first
+func (r *ExamRepository) FindExams(filter ExamFilter) ([]domain.Exam, error) {
+	query := r.db.Model(&domain.Exam{})
+	if filter.CourseID != 0 {
+		query = query.Where("course_id = ?", filter.CourseID)
+	}
+	if filter.StudentID != 0 {
+		query = query.Where("EXISTS (SELECT 1 FROM course_students cs WHERE cs.course_id = exams.course_id AND cs.user_id = ? AND cs.status IN ? AND cs.deleted_at IS NULL)",
+			filter.StudentID, []string{domain.EnrollmentStatusEnrolled, domain.EnrollmentStatusCompleted})
+	}
+	if filter.InstructorID != 0 {
+		query = query.Where("EXISTS (SELECT 1 FROM course_instructors ci WHERE ci.course_id = exams.course_id AND ci.user_id = ? AND ci.deleted_at IS NULL)",
+			filter.InstructorID)
+	}
+
+	var exams []domain.Exam
+	if err := query.Order("available_from ASC, id ASC").Find(&exams).Error; err != nil {
+		return nil, err
+	}
+	return exams, nil
+}
+
+// Create creates a new exam
+func (r *ExamRepository) Create(exam *domain.Exam) error {
+	return r.db.Create(exam).Error
+}
+
+// Update updates an exam
+func (r *ExamRepository) Update(exam *domain.Exam) error {
+	return r.db.Save(exam).Error
+}
+
+// Delete deletes an exam
+func (r *ExamRepository) Delete(id uint) error {
+	return r.db.Delete(&domain.Exam{}, id).Error
+}
+
+// GetQuestion retrieves a question of an exam
+func (r *ExamRepository) GetQuestion(examID, questionID uint) (*domain.ExamQuestion, error) {
+	var question domain.ExamQuestion
+	if err := r.db.Where("exam_id = ?", examID).First(&question, questionID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("question not found")
+		}
+		return nil, err
+	}
+	return &question, nil
+}
+
+// CreateQuestion creates a new exam question
+func (r *ExamRepository) CreateQuestion(question *domain.ExamQuestion) error {
+	return r.db.Create(question).Error
+}
+
+// UpdateQuestion updates an exam question
+func (r *ExamRepository) UpdateQuestion(question *domain.ExamQuestion) error {
+	return r.db.Save(question).Error
+}
+
+// DeleteQuestion deletes an exam question
+func (r *ExamRepository) DeleteQuestion(examID, questionID uint) error {
+	return r.db.Where("exam_id = ?", examID).Delete(&domain.ExamQuestion{}, questionID).Error
+}
+
+// GetFixedQuestions retrieves the questions every attempt at an exam gets,
+// that is all questions not drawn by a question pool, in delivery order
+func (r *ExamRepository) GetFixedQuestions(examID uint) ([]domain.ExamQuestion, error) {
+	var questions []domain.ExamQuestion
+	err := r.db.Where("exam_id = ? AND pool_id IS NULL", examID).
+		Order(`"order" ASC, id ASC`).
+		Find(&questions).Error
+	if err != nil {
+		return nil, err
+	}
+	return questions, nil
+}
+
+// GetQuestionsByIDs retrieves questions in the order of ids. Deleted
+// questions are included so that attempts keep the questions they were given.
+func (r *ExamRepository) GetQuestionsByIDs(ids []uint) ([]domain.ExamQuestion, error) {
+	var found []domain.ExamQuestion
+	if len(ids) == 0 {
+		return found, nil
+	}
+	if err := r.db.Unscoped().Where("id IN ?", ids).Find(&found).Error; err != nil {
+		return nil, err
+	}
+
+	byID := make(map[uint]domain.ExamQuestion, len(found))
+	for _, question := range found {
+		byID[question.ID] = question
+	}
+	questions := make([]domain.ExamQuestion, 0, len(ids))
+	for _, id := range ids {
+		if question, ok := byID[id]; ok {
+			questions = append(questions, question)
+		}
+	}
+	return questions, nil
+}
+
+// CountQuestions counts the fixed questions of an exam
+func (r *ExamRepository) CountQuestions(examID uint) (int64, error) {
+	var count int64
+	err := r.db.Model(&domain.ExamQuestion{}).Where("exam_id = ? AND pool_id IS NULL", examID).Count(&count).Error
+	return count, err
+}
+
+// GetPools retrieves the question pools of an exam
+func (r *ExamRepository) GetPools(examID uint) ([]domain.ExamQuestionPool, error) {
+	var pools []domain.ExamQuestionPool
+	if err := r.db.Where("exam_id = ?", examID).Order("id ASC").Find(&pools).Error; err != nil {
+		return nil, err
+	}
+	return pools, nil
+}
+
+// GetPool retrieves a question pool of an exam
+func (r *ExamRepository) GetPool(examID, poolID uint) (*domain.ExamQuestionPool, error) {
+	var pool domain.ExamQuestionPool
+	if err := r.db.Where("exam_id = ?", examID).First(&pool, poolID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("pool not found")
+		}
+		return nil, err
+	}
+	return &pool, nil
+}
+
+// CreatePool creates a new question pool
+func (r *ExamRepository) CreatePool(pool *domain.ExamQuestionPool) error {
+	return r.db.Create(pool).Error
+}
+
+// UpdatePool updates a question pool
+func (r *ExamRepository) UpdatePool(pool *domain.ExamQuestionPool) error {
+	return r.db.Save(pool).Error
+}
+
+// DeletePool deletes a question pool. Questions it already drew stay with
+// the attempts they were drawn for.
+func (r *ExamRepository) DeletePool(examID, poolID uint) error {
+	return r.db.Where("exam_id = ?", examID).Delete(&domain.ExamQuestionPool{}, poolID).Error
+}
+
+// FindPoolCandidates retrieves the bank questions of a course that a pool can
+// draw from, in a stable order
+func (r *ExamRepository) FindPoolCandidates(courseID uint, pool *domain.ExamQuestionPool) ([]domain.BankQuestion, error) {
+	var questions []domain.BankQuestion
+	err := applyBankQuestionFilter(r.db.Model(&domain.BankQuestion{}), poolFilter(courseID, pool)).
+		Order("id ASC").
+		Find(&questions).Error
+	if err != nil {
+		return nil, err
+	}
+	return questions, nil
+}
+
+// CountPoolCandidates counts the bank questions a pool can draw from
+func (r *ExamRepository) CountPoolCandidates(courseID uint, pool *domain.ExamQuestionPool) (int64, error) {
+	var count int64
+	err := applyBankQuestionFilter(r.db.Model(&domain.BankQuestion{}), poolFilter(courseID, pool)).Count(&count).Error
+	return count, err
+}
+
+// EnsurePoolQuestion returns the exam question a pool drew from a bank
+// question, creating it from the given copy the first time it is drawn
+func (r *ExamRepository) EnsurePoolQuestion(question *domain.ExamQuestion) (*domain.ExamQuestion, error) {
+	err := r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "pool_id"}, {Name: "bank_question_id"}},
+		DoNothing: true,
+	}).Omit(clause.Associations).Create(question).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var existing domain.ExamQuestion
+	err = r.db.Unscoped().
+		Where("pool_id = ? AND bank_question_id = ?", question.PoolID, question.BankQuestionID).
+		First(&existing).Error
+	if err != nil {
+		return nil, err
+	}
+	return &existing, nil
+}
+
+func poolFilter(courseID uint, pool *domain.ExamQuestionPool) BankQuestionFilter {
+	filter := BankQuestionFilter{
+		CourseID:   courseID,
+		Tag:        pool.Tag,
+		Difficulty: pool.Difficulty,
+	}
+	if pool.LearningOutcomeID != nil {
+		filter.LearningOutcomeID = *pool.LearningOutcomeID
+	}
+	return filter
+}
+
+// LockStudentExam serializes attempt creation for one student and exam until
+// the transaction ends
+func (r *ExamRepository) LockStudentExam(examID, userID uint) error {
+	return r.db.Exec("SELECT pg_advisory_xact_lock(?, ?)", int32(examID), int32(userID)).Error
+}
+
+// GetAttempt retrieves an attempt with its answers
+func (r *ExamRepository) GetAttempt(id uint) (*domain.ExamAttempt, error) {
+	var attempt domain.ExamAttempt
+	if err := r.db.Preload("Answers").First(&attempt, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("attempt not found")
+		}
+		return nil, err
+	}
+	return &attempt, nil
+}
+
+// LockAttempt retrieves an attempt and locks its row until the transaction ends
+func (r *ExamRepository) LockAttempt(id uint) (*domain.ExamAttempt, error) {
+	var attempt domain.ExamAttempt
+	err := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).First(&attempt, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("attempt not found")
+		}
+		return nil, err
+	}
+	return &attempt, nil
+}
+
+// GetUserAttempts retrieves a student's attempts at an exam, oldest first
+func (r *ExamRepository) GetUserAttempts(examID, userID uint) ([]domain.ExamAttempt, error) {
+	var attempts []domain.ExamAttempt
+	err := r.db.Preload("Answers").
+		Where("exam_id = ? AND user_id = ?", examID, userID).
+		Order("attempt_number ASC").
+		Find(&attempts).Error
+	if err != nil {
+		return nil, err
+	}
+	return attempts, nil
+}
+
+// GetExamAttempts retrieves every attempt at an exam with the students who made them
+func (r *ExamRepository) GetExamAttempts(examID uint) ([]domain.ExamAttempt, error) {
+	var attempts []domain.ExamAttempt
+	err := r.db.Preload("User").
+		Where("exam_id = ?", examID).
+		Order("user_id ASC, attempt_number ASC").
+		Find(&attempts).Error
+	if err != nil {
+		return nil, err
+	}
+	return attempts, nil
+}
+
+// CreateAttempt creates a new attempt
+func (r *ExamRepository) CreateAttempt(attempt *domain.ExamAttempt) error {
+	return r.db.Create(attempt).Error
+}
+
+// SaveAttempt updates an attempt without touching its answers
+func (r *ExamRepository) SaveAttempt(attempt *domain.ExamAttempt) error {
+	return r.db.Omit(clause.Associations).Save(attempt).Error
+}
+
+// FindExpiredAttempts retrieves the IDs of in-progress attempts whose deadline has passed
+func (r *ExamRepository) FindExpiredAttempts(before time.Time, limit int) ([]uint, error) {
+	var ids []uint
+	err := r.db.Model(&domain.ExamAttempt{}).
+		Where("status = ? AND deadline < ?", domain.ExamAttemptStatusInProgress, before).
+		Order("deadline ASC").
+		Limit(limit).
+		Pluck("id", &ids).Error
+	return ids, err
+}
+
+// UpsertAnswer stores the answer to a question, replacing any earlier answer
+// of the same attempt so that repeated saves are idempotent
+func (r *ExamRepository) UpsertAnswer(answer *domain.ExamAnswer) error {
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "exam_attempt_id"}, {Name: "question_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"answer", "updated_at"}),
+	}).Omit(clause.Associations).Create(answer).Error
+}
+
+// GetAnswers retrieves the answers of an attempt
+func (r *ExamRepository) GetAnswers(attemptID uint) ([]domain.ExamAnswer, error) {
+	var answers []domain.ExamAnswer
+	if err := r.db.Where("exam_attempt_id = ?", attemptID).Find(&answers).Error; err != nil {
+		return nil, err
+	}
+	return answers, nil
+}
+
+// GetAnswer retrieves an attempt's answer to a question
+func (r *ExamRepository) GetAnswer(attemptID, questionID uint) (*domain.ExamAnswer, error) {
+	var answer domain.ExamAnswer
+	err := r.db.Where("exam_attempt_id = ? AND question_id = ?", attemptID, questionID).First(&answer).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("answer not found")
+		}
+		return nil, err
+	}
+	return &answer, nil
+}
+
+// SaveAnswers updates the grading of answers. The answers' UpdatedAt is left
+// alone so that it keeps recording when the student last saved them.
+func (r *ExamRepository) SaveAnswers(answers []domain.ExamAnswer) error {
+	for i := range answers {
+		answer := &answers[i]
+		err := r.db.Model(answer).UpdateColumns(map[string]interface{}{
+			"is_correct":           answer.IsCorrect,
+			"points":               answer.Points,
+			"needs_manual_grading": answer.NeedsManualGrading,
+			"feedback":             answer.Feedback,
+			"graded_by":            answer.GradedBy,
+			"graded_at":            answer.GradedAt,
+		}).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
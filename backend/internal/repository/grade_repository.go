@@ -0,0 +1,523 @@
+package repository
+
+import (
+	"backend/internal/domain"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ItemResult is a student's best available result for a gradebook item
+type ItemResult struct {
+	ItemID uint
+	UserID uint
+	Graded bool    // a graded result exists; otherwise only ungraded work was handed in
+	Score  float64 // percent
+}
+
+// TranscriptRecord is a course a student took, with their course grade
+type TranscriptRecord struct {
+	CourseID         uint
+	Code             string
+	Title            string
+	Semester         string
+	Year             int
+	Credits          float64
+	EnrollmentStatus string
+	EnrolledAt       time.Time
+	OverallScore     float64
+	LetterGrade      string
+	GradePoints      float64
+}
+
+// GradeRepository handles database operations for the gradebook
+type GradeRepository struct {
+	db *gorm.DB
+}
+
+// NewGradeRepository creates a new grade repository
+func NewGradeRepository(db *gorm.DB) *GradeRepository {
+	return &GradeRepository{db}
+}
+
+// Transaction runs fn inside a database transaction with a repository bound to it
+func (r *GradeRepository) Transaction(fn func(tx *GradeRepository) error) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return fn(&GradeRepository{tx})
+	})
+}
+
+// LockStudentCourse serializes gradebook updates of one student in a course
+// until the transaction ends
+func (r *GradeRepository) LockStudentCourse(courseID, userID uint) error {
+	return r.db.Exec("SELECT pg_advisory_xact_lock(hashtext(?))", fmt.Sprintf("course_grade:%d:%d", courseID, userID)).Error
+}
+
+// GetItems retrieves the assessments and exams of a course
+func (r *GradeRepository) GetItems(courseID uint) ([]domain.GradebookItem, error) {
+	var assessments []domain.Assessment
+	if err := r.db.Where("course_id = ?", courseID).Order("due_date ASC, id ASC").Find(&assessments).Error; err != nil {
+		return nil, err
+	}
+	var exams []domain.Exam
+	if err := r.db.Where("course_id = ?", courseID).Order("available_to ASC, id ASC").Find(&exams).Error; err != nil {
+		return nil, err
+	}
+
+	items := make([]domain.GradebookItem, 0, len(assessments)+len(exams))
+	for _, a := range assessments {
+		item := domain.GradebookItem{Type: domain.GradeItemAssessment, ID: a.ID, Title: a.Title, Weight: a.Weight}
+		switch {
+		case !a.DueDate.IsZero():
+			item.DueDate = timePtr(a.DueDate)
+		case !a.AvailableTo.IsZero():
+			item.DueDate = timePtr(a.AvailableTo)
+		}
+		items = append(items, item)
+	}
+	for _, e := range exams {
+		item := domain.GradebookItem{Type: domain.GradeItemExam, ID: e.ID, Title: e.Title, Weight: e.Weight}
+		if !e.AvailableTo.IsZero() {
+			item.DueDate = timePtr(e.AvailableTo)
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// UpdateItemWeight sets the weight of an assessment or exam of a course
+func (r *GradeRepository) UpdateItemWeight(courseID uint, itemType string, itemID uint, weight float64) error {
+	var model interface{} = &domain.Assessment{}
+	if itemType == domain.GradeItemExam {
+		model = &domain.Exam{}
+	}
+	result := r.db.Model(model).Where("id = ? AND course_id = ?", itemID, courseID).Update("weight", weight)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("%s %d not found in course", itemType, itemID)
+	}
+	return nil
+}
+
+// GetStudentIDs retrieves the students enrolled in, or having completed, a course
+func (r *GradeRepository) GetStudentIDs(courseID uint) ([]uint, error) {
+	var ids []uint
+	err := r.db.Model(&domain.CourseStudent{}).
+		Where("course_id = ? AND status IN ?", courseID,
+			[]string{domain.EnrollmentStatusEnrolled, domain.EnrollmentStatusCompleted}).
+		Order("user_id ASC").
+		Pluck("user_id", &ids).Error
+	return ids, err
+}
+
+// GetSubmissionResults retrieves, per assessment of the course, each
+// student's latest graded submission, or their latest submission when none
+// is graded. userID narrows the results to one student when not zero.
+func (r *GradeRepository) GetSubmissionResults(courseID, userID uint) ([]ItemResult, error) {
+	query := r.db.Table("submissions").
+		Select("DISTINCT ON (submissions.assessment_id, submissions.user_id) submissions.assessment_id AS item_id, submissions.user_id, submissions.status = ? AS graded, submissions.score", domain.SubmissionStatusGraded).
+		Joins("JOIN assessments ON assessments.id = submissions.assessment_id AND assessments.deleted_at IS NULL").
+		Where("assessments.course_id = ? AND submissions.deleted_at IS NULL", courseID)
+	if userID != 0 {
+		query = query.Where("submissions.user_id = ?", userID)
+	}
+
+	var results []ItemResult
+	err := query.Order(clause.Expr{
+		SQL:  "submissions.assessment_id, submissions.user_id, submissions.status = ? DESC, submissions.attempt_number DESC",
+		Vars: []interface{}{domain.SubmissionStatusGraded},
+	}).Scan(&results).Error
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// GetExamResults retrieves, per exam of the course, each student's best
+// fully graded attempt. Students whose attempts still await grading are
+// reported without a graded result. userID narrows the results to one
+// student when not zero.
+func (r *GradeRepository) GetExamResults(courseID, userID uint) ([]ItemResult, error) {
+	graded := []string{domain.GradingStatusAutoGraded, domain.GradingStatusGraded}
+	query := r.db.Table("exam_attempts").
+		Select(`exam_attempts.exam_id AS item_id, exam_attempts.user_id,
+			COUNT(*) FILTER (WHERE exam_attempts.grading_status IN ?) > 0 AS graded,
+			COALESCE(MAX(exam_attempts.score) FILTER (WHERE exam_attempts.grading_status IN ?), 0) AS score`, graded, graded).
+		Joins("JOIN exams ON exams.id = exam_attempts.exam_id AND exams.deleted_at IS NULL").
+		Where("exams.course_id = ? AND exam_attempts.deleted_at IS NULL AND exam_attempts.status <> ?",
+			courseID, domain.ExamAttemptStatusInProgress).
+		Group("exam_attempts.exam_id, exam_attempts.user_id")
+	if userID != 0 {
+		query = query.Where("exam_attempts.user_id = ?", userID)
+	}
+
+	var results []ItemResult
+	if err := query.Scan(&results).Error; err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// GetGrades retrieves a student's gradebook items in a course
+func (r *GradeRepository) GetGrades(courseID, userID uint) ([]domain.Grade, error) {
+	var grades []domain.Grade
+	err := r.db.Where("course_id = ? AND user_id = ?", courseID, userID).
+		Order("id ASC").
+		Find(&grades).Error
+	if err != nil {
+		return nil, err
+	}
+	return grades, nil
+}
+
+// GetItemGrade retrieves a student's gradebook item for an assessment or exam
+func (r *GradeRepository) GetItemGrade(itemType string, itemID, userID uint) (*domain.Grade, error) {
+	column := "assessment_id"
+	if itemType == domain.GradeItemExam {
+		column = "exam_id"
+	}
+	var grade domain.Grade
+	err := r.db.Where(column+" = ? AND user_id = ?", itemID, userID).First(&grade).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("grade not found")
+		}
+		return nil, err
+	}
+	return &grade, nil
+}
+
+// SaveGrade creates or updates a gradebook item
+func (r *GradeRepository) SaveGrade(grade *domain.Grade) error {
+	return r.db.Omit(clause.Associations).Save(grade).Error
+}
+
+// DeleteGrades deletes gradebook items
+func (r *GradeRepository) DeleteGrades(ids []uint) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return r.db.Delete(&domain.Grade{}, ids).Error
+}
+
+// GetCourseGrade retrieves a student's course grade
+func (r *GradeRepository) GetCourseGrade(courseID, userID uint) (*domain.CourseGrade, error) {
+	var grade domain.CourseGrade
+	err := r.db.Where("course_id = ? AND user_id = ?", courseID, userID).First(&grade).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("course grade not found")
+		}
+		return nil, err
+	}
+	return &grade, nil
+}
+
+// SaveCourseGrade creates or updates a course grade
+func (r *GradeRepository) SaveCourseGrade(grade *domain.CourseGrade) error {
+	return r.db.Omit(clause.Associations).Save(grade).Error
+}
+
+// GetCourseGrades retrieves the course grades of every student of a course
+func (r *GradeRepository) GetCourseGrades(courseID uint) ([]domain.CourseGrade, error) {
+	var grades []domain.CourseGrade
+	err := r.db.Preload("User").
+		Where("course_id = ?", courseID).
+		Order("user_id ASC").
+		Find(&grades).Error
+	if err != nil {
+		return nil, err
+	}
+	return grades, nil
+}
+
+// GetReleasedCourseGrades retrieves a student's published course grades in
+// the courses whose grade release date passed by releasedBy
+func (r *GradeRepository) GetReleasedCourseGrades(userID uint, releasedBy time.Time) ([]domain.CourseGrade, error) {
+	var grades []domain.CourseGrade
+	err := r.db.Joins("JOIN courses ON courses.id = course_grades.course_id").
+		Where("course_grades.user_id = ? AND course_grades.publication_status = ?", userID, domain.PublicationPublished).
+		Where("courses.grades_release_at IS NULL OR courses.grades_release_at <= ?", releasedBy).
+		Order("course_grades.course_id ASC").
+		Find(&grades).Error
+	if err != nil {
+		return nil, err
+	}
+	return grades, nil
+}
+
+// GetUserCourseGrades retrieves a student's course grades in all courses
+func (r *GradeRepository) GetUserCourseGrades(userID uint) ([]domain.CourseGrade, error) {
+	var grades []domain.CourseGrade
+	err := r.db.Where("user_id = ?", userID).
+		Order("course_id ASC").
+		Find(&grades).Error
+	if err != nil {
+		return nil, err
+	}
+	return grades, nil
+}
+
+// GetScale retrieves the grade scale of a course, or the institution's
+// scale when courseID is nil
+func (r *GradeRepository) GetScale(courseID *uint) (*domain.GradeScale, error) {
+	query := r.db.Preload("Entries", func(db *gorm.DB) *gorm.DB {
+		return db.Order("min_score DESC")
+	})
+	if courseID == nil {
+		query = query.Where("course_id IS NULL")
+	} else {
+		query = query.Where("course_id = ?", *courseID)
+	}
+
+	var scale domain.GradeScale
+	if err := query.First(&scale).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("grade scale not found")
+		}
+		return nil, err
+	}
+	return &scale, nil
+}
+
+// SaveScale creates or updates a grade scale, replacing its entries
+func (r *GradeRepository) SaveScale(scale *domain.GradeScale) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit(clause.Associations).Save(scale).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("grade_scale_id = ?", scale.ID).Delete(&domain.GradeScaleEntry{}).Error; err != nil {
+			return err
+		}
+		for i := range scale.Entries {
+			scale.Entries[i].ID = 0
+			scale.Entries[i].GradeScaleID = scale.ID
+		}
+		if len(scale.Entries) == 0 {
+			return nil
+		}
+		return tx.Create(&scale.Entries).Error
+	})
+}
+
+// DeleteScale deletes a grade scale with its entries
+func (r *GradeRepository) DeleteScale(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("grade_scale_id = ?", id).Delete(&domain.GradeScaleEntry{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&domain.GradeScale{}, id).Error
+	})
+}
+
+func timePtr(t time.Time) *time.Time {
+	return &t
+}
+
+// GetItemCourseID retrieves the course of an assessment or exam
+func (r *GradeRepository) GetItemCourseID(itemType string, itemID uint) (uint, error) {
+	var model interface{} = &domain.Assessment{}
+	if itemType == domain.GradeItemExam {
+		model = &domain.Exam{}
+	}
+	var courseIDs []uint
+	if err := r.db.Model(model).Where("id = ?", itemID).Pluck("course_id", &courseIDs).Error; err != nil {
+		return 0, err
+	}
+	if len(courseIDs) == 0 {
+		return 0, fmt.Errorf("%s %d not found", itemType, itemID)
+	}
+	return courseIDs[0], nil
+}
+
+// GetGradedCourseIDs retrieves the courses that have course grades, leaving
+// out those with their own grade scale when withoutScale is set
+func (r *GradeRepository) GetGradedCourseIDs(withoutScale bool) ([]uint, error) {
+	query := r.db.Model(&domain.CourseGrade{}).Distinct("course_id")
+	if withoutScale {
+		query = query.Where("NOT EXISTS (SELECT 1 FROM grade_scales gs WHERE gs.course_id = course_grades.course_id)")
+	}
+	var ids []uint
+	err := query.Order("course_id ASC").Pluck("course_id", &ids).Error
+	return ids, err
+}
+
+// GetTranscriptRecords retrieves the courses a student is enrolled in, has
+// completed or withdrew from, with their course grades where they are
+// published and released by releasedBy
+func (r *GradeRepository) GetTranscriptRecords(userID uint, releasedBy time.Time) ([]TranscriptRecord, error) {
+	var records []TranscriptRecord
+	err := r.db.Table("course_students").
+		Select(`courses.id AS course_id, courses.code, courses.title, courses.semester, courses.year, courses.credits,
+			course_students.status AS enrollment_status, course_students.enrolled_at,
+			COALESCE(course_grades.overall_score, 0) AS overall_score,
+			COALESCE(course_grades.letter_grade, '') AS letter_grade,
+			COALESCE(course_grades.grade_points, 0) AS grade_points`).
+		Joins("JOIN courses ON courses.id = course_students.course_id AND courses.deleted_at IS NULL").
+		Joins(`LEFT JOIN course_grades ON course_grades.course_id = course_students.course_id
+			AND course_grades.user_id = course_students.user_id AND course_grades.deleted_at IS NULL
+			AND course_grades.publication_status = ? AND (courses.grades_release_at IS NULL OR courses.grades_release_at <= ?)`,
+			domain.PublicationPublished, releasedBy).
+		Where("course_students.user_id = ? AND course_students.deleted_at IS NULL AND course_students.status IN ?", userID,
+			[]string{domain.EnrollmentStatusEnrolled, domain.EnrollmentStatusCompleted, domain.EnrollmentStatusWithdrawn}).
+		Order("courses.year ASC, course_students.enrolled_at ASC, courses.id ASC").
+		Scan(&records).Error
+	if err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// UpdateStudentGPA stores a student's cumulative GPA on their student info
+func (r *GradeRepository) UpdateStudentGPA(userID uint, gpa float32) error {
+	return r.db.Model(&domain.StudentInfo{}).Where("user_id = ?", userID).Update("gpa", gpa).Error
+}
+
+// GetGradedStudentIDs retrieves the students that have a course grade
+func (r *GradeRepository) GetGradedStudentIDs() ([]uint, error) {
+	var ids []uint
+	err := r.db.Model(&domain.CourseGrade{}).Distinct("user_id").Order("user_id ASC").Pluck("user_id", &ids).Error
+	return ids, err
+}
+
+// GetStudent retrieves a user with their student info
+func (r *GradeRepository) GetStudent(userID uint) (*domain.User, error) {
+	var user domain.User
+	if err := r.db.Preload("StudentInfo").First(&user, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("user not found")
+		}
+		return nil, err
+	}
+	return &user, nil
+}
+
+// MovePublication moves the course grades of a course, and the gradebook
+// items they are based on, from one publication status to another.
+// userIDs narrows the move to some students when not empty. publishedAt is
+// stored on the moved rows. It returns the students whose grades moved.
+// Each student's gradebook is locked like in LockStudentCourse, so it must
+// run in a transaction.
+func (r *GradeRepository) MovePublication(courseID uint, userIDs []uint, from []string, to string, publishedAt *time.Time) ([]uint, error) {
+	candidates := func() ([]uint, error) {
+		query := r.db.Model(&domain.CourseGrade{}).Where("course_id = ? AND publication_status IN ?", courseID, from)
+		if len(userIDs) > 0 {
+			query = query.Where("user_id IN ?", userIDs)
+		}
+		var ids []uint
+		err := query.Order("user_id ASC").Pluck("user_id", &ids).Error
+		return ids, err
+	}
+
+	locked, err := candidates()
+	if err != nil {
+		return nil, err
+	}
+	for _, userID := range locked {
+		if err := r.LockStudentCourse(courseID, userID); err != nil {
+			return nil, err
+		}
+	}
+	// Look again now that concurrent recalculations are done, keeping to
+	// the students that are locked
+	current, err := candidates()
+	if err != nil {
+		return nil, err
+	}
+	isLocked := make(map[uint]bool, len(locked))
+	for _, userID := range locked {
+		isLocked[userID] = true
+	}
+	moved := current[:0]
+	for _, userID := range current {
+		if isLocked[userID] {
+			moved = append(moved, userID)
+		}
+	}
+	if len(moved) == 0 {
+		return moved, nil
+	}
+
+	updates := map[string]interface{}{"publication_status": to, "published_at": publishedAt}
+	if err := r.db.Model(&domain.CourseGrade{}).
+		Where("course_id = ? AND user_id IN ?", courseID, moved).
+		Updates(updates).Error; err != nil {
+		return nil, err
+	}
+	if err := r.db.Model(&domain.Grade{}).
+		Where("course_id = ? AND user_id IN ?", courseID, moved).
+		Updates(updates).Error; err != nil {
+		return nil, err
+	}
+	return moved, nil
+}
+
+// CountPublication counts the students of a course per publication status
+func (r *GradeRepository) CountPublication(courseID uint) (map[string]int, error) {
+	var rows []struct {
+		PublicationStatus string
+		Count             int
+	}
+	err := r.db.Model(&domain.CourseGrade{}).
+		Select("publication_status, COUNT(*) AS count").
+		Where("course_id = ?", courseID).
+		Group("publication_status").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	counts := map[string]int{
+		domain.PublicationDraft:     0,
+		domain.PublicationSubmitted: 0,
+		domain.PublicationApproved:  0,
+		domain.PublicationPublished: 0,
+	}
+	for _, row := range rows {
+		counts[row.PublicationStatus] += row.Count
+	}
+	return counts, nil
+}
+
+// CreateModeration records a step of a course's grade publication workflow
+func (r *GradeRepository) CreateModeration(moderation *domain.GradeModeration) error {
+	return r.db.Omit(clause.Associations).Create(moderation).Error
+}
+
+// GetModerations retrieves the grade publication history of a course, oldest first
+func (r *GradeRepository) GetModerations(courseID uint) ([]domain.GradeModeration, error) {
+	var moderations []domain.GradeModeration
+	err := r.db.Preload("Actor").
+		Where("course_id = ?", courseID).
+		Order("created_at ASC, id ASC").
+		Find(&moderations).Error
+	if err != nil {
+		return nil, err
+	}
+	return moderations, nil
+}
+
+// CreateAmendment records a change to a published grade
+func (r *GradeRepository) CreateAmendment(amendment *domain.GradeAmendment) error {
+	return r.db.Omit(clause.Associations).Create(amendment).Error
+}
+
+// GetAmendments retrieves the amendments of a course's grades, newest
+// first. userID narrows them to one student when not zero.
+func (r *GradeRepository) GetAmendments(courseID, userID uint) ([]domain.GradeAmendment, error) {
+	query := r.db.Preload("Amender").Where("course_id = ?", courseID)
+	if userID != 0 {
+		query = query.Where("user_id = ?", userID)
+	}
+
+	var amendments []domain.GradeAmendment
+	if err := query.Order("created_at DESC, id DESC").Find(&amendments).Error; err != nil {
+		return nil, err
+	}
+	return amendments, nil
+}
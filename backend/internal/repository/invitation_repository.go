@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"backend/internal/domain"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// InvitationRepository handles database operations for account invitations
+type InvitationRepository struct {
+	db *gorm.DB
+}
+
+// NewInvitationRepository creates a new invitation repository
+func NewInvitationRepository(db *gorm.DB) *InvitationRepository {
+	return &InvitationRepository{db}
+}
+
+// Transaction runs fn inside a database transaction with a repository bound to it
+func (r *InvitationRepository) Transaction(fn func(tx *InvitationRepository) error) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return fn(&InvitationRepository{tx})
+	})
+}
+
+// GetByID retrieves an invitation by ID
+func (r *InvitationRepository) GetByID(id uint) (*domain.Invitation, error) {
+	var invitation domain.Invitation
+	if err := r.db.First(&invitation, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("invitation not found")
+		}
+		return nil, err
+	}
+	return &invitation, nil
+}
+
+// LockByHash retrieves an invitation by the hash of its token and locks its
+// row until the transaction ends
+func (r *InvitationRepository) LockByHash(hash string) (*domain.Invitation, error) {
+	var invitation domain.Invitation
+	err := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("token_hash = ?", hash).
+		First(&invitation).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("invitation not found")
+		}
+		return nil, err
+	}
+	return &invitation, nil
+}
+
+// GetPending retrieves the invitations that have been neither accepted nor
+// expired, newest first
+func (r *InvitationRepository) GetPending(now time.Time) ([]domain.Invitation, error) {
+	var invitations []domain.Invitation
+	err := r.db.Where("accepted_at IS NULL AND expires_at > ?", now).
+		Order("created_at DESC, id DESC").
+		Find(&invitations).Error
+	if err != nil {
+		return nil, err
+	}
+	return invitations, nil
+}
+
+// Create creates a new invitation
+func (r *InvitationRepository) Create(invitation *domain.Invitation) error {
+	return r.db.Create(invitation).Error
+}
+
+// Save updates an invitation
+func (r *InvitationRepository) Save(invitation *domain.Invitation) error {
+	return r.db.Save(invitation).Error
+}
+
+// CreateUser creates the account of an accepted invitation
+func (r *InvitationRepository) CreateUser(user *domain.User) error {
+	return r.db.Create(user).Error
+}
+
+// Delete deletes an invitation
+func (r *InvitationRepository) Delete(id uint) error {
+	return r.db.Delete(&domain.Invitation{}, id).Error
+}
+
+// DeleteExpired deletes the invitations that expired without being accepted
+func (r *InvitationRepository) DeleteExpired() error {
+	return r.db.Where("accepted_at IS NULL AND expires_at < ?", time.Now()).Delete(&domain.Invitation{}).Error
+}
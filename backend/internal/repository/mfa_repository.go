@@ -0,0 +1,147 @@
+package repository
+
+import (
+	"backend/internal/domain"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// MFARepository handles database operations for two-factor authentication:
+// authenticator enrollments, recovery codes and sign-in challenges
+type MFARepository struct {
+	db *gorm.DB
+}
+
+// NewMFARepository creates a new MFA repository
+func NewMFARepository(db *gorm.DB) *MFARepository {
+	return &MFARepository{db}
+}
+
+// Transaction runs fn inside a database transaction with a repository bound to it
+func (r *MFARepository) Transaction(fn func(tx *MFARepository) error) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return fn(&MFARepository{tx})
+	})
+}
+
+// GetEnrollment retrieves a user's authenticator enrollment
+func (r *MFARepository) GetEnrollment(userID uint) (*domain.MFAEnrollment, error) {
+	var enrollment domain.MFAEnrollment
+	if err := r.db.Where("user_id = ?", userID).First(&enrollment).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("mfa enrollment not found")
+		}
+		return nil, err
+	}
+	return &enrollment, nil
+}
+
+// LockEnrollment retrieves a user's authenticator enrollment and locks its
+// row until the transaction ends
+func (r *MFARepository) LockEnrollment(userID uint) (*domain.MFAEnrollment, error) {
+	var enrollment domain.MFAEnrollment
+	err := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("user_id = ?", userID).
+		First(&enrollment).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("mfa enrollment not found")
+		}
+		return nil, err
+	}
+	return &enrollment, nil
+}
+
+// SaveEnrollment creates or updates an authenticator enrollment
+func (r *MFARepository) SaveEnrollment(enrollment *domain.MFAEnrollment) error {
+	return r.db.Save(enrollment).Error
+}
+
+// DeleteEnrollment deletes a user's authenticator enrollment and recovery
+// codes and reports whether there was an enrollment
+func (r *MFARepository) DeleteEnrollment(userID uint) (bool, error) {
+	if err := r.db.Where("user_id = ?", userID).Delete(&domain.MFARecoveryCode{}).Error; err != nil {
+		return false, err
+	}
+	result := r.db.Where("user_id = ?", userID).Delete(&domain.MFAEnrollment{})
+	return result.RowsAffected > 0, result.Error
+}
+
+// ReplaceRecoveryCodes replaces a user's recovery codes with new ones
+func (r *MFARepository) ReplaceRecoveryCodes(userID uint, hashes []string) error {
+	if err := r.db.Where("user_id = ?", userID).Delete(&domain.MFARecoveryCode{}).Error; err != nil {
+		return err
+	}
+	codes := make([]domain.MFARecoveryCode, 0, len(hashes))
+	for _, hash := range hashes {
+		codes = append(codes, domain.MFARecoveryCode{UserID: userID, CodeHash: hash})
+	}
+	if len(codes) == 0 {
+		return nil
+	}
+	return r.db.Create(&codes).Error
+}
+
+// UseRecoveryCode marks an unused recovery code of a user as used and
+// reports whether there was one
+func (r *MFARepository) UseRecoveryCode(userID uint, hash string, at time.Time) (bool, error) {
+	result := r.db.Model(&domain.MFARecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, hash).
+		Update("used_at", at)
+	return result.RowsAffected > 0, result.Error
+}
+
+// CountRecoveryCodes counts a user's unused recovery codes
+func (r *MFARepository) CountRecoveryCodes(userID uint) (int64, error) {
+	var count int64
+	err := r.db.Model(&domain.MFARecoveryCode{}).
+		Where("user_id = ? AND used_at IS NULL", userID).
+		Count(&count).Error
+	return count, err
+}
+
+// CreateChallenge creates a new sign-in challenge
+func (r *MFARepository) CreateChallenge(challenge *domain.MFAChallenge) error {
+	return r.db.Create(challenge).Error
+}
+
+// GetChallenge retrieves a sign-in challenge by the hash of its token
+func (r *MFARepository) GetChallenge(hash string) (*domain.MFAChallenge, error) {
+	var challenge domain.MFAChallenge
+	if err := r.db.Where("token_hash = ?", hash).First(&challenge).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("mfa challenge not found")
+		}
+		return nil, err
+	}
+	return &challenge, nil
+}
+
+// LockChallenge retrieves a sign-in challenge by the hash of its token and
+// locks its row until the transaction ends
+func (r *MFARepository) LockChallenge(hash string) (*domain.MFAChallenge, error) {
+	var challenge domain.MFAChallenge
+	err := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("token_hash = ?", hash).
+		First(&challenge).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("mfa challenge not found")
+		}
+		return nil, err
+	}
+	return &challenge, nil
+}
+
+// SaveChallenge updates a sign-in challenge
+func (r *MFARepository) SaveChallenge(challenge *domain.MFAChallenge) error {
+	return r.db.Save(challenge).Error
+}
+
+// DeleteExpiredChallenges deletes the sign-in challenges that expired
+func (r *MFARepository) DeleteExpiredChallenges() error {
+	return r.db.Where("expires_at < ?", time.Now()).Delete(&domain.MFAChallenge{}).Error
+}
@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"backend/internal/domain"
+
+	"gorm.io/gorm"
+)
+
+// CourseOutcome is a learning outcome of a course's syllabus
+type CourseOutcome struct {
+	ID        uint
+	CourseID  uint
+	Code      string
+	Knowledge string
+}
+
+// OutcomeSelection is a rubric level a student received for a learning outcome
+type OutcomeSelection struct {
+	CourseID          uint
+	LearningOutcomeID uint
+	UserID            uint
+	Level             string
+	Points            float64
+	MaxPoints         float64
+}
+
+// OutcomeReportRepository reads the data of learning outcome attainment reports
+type OutcomeReportRepository struct {
+	db *gorm.DB
+}
+
+// NewOutcomeReportRepository creates a new outcome report repository
+func NewOutcomeReportRepository(db *gorm.DB) *OutcomeReportRepository {
+	return &OutcomeReportRepository{db}
+}
+
+// GetCourseOutcomes retrieves the learning outcomes of the courses' syllabuses
+func (r *OutcomeReportRepository) GetCourseOutcomes(courseIDs []uint) ([]CourseOutcome, error) {
+	var outcomes []CourseOutcome
+	if len(courseIDs) == 0 {
+		return outcomes, nil
+	}
+	err := r.db.Table("learning_outcomes").
+		Select("learning_outcomes.id, syllabuses.course_id, learning_outcomes.code, learning_outcomes.knowledge").
+		Joins("JOIN syllabuses ON syllabuses.id = learning_outcomes.syllabus_id AND syllabuses.deleted_at IS NULL").
+		Where("syllabuses.course_id IN ? AND learning_outcomes.deleted_at IS NULL", courseIDs).
+		Order("syllabuses.course_id ASC, learning_outcomes.code ASC, learning_outcomes.id ASC").
+		Scan(&outcomes).Error
+	if err != nil {
+		return nil, err
+	}
+	return outcomes, nil
+}
+
+// GetCourseSelections retrieves the rubric selections of every student in a course
+func (r *OutcomeReportRepository) GetCourseSelections(courseID uint) ([]OutcomeSelection, error) {
+	var selections []OutcomeSelection
+	err := r.selections().Where("assessments.course_id = ?", courseID).Scan(&selections).Error
+	if err != nil {
+		return nil, err
+	}
+	return selections, nil
+}
+
+// GetStudentSelections retrieves the rubric selections of a student in all courses
+func (r *OutcomeReportRepository) GetStudentSelections(userID uint) ([]OutcomeSelection, error) {
+	var selections []OutcomeSelection
+	err := r.selections().Where("submissions.user_id = ?", userID).Scan(&selections).Error
+	if err != nil {
+		return nil, err
+	}
+	return selections, nil
+}
+
+// GetStudentCourseIDs retrieves the courses a student is or was enrolled in
+func (r *OutcomeReportRepository) GetStudentCourseIDs(userID uint) ([]uint, error) {
+	var ids []uint
+	err := r.db.Model(&domain.CourseStudent{}).
+		Where("user_id = ? AND status IN ?", userID,
+			[]string{domain.EnrollmentStatusEnrolled, domain.EnrollmentStatusCompleted}).
+		Order("course_id ASC").
+		Pluck("course_id", &ids).Error
+	return ids, err
+}
+
+// selections selects the rubric selections of each student's latest graded
+// submission to every assessment
+func (r *OutcomeReportRepository) selections() *gorm.DB {
+	return r.db.Table("rubric_selections").
+		Select("assessments.course_id, rubric_selections.learning_outcome_id, submissions.user_id, rubric_selections.level, rubric_selections.points, rubric_selections.max_points").
+		Joins("JOIN submissions ON submissions.id = rubric_selections.submission_id AND submissions.deleted_at IS NULL").
+		Joins("JOIN assessments ON assessments.id = submissions.assessment_id AND assessments.deleted_at IS NULL").
+		Where("submissions.status = ?", domain.SubmissionStatusGraded).
+		Where(`submissions.attempt_number = (SELECT MAX(s.attempt_number) FROM submissions s
+			WHERE s.assessment_id = submissions.assessment_id AND s.user_id = submissions.user_id
+			AND s.status = ? AND s.deleted_at IS NULL)`, domain.SubmissionStatusGraded)
+}
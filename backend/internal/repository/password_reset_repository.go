@@ -0,0 +1,110 @@
+package repository
+
+import (
+	"backend/internal/domain"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// PasswordResetRepository handles database operations for password reset
+// tokens and the requests for them
+type PasswordResetRepository struct {
+	db *gorm.DB
+}
+
+// NewPasswordResetRepository creates a new password reset repository
+func NewPasswordResetRepository(db *gorm.DB) *PasswordResetRepository {
+	return &PasswordResetRepository{db}
+}
+
+// Transaction runs fn inside a database transaction with a repository bound to it
+func (r *PasswordResetRepository) Transaction(fn func(tx *PasswordResetRepository) error) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return fn(&PasswordResetRepository{tx})
+	})
+}
+
+// RecordAttempt records a request for a reset link
+func (r *PasswordResetRepository) RecordAttempt(attempt *domain.PasswordResetAttempt) error {
+	return r.db.Create(attempt).Error
+}
+
+// CountAttempts counts the requests for reset links made since the given
+// time for an address and from a client
+func (r *PasswordResetRepository) CountAttempts(email, ip string, since time.Time) (int64, int64, error) {
+	var byEmail, byIP int64
+	err := r.db.Model(&domain.PasswordResetAttempt{}).
+		Where("email = ? AND created_at > ?", email, since).
+		Count(&byEmail).Error
+	if err != nil {
+		return 0, 0, err
+	}
+	err = r.db.Model(&domain.PasswordResetAttempt{}).
+		Where("ip_address = ? AND created_at > ?", ip, since).
+		Count(&byIP).Error
+	if err != nil {
+		return 0, 0, err
+	}
+	return byEmail, byIP, nil
+}
+
+// GetByHash retrieves a reset token by the hash of its token string
+func (r *PasswordResetRepository) GetByHash(hash string) (*domain.PasswordResetToken, error) {
+	var token domain.PasswordResetToken
+	if err := r.db.Where("token_hash = ?", hash).First(&token).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("password reset token not found")
+		}
+		return nil, err
+	}
+	return &token, nil
+}
+
+// LockByHash retrieves a reset token by the hash of its token string and
+// locks its row until the transaction ends
+func (r *PasswordResetRepository) LockByHash(hash string) (*domain.PasswordResetToken, error) {
+	var token domain.PasswordResetToken
+	err := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("token_hash = ?", hash).
+		First(&token).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("password reset token not found")
+		}
+		return nil, err
+	}
+	return &token, nil
+}
+
+// Create creates a new reset token
+func (r *PasswordResetRepository) Create(token *domain.PasswordResetToken) error {
+	return r.db.Create(token).Error
+}
+
+// Save updates a reset token
+func (r *PasswordResetRepository) Save(token *domain.PasswordResetToken) error {
+	return r.db.Save(token).Error
+}
+
+// DeleteUnused deletes a user's reset tokens that were never used, so that
+// only the newest link works
+func (r *PasswordResetRepository) DeleteUnused(userID uint) error {
+	return r.db.Where("user_id = ? AND used_at IS NULL", userID).Delete(&domain.PasswordResetToken{}).Error
+}
+
+// UpdateUser saves a user whose password was reset
+func (r *PasswordResetRepository) UpdateUser(user *domain.User) error {
+	return r.db.Omit(clause.Associations).Save(user).Error
+}
+
+// DeleteExpired deletes expired reset tokens and the requests made before
+// the given time
+func (r *PasswordResetRepository) DeleteExpired(attemptsBefore time.Time) error {
+	if err := r.db.Where("expires_at < ?", time.Now()).Delete(&domain.PasswordResetToken{}).Error; err != nil {
+		return err
+	}
+	return r.db.Where("created_at < ?", attemptsBefore).Delete(&domain.PasswordResetAttempt{}).Error
+}
@@ -0,0 +1,153 @@
+package repository
+
+import (
+	"backend/internal/domain"
+	"errors"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// BankQuestionFilter narrows a question bank listing; zero values are ignored
+type BankQuestionFilter struct {
+	CourseID          uint
+	Type              string
+	Tag               string
+	Difficulty        string
+	LearningOutcomeID uint
+}
+
+// QuestionBankRepository handles database operations for course question banks
+type QuestionBankRepository struct {
+	db *gorm.DB
+}
+
+// NewQuestionBankRepository creates a new question bank repository
+func NewQuestionBankRepository(db *gorm.DB) *QuestionBankRepository {
+	return &QuestionBankRepository{db}
+}
+
+// Transaction runs fn inside a database transaction with a repository bound to it
+func (r *QuestionBankRepository) Transaction(fn func(tx *QuestionBankRepository) error) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return fn(&QuestionBankRepository{tx})
+	})
+}
+
+// GetByID retrieves a bank question with its tags and learning outcomes
+func (r *QuestionBankRepository) GetByID(id uint) (*domain.BankQuestion, error) {
+	var question domain.BankQuestion
+	if err := r.db.Preload("Tags").Preload("LearningOutcomes").First(&question, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("question not found")
+		}
+		return nil, err
+	}
+	return &question, nil
+}
+
+// Find retrieves the bank questions matching the filter, oldest first
+func (r *QuestionBankRepository) Find(filter BankQuestionFilter) ([]domain.BankQuestion, error) {
+	var questions []domain.BankQuestion
+	err := applyBankQuestionFilter(r.db.Model(&domain.BankQuestion{}), filter).
+		Preload("Tags").
+		Preload("LearningOutcomes").
+		Order("id ASC").
+		Find(&questions).Error
+	if err != nil {
+		return nil, err
+	}
+	return questions, nil
+}
+
+// Create creates a bank question together with its tag and outcome links
+func (r *QuestionBankRepository) Create(question *domain.BankQuestion) error {
+	return r.db.Omit("Tags.*", "LearningOutcomes.*").Create(question).Error
+}
+
+// Update updates a bank question and replaces its tag and outcome links
+func (r *QuestionBankRepository) Update(question *domain.BankQuestion) error {
+	if err := r.db.Omit(clause.Associations).Save(question).Error; err != nil {
+		return err
+	}
+	if err := r.db.Model(question).Omit("Tags.*").Association("Tags").Replace(question.Tags); err != nil {
+		return err
+	}
+	return r.db.Model(question).Omit("LearningOutcomes.*").Association("LearningOutcomes").Replace(question.LearningOutcomes)
+}
+
+// Delete deletes a bank question. Exam questions already drawn from it are kept.
+func (r *QuestionBankRepository) Delete(id uint) error {
+	return r.db.Delete(&domain.BankQuestion{}, id).Error
+}
+
+// GetTags retrieves the tags of a course's question bank in name order
+func (r *QuestionBankRepository) GetTags(courseID uint) ([]domain.QuestionTag, error) {
+	var tags []domain.QuestionTag
+	if err := r.db.Where("course_id = ?", courseID).Order("name ASC").Find(&tags).Error; err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+// EnsureTags retrieves the course's tags with the given names, creating missing ones
+func (r *QuestionBankRepository) EnsureTags(courseID uint, names []string) ([]domain.QuestionTag, error) {
+	if len(names) == 0 {
+		return []domain.QuestionTag{}, nil
+	}
+
+	tags := make([]domain.QuestionTag, 0, len(names))
+	for _, name := range names {
+		tags = append(tags, domain.QuestionTag{CourseID: courseID, Name: name})
+	}
+	err := r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "course_id"}, {Name: "name"}},
+		DoNothing: true,
+	}).Create(&tags).Error
+	if err != nil {
+		return nil, err
+	}
+
+	tags = nil
+	if err := r.db.Where("course_id = ? AND name IN ?", courseID, names).Find(&tags).Error; err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+// GetCourseLearningOutcomes retrieves the learning outcomes with the given IDs
+// that belong to the course's syllabus
+func (r *QuestionBankRepository) GetCourseLearningOutcomes(courseID uint, ids []uint) ([]domain.LearningOutcome, error) {
+	var outcomes []domain.LearningOutcome
+	if len(ids) == 0 {
+		return outcomes, nil
+	}
+	err := r.db.Joins("JOIN syllabuses ON syllabuses.id = learning_outcomes.syllabus_id AND syllabuses.deleted_at IS NULL").
+		Where("syllabuses.course_id = ? AND learning_outcomes.id IN ?", courseID, ids).
+		Find(&outcomes).Error
+	if err != nil {
+		return nil, err
+	}
+	return outcomes, nil
+}
+
+// applyBankQuestionFilter narrows a query on bank_questions to the filter
+func applyBankQuestionFilter(query *gorm.DB, filter BankQuestionFilter) *gorm.DB {
+	if filter.CourseID != 0 {
+		query = query.Where("bank_questions.course_id = ?", filter.CourseID)
+	}
+	if filter.Type != "" {
+		query = query.Where("bank_questions.type = ?", filter.Type)
+	}
+	if filter.Difficulty != "" {
+		query = query.Where("bank_questions.difficulty = ?", filter.Difficulty)
+	}
+	if tag := strings.TrimSpace(filter.Tag); tag != "" {
+		query = query.Where("EXISTS (SELECT 1 FROM bank_question_tags bqt JOIN question_tags qt ON qt.id = bqt.question_tag_id WHERE bqt.bank_question_id = bank_questions.id AND LOWER(qt.name) = LOWER(?))", tag)
+	}
+	if filter.LearningOutcomeID != 0 {
+		query = query.Where("EXISTS (SELECT 1 FROM bank_question_outcomes bqo WHERE bqo.bank_question_id = bank_questions.id AND bqo.learning_outcome_id = ?)", filter.LearningOutcomeID)
+	}
+	return query
+}
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // RefreshTokenRepository handles database operations for refresh tokens
@@ -18,10 +19,33 @@ func NewRefreshTokenRepository(db *gorm.DB) *RefreshTokenRepository {
 	return &RefreshTokenRepository{db}
 }
 
-// GetByToken retrieves a refresh token by token string
-func (r *RefreshTokenRepository) GetByToken(token string) (*domain.RefreshToken, error) {
+// Transaction runs fn inside a database transaction with a repository bound to it
+func (r *RefreshTokenRepository) Transaction(fn func(tx *RefreshTokenRepository) error) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return fn(&RefreshTokenRepository{tx})
+	})
+}
+
+// GetByHash retrieves a refresh token by the hash of its token string
+func (r *RefreshTokenRepository) GetByHash(hash string) (*domain.RefreshToken, error) {
+	var refreshToken domain.RefreshToken
+	if err := r.db.Where("token_hash = ?", hash).First(&refreshToken).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("refresh token not found")
+		}
+		return nil, err
+	}
+	return &refreshToken, nil
+}
+
+// LockByHash retrieves a refresh token by the hash of its token string and
+// locks its row until the transaction ends
+func (r *RefreshTokenRepository) LockByHash(hash string) (*domain.RefreshToken, error) {
 	var refreshToken domain.RefreshToken
-	if err := r.db.Where("token = ?", token).First(&refreshToken).Error; err != nil {
+	err := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("token_hash = ?", hash).
+		First(&refreshToken).Error
+	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, errors.New("refresh token not found")
 		}
@@ -35,9 +59,34 @@ func (r *RefreshTokenRepository) Create(token *domain.RefreshToken) error {
 	return r.db.Create(token).Error
 }
 
-// DeleteByToken deletes a refresh token by token string
-func (r *RefreshTokenRepository) DeleteByToken(token string) error {
-	return r.db.Where("token = ?", token).Delete(&domain.RefreshToken{}).Error
+// Save updates a refresh token
+func (r *RefreshTokenRepository) Save(token *domain.RefreshToken) error {
+	return r.db.Save(token).Error
+}
+
+// GetActiveByUserID retrieves the current, unexpired token of each of a
+// user's token families, most recently used first
+func (r *RefreshTokenRepository) GetActiveByUserID(userID uint, now time.Time) ([]domain.RefreshToken, error) {
+	var tokens []domain.RefreshToken
+	err := r.db.Where("user_id = ? AND rotated_at IS NULL AND expires_at > ?", userID, now).
+		Order("last_used_at DESC, id DESC").
+		Find(&tokens).Error
+	if err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+// DeleteFamily deletes every token of a token family
+func (r *RefreshTokenRepository) DeleteFamily(familyID string) error {
+	return r.db.Where("family_id = ?", familyID).Delete(&domain.RefreshToken{}).Error
+}
+
+// DeleteUserFamily deletes every token of one of a user's token families
+// and reports whether there were any
+func (r *RefreshTokenRepository) DeleteUserFamily(userID uint, familyID string) (bool, error) {
+	result := r.db.Where("user_id = ? AND family_id = ?", userID, familyID).Delete(&domain.RefreshToken{})
+	return result.RowsAffected > 0, result.Error
 }
 
 // DeleteByUserID deletes all refresh tokens for a user
@@ -48,4 +97,4 @@ func (r *RefreshTokenRepository) DeleteByUserID(userID uint) error {
 // DeleteExpired deletes all expired refresh tokens
 func (r *RefreshTokenRepository) DeleteExpired() error {
 	return r.db.Where("expires_at < ?", time.Now()).Delete(&domain.RefreshToken{}).Error
-}
\ No newline at end of file
+}
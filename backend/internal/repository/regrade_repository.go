@@ -0,0 +1,223 @@
+package repository
+
+import (
+	"backend/internal/domain"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// RegradeFilter narrows a search for regrade requests; zero values match all
+type RegradeFilter struct {
+	CourseID   uint
+	UserID     uint
+	AssigneeID uint
+	Status     string
+}
+
+// RegradeRepository handles database operations for regrade requests
+type RegradeRepository struct {
+	db *gorm.DB
+}
+
+// NewRegradeRepository creates a new regrade repository
+func NewRegradeRepository(db *gorm.DB) *RegradeRepository {
+	return &RegradeRepository{db}
+}
+
+// Transaction runs fn inside a database transaction with a repository bound to it
+func (r *RegradeRepository) Transaction(fn func(tx *RegradeRepository) error) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return fn(&RegradeRepository{tx})
+	})
+}
+
+// LockItem serializes regrade requests for one submission or exam attempt
+// until the transaction ends
+func (r *RegradeRepository) LockItem(itemType string, id uint) error {
+	return r.db.Exec("SELECT pg_advisory_xact_lock(hashtext(?))", fmt.Sprintf("regrade:%s:%d", itemType, id)).Error
+}
+
+// Create creates a regrade request
+func (r *RegradeRepository) Create(request *domain.RegradeRequest) error {
+	return r.db.Omit(clause.Associations).Create(request).Error
+}
+
+// Save updates a regrade request
+func (r *RegradeRepository) Save(request *domain.RegradeRequest) error {
+	return r.db.Omit(clause.Associations).Save(request).Error
+}
+
+// GetByID retrieves a regrade request with its student, assignee and history
+func (r *RegradeRepository) GetByID(id uint) (*domain.RegradeRequest, error) {
+	var request domain.RegradeRequest
+	err := r.db.Preload("User").Preload("Assignee").
+		Preload("Events", func(db *gorm.DB) *gorm.DB {
+			return db.Order("created_at ASC, id ASC")
+		}).
+		Preload("Events.Actor").
+		First(&request, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("regrade request not found")
+		}
+		return nil, err
+	}
+	return &request, nil
+}
+
+// Lock retrieves a regrade request and locks its row until the transaction ends
+func (r *RegradeRepository) Lock(id uint) (*domain.RegradeRequest, error) {
+	var request domain.RegradeRequest
+	err := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).First(&request, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("regrade request not found")
+		}
+		return nil, err
+	}
+	return &request, nil
+}
+
+// Find retrieves regrade requests, newest first
+func (r *RegradeRepository) Find(filter RegradeFilter) ([]domain.RegradeRequest, error) {
+	query := r.db.Preload("User").Preload("Assignee")
+	if filter.CourseID != 0 {
+		query = query.Where("course_id = ?", filter.CourseID)
+	}
+	if filter.UserID != 0 {
+		query = query.Where("user_id = ?", filter.UserID)
+	}
+	if filter.AssigneeID != 0 {
+		query = query.Where("assignee_id = ?", filter.AssigneeID)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+
+	var requests []domain.RegradeRequest
+	if err := query.Order("created_at DESC, id DESC").Find(&requests).Error; err != nil {
+		return nil, err
+	}
+	return requests, nil
+}
+
+// HasActive reports whether a submission or exam attempt already has a
+// regrade request awaiting a decision
+func (r *RegradeRepository) HasActive(submissionID, examAttemptID *uint) (bool, error) {
+	query := r.db.Model(&domain.RegradeRequest{}).
+		Where("status IN ?", []string{domain.RegradeStatusOpen, domain.RegradeStatusUnderReview})
+	if submissionID != nil {
+		query = query.Where("submission_id = ?", *submissionID)
+	} else {
+		query = query.Where("exam_attempt_id = ?", *examAttemptID)
+	}
+
+	var count int64
+	if err := query.Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// AddEvent records a step in the history of a regrade request
+func (r *RegradeRepository) AddEvent(event *domain.RegradeEvent) error {
+	return r.db.Omit(clause.Associations).Create(event).Error
+}
+
+// GetSubmission retrieves a submission with its assessment
+func (r *RegradeRepository) GetSubmission(id uint) (*domain.Submission, error) {
+	var submission domain.Submission
+	if err := r.db.Preload("Assessment").First(&submission, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("submission not found")
+		}
+		return nil, err
+	}
+	return &submission, nil
+}
+
+// LockSubmission retrieves a submission and locks its row until the transaction ends
+func (r *RegradeRepository) LockSubmission(id uint) (*domain.Submission, error) {
+	var submission domain.Submission
+	err := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).First(&submission, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("submission not found")
+		}
+		return nil, err
+	}
+	return &submission, nil
+}
+
+// SaveSubmission updates a submission without touching its associations
+func (r *RegradeRepository) SaveSubmission(submission *domain.Submission) error {
+	return r.db.Omit(clause.Associations).Save(submission).Error
+}
+
+// GetAttempt retrieves an exam attempt with its exam
+func (r *RegradeRepository) GetAttempt(id uint) (*domain.ExamAttempt, error) {
+	var attempt domain.ExamAttempt
+	if err := r.db.Preload("Exam").First(&attempt, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("attempt not found")
+		}
+		return nil, err
+	}
+	return &attempt, nil
+}
+
+// LockAttempt retrieves an exam attempt and locks its row until the transaction ends
+func (r *RegradeRepository) LockAttempt(id uint) (*domain.ExamAttempt, error) {
+	var attempt domain.ExamAttempt
+	err := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).First(&attempt, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("attempt not found")
+		}
+		return nil, err
+	}
+	return &attempt, nil
+}
+
+// SaveAttempt updates an exam attempt without touching its answers
+func (r *RegradeRepository) SaveAttempt(attempt *domain.ExamAttempt) error {
+	return r.db.Omit(clause.Associations).Save(attempt).Error
+}
+
+// LastAnswerGrading retrieves who graded an answer of an exam attempt last
+// and when; graderID is nil when no answer was graded by hand
+func (r *RegradeRepository) LastAnswerGrading(attemptID uint) (graderID *uint, gradedAt *time.Time, err error) {
+	var answer domain.ExamAnswer
+	err = r.db.Where("exam_attempt_id = ? AND graded_by IS NOT NULL", attemptID).
+		Order("graded_at DESC, id DESC").
+		First(&answer).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, nil
+		}
+		return nil, nil, err
+	}
+	return answer.GradedBy, answer.GradedAt, nil
+}
+
+// GetMainInstructorID retrieves the main instructor of a course, or its
+// first instructor when none is marked as main
+func (r *RegradeRepository) GetMainInstructorID(courseID uint) (uint, error) {
+	var ids []uint
+	err := r.db.Model(&domain.CourseInstructor{}).
+		Where("course_id = ?", courseID).
+		Order("is_main DESC, id ASC").
+		Limit(1).
+		Pluck("user_id", &ids).Error
+	if err != nil {
+		return 0, err
+	}
+	if len(ids) == 0 {
+		return 0, errors.New("course has no instructor")
+	}
+	return ids[0], nil
+}
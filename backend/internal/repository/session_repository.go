@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"backend/internal/domain"
+	"errors"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SessionRepository handles database operations for course sessions
+type SessionRepository struct {
+	db *gorm.DB
+}
+
+// NewSessionRepository creates a new session repository
+func NewSessionRepository(db *gorm.DB) *SessionRepository {
+	return &SessionRepository{db}
+}
+
+// GetByID retrieves a session by ID
+func (r *SessionRepository) GetByID(id uint) (*domain.Session, error) {
+	var session domain.Session
+	if err := r.db.First(&session, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("session not found")
+		}
+		return nil, err
+	}
+	return &session, nil
+}
+
+// GetByCourse retrieves the sessions of a course in order
+func (r *SessionRepository) GetByCourse(courseID uint) ([]domain.Session, error) {
+	var sessions []domain.Session
+	err := r.db.Where("course_id = ?", courseID).
+		Order("number ASC, date ASC, id ASC").
+		Find(&sessions).Error
+	if err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+// NextNumber returns the number following the highest session number of a course
+func (r *SessionRepository) NextNumber(courseID uint) (int, error) {
+	var max *int
+	err := r.db.Model(&domain.Session{}).
+		Where("course_id = ?", courseID).
+		Select("MAX(number)").
+		Scan(&max).Error
+	if err != nil {
+		return 0, err
+	}
+	if max == nil {
+		return 1, nil
+	}
+	return *max + 1, nil
+}
+
+// Create creates a session
+func (r *SessionRepository) Create(session *domain.Session) error {
+	return r.db.Omit(clause.Associations).Create(session).Error
+}
+
+// Update updates a session
+func (r *SessionRepository) Update(session *domain.Session) error {
+	return r.db.Omit(clause.Associations).Save(session).Error
+}
+
+// Delete deletes a session
+func (r *SessionRepository) Delete(id uint) error {
+	return r.db.Delete(&domain.Session{}, id).Error
+}
@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"backend/internal/domain"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SettingRepository handles database operations for system settings
+type SettingRepository struct {
+	db *gorm.DB
+}
+
+// NewSettingRepository creates a new setting repository
+func NewSettingRepository(db *gorm.DB) *SettingRepository {
+	return &SettingRepository{db}
+}
+
+// GetAll retrieves every persisted setting
+func (r *SettingRepository) GetAll() ([]domain.SystemSetting, error) {
+	var settings []domain.SystemSetting
+	if err := r.db.Find(&settings).Error; err != nil {
+		return nil, err
+	}
+	return settings, nil
+}
+
+// SaveAll upserts the given settings and records a change entry for each of
+// them in a single transaction. oldValues holds the previous JSON values by key.
+func (r *SettingRepository) SaveAll(settings []domain.SystemSetting, oldValues map[string]string, changedBy uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		for _, setting := range settings {
+			setting.UpdatedBy = changedBy
+			setting.UpdatedAt = now
+			err := tx.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "key"}},
+				DoUpdates: clause.AssignmentColumns([]string{"value", "updated_by", "updated_at"}),
+			}).Create(&setting).Error
+			if err != nil {
+				return err
+			}
+
+			change := domain.SettingChange{
+				Key:       setting.Key,
+				OldValue:  oldValues[setting.Key],
+				NewValue:  setting.Value,
+				ChangedBy: changedBy,
+				ChangedAt: now,
+			}
+			if err := tx.Create(&change).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// GetHistory retrieves setting changes, newest first, optionally for a single key
+func (r *SettingRepository) GetHistory(key string, limit, offset int) ([]domain.SettingChange, error) {
+	query := r.db.Order("changed_at DESC, id DESC")
+	if key != "" {
+		query = query.Where("key = ?", key)
+	}
+
+	var changes []domain.SettingChange
+	if err := query.Limit(limit).Offset(offset).Find(&changes).Error; err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
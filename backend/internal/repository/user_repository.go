@@ -50,6 +50,18 @@ func (r *UserRepository) GetByEmail(email string) (*domain.User, error) {
 	return &user, nil
 }
 
+// StudentIDExists reports whether a student record with the given student ID exists
+func (r *UserRepository) StudentIDExists(studentID string) (bool, error) {
+	var count int64
+	err := r.db.Model(&domain.StudentInfo{}).Where("student_id = ?", studentID).Count(&count).Error
+	return count > 0, err
+}
+
+// Transaction runs fn inside a database transaction
+func (r *UserRepository) Transaction(fn func(tx *gorm.DB) error) error {
+	return r.db.Transaction(fn)
+}
+
 // Create creates a new user
 func (r *UserRepository) Create(user *domain.User) error {
 	return r.db.Create(user).Error
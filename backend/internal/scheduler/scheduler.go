@@ -0,0 +1,202 @@
+// Package scheduler runs background jobs on cron schedules inside the API
+// process. When several replicas run, each scheduled run of a job is taken
+// by whichever replica claims it first; the others skip it.
+package scheduler
+
+import (
+	"backend/pkg/cron"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Job is a named piece of background work run on a schedule
+type Job struct {
+	Name     string
+	Schedule cron.Schedule
+	Run      func(ctx context.Context) error
+}
+
+// Locker elects the replica that runs a job at its scheduled time tick.
+// TryLock returns ok when the caller may run the job, along with the
+// function that releases the lock, and not ok while another replica runs
+// the job or once the tick has been run.
+type Locker interface {
+	TryLock(ctx context.Context, name string, tick time.Time) (unlock func(), ok bool, err error)
+}
+
+// Scheduler runs registered jobs until it is stopped. Runs of one job never
+// overlap: a run that takes longer than the schedule's interval delays the
+// next one.
+type Scheduler struct {
+	locker  Locker
+	jobs    []Job
+	cancel  context.CancelFunc
+	running sync.WaitGroup
+	now     func() time.Time
+}
+
+// New creates a scheduler whose jobs are elected through locker; a nil
+// locker runs every job in this process
+func New(locker Locker) *Scheduler {
+	return &Scheduler{
+		locker: locker,
+		now:    time.Now,
+	}
+}
+
+// Register adds a job running on a cron schedule such as "*/5 * * * *" or
+// "@every 1m". It panics on invalid schedules and must be called before Start.
+func (s *Scheduler) Register(name, spec string, run func(ctx context.Context) error) {
+	s.jobs = append(s.jobs, Job{Name: name, Schedule: cron.MustParse(spec), Run: run})
+}
+
+// Start runs the registered jobs in the background
+func (s *Scheduler) Start() {
+	if s.cancel != nil {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	for _, job := range s.jobs {
+		s.running.Add(1)
+		go s.loop(ctx, job)
+	}
+	log.Printf("Started %d background jobs", len(s.jobs))
+}
+
+// Stop stops scheduling jobs and waits for running ones to finish, or until
+// ctx is done. Jobs see their context cancelled and should return early.
+func (s *Scheduler) Stop(ctx context.Context) error {
+	if s.cancel == nil {
+		return nil
+	}
+	s.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		s.running.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("background jobs still running: %w", ctx.Err())
+	}
+}
+
+// loop runs a job at every time its schedule fires until ctx is cancelled
+func (s *Scheduler) loop(ctx context.Context, job Job) {
+	defer s.running.Done()
+	for {
+		next := job.Schedule.Next(s.now())
+		if next.IsZero() {
+			log.Printf("Job %s has no further runs scheduled", job.Name)
+			return
+		}
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		s.run(ctx, job, next)
+	}
+}
+
+// run runs a job for its scheduled time tick if this replica wins the
+// tick. Failures and panics are logged; the job runs again at its next
+// scheduled time.
+func (s *Scheduler) run(ctx context.Context, job Job, tick time.Time) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("Job %s panicked: %v", job.Name, r)
+		}
+	}()
+
+	if s.locker != nil {
+		unlock, ok, err := s.locker.TryLock(ctx, job.Name, tick)
+		if err != nil {
+			if !errors.Is(err, context.Canceled) {
+				log.Printf("Job %s could not take its lock: %v", job.Name, err)
+			}
+			return
+		}
+		if !ok {
+			return
+		}
+		defer unlock()
+	}
+
+	started := s.now()
+	if err := job.Run(ctx); err != nil && !errors.Is(err, context.Canceled) {
+		log.Printf("Job %s failed after %s: %v", job.Name, s.now().Sub(started).Round(time.Millisecond), err)
+	}
+}
+
+// AdvisoryLocker elects job runners through Postgres session-level
+// advisory locks and the job_runs table. The lock is held on a dedicated
+// connection for the whole run and is released by the database should the
+// replica die; under it the tick is recorded as the job's last run, so a
+// replica whose timer fires after the run has finished skips it too.
+type AdvisoryLocker struct {
+	db *gorm.DB
+}
+
+// NewAdvisoryLocker creates a locker on the given database
+func NewAdvisoryLocker(db *gorm.DB) *AdvisoryLocker {
+	return &AdvisoryLocker{db}
+}
+
+// TryLock takes the advisory lock of a job without waiting for it and
+// claims tick, unless the job has already run for it
+func (l *AdvisoryLocker) TryLock(ctx context.Context, name string, tick time.Time) (func(), bool, error) {
+	sqlDB, err := l.db.DB()
+	if err != nil {
+		return nil, false, err
+	}
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+
+	key := "job:" + name
+	var locked bool
+	if err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock(hashtext($1))", key).Scan(&locked); err != nil {
+		conn.Close()
+		return nil, false, err
+	}
+	if !locked {
+		conn.Close()
+		return nil, false, nil
+	}
+
+	unlock := func() {
+		// The job's context may be cancelled by now; the lock still has to go
+		if _, err := conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock(hashtext($1))", key); err != nil {
+			log.Printf("Failed to release the lock of job %s: %v", name, err)
+		}
+		conn.Close()
+	}
+
+	result, err := conn.ExecContext(ctx, `INSERT INTO job_runs (name, last_run, updated_at) VALUES ($1, $2, NOW())
+		ON CONFLICT (name) DO UPDATE SET last_run = EXCLUDED.last_run, updated_at = NOW()
+		WHERE job_runs.last_run < EXCLUDED.last_run`, name, tick.UTC())
+	if err != nil {
+		unlock()
+		return nil, false, err
+	}
+	claimed, err := result.RowsAffected()
+	if err != nil || claimed == 0 {
+		unlock()
+		return nil, false, err
+	}
+	return unlock, true, nil
+}
@@ -2,11 +2,18 @@ package server
 
 import (
 	"backend/internal/config"
+	"backend/internal/domain"
 	"backend/internal/repository"
+	"backend/internal/scheduler"
 	"backend/internal/service"
 	"backend/internal/handlers" 
+	"backend/pkg/mail"
 	"context"
+	"errors"
+	"fmt"
+	"log"
 	"net/http"
+	"net/url"
 	"time"
 
 	"github.com/go-playground/validator/v10"
@@ -20,6 +27,8 @@ type Server struct {
 	echo   *echo.Echo
 	db     *gorm.DB
 	config *config.Config
+	jobs   *scheduler.Scheduler
+	mailer mail.Mailer
 }
 
 // New creates a new server
@@ -53,11 +62,24 @@ func New(cfg *config.Config, db *gorm.DB) (*Server, error) {
 		MaxAge: 86400, // Increase cache time for preflight requests
 	}))
 
+	// Outgoing mail; a server that cannot deliver verification and reset
+	// links should not start
+	mailer, err := newMailer(cfg.Mail, cfg.IsDevelopment())
+	if err != nil {
+		return nil, fmt.Errorf("failed to set up mail transport: %w", err)
+	}
+
+	if err := checkPublicBaseURL(cfg.Server.PublicBaseURL); err != nil {
+		return nil, err
+	}
+
 	// Create server
 	server := &Server{
 		echo:   e,
 		db:     db,
 		config: cfg,
+		jobs:   scheduler.New(scheduler.NewAdvisoryLocker(db)),
+		mailer: mailer,
 	}
 
 	// Setup routes
@@ -66,6 +88,19 @@ func New(cfg *config.Config, db *gorm.DB) (*Server, error) {
 	return server, nil
 }
 
+// checkPublicBaseURL checks that links to the server can be built: an
+// absolute http or https address is required
+func checkPublicBaseURL(base string) error {
+	if base == "" {
+		return errors.New("PUBLIC_BASE_URL is required outside development")
+	}
+	u, err := url.Parse(base)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("PUBLIC_BASE_URL must be an absolute http or https URL, got %q", base)
+	}
+	return nil
+}
+
 // Start starts the server
 func (s *Server) Start(address string) error {
 	// Use 0.0.0.0 to listen on all interfaces
@@ -75,9 +110,19 @@ func (s *Server) Start(address string) error {
 	return s.echo.Start(address)
 }
 
-// Shutdown gracefully shuts down the server
+// StartJobs starts the background jobs. Every replica runs the scheduler;
+// each run of a job happens on only one of them.
+func (s *Server) StartJobs() {
+	s.jobs.Start()
+}
+
+// Shutdown gracefully shuts down the server and its background jobs
 func (s *Server) Shutdown(ctx context.Context) error {
-	return s.echo.Shutdown(ctx)
+	err := s.echo.Shutdown(ctx)
+	if jobErr := s.jobs.Stop(ctx); err == nil {
+		err = jobErr
+	}
+	return err
 }
 
 // setupRoutes sets up the API routes
@@ -90,42 +135,156 @@ func (s *Server) setupRoutes() {
 	refreshTokenRepo := repository.NewRefreshTokenRepository(s.db)
 	courseRepo := repository.NewCourseRepository(s.db)
 	assessmentRepo := repository.NewAssessmentRepository(s.db)
+	enrollmentRepo := repository.NewEnrollmentRepository(s.db)
+	settingRepo := repository.NewSettingRepository(s.db)
+	examRepo := repository.NewExamRepository(s.db)
+	questionBankRepo := repository.NewQuestionBankRepository(s.db)
+	outcomeReportRepo := repository.NewOutcomeReportRepository(s.db)
+	gradeRepo := repository.NewGradeRepository(s.db)
+	documentRepo := repository.NewDocumentRepository(s.db)
+	regradeRepo := repository.NewRegradeRepository(s.db)
+	sessionRepo := repository.NewSessionRepository(s.db)
+	attendanceRepo := repository.NewAttendanceRepository(s.db)
+	invitationRepo := repository.NewInvitationRepository(s.db)
+	passwordResetRepo := repository.NewPasswordResetRepository(s.db)
+	mfaRepo := repository.NewMFARepository(s.db)
 	
 	// Parse JWT expiration
 	jwtExpiration, _ := time.ParseDuration(s.config.JWT.Expiration)
 	refreshExpiration, _ := time.ParseDuration(s.config.JWT.RefreshExpiration)
 
+	// Load system settings
+	settingsStore := service.NewSettingsStore(settingRepo, service.DefaultSettings(s.config.Upload.MaxSize))
+	if err := settingsStore.Reload(); err != nil {
+		log.Printf("Failed to load system settings, using defaults: %v", err)
+	}
+
 	// Initialize services
 	authService := service.NewAuthService(
 		userRepo,
 		refreshTokenRepo,
+		invitationRepo,
+		passwordResetRepo,
+		mfaRepo,
+		settingsStore,
+		s.mailer,
+		s.config.Server.PublicBaseURL,
 		s.config.JWT.Secret,
 		jwtExpiration,
 		refreshExpiration,
 	)
-	userService := service.NewUserService(userRepo, s.config.Upload.Directory)
+	userService := service.NewUserService(userRepo, s.config.Upload.Directory, settingsStore)
+	gradebook := service.NewGradebook(gradeRepo, settingsStore)
+	enrollmentManager := service.NewEnrollmentManager(enrollmentRepo, gradebook)
+	courseService := service.NewCourseService(courseRepo, userRepo, enrollmentRepo, enrollmentManager, gradebook)
+	graders := service.NewGraderRegistry()
+	attendancePolicies := service.NewAttendancePolicyEngine(attendanceRepo, courseRepo)
+	examEngine := service.NewExamEngine(examRepo, graders, gradebook, attendancePolicies)
+	examService := service.NewExamService(examRepo, courseRepo, examEngine)
+	questionBankService := service.NewQuestionBankService(questionBankRepo, courseRepo, graders)
+	assessmentService := service.NewAssessmentService(assessmentRepo, courseRepo, settingsStore, gradebook, s.config.Upload.Directory)
+	outcomeReportService := service.NewOutcomeReportService(outcomeReportRepo, courseRepo, userRepo)
+	gradeService := service.NewGradeService(gradeRepo, courseRepo, gradebook)
+	documentService := service.NewDocumentService(documentRepo, gradeRepo, gradebook, settingsStore, s.config.Upload.Directory, s.config.Server.PublicBaseURL)
+	regradeService := service.NewRegradeService(regradeRepo, courseRepo, gradebook, settingsStore)
+	sessionService := service.NewSessionService(sessionRepo, courseRepo)
+	attendanceService := service.NewAttendanceService(attendanceRepo, sessionRepo, courseRepo, attendancePolicies, settingsStore, s.config.Server.PublicBaseURL)
+	
+	// A new repeat policy changes which attempts count toward every GPA
+	settingsStore.OnChange(domain.SettingGPARepeatPolicy, func(domain.SystemSettings) {
+		go func() {
+			if err := gradebook.SyncAllGPAs(); err != nil {
+				log.Printf("Failed to sync GPAs after repeat policy change: %v", err)
+			}
+		}()
+	})
 	
+	// Background jobs
+	s.jobs.Register("prune-refresh-tokens", "0 * * * *", func(ctx context.Context) error {
+		return refreshTokenRepo.DeleteExpired()
+	})
+	s.jobs.Register("prune-invitations", "30 3 * * *", func(ctx context.Context) error {
+		return invitationRepo.DeleteExpired()
+	})
+	s.jobs.Register("prune-password-resets", "15 * * * *", func(ctx context.Context) error {
+		return authService.PrunePasswordResets()
+	})
+	s.jobs.Register("prune-mfa-challenges", "45 * * * *", func(ctx context.Context) error {
+		return authService.PruneMFAChallenges()
+	})
+	s.jobs.Register("expire-exam-attempts", "* * * * *", func(ctx context.Context) error {
+		for ctx.Err() == nil {
+			expired, err := examEngine.ExpireOverdue(100)
+			if err != nil || expired < 100 {
+				return err
+			}
+		}
+		return ctx.Err()
+	})
+	s.jobs.Register("close-attendance-windows", "* * * * *", func(ctx context.Context) error {
+		_, err := attendanceService.CloseExpiredWindows(ctx)
+		return err
+	})
+
 	// Initialize handlers
-	adminHandler := handler.NewAdminHandler(userRepo, courseRepo, assessmentRepo)
+	adminHandler := handler.NewAdminHandler(userRepo, courseRepo, assessmentRepo, enrollmentManager, settingsStore)
 
 	// Register routes
 	s.registerRoutes(
 		authService,
 		userService,
-		nil, // courseService
-		nil, // sessionService
-		nil, // attendanceService
+		courseService,
+		sessionService,
+		attendanceService,
 		nil, // syllabusService
-		nil, // assessmentService
-		nil, // examService
+		assessmentService,
+		examService,
+		questionBankService,
+		outcomeReportService,
 		nil, // forumService
-		nil, // gradeService
+		gradeService,
 		nil, // scheduleService
+		documentService,
+		regradeService,
 		s.config.JWT.Secret,
 		adminHandler, // Pass the admin handler
+		settingsStore,
 	)
 }
 
+// newMailer creates the mail transport the configuration selects. Only
+// development may leave it unset, in which case mail is logged.
+func newMailer(cfg config.MailConfig, development bool) (mail.Mailer, error) {
+	switch cfg.Transport {
+	case "":
+		if !development {
+			return nil, fmt.Errorf("MAIL_TRANSPORT is required outside development")
+		}
+		return mail.LogMailer{}, nil
+	case "log":
+		return mail.LogMailer{}, nil
+	case "smtp":
+		if cfg.SMTPHost == "" {
+			return nil, fmt.Errorf("SMTP_HOST is required for the smtp mail transport")
+		}
+		return &mail.SMTPMailer{
+			Host:     cfg.SMTPHost,
+			Port:     cfg.SMTPPort,
+			Username: cfg.SMTPUsername,
+			Password: cfg.SMTPPassword,
+			From:     cfg.From,
+		}, nil
+	case "file":
+		if cfg.Directory == "" {
+			return nil, fmt.Errorf("MAIL_DIRECTORY is required for the file mail transport")
+		}
+		return &mail.FileMailer{Dir: cfg.Directory, From: cfg.From}, nil
+	case "memory":
+		return &mail.MemoryMailer{}, nil
+	}
+	return nil, fmt.Errorf("unknown mail transport %q", cfg.Transport)
+}
+
 // CustomValidator is a custom validator for echo
 type CustomValidator struct {
 	validator *validator.Validate
@@ -0,0 +1,227 @@
+package service
+
+import (
+	"backend/internal/domain"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Default points of the rubric levels, from excellent to poor
+var defaultRubricPoints = [4]float64{4, 3, 2, 1}
+
+// GetRubric returns the rubric of an assessment with the most points it can award
+func (s *AssessmentService) GetRubric(c echo.Context) error {
+	assessment, err := s.assessmentFromParam(c)
+	if err != nil {
+		return err
+	}
+	if err := authorizeCourseAccess(c, s.courseRepo, assessment.CourseID); err != nil {
+		return err
+	}
+
+	items, err := s.assessmentRepo.GetRubricItems(assessment.ID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to get rubric")
+	}
+	var maxPoints float64
+	for i := range items {
+		maxPoints += items[i].MaxPoints()
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"items":     items,
+		"maxPoints": maxPoints,
+	})
+}
+
+// CreateRubricItem adds an item to the rubric of an assessment
+func (s *AssessmentService) CreateRubricItem(c echo.Context) error {
+	assessment, err := s.assessmentFromParam(c)
+	if err != nil {
+		return err
+	}
+	if err := authorizeCourseStaff(c, s.courseRepo, assessment.CourseID); err != nil {
+		return err
+	}
+
+	item := &domain.RubricItem{AssessmentID: assessment.ID}
+	if err := s.applyRubricItemRequest(c, assessment, item); err != nil {
+		return err
+	}
+	if err := s.assessmentRepo.CreateRubricItem(item); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to create rubric item")
+	}
+
+	return c.JSON(http.StatusCreated, item)
+}
+
+// UpdateRubricItem updates a rubric item. Grades already given keep the
+// levels and points chosen at the time.
+func (s *AssessmentService) UpdateRubricItem(c echo.Context) error {
+	assessment, item, err := s.rubricItemFromParam(c)
+	if err != nil {
+		return err
+	}
+
+	if err := s.applyRubricItemRequest(c, assessment, item); err != nil {
+		return err
+	}
+	if err := s.assessmentRepo.UpdateRubricItem(item); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to update rubric item")
+	}
+
+	return c.JSON(http.StatusOK, item)
+}
+
+// DeleteRubricItem removes an item from the rubric of an assessment
+func (s *AssessmentService) DeleteRubricItem(c echo.Context) error {
+	assessment, item, err := s.rubricItemFromParam(c)
+	if err != nil {
+		return err
+	}
+
+	if err := s.assessmentRepo.DeleteRubricItem(assessment.ID, item.ID); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to delete rubric item")
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"message": "Rubric item deleted successfully",
+	})
+}
+
+// rubricItemFromParam loads the assessment named by ":id" and its rubric
+// item named by ":itemId", checking that the current user is course staff
+func (s *AssessmentService) rubricItemFromParam(c echo.Context) (*domain.Assessment, *domain.RubricItem, error) {
+	assessment, err := s.assessmentFromParam(c)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := authorizeCourseStaff(c, s.courseRepo, assessment.CourseID); err != nil {
+		return nil, nil, err
+	}
+
+	itemID, err := parseIDParam(c, "itemId")
+	if err != nil {
+		return nil, nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid rubric item ID")
+	}
+	item, err := s.assessmentRepo.GetRubricItem(assessment.ID, itemID)
+	if err != nil {
+		return nil, nil, echo.NewHTTPError(http.StatusNotFound, "Rubric item not found")
+	}
+	return assessment, item, nil
+}
+
+// applyRubricItemRequest binds and validates a rubric item request and
+// copies it into the item. The learning outcome must belong to the course.
+func (s *AssessmentService) applyRubricItemRequest(c echo.Context, assessment *domain.Assessment, item *domain.RubricItem) error {
+	var req domain.RubricItemRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+	if err := c.Validate(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	outcome, err := s.assessmentRepo.GetCourseLearningOutcome(assessment.CourseID, req.LearningOutcomeID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "The learning outcome must belong to the course's syllabus")
+	}
+
+	item.LearningOutcomeID = outcome.ID
+	item.LearningOutcome = *outcome
+	item.KeyIndicator = req.KeyIndicator
+	item.ExcellentCriteria = req.ExcellentCriteria
+	item.GoodCriteria = req.GoodCriteria
+	item.AverageCriteria = req.AverageCriteria
+	item.PoorCriteria = req.PoorCriteria
+	item.Order = req.Order
+
+	points := []*float64{req.ExcellentPoints, req.GoodPoints, req.AveragePoints, req.PoorPoints}
+	targets := []*float64{&item.ExcellentPoints, &item.GoodPoints, &item.AveragePoints, &item.PoorPoints}
+	for i, p := range points {
+		if p != nil {
+			*targets[i] = *p
+		} else if item.ID == 0 {
+			*targets[i] = defaultRubricPoints[i]
+		}
+	}
+	for i := 1; i < len(targets); i++ {
+		if *targets[i] > *targets[i-1] {
+			return echo.NewHTTPError(http.StatusBadRequest,
+				fmt.Sprintf("%s must not be worth more points than %s", domain.RubricLevels[i], domain.RubricLevels[i-1]))
+		}
+	}
+	if item.MaxPoints() <= 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "The excellent level must be worth points")
+	}
+	return nil
+}
+
+// gradeWithRubric turns the chosen rubric levels into selections, a score
+// out of 100 and structured feedback. Every rubric item must be graded once.
+func gradeWithRubric(items []domain.RubricItem, choices []domain.RubricSelectionRequest, graderID uint, comment string) ([]domain.RubricSelection, float64, string, error) {
+	byItem := make(map[uint]*domain.RubricSelectionRequest, len(choices))
+	for i := range choices {
+		if _, ok := byItem[choices[i].RubricItemID]; ok {
+			return nil, 0, "", fmt.Errorf("rubric item %d is graded more than once", choices[i].RubricItemID)
+		}
+		byItem[choices[i].RubricItemID] = &choices[i]
+	}
+
+	var earned, total float64
+	var feedback strings.Builder
+	selections := make([]domain.RubricSelection, 0, len(items))
+	for i := range items {
+		item := &items[i]
+		choice, ok := byItem[item.ID]
+		if !ok {
+			return nil, 0, "", fmt.Errorf("rubric item %q has no level", item.KeyIndicator)
+		}
+		delete(byItem, item.ID)
+
+		points, criteria, _ := item.LevelPoints(choice.Level)
+		selection := domain.RubricSelection{
+			RubricItemID:      item.ID,
+			LearningOutcomeID: item.LearningOutcomeID,
+			Level:             choice.Level,
+			Points:            points,
+			MaxPoints:         item.MaxPoints(),
+			Criteria:          criteria,
+			Comment:           strings.TrimSpace(choice.Comment),
+			GradedBy:          graderID,
+		}
+		selections = append(selections, selection)
+		earned += selection.Points
+		total += selection.MaxPoints
+
+		feedback.WriteString(item.KeyIndicator)
+		if item.LearningOutcome.Code != "" {
+			fmt.Fprintf(&feedback, " [%s]", item.LearningOutcome.Code)
+		}
+		fmt.Fprintf(&feedback, ": %s (%g/%g)\n", choice.Level, selection.Points, selection.MaxPoints)
+		if criteria != "" {
+			fmt.Fprintf(&feedback, "  %s\n", criteria)
+		}
+		if selection.Comment != "" {
+			fmt.Fprintf(&feedback, "  %s\n", selection.Comment)
+		}
+	}
+	for i := range choices {
+		if _, ok := byItem[choices[i].RubricItemID]; ok {
+			return nil, 0, "", fmt.Errorf("rubric item %d does not belong to this assessment", choices[i].RubricItemID)
+		}
+	}
+	if total <= 0 {
+		return nil, 0, "", fmt.Errorf("the rubric awards no points")
+	}
+
+	if comment = strings.TrimSpace(comment); comment != "" {
+		fmt.Fprintf(&feedback, "\n%s\n", comment)
+	}
+	score := math.Round(earned/total*10000) / 100
+	return selections, score, strings.TrimRight(feedback.String(), "\n"), nil
+}
@@ -1,61 +1,211 @@
 package service
 
-import "github.com/labstack/echo/v4"
+import (
+	"backend/internal/domain"
+	"backend/internal/repository"
+	"backend/pkg/middleware"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// AssessmentService handles assessments and the files students submit for them
+type AssessmentService struct {
+	assessmentRepo  *repository.AssessmentRepository
+	courseRepo      *repository.CourseRepository
+	settings        *SettingsStore
+	gradebook       *Gradebook
+	uploadDirectory string
+}
 
-type AssessmentService struct{}
+// NewAssessmentService creates a new assessment service
+func NewAssessmentService(
+	assessmentRepo *repository.AssessmentRepository,
+	courseRepo *repository.CourseRepository,
+	settings *SettingsStore,
+	gradebook *Gradebook,
+	uploadDir string,
+) *AssessmentService {
+	return &AssessmentService{
+		assessmentRepo:  assessmentRepo,
+		courseRepo:      courseRepo,
+		settings:        settings,
+		gradebook:       gradebook,
+		uploadDirectory: uploadDir,
+	}
+}
 
+// GetAssessments returns the assessments visible to the current user,
+// optionally for one course
 func (s *AssessmentService) GetAssessments(c echo.Context) error {
-	return c.JSON(200, map[string]string{"message": "dummy get assessments"})
+	userID, err := middleware.GetUserIDFromToken(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Invalid token")
+	}
+	role, err := middleware.GetRoleFromToken(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Invalid token")
+	}
+
+	var filter repository.AssessmentFilter
+	if courseParam := c.QueryParam("courseId"); courseParam != "" {
+		courseID, err := strconv.ParseUint(courseParam, 10, 32)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid course ID")
+		}
+		filter.CourseID = uint(courseID)
+	}
+
+	// Scope the listing to the user's role
+	switch role {
+	case "admin":
+	case "instructor":
+		filter.InstructorID = userID
+	default:
+		filter.StudentID = userID
+	}
+
+	assessments, err := s.assessmentRepo.FindAssessments(filter)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to get assessments")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"assessments": assessments,
+	})
 }
 
+// GetAssessment returns an assessment
 func (s *AssessmentService) GetAssessment(c echo.Context) error {
-	return c.JSON(200, map[string]string{"message": "dummy get assessment"})
+	assessment, err := s.assessmentFromParam(c)
+	if err != nil {
+		return err
+	}
+	if err := authorizeCourseAccess(c, s.courseRepo, assessment.CourseID); err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, assessment)
 }
 
+// CreateAssessment creates an assessment in a course the current user teaches
 func (s *AssessmentService) CreateAssessment(c echo.Context) error {
-	return c.JSON(200, map[string]string{"message": "dummy create assessment"})
+	var req domain.CreateAssessmentRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+	if err := validateAssessmentRequest(c, &req); err != nil {
+		return err
+	}
+
+	if err := authorizeCourseStaff(c, s.courseRepo, req.CourseID); err != nil {
+		return err
+	}
+
+	assessment := &domain.Assessment{CourseID: req.CourseID}
+	applyAssessmentRequest(assessment, &req)
+	if err := s.assessmentRepo.Create(assessment); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to create assessment")
+	}
+
+	return c.JSON(http.StatusCreated, assessment)
 }
 
+// UpdateAssessment updates an assessment; its course cannot be changed.
+// Late penalties already recorded on submissions are kept.
 func (s *AssessmentService) UpdateAssessment(c echo.Context) error {
-	return c.JSON(200, map[string]string{"message": "dummy update assessment"})
+	assessment, err := s.assessmentFromParam(c)
+	if err != nil {
+		return err
+	}
+	if err := authorizeCourseStaff(c, s.courseRepo, assessment.CourseID); err != nil {
+		return err
+	}
+
+	var req domain.CreateAssessmentRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+	req.CourseID = assessment.CourseID
+	if err := validateAssessmentRequest(c, &req); err != nil {
+		return err
+	}
+
+	applyAssessmentRequest(assessment, &req)
+	if err := s.assessmentRepo.Update(assessment); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to update assessment")
+	}
+
+	return c.JSON(http.StatusOK, assessment)
 }
 
+// DeleteAssessment deletes an assessment. Submitted files are kept.
 func (s *AssessmentService) DeleteAssessment(c echo.Context) error {
-	return c.JSON(200, map[string]string{"message": "dummy delete assessment"})
-}
-
-func (s *AssessmentService) SubmitAssessment(c echo.Context) error {
-	return c.JSON(200, map[string]string{"message": "dummy submit assessment"})
-}
-
-func (s *AssessmentService) GetSubmissions(c echo.Context) error {
-	return c.JSON(200, map[string]string{"message": "dummy get submissions"})
-}
-
-func (s *AssessmentService) GetSubmission(c echo.Context) error {
-	return c.JSON(200, map[string]string{"message": "dummy get submission"})
-}
-
-func (s *AssessmentService) GradeSubmission(c echo.Context) error {
-	return c.JSON(200, map[string]string{"message": "dummy grade submission"})
-}
-
-func (s *AssessmentService) GetMySubmissions(c echo.Context) error {
-	return c.JSON(200, map[string]string{"message": "dummy get my submissions"})
-}
-
-func (s *AssessmentService) GetRubric(c echo.Context) error {
-	return c.JSON(200, map[string]string{"message": "dummy get rubric"})
+	assessment, err := s.assessmentFromParam(c)
+	if err != nil {
+		return err
+	}
+	if err := authorizeCourseStaff(c, s.courseRepo, assessment.CourseID); err != nil {
+		return err
+	}
+
+	if err := s.assessmentRepo.Delete(assessment.ID); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to delete assessment")
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"message": "Assessment deleted successfully",
+	})
 }
 
-func (s *AssessmentService) CreateRubricItem(c echo.Context) error {
-	return c.JSON(200, map[string]string{"message": "dummy create rubric item"})
+// assessmentFromParam loads the assessment named by ":id"
+func (s *AssessmentService) assessmentFromParam(c echo.Context) (*domain.Assessment, error) {
+	assessmentID, err := parseIDParam(c, "id")
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid assessment ID")
+	}
+	assessment, err := s.assessmentRepo.GetByID(assessmentID)
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusNotFound, "Assessment not found")
+	}
+	return assessment, nil
 }
 
-func (s *AssessmentService) UpdateRubricItem(c echo.Context) error {
-	return c.JSON(200, map[string]string{"message": "dummy update rubric item"})
+func validateAssessmentRequest(c echo.Context, req *domain.CreateAssessmentRequest) error {
+	if err := c.Validate(req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	if !req.AvailableTo.After(req.AvailableFrom) {
+		return echo.NewHTTPError(http.StatusBadRequest, "availableTo must be after availableFrom")
+	}
+	for _, t := range domain.ParseFileTypes(req.AllowedFileTypes) {
+		if !fileTypePattern.MatchString(t) {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("%q is not a valid file extension", t))
+		}
+	}
+	return nil
 }
 
-func (s *AssessmentService) DeleteRubricItem(c echo.Context) error {
-	return c.JSON(200, map[string]string{"message": "dummy delete rubric item"})
+func applyAssessmentRequest(assessment *domain.Assessment, req *domain.CreateAssessmentRequest) {
+	assessment.Type = req.Type
+	assessment.Title = req.Title
+	assessment.Description = req.Description
+	assessment.Weight = req.Weight
+	assessment.DueDate = req.DueDate
+	assessment.AvailableFrom = req.AvailableFrom
+	assessment.AvailableTo = req.AvailableTo
+	assessment.MaxAttempts = req.MaxAttempts
+	if assessment.MaxAttempts == 0 {
+		assessment.MaxAttempts = 1
+	}
+	assessment.PassingScore = req.PassingScore
+	assessment.QuestionsCount = req.QuestionsCount
+	assessment.RandomizeQuestions = req.RandomizeQuestions
+	assessment.AllowedFileTypes = strings.Join(domain.ParseFileTypes(req.AllowedFileTypes), ",")
+	assessment.MaxFileSize = req.MaxFileSize
+	assessment.LatePenaltyPerDay = req.LatePenaltyPerDay
+	assessment.MaxLatePenalty = req.MaxLatePenalty
 }
@@ -0,0 +1,404 @@
+package service
+
+import (
+	"backend/internal/domain"
+	"backend/internal/repository"
+	"backend/pkg/filetype"
+	"backend/pkg/middleware"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// ErrMaxSubmissionsReached is returned once a student has used every attempt
+var ErrMaxSubmissionsReached = errors.New("maximum number of submissions reached")
+
+// SubmitAssessment stores a file a student submits for an assessment. The
+// file must carry an allowed extension and its content must match that
+// extension. Each submission is kept as a separate attempt under
+// submissions/course_<id>/assessment_<id>/user_<id>/ in the upload
+// directory. Submissions past the due date are marked late and carry the
+// assessment's late penalty.
+func (s *AssessmentService) SubmitAssessment(c echo.Context) error {
+	userID, err := middleware.GetUserIDFromToken(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Invalid token")
+	}
+	role, err := middleware.GetRoleFromToken(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Invalid token")
+	}
+	if role != "student" {
+		return echo.NewHTTPError(http.StatusForbidden, "Only students can submit assessments")
+	}
+
+	assessment, err := s.assessmentFromParam(c)
+	if err != nil {
+		return err
+	}
+	if err := authorizeCourseAccess(c, s.courseRepo, assessment.CourseID); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	if !assessment.AvailableFrom.IsZero() && now.Before(assessment.AvailableFrom) {
+		return echo.NewHTTPError(http.StatusForbidden, "The assessment is not open for submissions yet")
+	}
+	if !assessment.AvailableTo.IsZero() && !now.Before(assessment.AvailableTo) {
+		return echo.NewHTTPError(http.StatusForbidden, "The assessment is closed for submissions")
+	}
+
+	file, err := c.FormFile("file")
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return echo.NewHTTPError(http.StatusRequestEntityTooLarge, "File is too large")
+		}
+		return echo.NewHTTPError(http.StatusBadRequest, "No file uploaded")
+	}
+
+	// Validate the file against the assessment and the system settings
+	settings := s.settings.Current()
+	allowed := submissionFileTypes(assessment, settings)
+	ext := strings.ToLower(filepath.Ext(file.Filename))
+	if !containsString(allowed, ext) {
+		return echo.NewHTTPError(http.StatusBadRequest,
+			"Invalid file type. Allowed types: "+strings.Join(allowed, ", "))
+	}
+	maxSize := settings.MaxFileSize
+	if assessment.MaxFileSize > 0 && assessment.MaxFileSize < maxSize {
+		maxSize = assessment.MaxFileSize
+	}
+	if file.Size > maxSize {
+		return echo.NewHTTPError(http.StatusRequestEntityTooLarge,
+			fmt.Sprintf("File is too large. Maximum size is %d bytes", maxSize))
+	}
+	if file.Size == 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "The file is empty")
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to open uploaded file")
+	}
+	defer src.Close()
+
+	detected, err := filetype.Detect(src, file.Size)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to read uploaded file")
+	}
+	if !detected.Matches(ext) {
+		return echo.NewHTTPError(http.StatusBadRequest,
+			fmt.Sprintf("The file's content does not match its %s extension", ext))
+	}
+
+	// Write the file next to its final place while hashing it, and move it
+	// there once the submission with its attempt number is committed. The
+	// temporary file is removed on every path that does not move it.
+	dir := submissionDirectory(assessment, userID)
+	if err := os.MkdirAll(filepath.Join(s.uploadDirectory, dir), 0750); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to create upload directory")
+	}
+	tmp, err := os.CreateTemp(filepath.Join(s.uploadDirectory, dir), ".upload-*")
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to create destination file")
+	}
+	defer os.Remove(tmp.Name())
+
+	hash := sha256.New()
+	if _, err := src.Seek(0, io.SeekStart); err != nil {
+		tmp.Close()
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to read uploaded file")
+	}
+	size, err := io.Copy(io.MultiWriter(tmp, hash), src)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to save file")
+	}
+
+	submission := &domain.Submission{
+		AssessmentID:   assessment.ID,
+		UserID:         userID,
+		FileName:       filepath.Base(file.Filename),
+		FileSize:       size,
+		ContentType:    detected.MIME,
+		Checksum:       hex.EncodeToString(hash.Sum(nil)),
+		SubmissionDate: now,
+		Status:         domain.SubmissionStatusSubmitted,
+	}
+	submission.LateDays, submission.LatePenalty = assessment.LatePenalty(now)
+	if submission.LateDays > 0 {
+		submission.IsLate = true
+		submission.Status = domain.SubmissionStatusLate
+	}
+
+	err = s.assessmentRepo.Transaction(func(tx *repository.AssessmentRepository) error {
+		if err := tx.LockStudentAssessment(assessment.ID, userID); err != nil {
+			return err
+		}
+		last, err := tx.LastAttemptNumber(assessment.ID, userID)
+		if err != nil {
+			return err
+		}
+		if assessment.MaxAttempts > 0 && last >= assessment.MaxAttempts {
+			return ErrMaxSubmissionsReached
+		}
+
+		submission.AttemptNumber = last + 1
+		submission.FilePath = filepath.ToSlash(filepath.Join(dir, fmt.Sprintf("attempt_%d%s", submission.AttemptNumber, ext)))
+		return tx.CreateSubmission(submission)
+	})
+	if errors.Is(err, ErrMaxSubmissionsReached) {
+		return echo.NewHTTPError(http.StatusConflict, err.Error())
+	}
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to save submission")
+	}
+
+	// A submission whose file cannot be put in place is withdrawn rather
+	// than left pointing at nothing
+	if err := os.Rename(tmp.Name(), filepath.Join(s.uploadDirectory, filepath.FromSlash(submission.FilePath))); err != nil {
+		if delErr := s.assessmentRepo.DeleteSubmission(submission.ID); delErr != nil {
+			log.Printf("Failed to withdraw submission %d without a file: %v", submission.ID, delErr)
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to save file")
+	}
+
+	return c.JSON(http.StatusCreated, submission)
+}
+
+// GetMySubmissions returns the current user's submissions to an assessment
+func (s *AssessmentService) GetMySubmissions(c echo.Context) error {
+	userID, err := middleware.GetUserIDFromToken(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Invalid token")
+	}
+
+	assessment, err := s.assessmentFromParam(c)
+	if err != nil {
+		return err
+	}
+
+	submissions, err := s.assessmentRepo.GetUserSubmissions(assessment.ID, userID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to get submissions")
+	}
+	released, err := resultsReleased(s.courseRepo, s.gradebook, assessment.CourseID, domain.GradeItemAssessment, assessment.ID, userID)
+	if err != nil {
+		return err
+	}
+	if !released {
+		for i := range submissions {
+			submissions[i].HideResults()
+		}
+	}
+
+	attemptsUsed := 0
+	if len(submissions) > 0 {
+		attemptsUsed = submissions[len(submissions)-1].AttemptNumber
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"submissions":  submissions,
+		"maxAttempts":  assessment.MaxAttempts,
+		"attemptsUsed": attemptsUsed,
+	})
+}
+
+// GetSubmissions returns every submission to an assessment
+func (s *AssessmentService) GetSubmissions(c echo.Context) error {
+	assessment, err := s.assessmentFromParam(c)
+	if err != nil {
+		return err
+	}
+	if err := authorizeCourseStaff(c, s.courseRepo, assessment.CourseID); err != nil {
+		return err
+	}
+
+	submissions, err := s.assessmentRepo.GetAssessmentSubmissions(assessment.ID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to get submissions")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"submissions": submissions,
+	})
+}
+
+// GetSubmission returns a submission to the student who made it or to
+// course staff. The student gets its results once the assessment's grades
+// are released.
+func (s *AssessmentService) GetSubmission(c echo.Context) error {
+	userID, err := middleware.GetUserIDFromToken(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Invalid token")
+	}
+
+	submission, assessment, err := s.submissionFromParam(c)
+	if err != nil {
+		return err
+	}
+	if submission.UserID == userID {
+		released, err := resultsReleased(s.courseRepo, s.gradebook, assessment.CourseID, domain.GradeItemAssessment, assessment.ID, userID)
+		if err != nil {
+			return err
+		}
+		if !released {
+			submission.HideResults()
+		}
+	}
+	return c.JSON(http.StatusOK, submission)
+}
+
+// DownloadSubmission sends the submitted file to the student who made it or
+// to course staff
+func (s *AssessmentService) DownloadSubmission(c echo.Context) error {
+	submission, _, err := s.submissionFromParam(c)
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join(s.uploadDirectory, filepath.FromSlash(submission.FilePath))
+	if _, err := os.Stat(path); err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, "Submitted file not found")
+	}
+
+	c.Response().Header().Set("X-Checksum-SHA256", submission.Checksum)
+	return c.Attachment(path, submission.FileName)
+}
+
+// GradeSubmission records the score of a submission, deducting its late
+// penalty. Assessments with a rubric are graded by choosing a level for
+// each rubric item; the score and feedback are computed from the levels.
+func (s *AssessmentService) GradeSubmission(c echo.Context) error {
+	graderID, err := middleware.GetUserIDFromToken(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Invalid token")
+	}
+
+	submission, assessment, err := s.submissionFromParam(c)
+	if err != nil {
+		return err
+	}
+	if err := authorizeCourseStaff(c, s.courseRepo, assessment.CourseID); err != nil {
+		return err
+	}
+
+	var req domain.GradeSubmissionRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+	if err := c.Validate(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	items, err := s.assessmentRepo.GetRubricItems(assessment.ID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to get rubric")
+	}
+	rawScore, feedback := req.Score, req.Feedback
+	var selections []domain.RubricSelection
+	if len(items) > 0 {
+		selections, rawScore, feedback, err = gradeWithRubric(items, req.Rubric, graderID, req.Feedback)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+	} else if len(req.Rubric) > 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "The assessment has no rubric")
+	}
+
+	now := time.Now()
+	submission.ApplyGrade(rawScore)
+	submission.Feedback = feedback
+	submission.Status = domain.SubmissionStatusGraded
+	submission.GradedBy = graderID
+	submission.GradedAt = &now
+	for i := range selections {
+		selections[i].SubmissionID = submission.ID
+	}
+	err = s.assessmentRepo.Transaction(func(tx *repository.AssessmentRepository) error {
+		if err := tx.SaveSubmission(submission); err != nil {
+			return err
+		}
+		return tx.ReplaceRubricSelections(submission.ID, selections)
+	})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to grade submission")
+	}
+	submission.RubricSelections = selections
+	s.gradebook.ResultChanged(domain.GradeItemAssessment, assessment.ID, submission.UserID)
+
+	return c.JSON(http.StatusOK, submission)
+}
+
+// submissionFromParam loads the submission named by ":submissionId" and
+// checks that the current user made it or is on the staff of its course
+func (s *AssessmentService) submissionFromParam(c echo.Context) (*domain.Submission, *domain.Assessment, error) {
+	userID, err := middleware.GetUserIDFromToken(c)
+	if err != nil {
+		return nil, nil, echo.NewHTTPError(http.StatusUnauthorized, "Invalid token")
+	}
+
+	submissionID, err := parseIDParam(c, "submissionId")
+	if err != nil {
+		return nil, nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid submission ID")
+	}
+	submission, err := s.assessmentRepo.GetSubmission(submissionID)
+	if err != nil {
+		return nil, nil, echo.NewHTTPError(http.StatusNotFound, "Submission not found")
+	}
+	assessment, err := s.assessmentRepo.GetByID(submission.AssessmentID)
+	if err != nil {
+		return nil, nil, echo.NewHTTPError(http.StatusNotFound, "Assessment not found")
+	}
+
+	if submission.UserID != userID {
+		if err := authorizeCourseStaff(c, s.courseRepo, assessment.CourseID); err != nil {
+			return nil, nil, err
+		}
+	}
+	return submission, assessment, nil
+}
+
+// submissionFileTypes returns the extensions a submission may have: those
+// of the assessment that the system settings allow, or all the settings
+// allow when the assessment does not restrict them
+func submissionFileTypes(assessment *domain.Assessment, settings domain.SystemSettings) []string {
+	types := assessment.FileTypeList()
+	if len(types) == 0 {
+		return settings.AllowedFileTypes
+	}
+	allowed := make([]string, 0, len(types))
+	for _, t := range types {
+		if settings.IsFileTypeAllowed(t) {
+			allowed = append(allowed, t)
+		}
+	}
+	return allowed
+}
+
+// submissionDirectory is where a student's files for an assessment are
+// kept, relative to the upload directory
+func submissionDirectory(assessment *domain.Assessment, userID uint) string {
+	return fmt.Sprintf("submissions/course_%d/assessment_%d/user_%d", assessment.CourseID, assessment.ID, userID)
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
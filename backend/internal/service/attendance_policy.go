@@ -0,0 +1,150 @@
+package service
+
+import (
+	"backend/internal/domain"
+	"backend/internal/repository"
+	"fmt"
+	"strings"
+)
+
+// AttendanceIneligibleError is returned when a student's attendance is too
+// low to start an exam
+type AttendanceIneligibleError struct {
+	ExamType     string
+	Rate         float64
+	RequiredRate float64
+}
+
+func (e *AttendanceIneligibleError) Error() string {
+	return fmt.Sprintf("your attendance in this course is %s%%, below the %s%% required to take %s exams",
+		formatPercent(e.Rate), formatPercent(e.RequiredRate), e.ExamType)
+}
+
+// AttendancePolicyEngine measures students' attendance against the policy
+// of their course, or the institution's default policy when the course has
+// none. Rates come from the students' attendance records; sessions nobody
+// recorded do not count.
+type AttendancePolicyEngine struct {
+	attendanceRepo *repository.AttendanceRepository
+	courseRepo     *repository.CourseRepository
+}
+
+// NewAttendancePolicyEngine creates a new attendance policy engine
+func NewAttendancePolicyEngine(attendanceRepo *repository.AttendanceRepository, courseRepo *repository.CourseRepository) *AttendancePolicyEngine {
+	return &AttendancePolicyEngine{
+		attendanceRepo: attendanceRepo,
+		courseRepo:     courseRepo,
+	}
+}
+
+// Policy returns the attendance policy that applies to a course
+func (p *AttendancePolicyEngine) Policy(courseID uint) (*domain.AttendancePolicy, error) {
+	policy, err := p.attendanceRepo.GetPolicy(courseID)
+	if err == nil {
+		return policy, nil
+	}
+	if err.Error() != "attendance policy not found" {
+		return nil, err
+	}
+	defaults := domain.DefaultAttendancePolicy(courseID)
+	return &defaults, nil
+}
+
+// Standing measures one student's attendance in a course
+func (p *AttendancePolicyEngine) Standing(courseID, userID uint) (*domain.AttendanceStanding, error) {
+	policy, err := p.Policy(courseID)
+	if err != nil {
+		return nil, err
+	}
+	records, err := p.attendanceRepo.GetCourseRecords(courseID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	summary := domain.AttendanceSummary{UserID: userID}
+	for _, record := range records {
+		summary.User = record.User.ToUserResponse()
+		summary.Add(record.Status)
+	}
+	standing := measure(policy, summary)
+	return &standing, nil
+}
+
+// Standings measures the attendance of every enrolled or completed student
+// of a course
+func (p *AttendancePolicyEngine) Standings(courseID uint) ([]domain.AttendanceStanding, error) {
+	policy, err := p.Policy(courseID)
+	if err != nil {
+		return nil, err
+	}
+	students, err := p.courseRepo.GetStudents(courseID)
+	if err != nil {
+		return nil, err
+	}
+	records, err := p.attendanceRepo.GetCourseRecords(courseID, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	summaries := make([]domain.AttendanceSummary, 0, len(students))
+	index := make(map[uint]int, len(students))
+	for _, student := range students {
+		if student.Status != domain.EnrollmentStatusEnrolled && student.Status != domain.EnrollmentStatusCompleted {
+			continue
+		}
+		index[student.UserID] = len(summaries)
+		summaries = append(summaries, domain.AttendanceSummary{UserID: student.UserID, User: student.User.ToUserResponse()})
+	}
+	for _, record := range records {
+		if i, ok := index[record.UserID]; ok {
+			summaries[i].Add(record.Status)
+		}
+	}
+
+	standings := make([]domain.AttendanceStanding, 0, len(summaries))
+	for _, summary := range summaries {
+		standings = append(standings, measure(policy, summary))
+	}
+	return standings, nil
+}
+
+// CheckExamEligibility returns an *AttendanceIneligibleError when the
+// course's policy gates the exam's type and the student's attendance is
+// below the required rate
+func (p *AttendancePolicyEngine) CheckExamEligibility(exam *domain.Exam, userID uint) error {
+	if p == nil {
+		return nil
+	}
+	policy, err := p.Policy(exam.CourseID)
+	if err != nil {
+		return err
+	}
+	if !policy.Gates(exam.Type) {
+		return nil
+	}
+
+	standing, err := p.Standing(exam.CourseID, userID)
+	if err != nil {
+		return err
+	}
+	if standing.Rate < policy.MinimumRate {
+		return &AttendanceIneligibleError{ExamType: exam.Type, Rate: standing.Rate, RequiredRate: policy.MinimumRate}
+	}
+	return nil
+}
+
+// measure rates a student's attendance summary under a policy
+func measure(policy *domain.AttendancePolicy, summary domain.AttendanceSummary) domain.AttendanceStanding {
+	rate := policy.Rate(summary)
+	return domain.AttendanceStanding{
+		AttendanceSummary: summary,
+		Rate:              rate,
+		RequiredRate:      policy.MinimumRate,
+		Standing:          policy.Standing(rate),
+	}
+}
+
+// formatPercent prints a percentage without trailing zeros
+func formatPercent(value float64) string {
+	return strings.TrimSuffix(strings.TrimRight(fmt.Sprintf("%.2f", value), "0"), ".")
+}
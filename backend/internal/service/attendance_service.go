@@ -1,37 +1,856 @@
 package service
 
-import "github.com/labstack/echo/v4"
+import (
+	"backend/internal/domain"
+	"backend/internal/repository"
+	"backend/pkg/middleware"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
 
-type AttendanceService struct{}
+	"github.com/labstack/echo/v4"
+)
 
-func (s *AttendanceService) GetSessionAttendance(c echo.Context) error {
-	return c.JSON(200, map[string]string{"message": "dummy get session attendance"})
+// defaultCheckInMinutes is how long a check-in window stays open unless
+// course staff choose otherwise
+const defaultCheckInMinutes = 15
+
+// Errors of attendance recording
+var (
+	ErrCheckInOpen   = errors.New("a check-in window is already open for this session")
+	ErrCheckInClosed = errors.New("check-in is closed for this session")
+	ErrNoOpenWindow  = errors.New("no check-in window is open for this session")
+)
+
+// AttendanceService records students' attendance of course sessions.
+// Course staff open a check-in window for a session, which shows a QR code
+// that rotates every 30 seconds; students scan it to check in and count as
+// late after the window's threshold. Closing the window marks enrolled
+// students without a record as absent. Staff can override any status in
+// bulk, giving a reason that is kept in the session's history. Students'
+// rates are measured against their course's attendance policy.
+type AttendanceService struct {
+	attendanceRepo *repository.AttendanceRepository
+	sessionRepo    *repository.SessionRepository
+	courseRepo     *repository.CourseRepository
+	policies       *AttendancePolicyEngine
+	settings       *SettingsStore
+	publicBaseURL  string
+	now            func() time.Time
 }
 
-func (s *AttendanceService) GetMyAttendance(c echo.Context) error {
-	return c.JSON(200, map[string]string{"message": "dummy get my attendance"})
+// NewAttendanceService creates a new attendance service
+func NewAttendanceService(
+	attendanceRepo *repository.AttendanceRepository,
+	sessionRepo *repository.SessionRepository,
+	courseRepo *repository.CourseRepository,
+	policies *AttendancePolicyEngine,
+	settings *SettingsStore,
+	publicBaseURL string,
+) *AttendanceService {
+	return &AttendanceService{
+		attendanceRepo: attendanceRepo,
+		sessionRepo:    sessionRepo,
+		courseRepo:     courseRepo,
+		policies:       policies,
+		settings:       settings,
+		publicBaseURL:  publicBaseURL,
+		now:            time.Now,
+	}
 }
 
-func (s *AttendanceService) MarkAttendance(c echo.Context) error {
-	return c.JSON(200, map[string]string{"message": "dummy mark attendance"})
+// OpenCheckIn opens a check-in window for a session and returns its first
+// token. A window whose time ran out without being closed is closed first.
+func (s *AttendanceService) OpenCheckIn(c echo.Context) error {
+	session, err := s.staffSession(c)
+	if err != nil {
+		return err
+	}
+	actorID, err := middleware.GetUserIDFromToken(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Invalid token")
+	}
+
+	var req domain.OpenCheckInRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+	if err := c.Validate(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	duration := req.DurationMinutes
+	if duration == 0 {
+		duration = defaultCheckInMinutes
+	}
+	lateAfter := s.settings.Current().AttendanceLate
+	if req.LateAfterMinutes != nil {
+		lateAfter = *req.LateAfterMinutes
+	}
+
+	secret, err := newWindowSecret()
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to open check-in")
+	}
+
+	now := s.now()
+	window := &domain.AttendanceWindow{
+		SessionID: session.ID,
+		CourseID:  session.CourseID,
+		Secret:    secret,
+		OpenedBy:  actorID,
+		OpenedAt:  now,
+		LateAfter: now.Add(time.Duration(lateAfter) * time.Minute),
+		ClosesAt:  now.Add(time.Duration(duration) * time.Minute),
+	}
+	err = s.attendanceRepo.Transaction(func(tx *repository.AttendanceRepository) error {
+		if err := tx.LockSession(session.ID); err != nil {
+			return err
+		}
+		previous, err := tx.GetUnclosedWindow(session.ID)
+		if err != nil && err.Error() != "check-in window not found" {
+			return err
+		}
+		if previous != nil {
+			if previous.OpenAt(now) {
+				return ErrCheckInOpen
+			}
+			if _, err := s.closeWindow(tx, previous, nil, previous.ClosesAt); err != nil {
+				return err
+			}
+		}
+		return tx.CreateWindow(window)
+	})
+	if err != nil {
+		if errors.Is(err, ErrCheckInOpen) {
+			return echo.NewHTTPError(http.StatusConflict, "A check-in window is already open for this session")
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to open check-in")
+	}
+
+	return c.JSON(http.StatusCreated, s.currentToken(window, now))
 }
 
+// GetCheckInToken returns the current token of a session's open check-in
+// window; clients poll it to rotate the QR code they show
+func (s *AttendanceService) GetCheckInToken(c echo.Context) error {
+	session, err := s.staffSession(c)
+	if err != nil {
+		return err
+	}
+
+	now := s.now()
+	window, err := s.attendanceRepo.GetUnclosedWindow(session.ID)
+	if err != nil {
+		if err.Error() == "check-in window not found" {
+			return echo.NewHTTPError(http.StatusNotFound, "No check-in window is open for this session")
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to get check-in window")
+	}
+	if !window.OpenAt(now) {
+		return echo.NewHTTPError(http.StatusNotFound, "No check-in window is open for this session")
+	}
+
+	return c.JSON(http.StatusOK, s.currentToken(window, now))
+}
+
+// CloseCheckIn closes a session's check-in window and marks the enrolled
+// students who did not check in as absent
+func (s *AttendanceService) CloseCheckIn(c echo.Context) error {
+	session, err := s.staffSession(c)
+	if err != nil {
+		return err
+	}
+	actorID, err := middleware.GetUserIDFromToken(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Invalid token")
+	}
+
+	var window *domain.AttendanceWindow
+	var absent int
+	err = s.attendanceRepo.Transaction(func(tx *repository.AttendanceRepository) error {
+		if err := tx.LockSession(session.ID); err != nil {
+			return err
+		}
+		window, err = tx.GetUnclosedWindow(session.ID)
+		if err != nil {
+			if err.Error() == "check-in window not found" {
+				return ErrNoOpenWindow
+			}
+			return err
+		}
+		absent, err = s.closeWindow(tx, window, &actorID, s.now())
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, ErrNoOpenWindow) {
+			return echo.NewHTTPError(http.StatusNotFound, "No check-in window is open for this session")
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to close check-in")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"window":       window,
+		"markedAbsent": absent,
+	})
+}
+
+// MarkMyAttendance checks the current student in to the session of a
+// scanned token, POSTed with the student's bearer token. The token may come
+// in the body or in the query string, so that an app can POST the scanned
+// link as it is. Checking in again keeps the first record.
 func (s *AttendanceService) MarkMyAttendance(c echo.Context) error {
-	return c.JSON(200, map[string]string{"message": "dummy mark my attendance"})
+	userID, err := middleware.GetUserIDFromToken(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Invalid token")
+	}
+
+	var req domain.CheckInRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+	if req.Token == "" {
+		req.Token = c.QueryParam("token")
+	}
+	if err := c.Validate(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	now := s.now()
+	windowID, err := parseCheckInToken(req.Token)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "The check-in code is invalid or has expired")
+	}
+	window, err := s.attendanceRepo.GetWindow(windowID)
+	if err != nil {
+		if err.Error() == "check-in window not found" {
+			return echo.NewHTTPError(http.StatusBadRequest, "The check-in code is invalid or has expired")
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to check in")
+	}
+	if err := verifyCheckInToken(window, req.Token, now); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "The check-in code is invalid or has expired")
+	}
+
+	enrollment, err := s.courseRepo.GetEnrollment(window.CourseID, userID)
+	if err != nil && err.Error() != "enrollment not found" {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to check enrollment")
+	}
+	if enrollment == nil || enrollment.Status != domain.EnrollmentStatusEnrolled {
+		return echo.NewHTTPError(http.StatusForbidden, "You are not enrolled in this course")
+	}
+
+	var record *domain.Attendance
+	created := false
+	err = s.attendanceRepo.Transaction(func(tx *repository.AttendanceRepository) error {
+		if err := tx.LockSession(window.SessionID); err != nil {
+			return err
+		}
+		// The window may have been closed while the token was checked
+		current, err := tx.GetWindow(window.ID)
+		if err != nil {
+			return err
+		}
+		if !current.OpenAt(now) {
+			return ErrCheckInClosed
+		}
+
+		record, err = tx.GetRecord(window.SessionID, userID)
+		if err != nil && err.Error() != "attendance not found" {
+			return err
+		}
+		// Only absences marked when an earlier window closed give way
+		if record != nil && record.Source != domain.AttendanceSourceClosing {
+			return nil
+		}
+
+		status := domain.AttendancePresent
+		if now.After(current.LateAfter) {
+			status = domain.AttendanceLate
+		}
+		created = true
+		_, err = s.setStatus(tx, window.SessionID, userID, record, status, domain.AttendanceSourceCheckIn, &now, nil, "", nil)
+		if err != nil {
+			return err
+		}
+		record, err = tx.GetRecord(window.SessionID, userID)
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, ErrCheckInClosed) {
+			return echo.NewHTTPError(http.StatusConflict, "Check-in is closed for this session")
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to check in")
+	}
+
+	if created {
+		return c.JSON(http.StatusCreated, record)
+	}
+	return c.JSON(http.StatusOK, record)
 }
 
+// MarkAttendance overrides the attendance status of students for a session
+// in bulk. Every change needs a reason, given per student or for the whole
+// request, and is kept in the session's history.
+func (s *AttendanceService) MarkAttendance(c echo.Context) error {
+	session, err := s.staffSession(c)
+	if err != nil {
+		return err
+	}
+	actorID, err := middleware.GetUserIDFromToken(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Invalid token")
+	}
+
+	var req domain.OverrideAttendanceRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+	if err := c.Validate(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	students, err := s.courseRepo.GetStudents(session.CourseID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to get course students")
+	}
+	enrolled := make(map[uint]bool, len(students))
+	for _, student := range students {
+		if student.Status == domain.EnrollmentStatusEnrolled || student.Status == domain.EnrollmentStatusCompleted {
+			enrolled[student.UserID] = true
+		}
+	}
+	seen := make(map[uint]bool, len(req.Records))
+	for i := range req.Records {
+		override := &req.Records[i]
+		if seen[override.UserID] {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("User %d is listed more than once", override.UserID))
+		}
+		seen[override.UserID] = true
+		if !enrolled[override.UserID] {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("User %d is not enrolled in this course", override.UserID))
+		}
+		override.Reason = strings.TrimSpace(override.Reason)
+		if override.Reason == "" {
+			override.Reason = strings.TrimSpace(req.Reason)
+		}
+		if override.Reason == "" {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("A reason is required for user %d", override.UserID))
+		}
+	}
+
+	changed := 0
+	err = s.attendanceRepo.Transaction(func(tx *repository.AttendanceRepository) error {
+		if err := tx.LockSession(session.ID); err != nil {
+			return err
+		}
+		for _, override := range req.Records {
+			record, err := tx.GetRecord(session.ID, override.UserID)
+			if err != nil && err.Error() != "attendance not found" {
+				return err
+			}
+			var comment *string
+			if override.Comment != "" {
+				comment = &override.Comment
+			}
+			done, err := s.setStatus(tx, session.ID, override.UserID, record, override.Status,
+				domain.AttendanceSourceManual, nil, comment, override.Reason, &actorID)
+			if err != nil {
+				return err
+			}
+			if done {
+				changed++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to record attendance")
+	}
+
+	records, err := s.attendanceRepo.GetSessionRecords(session.ID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to get attendance")
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"changed": changed,
+		"items":   records,
+	})
+}
+
+// GetSessionAttendance returns the attendance records of a session, the
+// enrolled students without one and the session's check-in window
+func (s *AttendanceService) GetSessionAttendance(c echo.Context) error {
+	session, err := s.staffSession(c)
+	if err != nil {
+		return err
+	}
+
+	records, err := s.attendanceRepo.GetSessionRecords(session.ID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to get attendance")
+	}
+	students, err := s.courseRepo.GetStudents(session.CourseID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to get course students")
+	}
+	recorded := make(map[uint]bool, len(records))
+	for _, record := range records {
+		recorded[record.UserID] = true
+	}
+	missing := make([]domain.UserResponse, 0)
+	for _, student := range students {
+		if student.Status == domain.EnrollmentStatusEnrolled && !recorded[student.UserID] {
+			missing = append(missing, student.User.ToUserResponse())
+		}
+	}
+
+	var window *domain.AttendanceWindow
+	window, err = s.attendanceRepo.GetUnclosedWindow(session.ID)
+	if err != nil && err.Error() != "check-in window not found" {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to get check-in window")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"items":      records,
+		"unrecorded": missing,
+		"window":     window,
+	})
+}
+
+// GetSessionAttendanceHistory returns the changes of a session's attendance records
+func (s *AttendanceService) GetSessionAttendanceHistory(c echo.Context) error {
+	session, err := s.staffSession(c)
+	if err != nil {
+		return err
+	}
+
+	changes, err := s.attendanceRepo.GetChanges(session.ID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to get attendance history")
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{"items": changes})
+}
+
+// GetMyAttendance returns the current user's attendance across courses
+func (s *AttendanceService) GetMyAttendance(c echo.Context) error {
+	userID, err := middleware.GetUserIDFromToken(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Invalid token")
+	}
+	return s.userAttendance(c, userID)
+}
+
+// GetUserAttendance returns a user's attendance across courses
 func (s *AttendanceService) GetUserAttendance(c echo.Context) error {
-	return c.JSON(200, map[string]string{"message": "dummy get user attendance"})
+	userID, err := parseIDParam(c, "id")
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid user ID")
+	}
+	return s.userAttendance(c, userID)
 }
 
+// GetCourseAttendance returns each student's attendance counts and rate in
+// a course
 func (s *AttendanceService) GetCourseAttendance(c echo.Context) error {
-	return c.JSON(200, map[string]string{"message": "dummy get course attendance"})
+	courseID, err := s.staffCourse(c)
+	if err != nil {
+		return err
+	}
+
+	standings, err := s.policies.Standings(courseID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to get attendance")
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{"items": standings})
+}
+
+// GetAtRiskStudents returns the students of a course whose attendance is
+// below the required rate or within the policy's warning margin of it,
+// lowest rate first
+func (s *AttendanceService) GetAtRiskStudents(c echo.Context) error {
+	courseID, err := s.staffCourse(c)
+	if err != nil {
+		return err
+	}
+
+	policy, err := s.policies.Policy(courseID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to get attendance policy")
+	}
+	standings, err := s.policies.Standings(courseID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to get attendance")
+	}
+	atRisk := make([]domain.AttendanceStanding, 0)
+	for _, standing := range standings {
+		if standing.Standing != domain.StandingGood {
+			atRisk = append(atRisk, standing)
+		}
+	}
+	sort.SliceStable(atRisk, func(i, j int) bool { return atRisk[i].Rate < atRisk[j].Rate })
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"policy": policy.ToResponse(),
+		"items":  atRisk,
+	})
 }
 
+// GetAttendancePolicy returns the attendance policy that applies to a course
+func (s *AttendanceService) GetAttendancePolicy(c echo.Context) error {
+	courseID, err := parseIDParam(c, "id")
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid course ID")
+	}
+	if _, err := s.courseRepo.GetByID(courseID); err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, "Course not found")
+	}
+	if err := authorizeCourseAccess(c, s.courseRepo, courseID); err != nil {
+		return err
+	}
+
+	policy, err := s.policies.Policy(courseID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to get attendance policy")
+	}
+	return c.JSON(http.StatusOK, policy.ToResponse())
+}
+
+// UpdateAttendancePolicy sets the attendance policy of a course
+func (s *AttendanceService) UpdateAttendancePolicy(c echo.Context) error {
+	courseID, err := parseIDParam(c, "id")
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid course ID")
+	}
+	if _, err := s.courseRepo.GetByID(courseID); err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, "Course not found")
+	}
+	actorID, err := middleware.GetUserIDFromToken(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Invalid token")
+	}
+
+	var req domain.AttendancePolicyRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+	if err := c.Validate(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	policy, err := s.policies.Policy(courseID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to get attendance policy")
+	}
+	policy.MinimumRate = req.MinimumRate
+	policy.WarningMargin = req.WarningMargin
+	policy.LateCredit = req.LateCredit
+	policy.ExcusedMode = req.ExcusedMode
+	policy.SetGatedExamTypes(req.GatedExamTypes)
+	policy.UpdatedBy = actorID
+	if err := s.attendanceRepo.SavePolicy(policy); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to save attendance policy")
+	}
+
+	return c.JSON(http.StatusOK, policy.ToResponse())
+}
+
+// DeleteAttendancePolicy returns a course to the institution's default policy
+func (s *AttendanceService) DeleteAttendancePolicy(c echo.Context) error {
+	courseID, err := parseIDParam(c, "id")
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid course ID")
+	}
+	if _, err := s.courseRepo.GetByID(courseID); err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, "Course not found")
+	}
+
+	if err := s.attendanceRepo.DeletePolicy(courseID); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to delete attendance policy")
+	}
+	policy := domain.DefaultAttendancePolicy(courseID)
+	return c.JSON(http.StatusOK, policy.ToResponse())
+}
+
+// GetMyCourseAttendance returns the current student's attendance of every
+// session of a course
 func (s *AttendanceService) GetMyCourseAttendance(c echo.Context) error {
-	return c.JSON(200, map[string]string{"message": "dummy get my course attendance"})
+	courseID, err := parseIDParam(c, "id")
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid course ID")
+	}
+	userID, err := middleware.GetUserIDFromToken(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Invalid token")
+	}
+	if _, err := s.courseRepo.GetByID(courseID); err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, "Course not found")
+	}
+	if err := authorizeCourseAccess(c, s.courseRepo, courseID); err != nil {
+		return err
+	}
+	return s.courseAttendance(c, courseID, userID)
 }
 
+// GetUserCourseAttendance returns a student's attendance of every session
+// of a course; students may only view their own
 func (s *AttendanceService) GetUserCourseAttendance(c echo.Context) error {
-	return c.JSON(200, map[string]string{"message": "dummy get user course attendance"})
+	courseID, err := parseIDParam(c, "id")
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid course ID")
+	}
+	userID, err := parseIDParam(c, "userId")
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid user ID")
+	}
+	if _, err := s.courseRepo.GetByID(courseID); err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, "Course not found")
+	}
+
+	currentUserID, err := middleware.GetUserIDFromToken(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Invalid token")
+	}
+	if currentUserID == userID {
+		err = authorizeCourseAccess(c, s.courseRepo, courseID)
+	} else {
+		err = authorizeCourseStaff(c, s.courseRepo, courseID)
+	}
+	if err != nil {
+		return err
+	}
+	return s.courseAttendance(c, courseID, userID)
+}
+
+// CloseExpiredWindows closes the check-in windows whose time is up but that
+// staff left open, as of their closing time, and returns how many it closed
+func (s *AttendanceService) CloseExpiredWindows(ctx context.Context) (int, error) {
+	windows, err := s.attendanceRepo.GetExpiredWindows(s.now(), 100)
+	if err != nil {
+		return 0, err
+	}
+
+	closed := 0
+	for _, expired := range windows {
+		if err := ctx.Err(); err != nil {
+			return closed, err
+		}
+		err := s.attendanceRepo.Transaction(func(tx *repository.AttendanceRepository) error {
+			if err := tx.LockSession(expired.SessionID); err != nil {
+				return err
+			}
+			// Staff may have closed it since it was listed
+			window, err := tx.GetWindow(expired.ID)
+			if err != nil {
+				return err
+			}
+			if window.ClosedAt != nil {
+				return nil
+			}
+			if _, err := s.closeWindow(tx, window, nil, window.ClosesAt); err != nil {
+				return err
+			}
+			closed++
+			return nil
+		})
+		if err != nil {
+			return closed, err
+		}
+	}
+	return closed, nil
+}
+
+// closeWindow closes a check-in window at the given time and marks the
+// enrolled students without a record for its session as absent. It
+// returns how many students were marked; the session must be locked.
+func (s *AttendanceService) closeWindow(tx *repository.AttendanceRepository, window *domain.AttendanceWindow, actorID *uint, at time.Time) (int, error) {
+	window.ClosedAt = &at
+	window.ClosedBy = actorID
+	if err := tx.SaveWindow(window); err != nil {
+		return 0, err
+	}
+
+	userIDs, err := tx.GetUnrecordedStudentIDs(window.CourseID, window.SessionID)
+	if err != nil {
+		return 0, err
+	}
+	for _, userID := range userIDs {
+		_, err := s.setStatus(tx, window.SessionID, userID, nil, domain.AttendanceAbsent,
+			domain.AttendanceSourceClosing, nil, nil, "Did not check in", actorID)
+		if err != nil {
+			return 0, err
+		}
+	}
+	return len(userIDs), nil
+}
+
+// setStatus creates or updates a student's attendance record and records
+// the change. A nil comment keeps the record's comment. It reports whether
+// anything changed.
+func (s *AttendanceService) setStatus(tx *repository.AttendanceRepository, sessionID, userID uint, record *domain.Attendance,
+	status, source string, checkInTime *time.Time, comment *string, reason string, actorID *uint) (bool, error) {
+	now := s.now()
+	oldStatus := ""
+	if record == nil {
+		record = &domain.Attendance{SessionID: sessionID, UserID: userID, CreatedAt: now}
+	} else {
+		oldStatus = record.Status
+		if record.Status == status && (comment == nil || *comment == record.Comment) {
+			return false, nil
+		}
+	}
+
+	record.Status = status
+	record.Source = source
+	record.RecordedBy = actorID
+	if checkInTime != nil {
+		record.CheckInTime = checkInTime
+	}
+	if comment != nil {
+		record.Comment = *comment
+	}
+	record.UpdatedAt = now
+	if err := tx.SaveRecord(record); err != nil {
+		return false, err
+	}
+
+	if oldStatus == status {
+		return true, nil
+	}
+	change := &domain.AttendanceChange{
+		AttendanceID: record.ID,
+		SessionID:    sessionID,
+		UserID:       userID,
+		OldStatus:    oldStatus,
+		NewStatus:    status,
+		Source:       source,
+		Reason:       reason,
+		ChangedAt:    now,
+	}
+	if actorID != nil {
+		change.ChangedBy = *actorID
+	} else if source == domain.AttendanceSourceCheckIn {
+		change.ChangedBy = userID
+	}
+	return true, tx.AddChange(change)
+}
+
+// currentToken returns a window's token for the current period with the
+// link to encode in the QR code, under the configured public base URL
+func (s *AttendanceService) currentToken(window *domain.AttendanceWindow, now time.Time) domain.CheckInToken {
+	step := checkInStep(window, now)
+	token := checkInToken(window, step)
+	expiresAt := window.OpenedAt.Add(time.Duration(step+1) * checkInTokenPeriod)
+	if expiresAt.After(window.ClosesAt) {
+		expiresAt = window.ClosesAt
+	}
+	return domain.CheckInToken{
+		WindowID:  window.ID,
+		Token:     token,
+		QRPayload: fmt.Sprintf("%s/api/v1/attendance/check-in?token=%s", s.publicBaseURL, url.QueryEscape(token)),
+		ExpiresAt: expiresAt,
+		LateAfter: window.LateAfter,
+		ClosesAt:  window.ClosesAt,
+	}
+}
+
+// staffSession loads the session named by the ":id" path parameter and
+// checks that the current user is on the staff of its course
+func (s *AttendanceService) staffSession(c echo.Context) (*domain.Session, error) {
+	sessionID, err := parseIDParam(c, "id")
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid session ID")
+	}
+	session, err := s.sessionRepo.GetByID(sessionID)
+	if err != nil {
+		if err.Error() == "session not found" {
+			return nil, echo.NewHTTPError(http.StatusNotFound, "Session not found")
+		}
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "Failed to get session")
+	}
+	if err := authorizeCourseStaff(c, s.courseRepo, session.CourseID); err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// staffCourse parses the ":id" course path parameter and checks that the
+// current user is on the staff of the course
+func (s *AttendanceService) staffCourse(c echo.Context) (uint, error) {
+	courseID, err := parseIDParam(c, "id")
+	if err != nil {
+		return 0, echo.NewHTTPError(http.StatusBadRequest, "Invalid course ID")
+	}
+	if _, err := s.courseRepo.GetByID(courseID); err != nil {
+		return 0, echo.NewHTTPError(http.StatusNotFound, "Course not found")
+	}
+	if err := authorizeCourseStaff(c, s.courseRepo, courseID); err != nil {
+		return 0, err
+	}
+	return courseID, nil
+}
+
+// userAttendance responds with a user's attendance records across courses
+func (s *AttendanceService) userAttendance(c echo.Context, userID uint) error {
+	records, err := s.attendanceRepo.GetUserRecords(userID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to get attendance")
+	}
+	items := make([]domain.SessionAttendance, 0, len(records))
+	for i := range records {
+		items = append(items, sessionAttendance(&records[i].Session, &records[i]))
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{"items": items})
+}
+
+// courseAttendance responds with a student's attendance of every session of
+// a course and their standing under the course's policy
+func (s *AttendanceService) courseAttendance(c echo.Context, courseID, userID uint) error {
+	sessions, err := s.sessionRepo.GetByCourse(courseID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to get sessions")
+	}
+	records, err := s.attendanceRepo.GetCourseRecords(courseID, userID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to get attendance")
+	}
+	bySession := make(map[uint]*domain.Attendance, len(records))
+	for i := range records {
+		bySession[records[i].SessionID] = &records[i]
+	}
+	items := make([]domain.SessionAttendance, 0, len(sessions))
+	for i := range sessions {
+		items = append(items, sessionAttendance(&sessions[i], bySession[sessions[i].ID]))
+	}
+
+	standing, err := s.policies.Standing(courseID, userID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to get attendance")
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"items":    items,
+		"standing": standing,
+	})
+}
+
+// sessionAttendance combines a session with a student's record for it, if any
+func sessionAttendance(session *domain.Session, record *domain.Attendance) domain.SessionAttendance {
+	item := domain.SessionAttendance{
+		SessionID: session.ID,
+		CourseID:  session.CourseID,
+		Number:    session.Number,
+		Title:     session.Title,
+		Date:      session.Date,
+	}
+	if record != nil {
+		item.Status = record.Status
+		item.CheckInTime = record.CheckInTime
+		item.Comment = record.Comment
+		item.Source = record.Source
+	}
+	return item
 }
@@ -0,0 +1,86 @@
+package service
+
+import (
+	"backend/internal/domain"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// checkInTokenPeriod is how long a check-in token is shown before it rotates
+const checkInTokenPeriod = 30 * time.Second
+
+// ErrCheckInToken is returned for check-in tokens that are malformed,
+// forged or no longer current
+var ErrCheckInToken = errors.New("the check-in code is invalid or has expired")
+
+// newWindowSecret generates the key that signs a window's check-in tokens
+func newWindowSecret() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// checkInStep numbers the token periods since a window opened
+func checkInStep(window *domain.AttendanceWindow, now time.Time) int64 {
+	return int64(now.Sub(window.OpenedAt) / checkInTokenPeriod)
+}
+
+// checkInToken returns a window's token for the given step: the window ID,
+// the step and an HMAC over both with the window's secret
+func checkInToken(window *domain.AttendanceWindow, step int64) string {
+	return fmt.Sprintf("%d.%d.%s", window.ID, step,
+		base64.RawURLEncoding.EncodeToString(checkInMAC(window, step)))
+}
+
+// checkInMAC signs a step of a window, truncated to 16 bytes to keep QR codes small
+func checkInMAC(window *domain.AttendanceWindow, step int64) []byte {
+	mac := hmac.New(sha256.New, []byte(window.Secret))
+	fmt.Fprintf(mac, "%d.%d", window.ID, step)
+	return mac.Sum(nil)[:16]
+}
+
+// parseCheckInToken extracts the window ID from a token so that the window
+// can be loaded before the token is verified
+func parseCheckInToken(token string) (uint, error) {
+	parts := strings.Split(strings.TrimSpace(token), ".")
+	if len(parts) != 3 {
+		return 0, ErrCheckInToken
+	}
+	id, err := strconv.ParseUint(parts[0], 10, 32)
+	if err != nil {
+		return 0, ErrCheckInToken
+	}
+	return uint(id), nil
+}
+
+// verifyCheckInToken checks a token's signature and that it is the current
+// token of the window or the one it just replaced
+func verifyCheckInToken(window *domain.AttendanceWindow, token string, now time.Time) error {
+	parts := strings.Split(strings.TrimSpace(token), ".")
+	if len(parts) != 3 || parts[0] != strconv.FormatUint(uint64(window.ID), 10) {
+		return ErrCheckInToken
+	}
+	step, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return ErrCheckInToken
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil || !hmac.Equal(mac, checkInMAC(window, step)) {
+		return ErrCheckInToken
+	}
+	current := checkInStep(window, now)
+	if step != current && step != current-1 {
+		return ErrCheckInToken
+	}
+	return nil
+}
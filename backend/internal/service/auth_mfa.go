@@ -0,0 +1,643 @@
+package service
+
+import (
+	"backend/internal/domain"
+	"backend/internal/repository"
+	"backend/pkg/middleware"
+	"backend/pkg/totp"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Two-factor authentication limits
+const (
+	mfaChallengeTTL      = 5 * time.Minute
+	mfaChallengeAttempts = 5  // wrong codes before a sign-in has to start over
+	mfaRecoveryCodeCount = 10 // recovery codes handed out at a time
+	mfaClockSkew         = 1  // time steps of authenticator clock drift allowed either way
+)
+
+// Errors of two-factor authentication
+var (
+	ErrMFAChallenge   = errors.New("the sign-in has expired; sign in again")
+	ErrMFACode        = errors.New("invalid authentication code")
+	ErrMFANotEnrolled = errors.New("two-factor authentication is not set up")
+	ErrMFAEnabled     = errors.New("two-factor authentication is already enabled")
+)
+
+// recoveryCodeEncoding spells recovery codes in lowercase base32
+var recoveryCodeEncoding = base32.NewEncoding("abcdefghijklmnopqrstuvwxyz234567").WithPadding(base32.NoPadding)
+
+// VerifyMFA completes a sign-in with an authenticator code or a recovery
+// code and returns the access and refresh tokens. A sign-in allows a few
+// wrong codes before it has to start over.
+func (s *AuthService) VerifyMFA(c echo.Context) error {
+	var req domain.MFAVerifyRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+
+	if err := c.Validate(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	now := time.Now()
+	var userID uint
+	var failure error
+	err := s.mfaRepo.Transaction(func(tx *repository.MFARepository) error {
+		challenge, err := lockMFAChallenge(tx, req.MFAToken, now)
+		if err != nil {
+			return err
+		}
+		userID = challenge.UserID
+
+		enrollment, err := confirmedEnrollment(tx, challenge.UserID)
+		if err != nil {
+			return err
+		}
+		if req.RecoveryCode != "" {
+			failure = useRecoveryCode(tx, challenge.UserID, req.RecoveryCode, now)
+		} else {
+			failure = s.checkMFACode(tx, enrollment, req.Code, now)
+		}
+		return settleMFAChallenge(tx, challenge, failure, now)
+	})
+	if err == nil {
+		err = failure
+	}
+	if err != nil {
+		return mfaHTTPError(err)
+	}
+
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, ErrMFAChallenge.Error())
+	}
+	return s.issueSession(c, user, http.StatusOK)
+}
+
+// EnrollMFA sets up an authenticator during a sign-in that needs one
+// because the user's role requires it. The user confirms it with
+// ConfirmMFAEnrollment to finish signing in.
+func (s *AuthService) EnrollMFA(c echo.Context) error {
+	var req domain.MFAEnrollRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+
+	if err := c.Validate(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	challenge, err := s.mfaRepo.GetChallenge(hashToken(strings.TrimSpace(req.MFAToken)))
+	if err != nil || !challenge.Open(time.Now()) {
+		return echo.NewHTTPError(http.StatusUnauthorized, ErrMFAChallenge.Error())
+	}
+	user, err := s.userRepo.GetByID(challenge.UserID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, ErrMFAChallenge.Error())
+	}
+
+	setup, err := s.setupMFA(user)
+	if err != nil {
+		return mfaHTTPError(err)
+	}
+	return c.JSON(http.StatusOK, setup)
+}
+
+// ConfirmMFAEnrollment confirms the authenticator set up during a sign-in
+// with its first code, finishes the sign-in and hands out recovery codes
+func (s *AuthService) ConfirmMFAEnrollment(c echo.Context) error {
+	var req domain.MFAEnrollRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+
+	if err := c.Validate(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	if strings.TrimSpace(req.Code) == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "Authentication code is required")
+	}
+
+	now := time.Now()
+	var userID uint
+	var codes []string
+	var failure error
+	err := s.mfaRepo.Transaction(func(tx *repository.MFARepository) error {
+		challenge, err := lockMFAChallenge(tx, req.MFAToken, now)
+		if err != nil {
+			return err
+		}
+		userID = challenge.UserID
+
+		codes, failure = s.confirmMFA(tx, challenge.UserID, req.Code, now)
+		if failure != nil && !errors.Is(failure, ErrMFACode) {
+			return failure
+		}
+		return settleMFAChallenge(tx, challenge, failure, now)
+	})
+	if err == nil {
+		err = failure
+	}
+	if err != nil {
+		return mfaHTTPError(err)
+	}
+
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, ErrMFAChallenge.Error())
+	}
+	accessToken, refreshToken, err := s.startSession(c, user)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"access_token":   accessToken,
+		"refresh_token":  refreshToken,
+		"user":           user.ToUserResponse(),
+		"recovery_codes": codes,
+	})
+}
+
+// GetMFAStatus returns the current user's two-factor authentication status
+func (s *AuthService) GetMFAStatus(c echo.Context) error {
+	userID, err := middleware.GetUserIDFromToken(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Invalid token")
+	}
+
+	role, err := middleware.GetRoleFromToken(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Invalid token")
+	}
+
+	status := domain.MFAStatus{
+		Required: s.settings.Current().IsMFARequired(role),
+	}
+	enrollment, err := s.mfaRepo.GetEnrollment(userID)
+	if err != nil && err.Error() != "mfa enrollment not found" {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to get two-factor authentication status")
+	}
+	if err == nil {
+		status.Enabled = enrollment.ConfirmedAt != nil
+		status.Pending = enrollment.ConfirmedAt == nil
+		status.ConfirmedAt = enrollment.ConfirmedAt
+	}
+	if status.Enabled {
+		status.RecoveryCodesLeft, err = s.mfaRepo.CountRecoveryCodes(userID)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to get two-factor authentication status")
+		}
+	}
+
+	return c.JSON(http.StatusOK, status)
+}
+
+// SetupMFA starts setting up an authenticator for the current user. It
+// takes effect once ConfirmMFA receives a first code from it.
+func (s *AuthService) SetupMFA(c echo.Context) error {
+	userID, err := middleware.GetUserIDFromToken(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Invalid token")
+	}
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, "User not found")
+	}
+
+	setup, err := s.setupMFA(user)
+	if err != nil {
+		return mfaHTTPError(err)
+	}
+	return c.JSON(http.StatusOK, setup)
+}
+
+// ConfirmMFA turns two-factor authentication on for the current user with
+// a first code from the authenticator and hands out recovery codes
+func (s *AuthService) ConfirmMFA(c echo.Context) error {
+	userID, err := middleware.GetUserIDFromToken(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Invalid token")
+	}
+
+	var req domain.MFACodeRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+
+	if err := c.Validate(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	var codes []string
+	err = s.mfaRepo.Transaction(func(tx *repository.MFARepository) error {
+		var err error
+		codes, err = s.confirmMFA(tx, userID, req.Code, time.Now())
+		return err
+	})
+	if err != nil {
+		return mfaHTTPError(err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message":        "Two-factor authentication enabled",
+		"recovery_codes": codes,
+	})
+}
+
+// RegenerateRecoveryCodes replaces the current user's recovery codes
+func (s *AuthService) RegenerateRecoveryCodes(c echo.Context) error {
+	userID, err := middleware.GetUserIDFromToken(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Invalid token")
+	}
+
+	var req domain.MFACodeRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+
+	if err := c.Validate(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	var codes []string
+	err = s.mfaRepo.Transaction(func(tx *repository.MFARepository) error {
+		enrollment, err := confirmedEnrollment(tx, userID)
+		if err != nil {
+			return err
+		}
+		if err := s.checkMFACode(tx, enrollment, req.Code, time.Now()); err != nil {
+			return err
+		}
+		codes, err = replaceRecoveryCodes(tx, userID)
+		return err
+	})
+	if err != nil {
+		return mfaHTTPError(err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"recovery_codes": codes,
+	})
+}
+
+// DisableMFA turns two-factor authentication off for the current user,
+// unless their role requires it
+func (s *AuthService) DisableMFA(c echo.Context) error {
+	userID, err := middleware.GetUserIDFromToken(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Invalid token")
+	}
+
+	var req domain.DisableMFARequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+
+	if err := c.Validate(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, "User not found")
+	}
+	if s.settings.Current().IsMFARequired(user.Role) {
+		return echo.NewHTTPError(http.StatusForbidden, "Two-factor authentication is mandatory for your role")
+	}
+	if !user.CheckPassword(req.Password) {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Password is incorrect")
+	}
+
+	err = s.mfaRepo.Transaction(func(tx *repository.MFARepository) error {
+		enrollment, err := confirmedEnrollment(tx, userID)
+		if err != nil {
+			return err
+		}
+		if err := s.checkMFACode(tx, enrollment, req.Code, time.Now()); err != nil {
+			return err
+		}
+		_, err = tx.DeleteEnrollment(userID)
+		return err
+	})
+	if err != nil {
+		return mfaHTTPError(err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"message": "Two-factor authentication disabled",
+	})
+}
+
+// ResetUserMFA removes a user's authenticator and recovery codes, for users
+// who lost both. Users whose role requires two-factor authentication set
+// up a new authenticator at their next sign-in.
+func (s *AuthService) ResetUserMFA(c echo.Context) error {
+	id, err := parseIDParam(c, "id")
+	if err != nil {
+		return err
+	}
+	if _, err := s.userRepo.GetByID(id); err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, "User not found")
+	}
+
+	found, err := s.mfaRepo.DeleteEnrollment(id)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to reset two-factor authentication")
+	}
+	if !found {
+		return echo.NewHTTPError(http.StatusNotFound, "Two-factor authentication is not set up for this user")
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"message": "Two-factor authentication reset successfully",
+	})
+}
+
+// PruneMFAChallenges deletes the sign-in challenges that expired
+func (s *AuthService) PruneMFAChallenges() error {
+	return s.mfaRepo.DeleteExpiredChallenges()
+}
+
+// signIn finishes a sign-in once the password, or an invitation, checked
+// out. Users with an authenticator, or whose role requires one, get an MFA
+// challenge to complete instead of tokens.
+func (s *AuthService) signIn(c echo.Context, user *domain.User, status int) error {
+	enrollment, err := s.mfaRepo.GetEnrollment(user.ID)
+	if err != nil && err.Error() != "mfa enrollment not found" {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to sign in")
+	}
+	enabled := err == nil && enrollment.ConfirmedAt != nil
+	if !enabled && !s.settings.Current().IsMFARequired(user.Role) {
+		return s.issueSession(c, user, status)
+	}
+
+	token, err := randomHex(32)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to sign in")
+	}
+	challenge := &domain.MFAChallenge{
+		UserID:    user.ID,
+		TokenHash: hashToken(token),
+		ExpiresAt: time.Now().Add(mfaChallengeTTL),
+	}
+	if err := s.mfaRepo.CreateChallenge(challenge); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to sign in")
+	}
+
+	return c.JSON(status, map[string]interface{}{
+		"mfa_required":        true,
+		"mfa_token":           token,
+		"expires_at":          challenge.ExpiresAt,
+		"enrollment_required": !enabled,
+	})
+}
+
+// issueSession starts a session and answers with its tokens and the user
+func (s *AuthService) issueSession(c echo.Context, user *domain.User, status int) error {
+	accessToken, refreshToken, err := s.startSession(c, user)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(status, map[string]interface{}{
+		"access_token":  accessToken,
+		"refresh_token": refreshToken,
+		"user":          user.ToUserResponse(),
+	})
+}
+
+// setupMFA gives a user a new, unconfirmed authenticator secret, replacing
+// any earlier unconfirmed one
+func (s *AuthService) setupMFA(user *domain.User) (*domain.MFASetup, error) {
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+	sealed, err := s.sealMFASecret(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.mfaRepo.Transaction(func(tx *repository.MFARepository) error {
+		enrollment, err := tx.LockEnrollment(user.ID)
+		if err != nil {
+			if err.Error() != "mfa enrollment not found" {
+				return err
+			}
+			enrollment = &domain.MFAEnrollment{UserID: user.ID}
+		}
+		if enrollment.ConfirmedAt != nil {
+			return ErrMFAEnabled
+		}
+		enrollment.Secret = sealed
+		enrollment.LastStep = 0
+		return tx.SaveEnrollment(enrollment)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	issuer := s.settings.Current().SystemName
+	return &domain.MFASetup{
+		Secret:          secret,
+		ProvisioningURI: totp.ProvisioningURI(issuer, user.Email, secret),
+		Issuer:          issuer,
+		Account:         user.Email,
+	}, nil
+}
+
+// confirmMFA confirms a user's unconfirmed authenticator with a code from
+// it and returns the user's new recovery codes
+func (s *AuthService) confirmMFA(tx *repository.MFARepository, userID uint, code string, now time.Time) ([]string, error) {
+	enrollment, err := tx.LockEnrollment(userID)
+	if err != nil {
+		if err.Error() == "mfa enrollment not found" {
+			return nil, ErrMFANotEnrolled
+		}
+		return nil, err
+	}
+	if enrollment.ConfirmedAt != nil {
+		return nil, ErrMFAEnabled
+	}
+	if err := s.checkMFACode(tx, enrollment, code, now); err != nil {
+		return nil, err
+	}
+
+	enrollment.ConfirmedAt = &now
+	if err := tx.SaveEnrollment(enrollment); err != nil {
+		return nil, err
+	}
+	return replaceRecoveryCodes(tx, userID)
+}
+
+// checkMFACode checks a code against a locked enrollment. A code is
+// accepted once: codes of the step of the last accepted code or earlier
+// are refused.
+func (s *AuthService) checkMFACode(tx *repository.MFARepository, enrollment *domain.MFAEnrollment, code string, now time.Time) error {
+	secret, err := s.openMFASecret(enrollment.Secret)
+	if err != nil {
+		return err
+	}
+	step, ok := totp.Validate(secret, code, now, mfaClockSkew)
+	if !ok || step <= enrollment.LastStep {
+		return ErrMFACode
+	}
+	enrollment.LastStep = step
+	return tx.SaveEnrollment(enrollment)
+}
+
+// sealMFASecret encrypts an authenticator secret for storage with a key
+// derived from the JWT secret; changing that secret means users have to set
+// up their authenticators again
+func (s *AuthService) sealMFASecret(secret string) (string, error) {
+	gcm, err := s.mfaCipher()
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(gcm.Seal(nonce, nonce, []byte(secret), nil)), nil
+}
+
+// openMFASecret decrypts a stored authenticator secret
+func (s *AuthService) openMFASecret(sealed string) (string, error) {
+	gcm, err := s.mfaCipher()
+	if err != nil {
+		return "", err
+	}
+	data, err := base64.StdEncoding.DecodeString(sealed)
+	if err != nil || len(data) < gcm.NonceSize() {
+		return "", errors.New("malformed authenticator secret")
+	}
+	secret, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", err
+	}
+	return string(secret), nil
+}
+
+func (s *AuthService) mfaCipher() (cipher.AEAD, error) {
+	key := sha256.Sum256([]byte("mfa-secret." + s.jwtSecret))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// lockMFAChallenge locks the open challenge of a token
+func lockMFAChallenge(tx *repository.MFARepository, token string, now time.Time) (*domain.MFAChallenge, error) {
+	challenge, err := tx.LockChallenge(hashToken(strings.TrimSpace(token)))
+	if err != nil {
+		if err.Error() == "mfa challenge not found" {
+			return nil, ErrMFAChallenge
+		}
+		return nil, err
+	}
+	if !challenge.Open(now) {
+		return nil, ErrMFAChallenge
+	}
+	return challenge, nil
+}
+
+// settleMFAChallenge uses up a challenge that was completed, or counts a
+// wrong code against it and closes it after too many
+func settleMFAChallenge(tx *repository.MFARepository, challenge *domain.MFAChallenge, failure error, now time.Time) error {
+	switch {
+	case failure == nil:
+		challenge.UsedAt = &now
+	case errors.Is(failure, ErrMFACode):
+		challenge.Attempts++
+		if challenge.Attempts >= mfaChallengeAttempts {
+			challenge.UsedAt = &now
+		}
+	default:
+		return nil
+	}
+	return tx.SaveChallenge(challenge)
+}
+
+// confirmedEnrollment locks a user's confirmed enrollment
+func confirmedEnrollment(tx *repository.MFARepository, userID uint) (*domain.MFAEnrollment, error) {
+	enrollment, err := tx.LockEnrollment(userID)
+	if err != nil {
+		if err.Error() == "mfa enrollment not found" {
+			return nil, ErrMFANotEnrolled
+		}
+		return nil, err
+	}
+	if enrollment.ConfirmedAt == nil {
+		return nil, ErrMFANotEnrolled
+	}
+	return enrollment, nil
+}
+
+// useRecoveryCode uses up one of a user's recovery codes
+func useRecoveryCode(tx *repository.MFARepository, userID uint, code string, now time.Time) error {
+	used, err := tx.UseRecoveryCode(userID, hashToken(normalizeRecoveryCode(code)), now)
+	if err != nil {
+		return err
+	}
+	if !used {
+		return ErrMFACode
+	}
+	return nil
+}
+
+// replaceRecoveryCodes gives a user new recovery codes, formatted like
+// "abcde-fghij", and returns them
+func replaceRecoveryCodes(tx *repository.MFARepository, userID uint) ([]string, error) {
+	codes := make([]string, 0, mfaRecoveryCodeCount)
+	hashes := make([]string, 0, mfaRecoveryCodeCount)
+	for i := 0; i < mfaRecoveryCodeCount; i++ {
+		b := make([]byte, 7)
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+		raw := recoveryCodeEncoding.EncodeToString(b)[:10]
+		codes = append(codes, raw[:5]+"-"+raw[5:])
+		hashes = append(hashes, hashToken(raw))
+	}
+	if err := tx.ReplaceRecoveryCodes(userID, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// normalizeRecoveryCode drops the separators and case users may type a
+// recovery code with
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
+
+// mfaHTTPError maps two-factor authentication errors to HTTP errors
+func mfaHTTPError(err error) error {
+	switch {
+	case errors.Is(err, ErrMFAChallenge):
+		return echo.NewHTTPError(http.StatusUnauthorized, "Sign-in expired; sign in again")
+	case errors.Is(err, ErrMFACode):
+		return echo.NewHTTPError(http.StatusUnauthorized, "Invalid authentication code")
+	case errors.Is(err, ErrMFANotEnrolled):
+		return echo.NewHTTPError(http.StatusConflict, "Two-factor authentication is not set up")
+	case errors.Is(err, ErrMFAEnabled):
+		return echo.NewHTTPError(http.StatusConflict, "Two-factor authentication is already enabled")
+	}
+	return echo.NewHTTPError(http.StatusInternalServerError, "Failed to process two-factor authentication")
+}
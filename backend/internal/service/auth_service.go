@@ -4,19 +4,46 @@ import (
 	"backend/internal/domain"
 	"backend/internal/repository"
 	"backend/pkg/auth"
+	"backend/pkg/mail"
+	"backend/pkg/middleware"
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
 	"net/http"
+	"net/url"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/labstack/echo/v4"
 )
 
+// ErrRefreshTokenExpired is returned for refresh tokens past their expiry
+var ErrRefreshTokenExpired = errors.New("refresh token expired")
+
+// Errors of accepting invitations
+var (
+	ErrInvitationUsed = errors.New("invitation has expired or was already accepted")
+	ErrAccountExists  = errors.New("an account with this email address already exists")
+)
+
+// defaultInvitationDays is how long an invitation can be accepted unless the
+// admin chooses otherwise
+const defaultInvitationDays = 7
+
 // AuthService handles authentication
 type AuthService struct {
 	userRepo          *repository.UserRepository
 	refreshTokenRepo  *repository.RefreshTokenRepository
+	invitationRepo    *repository.InvitationRepository
+	passwordResetRepo *repository.PasswordResetRepository
+	mfaRepo           *repository.MFARepository
+	settings          *SettingsStore
+	mailer            mail.Mailer
+	publicBaseURL     string
 	jwtSecret         string
 	jwtExpiration     time.Duration
 	refreshExpiration time.Duration
@@ -26,6 +53,12 @@ type AuthService struct {
 func NewAuthService(
 	userRepo *repository.UserRepository,
 	refreshTokenRepo *repository.RefreshTokenRepository,
+	invitationRepo *repository.InvitationRepository,
+	passwordResetRepo *repository.PasswordResetRepository,
+	mfaRepo *repository.MFARepository,
+	settings *SettingsStore,
+	mailer mail.Mailer,
+	publicBaseURL string,
 	jwtSecret string,
 	jwtExpiration time.Duration,
 	refreshExpiration time.Duration,
@@ -33,13 +66,20 @@ func NewAuthService(
 	return &AuthService{
 		userRepo:          userRepo,
 		refreshTokenRepo:  refreshTokenRepo,
+		invitationRepo:    invitationRepo,
+		passwordResetRepo: passwordResetRepo,
+		mfaRepo:           mfaRepo,
+		settings:          settings,
+		mailer:            mailer,
+		publicBaseURL:     publicBaseURL,
 		jwtSecret:         jwtSecret,
 		jwtExpiration:     jwtExpiration,
 		refreshExpiration: refreshExpiration,
 	}
 }
 
-// Login authenticates a user and returns JWT tokens
+// Login authenticates a user and returns JWT tokens. Users who sign in with
+// two factors get an MFA challenge token instead, to complete with VerifyMFA.
 func (s *AuthService) Login(c echo.Context) error {
 	// Parse request
 	var loginReq struct {
@@ -66,37 +106,19 @@ func (s *AuthService) Login(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusUnauthorized, "Invalid credentials")
 	}
 	
-	// Generate tokens
-	accessToken, err := auth.GenerateToken(user.ID, user.Role, s.jwtSecret, s.jwtExpiration)
-	if err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to generate access token")
-	}
-	
-	refreshToken, err := s.generateRefreshToken(user.ID)
-	if err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to generate refresh token")
-	}
-	
-	// Prepare user response
-	userResponse := domain.UserResponse{
-		ID:        user.ID,
-		Username:  user.Username,
-		Name:      user.Name,
-		Email:     user.Email,
-		Role:      user.Role,
-		CreatedAt: user.CreatedAt,
-		UpdatedAt: user.UpdatedAt,
+	// Self-registered accounts sign in once their address is verified
+	if user.EmailVerifiedAt == nil {
+		return echo.NewHTTPError(http.StatusForbidden, "Email address not verified")
 	}
 	
-	// Return tokens
-	return c.JSON(http.StatusOK, map[string]interface{}{
-		"access_token": accessToken,
-		"refresh_token": refreshToken,
-		"user": userResponse,
-	})
+	// Return tokens, or an MFA challenge when a second factor is needed
+	return s.signIn(c, user, http.StatusOK)
 }
 
-// Register creates a new user account
+// Register creates a student account for anyone who signs up, when the
+// registration settings allow their email address. The account can sign in
+// once its address is verified through the link mailed to it; instructor
+// and admin accounts come from admins and invitations only.
 func (s *AuthService) Register(c echo.Context) error {
 	// Parse request
 	var registerReq struct {
@@ -114,6 +136,15 @@ func (s *AuthService) Register(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
 	}
 	
+	settings := s.settings.Current()
+	if !settings.Registration {
+		return echo.NewHTTPError(http.StatusForbidden, "Self-registration is disabled")
+	}
+	email := normalizeEmail(registerReq.Email)
+	if !settings.IsRegistrationEmailAllowed(email) {
+		return echo.NewHTTPError(http.StatusForbidden, "Registration is not open to this email domain")
+	}
+	
 	// Check if username already exists
 	_, err := s.userRepo.GetByUsername(registerReq.Username)
 	if err == nil {
@@ -121,16 +152,17 @@ func (s *AuthService) Register(c echo.Context) error {
 	}
 	
 	// Check if email already exists
-	_, err = s.userRepo.GetByEmail(registerReq.Email)
+	_, err = s.userRepo.GetByEmail(email)
 	if err == nil {
 		return echo.NewHTTPError(http.StatusConflict, "Email already exists")
 	}
 	
 	// Create new user
 	user := &domain.User{
-		Username:  registerReq.Username,
-		Name:      registerReq.Name,
-		Email:     registerReq.Email,
+		Username: registerReq.Username,
+		Name:     registerReq.Name,
+		Email:    email,
+		Role:     "student",
 	}
 	
 	// Set password
@@ -138,51 +170,262 @@ func (s *AuthService) Register(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to hash password")
 	}
 	
-	// Determine role based on username pattern
-	if strings.HasPrefix(registerReq.Username, "admin_") {
-		user.Role = "admin"
-	} else if strings.HasPrefix(registerReq.Username, "instructor_") {
-		user.Role = "instructor"
-	} else {
-		user.Role = "student"
-	}
-	
 	// Save user to database
 	if err := s.userRepo.Create(user); err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to create user")
 	}
 	
-	// Generate tokens
-	accessToken, err := auth.GenerateToken(user.ID, user.Role, s.jwtSecret, s.jwtExpiration)
+	// The account exists either way; a lost email can be sent again
+	if err := s.sendVerificationEmail(c, user); err != nil {
+		log.Printf("Failed to send verification email to user %d: %v", user.ID, err)
+	}
+	
+	return c.JSON(http.StatusCreated, map[string]interface{}{
+		"message": "Account created. Follow the link sent to your email address to verify it before signing in.",
+		"user":    user.ToUserResponse(),
+	})
+}
+
+// VerifyEmail verifies a user's email address with the token of the link
+// mailed to them. The token may come in the query string, when the link is
+// opened, or in the body.
+func (s *AuthService) VerifyEmail(c echo.Context) error {
+	token := c.QueryParam("token")
+	if token == "" {
+		var verifyReq struct {
+			Token string `json:"token"`
+		}
+		if err := c.Bind(&verifyReq); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+		}
+		token = verifyReq.Token
+	}
+	
+	userID, err := parseEmailVerificationToken(token)
 	if err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to generate access token")
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		if err.Error() == "user not found" {
+			return echo.NewHTTPError(http.StatusBadRequest, ErrVerificationToken.Error())
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to verify email address")
+	}
+	if err := verifyEmailVerificationToken(s.jwtSecret, user, token, time.Now()); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	if user.EmailVerifiedAt != nil {
+		return echo.NewHTTPError(http.StatusConflict, "Email address already verified")
+	}
+	
+	now := time.Now()
+	user.EmailVerifiedAt = &now
+	if err := s.userRepo.Update(user); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to verify email address")
+	}
+	
+	return c.JSON(http.StatusOK, map[string]string{
+		"message": "Email address verified. You can now sign in.",
+	})
+}
+
+// ResendVerification mails a new verification link to an unverified
+// account. The answer is the same whether or not there is such an account.
+func (s *AuthService) ResendVerification(c echo.Context) error {
+	var resendReq struct {
+		Email string `json:"email" validate:"required,email"`
+	}
+	
+	if err := c.Bind(&resendReq); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+	
+	if err := c.Validate(&resendReq); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
 	}
 	
-	refreshToken, err := s.generateRefreshToken(user.ID)
+	user, err := s.userRepo.GetByEmail(normalizeEmail(resendReq.Email))
+	if err == nil && user.EmailVerifiedAt == nil {
+		if err := s.sendVerificationEmail(c, user); err != nil {
+			log.Printf("Failed to send verification email to user %d: %v", user.ID, err)
+		}
+	}
+	
+	return c.JSON(http.StatusOK, map[string]string{
+		"message": "If an unverified account uses this address, a new verification link has been sent to it.",
+	})
+}
+
+// CreateInvitation invites someone to create an account with a given role
+// and mails them the invitation code. The code is returned once so that
+// the admin can pass it on should the mail not arrive.
+func (s *AuthService) CreateInvitation(c echo.Context) error {
+	adminID, err := middleware.GetUserIDFromToken(c)
 	if err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to generate refresh token")
+		return echo.NewHTTPError(http.StatusUnauthorized, "Invalid token")
+	}
+	
+	var req domain.CreateInvitationRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+	
+	if err := c.Validate(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
 	}
 	
-	// Prepare user response
-	userResponse := domain.UserResponse{
-		ID:        user.ID,
-		Username:  user.Username,
-		Name:      user.Name,
-		Email:     user.Email,
-		Role:      user.Role,
-		CreatedAt: user.CreatedAt,
-		UpdatedAt: user.UpdatedAt,
+	email := normalizeEmail(req.Email)
+	if _, err := s.userRepo.GetByEmail(email); err == nil {
+		return echo.NewHTTPError(http.StatusConflict, "Email already exists")
+	}
+	days := req.ExpiresIn
+	if days == 0 {
+		days = defaultInvitationDays
+	}
+	
+	token, err := randomHex(32)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to create invitation")
+	}
+	invitation := &domain.Invitation{
+		Email:     email,
+		Role:      req.Role,
+		TokenHash: hashToken(token),
+		InvitedBy: adminID,
+		ExpiresAt: time.Now().AddDate(0, 0, days),
+	}
+	if err := s.invitationRepo.Create(invitation); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to create invitation")
+	}
+	
+	settings := s.settings.Current()
+	err = s.mailer.Send(c.Request().Context(), mail.Message{
+		To:      email,
+		Subject: fmt.Sprintf("You are invited to %s", settings.SystemName),
+		Body: fmt.Sprintf("You have been invited to join %s at %s as %s %s.\n\n"+
+			"Create your account with this invitation code:\n\n%s\n\n"+
+			"The invitation expires on %s.\n",
+			settings.SystemName, settings.InstitutionName, article(req.Role), req.Role,
+			token, invitation.ExpiresAt.UTC().Format("January 2, 2006 15:04 MST")),
+	})
+	if err != nil {
+		log.Printf("Failed to send invitation %d: %v", invitation.ID, err)
 	}
 	
-	// Return tokens
 	return c.JSON(http.StatusCreated, map[string]interface{}{
-		"access_token": accessToken,
-		"refresh_token": refreshToken,
-		"user": userResponse,
+		"invitation": invitation,
+		"token":      token,
+		"mailed":     err == nil,
+	})
+}
+
+// GetInvitations returns the invitations that can still be accepted
+func (s *AuthService) GetInvitations(c echo.Context) error {
+	invitations, err := s.invitationRepo.GetPending(time.Now())
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to get invitations")
+	}
+	
+	return c.JSON(http.StatusOK, map[string]interface{}{"items": invitations})
+}
+
+// RevokeInvitation deletes an invitation that has not been accepted
+func (s *AuthService) RevokeInvitation(c echo.Context) error {
+	id, err := parseIDParam(c, "id")
+	if err != nil {
+		return err
+	}
+	
+	invitation, err := s.invitationRepo.GetByID(id)
+	if err != nil {
+		if err.Error() == "invitation not found" {
+			return echo.NewHTTPError(http.StatusNotFound, "Invitation not found")
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to revoke invitation")
+	}
+	if invitation.AcceptedAt != nil {
+		return echo.NewHTTPError(http.StatusConflict, "Invitation has already been accepted")
+	}
+	if err := s.invitationRepo.Delete(invitation.ID); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to revoke invitation")
+	}
+	
+	return c.JSON(http.StatusOK, map[string]string{
+		"message": "Invitation revoked successfully",
+	})
+}
+
+// AcceptInvitation creates the account an invitation is for, with the
+// invitation's role and email address, and signs it in. Invitations work
+// whatever the self-registration settings, and the address counts as
+// verified since the code was mailed to it.
+func (s *AuthService) AcceptInvitation(c echo.Context) error {
+	var req domain.AcceptInvitationRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+	
+	if err := c.Validate(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	
+	// Check if username already exists
+	if _, err := s.userRepo.GetByUsername(req.Username); err == nil {
+		return echo.NewHTTPError(http.StatusConflict, "Username already exists")
+	}
+	
+	now := time.Now()
+	user := &domain.User{
+		Username:        req.Username,
+		Name:            req.Name,
+		EmailVerifiedAt: &now,
+	}
+	if err := user.SetPassword(req.Password); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to hash password")
+	}
+	
+	err := s.invitationRepo.Transaction(func(tx *repository.InvitationRepository) error {
+		invitation, err := tx.LockByHash(hashToken(strings.TrimSpace(req.Token)))
+		if err != nil {
+			return err
+		}
+		if !invitation.Pending(now) {
+			return ErrInvitationUsed
+		}
+		if _, err := s.userRepo.GetByEmail(invitation.Email); err == nil {
+			return ErrAccountExists
+		}
+	
+		user.Email = invitation.Email
+		user.Role = invitation.Role
+		if err := tx.CreateUser(user); err != nil {
+			return err
+		}
+		invitation.AcceptedAt = &now
+		invitation.AcceptedBy = &user.ID
+		return tx.Save(invitation)
 	})
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrInvitationUsed):
+			return echo.NewHTTPError(http.StatusGone, "Invitation has expired or was already accepted")
+		case errors.Is(err, ErrAccountExists):
+			return echo.NewHTTPError(http.StatusConflict, "Email already exists")
+		case err.Error() == "invitation not found":
+			return echo.NewHTTPError(http.StatusNotFound, "Invalid invitation code")
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to create user")
+	}
+	
+	// Return tokens, or an MFA challenge when the role requires a second factor
+	return s.signIn(c, user, http.StatusCreated)
 }
 
-// RefreshToken refreshes an access token
+// RefreshToken exchanges a refresh token for a new access token and a new
+// refresh token of the same family. The presented token is rotated out;
+// presenting it again means it was stolen, so the whole family is revoked
+// and the session has to sign in again.
 func (s *AuthService) RefreshToken(c echo.Context) error {
 	// Parse request
 	var refreshReq struct {
@@ -197,38 +440,68 @@ func (s *AuthService) RefreshToken(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
 	}
 	
-	// Get refresh token
-	refreshToken, err := s.refreshTokenRepo.GetByToken(refreshReq.RefreshToken)
-	if err != nil {
-		return echo.NewHTTPError(http.StatusUnauthorized, "Invalid refresh token")
-	}
-	
-	// Check if token is expired
-	if time.Now().After(refreshToken.ExpiresAt) {
-		// Delete expired token
-		s.refreshTokenRepo.DeleteByToken(refreshReq.RefreshToken)
-		return echo.NewHTTPError(http.StatusUnauthorized, "Refresh token expired")
+	now := time.Now()
+	var user *domain.User
+	var next *domain.RefreshToken
+	var nextToken, familyID string
+	reused := false
+	err := s.refreshTokenRepo.Transaction(func(tx *repository.RefreshTokenRepository) error {
+		current, err := tx.LockByHash(hashToken(refreshReq.RefreshToken))
+		if err != nil {
+			return err
+		}
+		familyID = current.FamilyID
+		if current.RotatedAt != nil {
+			reused = true
+			return tx.DeleteFamily(current.FamilyID)
+		}
+		if now.After(current.ExpiresAt) {
+			return ErrRefreshTokenExpired
+		}
+		
+		user, err = s.userRepo.GetByID(current.UserID)
+		if err != nil {
+			return err
+		}
+		
+		next, nextToken, err = s.issueRefreshToken(tx, c, user.ID, current.FamilyID, current.AuthenticatedAt)
+		if err != nil {
+			return err
+		}
+		current.RotatedAt = &now
+		current.ReplacedByID = &next.ID
+		return tx.Save(current)
+	})
+	if reused && err == nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Refresh token reuse detected; the session has been signed out")
 	}
-	
-	// Get user
-	user, err := s.userRepo.GetByID(refreshToken.UserID)
 	if err != nil {
-		return echo.NewHTTPError(http.StatusNotFound, "User not found")
+		switch {
+		case errors.Is(err, ErrRefreshTokenExpired):
+			s.refreshTokenRepo.DeleteFamily(familyID)
+			return echo.NewHTTPError(http.StatusUnauthorized, "Refresh token expired")
+		case err.Error() == "refresh token not found":
+			return echo.NewHTTPError(http.StatusUnauthorized, "Invalid refresh token")
+		case err.Error() == "user not found":
+			return echo.NewHTTPError(http.StatusNotFound, "User not found")
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to refresh token")
 	}
 	
 	// Generate new access token
-	accessToken, err := auth.GenerateToken(user.ID, user.Role, s.jwtSecret, s.jwtExpiration)
+	accessToken, err := auth.GenerateToken(user.ID, user.Role, next.FamilyID, s.jwtSecret, s.jwtExpiration)
 	if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to generate access token")
 	}
 	
-	// Return new access token
+	// Return the new token pair
 	return c.JSON(http.StatusOK, map[string]string{
-		"access_token": accessToken,
+		"access_token":  accessToken,
+		"refresh_token": nextToken,
 	})
 }
 
-// Logout invalidates a refresh token
+// Logout signs out the session of a refresh token
 func (s *AuthService) Logout(c echo.Context) error {
 	// Parse request
 	var logoutReq struct {
@@ -239,9 +512,22 @@ func (s *AuthService) Logout(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
 	}
 	
-	// Delete the refresh token
-	err := s.refreshTokenRepo.DeleteByToken(logoutReq.RefreshToken)
+	userID, err := middleware.GetUserIDFromToken(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Invalid token")
+	}
+	
+	// Delete every token of the session
+	refreshToken, err := s.refreshTokenRepo.GetByHash(hashToken(logoutReq.RefreshToken))
 	if err != nil {
+		if err.Error() == "refresh token not found" {
+			return c.JSON(http.StatusOK, map[string]string{
+				"message": "Successfully logged out",
+			})
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to logout")
+	}
+	if _, err := s.refreshTokenRepo.DeleteUserFamily(userID, refreshToken.FamilyID); err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to logout")
 	}
 	
@@ -250,27 +536,164 @@ func (s *AuthService) Logout(c echo.Context) error {
 	})
 }
 
-// generateRefreshToken generates a new refresh token
-func (s *AuthService) generateRefreshToken(userID uint) (string, error) {
-	// Generate random token
-	b := make([]byte, 32)
-	if _, err := rand.Read(b); err != nil {
-		return "", err
+// LogoutEverywhere signs the current user out of all their sessions
+func (s *AuthService) LogoutEverywhere(c echo.Context) error {
+	userID, err := middleware.GetUserIDFromToken(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Invalid token")
 	}
-	tokenString := hex.EncodeToString(b)
 	
-	// Store in database
-	expiresAt := time.Now().Add(s.refreshExpiration)
-	refreshToken := &domain.RefreshToken{
-		UserID:    userID,
-		Token:     tokenString,
-		ExpiresAt: expiresAt,
+	if err := s.refreshTokenRepo.DeleteByUserID(userID); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to logout")
+	}
+	
+	return c.JSON(http.StatusOK, map[string]string{
+		"message": "Successfully logged out of all sessions",
+	})
+}
+
+// GetSessions returns the current user's signed-in sessions
+func (s *AuthService) GetSessions(c echo.Context) error {
+	userID, err := middleware.GetUserIDFromToken(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Invalid token")
 	}
 	
-	err := s.refreshTokenRepo.Create(refreshToken)
+	tokens, err := s.refreshTokenRepo.GetActiveByUserID(userID, time.Now())
 	if err != nil {
-		return "", err
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to get sessions")
+	}
+	current := middleware.GetSessionIDFromToken(c)
+	sessions := make([]domain.AuthSession, 0, len(tokens))
+	for i := range tokens {
+		sessions = append(sessions, tokens[i].ToAuthSession(current))
+	}
+	
+	return c.JSON(http.StatusOK, map[string]interface{}{"items": sessions})
+}
+
+// RevokeSession signs the current user out of one of their sessions
+func (s *AuthService) RevokeSession(c echo.Context) error {
+	userID, err := middleware.GetUserIDFromToken(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Invalid token")
+	}
+	
+	found, err := s.refreshTokenRepo.DeleteUserFamily(userID, c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to revoke session")
+	}
+	if !found {
+		return echo.NewHTTPError(http.StatusNotFound, "Session not found")
+	}
+	
+	return c.JSON(http.StatusOK, map[string]string{
+		"message": "Session revoked successfully",
+	})
+}
+
+// startSession issues the access token and the first refresh token of a
+// new token family for a user who just signed in
+func (s *AuthService) startSession(c echo.Context, user *domain.User) (string, string, error) {
+	familyID, err := randomHex(16)
+	if err != nil {
+		return "", "", echo.NewHTTPError(http.StatusInternalServerError, "Failed to generate refresh token")
+	}
+	
+	_, refreshToken, err := s.issueRefreshToken(s.refreshTokenRepo, c, user.ID, familyID, time.Now())
+	if err != nil {
+		return "", "", echo.NewHTTPError(http.StatusInternalServerError, "Failed to generate refresh token")
+	}
+	
+	accessToken, err := auth.GenerateToken(user.ID, user.Role, familyID, s.jwtSecret, s.jwtExpiration)
+	if err != nil {
+		return "", "", echo.NewHTTPError(http.StatusInternalServerError, "Failed to generate access token")
+	}
+	return accessToken, refreshToken, nil
+}
+
+// issueRefreshToken generates a refresh token of a token family and stores
+// its hash along with the client it was issued to
+func (s *AuthService) issueRefreshToken(repo *repository.RefreshTokenRepository, c echo.Context, userID uint, familyID string,
+	authenticatedAt time.Time) (*domain.RefreshToken, string, error) {
+	tokenString, err := randomHex(32)
+	if err != nil {
+		return nil, "", err
+	}
+	
+	now := time.Now()
+	refreshToken := &domain.RefreshToken{
+		UserID:          userID,
+		TokenHash:       hashToken(tokenString),
+		FamilyID:        familyID,
+		UserAgent:       clientDetail(c.Request().UserAgent(), 500),
+		IPAddress:       clientDetail(c.RealIP(), 64),
+		AuthenticatedAt: authenticatedAt,
+		LastUsedAt:      now,
+		ExpiresAt:       now.Add(s.refreshExpiration),
+	}
+	if err := repo.Create(refreshToken); err != nil {
+		return nil, "", err
 	}
 	
-	return tokenString, nil
-}
\ No newline at end of file
+	return refreshToken, tokenString, nil
+}
+
+// sendVerificationEmail mails a user the link that verifies their address,
+// under the configured public base URL
+func (s *AuthService) sendVerificationEmail(c echo.Context, user *domain.User) error {
+	expiresAt := time.Now().Add(emailVerificationTTL)
+	token := emailVerificationToken(s.jwtSecret, user, expiresAt)
+	link := fmt.Sprintf("%s/api/v1/auth/verify-email?token=%s", s.publicBaseURL, url.QueryEscape(token))
+	
+	settings := s.settings.Current()
+	return s.mailer.Send(c.Request().Context(), mail.Message{
+		To:      user.Email,
+		Subject: fmt.Sprintf("Verify your email address for %s", settings.SystemName),
+		Body: fmt.Sprintf("Hello %s,\n\nOpen this link to verify your email address and finish creating your account:\n\n%s\n\n"+
+			"The link expires in %d hours. If you did not sign up, ignore this message.\n",
+			user.Name, link, int(emailVerificationTTL.Hours())),
+	})
+}
+
+// hashToken returns the hash a refresh token or invitation code is stored under
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// clientDetail cuts a client supplied value down to at most max bytes,
+// keeping it valid UTF-8
+func clientDetail(value string, max int) string {
+	if len(value) <= max {
+		return value
+	}
+	value = value[:max]
+	for !utf8.ValidString(value) {
+		value = value[:len(value)-1]
+	}
+	return value
+}
+
+// normalizeEmail lowercases an email address so that it is stored and
+// looked up in one form
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// article returns the indefinite article for a role
+func article(role string) string {
+	if strings.IndexAny(role[:1], "aeiou") == 0 {
+		return "an"
+	}
+	return "a"
+}
+
+// randomHex returns n random bytes, hex encoded
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,72 @@
+package service
+
+import (
+	"backend/internal/domain"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// emailVerificationTTL is how long an email verification link stays valid
+const emailVerificationTTL = 48 * time.Hour
+
+// ErrVerificationToken is returned for email verification tokens that are
+// malformed, forged, expired or meant for an address the user no longer has
+var ErrVerificationToken = errors.New("the verification link is invalid or has expired")
+
+// emailVerificationToken returns the token of a user's verification link:
+// the user ID, the expiry and an HMAC over both and the email address being
+// verified. Once the address is verified the token has no further use, and
+// changing the address invalidates it.
+func emailVerificationToken(secret string, user *domain.User, expiresAt time.Time) string {
+	expires := expiresAt.Unix()
+	return fmt.Sprintf("%d.%d.%s", user.ID, expires,
+		base64.RawURLEncoding.EncodeToString(emailVerificationMAC(secret, user.ID, user.Email, expires)))
+}
+
+// emailVerificationMAC signs a verification of an address
+func emailVerificationMAC(secret string, userID uint, email string, expires int64) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "email-verification.%d.%d.%s", userID, expires, strings.ToLower(email))
+	return mac.Sum(nil)
+}
+
+// parseEmailVerificationToken extracts the user ID from a token so that the
+// user can be loaded before the token is verified
+func parseEmailVerificationToken(token string) (uint, error) {
+	parts := strings.Split(strings.TrimSpace(token), ".")
+	if len(parts) != 3 {
+		return 0, ErrVerificationToken
+	}
+	id, err := strconv.ParseUint(parts[0], 10, 32)
+	if err != nil {
+		return 0, ErrVerificationToken
+	}
+	return uint(id), nil
+}
+
+// verifyEmailVerificationToken checks a token's signature against the
+// user's current address and that it has not expired
+func verifyEmailVerificationToken(secret string, user *domain.User, token string, now time.Time) error {
+	parts := strings.Split(strings.TrimSpace(token), ".")
+	if len(parts) != 3 || parts[0] != strconv.FormatUint(uint64(user.ID), 10) {
+		return ErrVerificationToken
+	}
+	expires, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return ErrVerificationToken
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil || !hmac.Equal(mac, emailVerificationMAC(secret, user.ID, user.Email, expires)) {
+		return ErrVerificationToken
+	}
+	if !now.Before(time.Unix(expires, 0)) {
+		return ErrVerificationToken
+	}
+	return nil
+}
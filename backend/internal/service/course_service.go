@@ -1,49 +1,683 @@
 package service
 
-import "github.com/labstack/echo/v4"
+import (
+	"backend/internal/domain"
+	"backend/internal/repository"
+	"backend/pkg/middleware"
+	"net/http"
+	"strconv"
+	"time"
 
-type CourseService struct{}
+	"github.com/labstack/echo/v4"
+)
 
+// CourseService handles course-related operations
+type CourseService struct {
+	courseRepo     *repository.CourseRepository
+	userRepo       *repository.UserRepository
+	enrollmentRepo *repository.EnrollmentRepository
+	enrollments    *EnrollmentManager
+	gradebook      *Gradebook
+}
+
+// NewCourseService creates a new course service
+func NewCourseService(
+	courseRepo *repository.CourseRepository,
+	userRepo *repository.UserRepository,
+	enrollmentRepo *repository.EnrollmentRepository,
+	enrollments *EnrollmentManager,
+	gradebook *Gradebook,
+) *CourseService {
+	return &CourseService{
+		courseRepo:     courseRepo,
+		userRepo:       userRepo,
+		enrollmentRepo: enrollmentRepo,
+		enrollments:    enrollments,
+		gradebook:      gradebook,
+	}
+}
+
+// GetCourses returns the courses visible to the current user.
+// Students see the courses they are enrolled in, instructors the courses
+// they teach and admins every course.
 func (s *CourseService) GetCourses(c echo.Context) error {
-	return c.JSON(200, map[string]string{"message": "dummy get courses"})
+	userID, err := middleware.GetUserIDFromToken(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Invalid token")
+	}
+	role, err := middleware.GetRoleFromToken(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Invalid token")
+	}
+
+	// Parse pagination parameters
+	limit, _ := strconv.Atoi(c.QueryParam("limit"))
+	page, _ := strconv.Atoi(c.QueryParam("page"))
+
+	if limit <= 0 {
+		limit = 10
+	}
+	if page <= 0 {
+		page = 1
+	}
+
+	offset := (page - 1) * limit
+
+	// Parse filters
+	filter := repository.CourseFilter{
+		Semester: c.QueryParam("semester"),
+		Category: c.QueryParam("category"),
+	}
+	if yearParam := c.QueryParam("year"); yearParam != "" {
+		year, err := strconv.Atoi(yearParam)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid year")
+		}
+		filter.Year = year
+	}
+
+	// Scope the listing to the user's role
+	switch role {
+	case "admin":
+	case "instructor":
+		filter.InstructorID = userID
+	default:
+		filter.StudentID = userID
+	}
+
+	courses, count, err := s.courseRepo.FindCourses(filter, limit, offset)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to get courses")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"courses":     courses,
+		"total":       count,
+		"page":        page,
+		"limit":       limit,
+		"total_pages": (count + int64(limit) - 1) / int64(limit),
+	})
 }
 
+// GetCourse returns a course with its sessions and instructors
 func (s *CourseService) GetCourse(c echo.Context) error {
-	return c.JSON(200, map[string]string{"message": "dummy get course"})
+	courseID, err := parseIDParam(c, "id")
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid course ID")
+	}
+
+	if err := s.authorizeCourseAccess(c, courseID); err != nil {
+		return err
+	}
+
+	course, err := s.courseRepo.GetByIDWithDetails(courseID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, "Course not found")
+	}
+
+	instructors := make([]domain.CourseInstructorResponse, 0, len(course.CourseInstructors))
+	for _, instructor := range course.CourseInstructors {
+		instructors = append(instructors, instructor.ToCourseInstructorResponse())
+	}
+	course.CourseInstructors = nil
+
+	return c.JSON(http.StatusOK, domain.CourseDetailResponse{
+		Course:      *course,
+		Instructors: instructors,
+	})
 }
 
+// CreateCourse creates a new course
 func (s *CourseService) CreateCourse(c echo.Context) error {
-	return c.JSON(200, map[string]string{"message": "dummy create course"})
+	// Parse request
+	var course domain.Course
+	if err := c.Bind(&course); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+
+	if course.Code == "" || course.Title == "" || course.Semester == "" || course.Year <= 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "Code, title, semester and year are required")
+	}
+	if course.Credits < 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "Credits cannot be negative")
+	}
+
+	course.ID = 0
+	course.CreatedAt = time.Now()
+	course.UpdatedAt = time.Now()
+
+	if err := s.courseRepo.Create(&course); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to create course")
+	}
+
+	return c.JSON(http.StatusCreated, course)
 }
 
+// UpdateCourse updates an existing course
 func (s *CourseService) UpdateCourse(c echo.Context) error {
-	return c.JSON(200, map[string]string{"message": "dummy update course"})
+	courseID, err := parseIDParam(c, "id")
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid course ID")
+	}
+
+	course, err := s.courseRepo.GetByID(courseID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, "Course not found")
+	}
+
+	// Parse request
+	var updateCourse struct {
+		domain.Course
+		Capacity         *int     `json:"capacity"`
+		RequiresApproval *bool    `json:"requiresApproval"`
+		Credits          *float64 `json:"credits"`
+	}
+	if err := c.Bind(&updateCourse); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+
+	// Update fields - only update the fields that are provided
+	if updateCourse.Code != "" {
+		course.Code = updateCourse.Code
+	}
+	if updateCourse.Title != "" {
+		course.Title = updateCourse.Title
+	}
+	if updateCourse.Description != "" {
+		course.Description = updateCourse.Description
+	}
+	if updateCourse.Category != "" {
+		course.Category = updateCourse.Category
+	}
+	if updateCourse.Semester != "" {
+		course.Semester = updateCourse.Semester
+	}
+	if updateCourse.Year > 0 {
+		course.Year = updateCourse.Year
+	}
+	capacityRaised := false
+	if updateCourse.Capacity != nil {
+		if *updateCourse.Capacity < 0 {
+			return echo.NewHTTPError(http.StatusBadRequest, "Capacity cannot be negative")
+		}
+		capacityRaised = *updateCourse.Capacity != course.Capacity &&
+			(*updateCourse.Capacity == 0 || *updateCourse.Capacity > course.Capacity)
+		course.Capacity = *updateCourse.Capacity
+	}
+	if updateCourse.RequiresApproval != nil {
+		course.RequiresApproval = *updateCourse.RequiresApproval
+	}
+	creditsChanged := false
+	if updateCourse.Credits != nil {
+		if *updateCourse.Credits < 0 {
+			return echo.NewHTTPError(http.StatusBadRequest, "Credits cannot be negative")
+		}
+		creditsChanged = *updateCourse.Credits != course.Credits
+		course.Credits = *updateCourse.Credits
+	}
+	if updateCourse.AddDeadline != nil {
+		course.AddDeadline = updateCourse.AddDeadline
+	}
+	if updateCourse.DropDeadline != nil {
+		course.DropDeadline = updateCourse.DropDeadline
+	}
+	if updateCourse.WithdrawDeadline != nil {
+		course.WithdrawDeadline = updateCourse.WithdrawDeadline
+	}
+	releaseChanged := false
+	if updateCourse.GradesReleaseAt != nil {
+		releaseChanged = course.GradesReleaseAt == nil || !updateCourse.GradesReleaseAt.Equal(*course.GradesReleaseAt)
+		course.GradesReleaseAt = updateCourse.GradesReleaseAt
+	}
+
+	course.UpdatedAt = time.Now()
+	if err := s.courseRepo.Update(course); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to update course")
+	}
+
+	// Hand newly available seats to the waitlist
+	if capacityRaised {
+		actorID, _ := middleware.GetUserIDFromToken(c)
+		if err := s.enrollments.PromoteWaitlist(course.ID, actorID); err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to promote waitlisted students")
+		}
+	}
+	// Credits weight the course in its students' GPAs, which only count
+	// released grades
+	if creditsChanged || releaseChanged {
+		if err := s.gradebook.SyncCourseGPAs(course.ID); err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to update student GPAs")
+		}
+	}
+
+	return c.JSON(http.StatusOK, course)
 }
 
+// DeleteCourse deletes a course
 func (s *CourseService) DeleteCourse(c echo.Context) error {
-	return c.JSON(200, map[string]string{"message": "dummy delete course"})
+	courseID, err := parseIDParam(c, "id")
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid course ID")
+	}
+
+	if _, err := s.courseRepo.GetByID(courseID); err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, "Course not found")
+	}
+
+	if err := s.courseRepo.Delete(courseID); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to delete course")
+	}
+	// Deleted courses drop off their students' transcripts
+	if err := s.gradebook.SyncCourseGPAs(courseID); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to update student GPAs")
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"message": "Course deleted successfully",
+	})
 }
 
+// GetCourseInstructors returns the instructors of a course
 func (s *CourseService) GetCourseInstructors(c echo.Context) error {
-	return c.JSON(200, map[string]string{"message": "dummy get course instructors"})
+	courseID, err := parseIDParam(c, "id")
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid course ID")
+	}
+
+	if err := s.authorizeCourseAccess(c, courseID); err != nil {
+		return err
+	}
+
+	instructors, err := s.courseRepo.GetInstructors(courseID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to get course instructors")
+	}
+
+	response := make([]domain.CourseInstructorResponse, 0, len(instructors))
+	for _, instructor := range instructors {
+		response = append(response, instructor.ToCourseInstructorResponse())
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"instructors": response,
+	})
 }
 
+// AddCourseInstructor assigns an instructor to a course
 func (s *CourseService) AddCourseInstructor(c echo.Context) error {
-	return c.JSON(200, map[string]string{"message": "dummy add course instructor"})
+	courseID, err := parseIDParam(c, "id")
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid course ID")
+	}
+
+	// Parse request
+	var addReq struct {
+		UserID uint `json:"userId" validate:"required"`
+		IsMain bool `json:"isMain"`
+	}
+	if err := c.Bind(&addReq); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+	if err := c.Validate(&addReq); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	if _, err := s.courseRepo.GetByID(courseID); err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, "Course not found")
+	}
+
+	user, err := s.userRepo.GetByID(addReq.UserID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, "User not found")
+	}
+	if user.Role != "instructor" {
+		return echo.NewHTTPError(http.StatusBadRequest, "User is not an instructor")
+	}
+
+	if _, err := s.courseRepo.GetInstructor(courseID, user.ID); err == nil {
+		return echo.NewHTTPError(http.StatusConflict, "Instructor already assigned to this course")
+	}
+
+	instructor := &domain.CourseInstructor{
+		CourseID: courseID,
+		UserID:   user.ID,
+		IsMain:   addReq.IsMain,
+	}
+	if err := s.courseRepo.AddInstructor(instructor); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to add course instructor")
+	}
+	instructor.User = *user
+
+	return c.JSON(http.StatusCreated, instructor.ToCourseInstructorResponse())
 }
 
+// RemoveCourseInstructor removes an instructor from a course
 func (s *CourseService) RemoveCourseInstructor(c echo.Context) error {
-	return c.JSON(200, map[string]string{"message": "dummy remove course instructor"})
+	courseID, err := parseIDParam(c, "id")
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid course ID")
+	}
+	userID, err := parseIDParam(c, "userId")
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid user ID")
+	}
+
+	if _, err := s.courseRepo.GetInstructor(courseID, userID); err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, "Course instructor not found")
+	}
+
+	if err := s.courseRepo.RemoveInstructor(courseID, userID); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to remove course instructor")
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"message": "Instructor removed successfully",
+	})
 }
 
+// GetCourseStudents returns the students enrolled in a course
 func (s *CourseService) GetCourseStudents(c echo.Context) error {
-	return c.JSON(200, map[string]string{"message": "dummy get course students"})
+	courseID, err := parseIDParam(c, "id")
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid course ID")
+	}
+
+	if err := s.authorizeCourseStaff(c, courseID); err != nil {
+		return err
+	}
+
+	students, err := s.courseRepo.GetStudents(courseID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to get course students")
+	}
+
+	response := make([]domain.CourseStudentResponse, 0, len(students))
+	for _, student := range students {
+		response = append(response, student.ToCourseStudentResponse())
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"students": response,
+	})
 }
 
+// EnrollStudent enrolls a student in a course on behalf of the course staff.
+// Staff enrollments skip the approval step; admins may also bypass the add deadline.
 func (s *CourseService) EnrollStudent(c echo.Context) error {
-	return c.JSON(200, map[string]string{"message": "dummy enroll student"})
+	courseID, err := parseIDParam(c, "id")
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid course ID")
+	}
+
+	if err := s.authorizeCourseStaff(c, courseID); err != nil {
+		return err
+	}
+
+	// Parse request
+	var enrollReq domain.EnrollmentRequest
+	if err := c.Bind(&enrollReq); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+	if err := c.Validate(&enrollReq); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	user, err := s.userRepo.GetByID(enrollReq.UserID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, "User not found")
+	}
+	if user.Role != "student" {
+		return echo.NewHTTPError(http.StatusBadRequest, "User is not a student")
+	}
+
+	action := s.enrollmentAction(c, courseID, user.ID, enrollReq.Reason)
+	action.Approved = true
+	enrollment, err := s.enrollments.Enroll(action)
+	if err != nil {
+		return enrollmentHTTPError(err)
+	}
+	enrollment.User = *user
+
+	return c.JSON(http.StatusCreated, enrollment.ToCourseStudentResponse())
 }
 
+// UnenrollStudent drops or withdraws a student from a course on behalf of the course staff
 func (s *CourseService) UnenrollStudent(c echo.Context) error {
-	return c.JSON(200, map[string]string{"message": "dummy unenroll student"})
+	courseID, err := parseIDParam(c, "id")
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid course ID")
+	}
+	userID, err := parseIDParam(c, "userId")
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid user ID")
+	}
+
+	if err := s.authorizeCourseStaff(c, courseID); err != nil {
+		return err
+	}
+
+	var dropReq domain.EnrollmentActionRequest
+	if err := c.Bind(&dropReq); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+
+	enrollment, err := s.enrollments.Drop(s.enrollmentAction(c, courseID, userID, dropReq.Reason))
+	if err != nil {
+		return enrollmentHTTPError(err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message": "Student removed from course",
+		"status":  enrollment.Status,
+	})
+}
+
+// ApproveEnrollment approves a pending enrollment request
+func (s *CourseService) ApproveEnrollment(c echo.Context) error {
+	return s.changeEnrollment(c, s.enrollments.Approve)
+}
+
+// CompleteEnrollment marks an enrolled student as having completed the course
+func (s *CourseService) CompleteEnrollment(c echo.Context) error {
+	return s.changeEnrollment(c, s.enrollments.Complete)
+}
+
+// RequestEnrollment lets the current student request a seat in a course
+func (s *CourseService) RequestEnrollment(c echo.Context) error {
+	courseID, err := parseIDParam(c, "id")
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid course ID")
+	}
+	userID, err := middleware.GetUserIDFromToken(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Invalid token")
+	}
+
+	var enrollReq domain.EnrollmentActionRequest
+	if err := c.Bind(&enrollReq); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+
+	enrollment, err := s.enrollments.Enroll(EnrollmentAction{
+		CourseID: courseID,
+		UserID:   userID,
+		ActorID:  userID,
+		Reason:   enrollReq.Reason,
+	})
+	if err != nil {
+		return enrollmentHTTPError(err)
+	}
+
+	return c.JSON(http.StatusCreated, map[string]interface{}{
+		"courseId":     enrollment.CourseID,
+		"status":       enrollment.Status,
+		"enrolledAt":   enrollment.EnrolledAt,
+		"waitlistedAt": enrollment.WaitlistedAt,
+	})
+}
+
+// DropMyEnrollment lets the current student drop or withdraw from a course
+func (s *CourseService) DropMyEnrollment(c echo.Context) error {
+	courseID, err := parseIDParam(c, "id")
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid course ID")
+	}
+	userID, err := middleware.GetUserIDFromToken(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Invalid token")
+	}
+
+	var dropReq domain.EnrollmentActionRequest
+	if err := c.Bind(&dropReq); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+
+	enrollment, err := s.enrollments.Drop(EnrollmentAction{
+		CourseID: courseID,
+		UserID:   userID,
+		ActorID:  userID,
+		Reason:   dropReq.Reason,
+	})
+	if err != nil {
+		return enrollmentHTTPError(err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"courseId": enrollment.CourseID,
+		"status":   enrollment.Status,
+	})
+}
+
+// GetWaitlist returns the waitlist of a course in the order seats will be offered
+func (s *CourseService) GetWaitlist(c echo.Context) error {
+	courseID, err := parseIDParam(c, "id")
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid course ID")
+	}
+
+	if err := s.authorizeCourseStaff(c, courseID); err != nil {
+		return err
+	}
+
+	waitlist, err := s.enrollmentRepo.GetWaitlist(courseID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to get waitlist")
+	}
+
+	response := make([]domain.CourseStudentResponse, 0, len(waitlist))
+	for _, enrollment := range waitlist {
+		response = append(response, enrollment.ToCourseStudentResponse())
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"waitlist": response,
+	})
+}
+
+// GetEnrollmentHistory returns every status change of a student's enrollment in a course.
+// Students may only read their own history.
+func (s *CourseService) GetEnrollmentHistory(c echo.Context) error {
+	courseID, err := parseIDParam(c, "id")
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid course ID")
+	}
+	userID, err := parseIDParam(c, "userId")
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid user ID")
+	}
+
+	currentUserID, err := middleware.GetUserIDFromToken(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Invalid token")
+	}
+	if currentUserID != userID {
+		if err := s.authorizeCourseStaff(c, courseID); err != nil {
+			return err
+		}
+	}
+
+	history, err := s.enrollmentRepo.GetHistory(courseID, userID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to get enrollment history")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"history": history,
+	})
+}
+
+// GetStudentEnrollmentHistory returns a student's enrollment history across all courses
+func (s *CourseService) GetStudentEnrollmentHistory(c echo.Context) error {
+	userID, err := parseIDParam(c, "id")
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid user ID")
+	}
+
+	if _, err := s.userRepo.GetByID(userID); err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, "User not found")
+	}
+
+	history, err := s.enrollmentRepo.GetUserHistory(userID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to get enrollment history")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"history": history,
+	})
+}
+
+// changeEnrollment applies a staff-initiated enrollment change to ":id"/":userId"
+func (s *CourseService) changeEnrollment(c echo.Context, change func(EnrollmentAction) (*domain.CourseStudent, error)) error {
+	courseID, err := parseIDParam(c, "id")
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid course ID")
+	}
+	userID, err := parseIDParam(c, "userId")
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid user ID")
+	}
+
+	if err := s.authorizeCourseStaff(c, courseID); err != nil {
+		return err
+	}
+
+	var changeReq domain.EnrollmentActionRequest
+	if err := c.Bind(&changeReq); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+
+	enrollment, err := change(s.enrollmentAction(c, courseID, userID, changeReq.Reason))
+	if err != nil {
+		return enrollmentHTTPError(err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"courseId": enrollment.CourseID,
+		"userId":   enrollment.UserID,
+		"status":   enrollment.Status,
+	})
+}
+
+// enrollmentAction builds a staff enrollment action; admins act as registrar and bypass deadlines
+func (s *CourseService) enrollmentAction(c echo.Context, courseID, userID uint, reason string) EnrollmentAction {
+	actorID, _ := middleware.GetUserIDFromToken(c)
+	role, _ := middleware.GetRoleFromToken(c)
+	return EnrollmentAction{
+		CourseID: courseID,
+		UserID:   userID,
+		ActorID:  actorID,
+		Reason:   reason,
+		Override: role == "admin",
+	}
+}
+
+// authorizeCourseAccess checks that the current user may view the course
+func (s *CourseService) authorizeCourseAccess(c echo.Context, courseID uint) error {
+	return authorizeCourseAccess(c, s.courseRepo, courseID)
+}
+
+// authorizeCourseStaff checks that the current user is an admin or teaches the course
+func (s *CourseService) authorizeCourseStaff(c echo.Context, courseID uint) error {
+	return authorizeCourseStaff(c, s.courseRepo, courseID)
 }
@@ -0,0 +1,272 @@
+package service
+
+import (
+	"backend/internal/domain"
+	"backend/pkg/pdf"
+	"fmt"
+	"image"
+	"strings"
+	"time"
+)
+
+// documentStamp is what every issued document carries to be verified
+type documentStamp struct {
+	Institution string
+	Code        string // formatted verification code
+	VerifyURL   string
+	IssuedAt    time.Time
+}
+
+// Layout of the A4 documents
+const (
+	pageMargin    = 50.0
+	footerTop     = pdf.A4Height - 60
+	contentBottom = footerTop - 20
+)
+
+// Columns of the course tables: code, title, credits, score, grade, points
+var courseColumns = [...]float64{pageMargin, pageMargin + 70, 390, 445, 465, pdf.A4Width - pageMargin}
+
+// reportWriter lays out an A4 report page by page
+type reportWriter struct {
+	doc   *pdf.Document
+	page  *pdf.Page
+	stamp documentStamp
+	title string
+	y     float64
+}
+
+func newReportWriter(stamp documentStamp, title, subject string) *reportWriter {
+	doc := pdf.New(pdf.A4Width, pdf.A4Height)
+	doc.SetInfo(pdf.Info{
+		Title:        title,
+		Author:       stamp.Institution,
+		Subject:      subject,
+		Keywords:     "verification code " + stamp.Code,
+		Creator:      stamp.Institution,
+		CreationDate: stamp.IssuedAt,
+	})
+	w := &reportWriter{doc: doc, stamp: stamp, title: title}
+	w.newPage()
+	return w
+}
+
+func (w *reportWriter) newPage() {
+	w.page = w.doc.AddPage()
+	w.page.TextCenter(pdf.A4Width/2, 60, pdf.SansBold, 16, w.stamp.Institution)
+	w.page.TextCenter(pdf.A4Width/2, 80, pdf.SansBold, 12, w.title)
+	w.page.Line(pageMargin, 92, pdf.A4Width-pageMargin, 92, 1)
+	w.y = 115
+}
+
+// ensure starts a new page unless height points still fit on this one
+func (w *reportWriter) ensure(height float64) {
+	if w.y+height > contentBottom {
+		w.newPage()
+	}
+}
+
+// field writes a label and its value on one line
+func (w *reportWriter) field(x float64, label, value string) {
+	w.page.Text(x, w.y, pdf.SansBold, 10, label)
+	w.page.Text(x+90, w.y, pdf.Sans, 10, value)
+}
+
+func (w *reportWriter) holder(t *domain.Transcript) {
+	w.field(pageMargin, "Name", t.Name)
+	w.field(pdf.A4Width/2, "Student ID", orDash(t.StudentID))
+	w.y += 15
+	w.field(pageMargin, "Major", orDash(t.Major))
+	w.field(pdf.A4Width/2, "Date of issue", w.stamp.IssuedAt.Format("2 January 2006"))
+	w.y += 25
+}
+
+// semester writes a semester's course table with its totals
+func (w *reportWriter) semester(s *domain.TranscriptSemester) {
+	w.ensure(60)
+	w.page.Text(pageMargin, w.y, pdf.SansBold, 11, fmt.Sprintf("%s %d", s.Semester, s.Year))
+	w.y += 16
+	w.tableHeader()
+
+	for _, course := range s.Courses {
+		lines := pdf.Wrap(pdf.Sans, 9, course.Title, courseColumns[2]-courseColumns[1]-40)
+		w.ensure(float64(len(lines))*11 + 4)
+		code := course.Code
+		if course.Repeated && !course.Cumulative {
+			code += " *"
+		}
+		w.page.Text(courseColumns[0], w.y, pdf.Sans, 9, code)
+		for i, line := range lines {
+			w.page.Text(courseColumns[1], w.y+float64(i)*11, pdf.Sans, 9, line)
+		}
+		w.page.TextRight(courseColumns[2], w.y, pdf.Sans, 9, formatNumber(course.Credits))
+		if course.LetterGrade != "" && course.Status != domain.EnrollmentStatusWithdrawn {
+			w.page.TextRight(courseColumns[3], w.y, pdf.Sans, 9, fmt.Sprintf("%.2f", course.OverallScore))
+		}
+		w.page.Text(courseColumns[4], w.y, pdf.Sans, 9, orDash(course.LetterGrade))
+		if course.LetterGrade != "" {
+			w.page.TextRight(courseColumns[5], w.y, pdf.Sans, 9, fmt.Sprintf("%.2f", course.GradePoints))
+		}
+		if course.Status == domain.EnrollmentStatusEnrolled {
+			w.page.Text(courseColumns[4]+25, w.y, pdf.Sans, 7, "in progress")
+		}
+		w.y += float64(len(lines))*11 + 3
+	}
+
+	w.page.Line(pageMargin, w.y-6, pdf.A4Width-pageMargin, w.y-6, 0.5)
+	w.y += 6
+	w.page.TextRight(pdf.A4Width-pageMargin, w.y, pdf.SansBold, 9,
+		fmt.Sprintf("Credits %s    Semester GPA %.2f", formatNumber(s.Credits), s.GPA))
+	w.y += 24
+}
+
+func (w *reportWriter) tableHeader() {
+	w.page.FillRect(pageMargin-4, w.y-10, pdf.A4Width-2*pageMargin+8, 14, 0.9)
+	w.page.Text(courseColumns[0], w.y, pdf.SansBold, 9, "Code")
+	w.page.Text(courseColumns[1], w.y, pdf.SansBold, 9, "Course")
+	w.page.TextRight(courseColumns[2], w.y, pdf.SansBold, 9, "Credits")
+	w.page.TextRight(courseColumns[3], w.y, pdf.SansBold, 9, "Score")
+	w.page.Text(courseColumns[4], w.y, pdf.SansBold, 9, "Grade")
+	w.page.TextRight(courseColumns[5], w.y, pdf.SansBold, 9, "Points")
+	w.y += 16
+}
+
+// summary writes the cumulative totals
+func (w *reportWriter) summary(t *domain.Transcript, label string) {
+	w.ensure(70)
+	w.page.StrokeRect(pageMargin, w.y-12, pdf.A4Width-2*pageMargin, 38, 0.75)
+	w.page.Text(pageMargin+10, w.y+2, pdf.SansBold, 11, fmt.Sprintf("%s %.2f", label, t.CumulativeGPA))
+	w.page.Text(pageMargin+10, w.y+18, pdf.Sans, 9, fmt.Sprintf("Credits attempted %s, earned %s",
+		formatNumber(t.AttemptedCredits), formatNumber(t.EarnedCredits)))
+	w.y += 42
+	note := fmt.Sprintf("Repeated courses count toward the cumulative GPA with their %s attempt; "+
+		"attempts marked * do not. W: withdrawn.", t.RepeatPolicy)
+	for _, line := range pdf.Wrap(pdf.Sans, 8, note, pdf.A4Width-2*pageMargin) {
+		w.page.Text(pageMargin, w.y, pdf.Sans, 8, line)
+		w.y += 10
+	}
+}
+
+// finish writes the verification footer on every page and renders the document
+func (w *reportWriter) finish() []byte {
+	pages := w.doc.Pages()
+	for i, page := range pages {
+		page.Line(pageMargin, footerTop, pdf.A4Width-pageMargin, footerTop, 0.5)
+		page.Text(pageMargin, footerTop+14, pdf.Sans, 8, "Verification code")
+		page.Text(pageMargin+75, footerTop+14, pdf.CourierBold, 9, w.stamp.Code)
+		page.Text(pageMargin, footerTop+26, pdf.Sans, 8, "Verify this document at "+w.stamp.VerifyURL)
+		page.TextRight(pdf.A4Width-pageMargin, footerTop+14, pdf.Sans, 8, fmt.Sprintf("Page %d of %d", i+1, len(pages)))
+	}
+	return w.doc.Bytes()
+}
+
+// renderTranscript renders an official transcript
+func renderTranscript(t *domain.Transcript, stamp documentStamp) []byte {
+	w := newReportWriter(stamp, "Official Academic Transcript", "Transcript of "+t.Name)
+	w.holder(t)
+	if len(t.Semesters) == 0 {
+		w.page.Text(pageMargin, w.y, pdf.Sans, 10, "No courses recorded.")
+		w.y += 20
+	}
+	for i := range t.Semesters {
+		w.semester(&t.Semesters[i])
+	}
+	w.summary(t, "Cumulative GPA")
+	return w.finish()
+}
+
+// renderGradeReport renders the grade report of the last semester of t,
+// which runs up to and including that semester
+func renderGradeReport(t *domain.Transcript, stamp documentStamp) []byte {
+	semester := &t.Semesters[len(t.Semesters)-1]
+	title := fmt.Sprintf("Grade Report %s %d", semester.Semester, semester.Year)
+	w := newReportWriter(stamp, title, title+" of "+t.Name)
+	w.holder(t)
+	w.semester(semester)
+	w.summary(t, fmt.Sprintf("Cumulative GPA through %s %d", semester.Semester, semester.Year))
+	return w.finish()
+}
+
+// renderIDCard renders a student ID card; photo may be nil
+func renderIDCard(user *domain.User, photo image.Image, validUntil time.Time, stamp documentStamp) []byte {
+	doc := pdf.New(pdf.CardWidth, pdf.CardHeight)
+	doc.SetInfo(pdf.Info{
+		Title:        "Student ID Card",
+		Author:       stamp.Institution,
+		Subject:      "Student ID card of " + user.Name,
+		Keywords:     "verification code " + stamp.Code,
+		Creator:      stamp.Institution,
+		CreationDate: stamp.IssuedAt,
+	})
+	page := doc.AddPage()
+
+	const margin = 10.0
+	page.FillRect(0, 0, pdf.CardWidth, 26, 0.85)
+	page.Text(margin, 12, pdf.SansBold, 9, fitText(pdf.SansBold, 9, stamp.Institution, pdf.CardWidth-2*margin))
+	page.Text(margin, 22, pdf.Sans, 7, "STUDENT IDENTITY CARD")
+
+	photoX, photoY, photoW, photoH := margin, 34.0, 56.0, 72.0
+	if photo != nil {
+		page.DrawImage(doc.AddImage(photo), photoX, photoY, photoW, photoH)
+	} else {
+		page.FillRect(photoX, photoY, photoW, photoH, 0.93)
+		page.TextCenter(photoX+photoW/2, photoY+photoH/2+6, pdf.SansBold, 18, initials(user.Name))
+	}
+	page.StrokeRect(photoX, photoY, photoW, photoH, 0.5)
+
+	x := photoX + photoW + 10
+	width := pdf.CardWidth - x - margin
+	page.Text(x, 44, pdf.SansBold, 10, fitText(pdf.SansBold, 10, user.Name, width))
+	rows := []struct{ label, value string }{
+		{"Student ID", orDash(user.StudentInfo.StudentID)},
+		{"Major", orDash(user.StudentInfo.Major)},
+		{"Degree", orDash(user.StudentInfo.Degree)},
+		{"Valid until", validUntil.Format("02 Jan 2006")},
+	}
+	y := 58.0
+	for _, row := range rows {
+		page.Text(x, y, pdf.Sans, 6, strings.ToUpper(row.label))
+		page.Text(x, y+8, pdf.SansBold, 8, fitText(pdf.SansBold, 8, row.value, width))
+		y += 17
+	}
+
+	page.Line(0, pdf.CardHeight-24, pdf.CardWidth, pdf.CardHeight-24, 0.5)
+	page.Text(margin, pdf.CardHeight-14, pdf.CourierBold, 7, stamp.Code)
+	page.Text(margin, pdf.CardHeight-6, pdf.Sans, 5, fitText(pdf.Sans, 5, "Verify at "+stamp.VerifyURL, pdf.CardWidth-2*margin))
+	return doc.Bytes()
+}
+
+// fitText shortens text with an ellipsis until it fits the width
+func fitText(font pdf.Font, size float64, text string, width float64) string {
+	if pdf.Width(font, size, text) <= width {
+		return text
+	}
+	runes := []rune(text)
+	for len(runes) > 0 && pdf.Width(font, size, string(runes)+"...") > width {
+		runes = runes[:len(runes)-1]
+	}
+	return string(runes) + "..."
+}
+
+func initials(name string) string {
+	var b strings.Builder
+	for _, word := range strings.Fields(name) {
+		b.WriteString(strings.ToUpper(string([]rune(word)[:1])))
+		if b.Len() >= 2 {
+			break
+		}
+	}
+	return b.String()
+}
+
+func orDash(value string) string {
+	if strings.TrimSpace(value) == "" {
+		return "-"
+	}
+	return value
+}
+
+// formatNumber formats credits without needless decimals
+func formatNumber(value float64) string {
+	return strings.TrimSuffix(fmt.Sprintf("%.1f", value), ".0")
+}
@@ -0,0 +1,399 @@
+package service
+
+import (
+	"backend/internal/domain"
+	"backend/internal/repository"
+	"backend/pkg/middleware"
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/jpeg" // decoders for profile photos
+	_ "image/png"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// idCardValidity is how long an ID card is valid after it is issued
+const idCardValidity = 365 * 24 * time.Hour
+
+// maxPhotoSide bounds the resolution of the photo on ID cards
+const maxPhotoSide = 320
+
+// DocumentService issues official PDF documents — transcripts, semester
+// grade reports and student ID cards — and verifies them. Every issued
+// document carries a verification code and is recorded with the SHA-256 of
+// its bytes, so anyone holding a copy can check that it is authentic and
+// unaltered.
+type DocumentService struct {
+	documentRepo    *repository.DocumentRepository
+	gradeRepo       *repository.GradeRepository
+	gradebook       *Gradebook
+	settings        *SettingsStore
+	uploadDirectory string
+	publicBaseURL   string
+	now             func() time.Time
+}
+
+// NewDocumentService creates a new document service
+func NewDocumentService(
+	documentRepo *repository.DocumentRepository,
+	gradeRepo *repository.GradeRepository,
+	gradebook *Gradebook,
+	settings *SettingsStore,
+	uploadDir string,
+	publicBaseURL string,
+) *DocumentService {
+	return &DocumentService{
+		documentRepo:    documentRepo,
+		gradeRepo:       gradeRepo,
+		gradebook:       gradebook,
+		settings:        settings,
+		uploadDirectory: uploadDir,
+		publicBaseURL:   publicBaseURL,
+		now:             time.Now,
+	}
+}
+
+// IssueTranscript issues a student's official transcript
+func (s *DocumentService) IssueTranscript(c echo.Context) error {
+	userID, err := s.holderFromParam(c)
+	if err != nil {
+		return err
+	}
+
+	transcript, err := s.gradebook.Transcript(userID)
+	if err != nil {
+		return documentSourceError(err)
+	}
+
+	return s.issue(c, domain.DocumentTypeTranscript, userID, "Official Academic Transcript", nil,
+		fmt.Sprintf("transcript-%s.pdf", fileNamePart(transcript.StudentID)),
+		func(stamp documentStamp) []byte {
+			return renderTranscript(transcript, stamp)
+		})
+}
+
+// IssueGradeReport issues a student's grade report for the semester given
+// by the "semester" and "year" query parameters
+func (s *DocumentService) IssueGradeReport(c echo.Context) error {
+	userID, err := s.holderFromParam(c)
+	if err != nil {
+		return err
+	}
+	semester := strings.TrimSpace(c.QueryParam("semester"))
+	year, err := strconv.Atoi(c.QueryParam("year"))
+	if semester == "" || err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "semester and year are required")
+	}
+
+	report, err := s.gradebook.GradeReport(userID, semester, year)
+	if err != nil {
+		return documentSourceError(err)
+	}
+	last := report.Semesters[len(report.Semesters)-1]
+
+	title := fmt.Sprintf("Grade Report %s %d", last.Semester, last.Year)
+	return s.issue(c, domain.DocumentTypeGradeReport, userID, title, nil,
+		fmt.Sprintf("grade-report-%s-%s-%d.pdf", fileNamePart(report.StudentID), fileNamePart(last.Semester), last.Year),
+		func(stamp documentStamp) []byte {
+			return renderGradeReport(report, stamp)
+		})
+}
+
+// IssueIDCard issues a student ID card with the student's profile photo
+func (s *DocumentService) IssueIDCard(c echo.Context) error {
+	userID, err := s.holderFromParam(c)
+	if err != nil {
+		return err
+	}
+
+	user, err := s.gradeRepo.GetStudent(userID)
+	if err != nil {
+		return documentSourceError(err)
+	}
+	if user.Role != "student" {
+		return echo.NewHTTPError(http.StatusBadRequest, "ID cards are only issued to students")
+	}
+	photo := s.loadPhoto(user.ProfilePhotoURL)
+
+	validUntil := s.now().Add(idCardValidity)
+	return s.issue(c, domain.DocumentTypeIDCard, userID, "Student ID Card", &validUntil,
+		fmt.Sprintf("id-card-%s.pdf", fileNamePart(user.StudentInfo.StudentID)),
+		func(stamp documentStamp) []byte {
+			return renderIDCard(user, photo, validUntil, stamp)
+		})
+}
+
+// GetUserDocuments lists the documents issued to a user
+func (s *DocumentService) GetUserDocuments(c echo.Context) error {
+	userID, err := s.holderFromParam(c)
+	if err != nil {
+		return err
+	}
+
+	documents, err := s.documentRepo.GetUserDocuments(userID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to get documents")
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"items": documents,
+	})
+}
+
+// RevokeDocument revokes an issued document, e.g. after a grade was
+// corrected; verification reports it as revoked from then on
+func (s *DocumentService) RevokeDocument(c echo.Context) error {
+	document, err := s.documentRepo.GetByCode(normalizeDocumentCode(c.Param("code")))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, "Document not found")
+	}
+	if document.RevokedAt != nil {
+		return echo.NewHTTPError(http.StatusConflict, "The document is already revoked")
+	}
+
+	var req domain.RevokeDocumentRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+	if err := c.Validate(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	now := s.now()
+	document.RevokedAt = &now
+	document.RevokeReason = strings.TrimSpace(req.Reason)
+	if err := s.documentRepo.Save(document); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to revoke document")
+	}
+	return c.JSON(http.StatusOK, document)
+}
+
+// VerifyDocument is the public verification endpoint. It confirms that the
+// verification code belongs to a document issued by the institution and
+// whether that document is still valid. When a copy of the PDF is uploaded
+// as "file", it also reports whether the copy is byte for byte the one
+// issued.
+func (s *DocumentService) VerifyDocument(c echo.Context) error {
+	document, err := s.documentRepo.GetByCode(normalizeDocumentCode(c.Param("code")))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, "No document was issued with this verification code")
+	}
+
+	verification := domain.DocumentVerification{
+		Code:        formatDocumentCode(document.Code),
+		Valid:       true,
+		Status:      "valid",
+		Type:        document.Type,
+		Title:       document.Title,
+		HolderName:  document.User.Name,
+		Institution: s.settings.Current().InstitutionName,
+		IssuedAt:    document.IssuedAt,
+		ValidUntil:  document.ValidUntil,
+		RevokedAt:   document.RevokedAt,
+		Checksum:    document.Checksum,
+	}
+	switch {
+	case document.RevokedAt != nil:
+		verification.Valid, verification.Status = false, "revoked"
+	case document.ValidUntil != nil && s.now().After(*document.ValidUntil):
+		verification.Valid, verification.Status = false, "expired"
+	}
+
+	if c.Request().Method == http.MethodPost {
+		file, err := c.FormFile("file")
+		if err != nil {
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				return echo.NewHTTPError(http.StatusRequestEntityTooLarge, "File is too large")
+			}
+			return echo.NewHTTPError(http.StatusBadRequest, "No file uploaded")
+		}
+		src, err := file.Open()
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Failed to read uploaded file")
+		}
+		defer src.Close()
+
+		hash := sha256.New()
+		if _, err := io.Copy(hash, src); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Failed to read uploaded file")
+		}
+		unaltered := hex.EncodeToString(hash.Sum(nil)) == document.Checksum
+		verification.Unaltered = &unaltered
+		if !unaltered {
+			verification.Valid = false
+		}
+	}
+
+	return c.JSON(http.StatusOK, verification)
+}
+
+// issue renders a document stamped with a new verification code, records
+// it and sends it as a PDF download
+func (s *DocumentService) issue(c echo.Context, docType string, userID uint, title string, validUntil *time.Time,
+	filename string, render func(documentStamp) []byte) error {
+	issuerID, err := middleware.GetUserIDFromToken(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Invalid token")
+	}
+	code, err := newDocumentCode()
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to issue document")
+	}
+
+	now := s.now().UTC().Truncate(time.Second)
+	data := render(documentStamp{
+		Institution: s.settings.Current().InstitutionName,
+		Code:        formatDocumentCode(code),
+		VerifyURL:   verifyURL(s.publicBaseURL, code),
+		IssuedAt:    now,
+	})
+	sum := sha256.Sum256(data)
+
+	document := &domain.IssuedDocument{
+		Code:       code,
+		Type:       docType,
+		UserID:     userID,
+		Title:      title,
+		Checksum:   hex.EncodeToString(sum[:]),
+		Size:       int64(len(data)),
+		IssuedBy:   issuerID,
+		IssuedAt:   now,
+		ValidUntil: validUntil,
+	}
+	if err := s.documentRepo.Create(document); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to issue document")
+	}
+
+	c.Response().Header().Set("X-Document-Code", document.Code)
+	c.Response().Header().Set("X-Checksum-SHA256", document.Checksum)
+	c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", filename))
+	return c.Blob(http.StatusOK, "application/pdf", data)
+}
+
+// holderFromParam parses the user named by ":id", who students may only be
+// themselves; admins may name anyone
+func (s *DocumentService) holderFromParam(c echo.Context) (uint, error) {
+	currentUserID, err := middleware.GetUserIDFromToken(c)
+	if err != nil {
+		return 0, echo.NewHTTPError(http.StatusUnauthorized, "Invalid token")
+	}
+	role, err := middleware.GetRoleFromToken(c)
+	if err != nil {
+		return 0, echo.NewHTTPError(http.StatusUnauthorized, "Invalid token")
+	}
+
+	userID, err := parseIDParam(c, "id")
+	if err != nil {
+		return 0, echo.NewHTTPError(http.StatusBadRequest, "Invalid user ID")
+	}
+	if role != "admin" && userID != currentUserID {
+		return 0, echo.NewHTTPError(http.StatusForbidden, "You can only obtain your own documents")
+	}
+	return userID, nil
+}
+
+// loadPhoto loads and shrinks a profile photo; documents are issued
+// without a photo when it is missing or unreadable
+func (s *DocumentService) loadPhoto(relativePath string) image.Image {
+	if relativePath == "" || s.uploadDirectory == "" {
+		return nil
+	}
+	f, err := os.Open(filepath.Join(s.uploadDirectory, filepath.Clean("/"+relativePath)))
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return nil
+	}
+	return shrinkImage(img, maxPhotoSide)
+}
+
+// shrinkImage scales an image down so that neither side exceeds maxSide
+func shrinkImage(img image.Image, maxSide int) image.Image {
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	if w <= maxSide && h <= maxSide {
+		return img
+	}
+	scale := float64(maxSide) / float64(w)
+	if h > w {
+		scale = float64(maxSide) / float64(h)
+	}
+	dw, dh := int(float64(w)*scale), int(float64(h)*scale)
+	if dw < 1 {
+		dw = 1
+	}
+	if dh < 1 {
+		dh = 1
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		for x := 0; x < dw; x++ {
+			dst.Set(x, y, img.At(bounds.Min.X+int(float64(x)/scale), bounds.Min.Y+int(float64(y)/scale)))
+		}
+	}
+	return dst
+}
+
+// documentSourceError maps failures to load a document's data to HTTP errors
+func documentSourceError(err error) error {
+	switch {
+	case errors.Is(err, ErrSemesterNotFound):
+		return echo.NewHTTPError(http.StatusNotFound, err.Error())
+	case err.Error() == "user not found":
+		return echo.NewHTTPError(http.StatusNotFound, "User not found")
+	default:
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to load document data")
+	}
+}
+
+// newDocumentCode generates a random verification code of 16 base32 characters
+func newDocumentCode() (string, error) {
+	buf := make([]byte, 10)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base32.StdEncoding.EncodeToString(buf), nil
+}
+
+// formatDocumentCode groups a code in blocks of four for printing
+func formatDocumentCode(code string) string {
+	var b bytes.Buffer
+	for i, r := range code {
+		if i > 0 && i%4 == 0 {
+			b.WriteByte('-')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// normalizeDocumentCode accepts codes as printed, in any case and with or
+// without separators
+func normalizeDocumentCode(code string) string {
+	code = strings.ToUpper(code)
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
+
+// verifyURL is the public verification address of a code, under the
+// configured public base URL
+func verifyURL(base, code string) string {
+	return fmt.Sprintf("%s/api/v1/verify/%s", base, formatDocumentCode(code))
+}
@@ -0,0 +1,302 @@
+package service
+
+import (
+	"backend/internal/domain"
+	"backend/internal/repository"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Enrollment errors returned by EnrollmentManager
+var (
+	ErrAlreadyEnrolled        = errors.New("student is already enrolled in or has completed this course")
+	ErrEnrollmentNotFound     = errors.New("enrollment not found")
+	ErrAddDeadlinePassed      = errors.New("the add deadline for this course has passed")
+	ErrWithdrawDeadlinePassed = errors.New("the withdraw deadline for this course has passed")
+	ErrInvalidTransition      = errors.New("enrollment status change not allowed")
+)
+
+// EnrollmentAction describes who changes which enrollment and why.
+// Override lets the registrar bypass add/drop deadlines.
+type EnrollmentAction struct {
+	CourseID uint
+	UserID   uint
+	ActorID  uint
+	Reason   string
+	Override bool
+	// Approved places the student directly, skipping the course's approval step
+	Approved bool
+}
+
+// EnrollmentManager implements the enrollment lifecycle:
+// pending -> enrolled/waitlisted -> dropped/withdrawn/completed.
+// Seats are allocated under a row lock on the course so concurrent requests
+// never overbook it, and freed seats are handed to the waitlist in FIFO order.
+type EnrollmentManager struct {
+	enrollmentRepo *repository.EnrollmentRepository
+	gradebook      *Gradebook
+	now            func() time.Time
+}
+
+// NewEnrollmentManager creates a new enrollment manager
+func NewEnrollmentManager(enrollmentRepo *repository.EnrollmentRepository, gradebook *Gradebook) *EnrollmentManager {
+	return &EnrollmentManager{
+		enrollmentRepo: enrollmentRepo,
+		gradebook:      gradebook,
+		now:            time.Now,
+	}
+}
+
+// Enroll requests a seat for a student. The enrollment stays pending when
+// the course requires approval, otherwise the student is enrolled or
+// waitlisted straight away.
+func (m *EnrollmentManager) Enroll(action EnrollmentAction) (*domain.CourseStudent, error) {
+	var result *domain.CourseStudent
+	err := m.enrollmentRepo.Transaction(func(tx *repository.EnrollmentRepository) error {
+		enrollment, err := m.enroll(tx, action)
+		result = enrollment
+		return err
+	})
+	return result, err
+}
+
+// EnrollTx is Enroll running inside an existing enrollment transaction
+func (m *EnrollmentManager) EnrollTx(tx *repository.EnrollmentRepository, action EnrollmentAction) (*domain.CourseStudent, error) {
+	return m.enroll(tx, action)
+}
+
+func (m *EnrollmentManager) enroll(tx *repository.EnrollmentRepository, action EnrollmentAction) (*domain.CourseStudent, error) {
+	course, err := tx.LockCourse(action.CourseID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := m.now()
+	if course.AddDeadline != nil && now.After(*course.AddDeadline) && !action.Override {
+		return nil, ErrAddDeadlinePassed
+	}
+
+	enrollment, err := tx.GetEnrollment(action.CourseID, action.UserID)
+	if err != nil {
+		enrollment = &domain.CourseStudent{
+			CourseID: action.CourseID,
+			UserID:   action.UserID,
+		}
+	}
+	if !domain.CanTransitionEnrollment(enrollment.Status, domain.EnrollmentStatusPending) {
+		return nil, ErrAlreadyEnrolled
+	}
+
+	enrollment.EnrolledAt = now
+	enrollment.WaitlistedAt = nil
+	if err := m.transition(tx, enrollment, domain.EnrollmentStatusPending, action); err != nil {
+		return nil, err
+	}
+
+	if course.RequiresApproval && !action.Approved {
+		return enrollment, nil
+	}
+	if err := m.place(tx, course, enrollment, action); err != nil {
+		return nil, err
+	}
+	return enrollment, nil
+}
+
+// Approve moves a pending enrollment to enrolled, or to the waitlist when the course is full
+func (m *EnrollmentManager) Approve(action EnrollmentAction) (*domain.CourseStudent, error) {
+	var result *domain.CourseStudent
+	err := m.enrollmentRepo.Transaction(func(tx *repository.EnrollmentRepository) error {
+		course, err := tx.LockCourse(action.CourseID)
+		if err != nil {
+			return err
+		}
+		enrollment, err := tx.GetEnrollment(action.CourseID, action.UserID)
+		if err != nil {
+			return ErrEnrollmentNotFound
+		}
+		if enrollment.Status != domain.EnrollmentStatusPending {
+			return ErrInvalidTransition
+		}
+		if err := m.place(tx, course, enrollment, action); err != nil {
+			return err
+		}
+		result = enrollment
+		return nil
+	})
+	return result, err
+}
+
+// Drop takes a student out of a course. Enrolled students are dropped until
+// the drop deadline and withdrawn until the withdraw deadline; pending and
+// waitlisted requests can always be dropped. A freed seat goes to the waitlist.
+func (m *EnrollmentManager) Drop(action EnrollmentAction) (*domain.CourseStudent, error) {
+	var result *domain.CourseStudent
+	err := m.enrollmentRepo.Transaction(func(tx *repository.EnrollmentRepository) error {
+		course, err := tx.LockCourse(action.CourseID)
+		if err != nil {
+			return err
+		}
+		enrollment, err := tx.GetEnrollment(action.CourseID, action.UserID)
+		if err != nil {
+			return ErrEnrollmentNotFound
+		}
+
+		now := m.now()
+		wasEnrolled := enrollment.Status == domain.EnrollmentStatusEnrolled
+		target := domain.EnrollmentStatusDropped
+		if wasEnrolled && course.DropDeadline != nil && now.After(*course.DropDeadline) {
+			if course.WithdrawDeadline != nil && now.After(*course.WithdrawDeadline) && !action.Override {
+				return ErrWithdrawDeadlinePassed
+			}
+			target = domain.EnrollmentStatusWithdrawn
+		}
+
+		if err := m.transition(tx, enrollment, target, action); err != nil {
+			return err
+		}
+		result = enrollment
+
+		if wasEnrolled {
+			return m.promoteWaitlist(tx, course, action.ActorID)
+		}
+		return nil
+	})
+	if err == nil {
+		// Dropped and withdrawn courses no longer count toward the GPA
+		m.gradebook.EnrollmentChanged(action.UserID)
+	}
+	return result, err
+}
+
+// Complete marks an enrolled student as having finished the course
+func (m *EnrollmentManager) Complete(action EnrollmentAction) (*domain.CourseStudent, error) {
+	var result *domain.CourseStudent
+	err := m.enrollmentRepo.Transaction(func(tx *repository.EnrollmentRepository) error {
+		enrollment, err := tx.GetEnrollment(action.CourseID, action.UserID)
+		if err != nil {
+			return ErrEnrollmentNotFound
+		}
+		if err := m.transition(tx, enrollment, domain.EnrollmentStatusCompleted, action); err != nil {
+			return err
+		}
+		result = enrollment
+		return nil
+	})
+	return result, err
+}
+
+// PromoteWaitlist fills any free seats of a course from its waitlist,
+// e.g. after the course capacity was raised
+func (m *EnrollmentManager) PromoteWaitlist(courseID, actorID uint) error {
+	return m.enrollmentRepo.Transaction(func(tx *repository.EnrollmentRepository) error {
+		course, err := tx.LockCourse(courseID)
+		if err != nil {
+			return err
+		}
+		return m.promoteWaitlist(tx, course, actorID)
+	})
+}
+
+// place enrolls the student when a seat is free and nobody is queued ahead,
+// otherwise appends them to the waitlist
+func (m *EnrollmentManager) place(tx *repository.EnrollmentRepository, course *domain.Course, enrollment *domain.CourseStudent, action EnrollmentAction) error {
+	hasSeat, err := m.hasFreeSeat(tx, course)
+	if err != nil {
+		return err
+	}
+	if hasSeat {
+		next, err := tx.NextWaitlisted(course.ID)
+		if err != nil {
+			return err
+		}
+		hasSeat = next == nil
+	}
+
+	now := m.now()
+	if hasSeat {
+		enrollment.EnrolledAt = now
+		return m.transition(tx, enrollment, domain.EnrollmentStatusEnrolled, action)
+	}
+	enrollment.WaitlistedAt = &now
+	return m.transition(tx, enrollment, domain.EnrollmentStatusWaitlisted, action)
+}
+
+func (m *EnrollmentManager) promoteWaitlist(tx *repository.EnrollmentRepository, course *domain.Course, actorID uint) error {
+	for {
+		hasSeat, err := m.hasFreeSeat(tx, course)
+		if err != nil || !hasSeat {
+			return err
+		}
+		next, err := tx.NextWaitlisted(course.ID)
+		if err != nil || next == nil {
+			return err
+		}
+
+		next.EnrolledAt = m.now()
+		action := EnrollmentAction{
+			CourseID: course.ID,
+			UserID:   next.UserID,
+			ActorID:  actorID,
+			Reason:   "promoted from waitlist",
+		}
+		if err := m.transition(tx, next, domain.EnrollmentStatusEnrolled, action); err != nil {
+			return err
+		}
+	}
+}
+
+func (m *EnrollmentManager) hasFreeSeat(tx *repository.EnrollmentRepository, course *domain.Course) (bool, error) {
+	if course.Capacity <= 0 {
+		return true, nil
+	}
+	enrolled, err := tx.CountByStatus(course.ID, domain.EnrollmentStatusEnrolled)
+	if err != nil {
+		return false, err
+	}
+	return enrolled < int64(course.Capacity), nil
+}
+
+// transition moves the enrollment to a new status and records who did it and when
+func (m *EnrollmentManager) transition(tx *repository.EnrollmentRepository, enrollment *domain.CourseStudent, to string, action EnrollmentAction) error {
+	from := enrollment.Status
+	if !domain.CanTransitionEnrollment(from, to) {
+		return ErrInvalidTransition
+	}
+
+	enrollment.Status = to
+	if err := tx.Save(enrollment); err != nil {
+		return err
+	}
+
+	return tx.CreateTransition(&domain.EnrollmentTransition{
+		CourseStudentID: enrollment.ID,
+		CourseID:        enrollment.CourseID,
+		UserID:          enrollment.UserID,
+		FromStatus:      from,
+		ToStatus:        to,
+		Reason:          action.Reason,
+		ChangedBy:       action.ActorID,
+		ChangedAt:       m.now(),
+	})
+}
+
+// enrollmentHTTPError maps enrollment errors to HTTP errors
+func enrollmentHTTPError(err error) error {
+	switch {
+	case errors.Is(err, ErrAlreadyEnrolled):
+		return echo.NewHTTPError(http.StatusConflict, err.Error())
+	case errors.Is(err, ErrEnrollmentNotFound):
+		return echo.NewHTTPError(http.StatusNotFound, "Enrollment not found")
+	case errors.Is(err, ErrAddDeadlinePassed), errors.Is(err, ErrWithdrawDeadlinePassed):
+		return echo.NewHTTPError(http.StatusForbidden, err.Error())
+	case errors.Is(err, ErrInvalidTransition):
+		return echo.NewHTTPError(http.StatusConflict, err.Error())
+	case err.Error() == "course not found":
+		return echo.NewHTTPError(http.StatusNotFound, "Course not found")
+	default:
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to update enrollment")
+	}
+}
@@ -0,0 +1,122 @@
+package service
+
+import (
+	"backend/internal/domain"
+	"backend/internal/repository"
+	crand "crypto/rand"
+	"encoding/binary"
+	"encoding/json"
+	"math/rand"
+)
+
+// newAttemptSeed returns a random seed for a new attempt
+func newAttemptSeed() (int64, error) {
+	var buf [8]byte
+	if _, err := crand.Read(buf[:]); err != nil {
+		return 0, err
+	}
+	return int64(binary.BigEndian.Uint64(buf[:]) &^ (1 << 63)), nil
+}
+
+// questionSource is what drawing an attempt's questions reads and writes;
+// the exam repository inside the attempt's transaction
+type questionSource interface {
+	GetFixedQuestions(examID uint) ([]domain.ExamQuestion, error)
+	GetPools(examID uint) ([]domain.ExamQuestionPool, error)
+	FindPoolCandidates(courseID uint, pool *domain.ExamQuestionPool) ([]domain.BankQuestion, error)
+	EnsurePoolQuestion(question *domain.ExamQuestion) (*domain.ExamQuestion, error)
+}
+
+// drawQuestions picks the questions of a new attempt: every fixed question
+// of the exam and DrawCount bank questions from each of its pools. The seed
+// alone decides which questions are drawn and, for exams with
+// RandomizeQuestions, their order.
+func drawQuestions(tx questionSource, exam *domain.Exam, seed int64) ([]uint, error) {
+	rng := rand.New(rand.NewSource(seed))
+
+	fixed, err := tx.GetFixedQuestions(exam.ID)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]uint, 0, len(fixed))
+	for _, question := range fixed {
+		ids = append(ids, question.ID)
+	}
+
+	pools, err := tx.GetPools(exam.ID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range pools {
+		pool := &pools[i]
+		candidates, err := tx.FindPoolCandidates(exam.CourseID, pool)
+		if err != nil {
+			return nil, err
+		}
+		rng.Shuffle(len(candidates), func(a, b int) {
+			candidates[a], candidates[b] = candidates[b], candidates[a]
+		})
+		if len(candidates) > pool.DrawCount {
+			candidates = candidates[:pool.DrawCount]
+		}
+
+		for j := range candidates {
+			question := candidates[j].ToExamQuestion(exam.ID)
+			question.PoolID = &pool.ID
+			if pool.Points > 0 {
+				question.Points = pool.Points
+			}
+			drawn, err := tx.EnsurePoolQuestion(&question)
+			if err != nil {
+				return nil, err
+			}
+			ids = append(ids, drawn.ID)
+		}
+	}
+
+	if exam.RandomizeQuestions {
+		rng.Shuffle(len(ids), func(a, b int) { ids[a], ids[b] = ids[b], ids[a] })
+	}
+	return ids, nil
+}
+
+// attemptQuestions retrieves the questions of an attempt in delivery order
+func attemptQuestions(repo *repository.ExamRepository, attempt *domain.ExamAttempt) ([]domain.ExamQuestion, error) {
+	ids := attempt.QuestionIDList()
+	if len(ids) == 0 {
+		return repo.GetFixedQuestions(attempt.ExamID)
+	}
+	return repo.GetQuestionsByIDs(ids)
+}
+
+// findQuestion returns the question with the given ID, or nil
+func findQuestion(questions []domain.ExamQuestion, id uint) *domain.ExamQuestion {
+	for i := range questions {
+		if questions[i].ID == id {
+			return &questions[i]
+		}
+	}
+	return nil
+}
+
+// shuffleOptions returns the question's options in an order derived from the
+// attempt's seed, so that a student sees the same order on every reload.
+// Only choice questions are shuffled; answers name options by their text,
+// so grading does not depend on the order.
+func shuffleOptions(question *domain.ExamQuestion, seed int64) string {
+	if question.Type != domain.QuestionTypeMultipleChoice && question.Type != domain.QuestionTypeMultiSelect {
+		return question.Options
+	}
+	var options []json.RawMessage
+	if err := json.Unmarshal([]byte(question.Options), &options); err != nil {
+		return question.Options
+	}
+
+	rng := rand.New(rand.NewSource(seed ^ int64(question.ID)*0x5bd1e995))
+	rng.Shuffle(len(options), func(a, b int) { options[a], options[b] = options[b], options[a] })
+	shuffled, err := json.Marshal(options)
+	if err != nil {
+		return question.Options
+	}
+	return string(shuffled)
+}
@@ -0,0 +1,197 @@
+package service
+
+import (
+	"backend/internal/domain"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// fakeQuestionSource serves an exam's questions from memory. Drawn bank
+// question n becomes exam question 1000+n, the same on every draw.
+type fakeQuestionSource struct {
+	fixed      []domain.ExamQuestion
+	pools      []domain.ExamQuestionPool
+	candidates map[uint][]domain.BankQuestion
+	ensured    []domain.ExamQuestion
+}
+
+func (f *fakeQuestionSource) GetFixedQuestions(examID uint) ([]domain.ExamQuestion, error) {
+	return f.fixed, nil
+}
+
+func (f *fakeQuestionSource) GetPools(examID uint) ([]domain.ExamQuestionPool, error) {
+	return f.pools, nil
+}
+
+func (f *fakeQuestionSource) FindPoolCandidates(courseID uint, pool *domain.ExamQuestionPool) ([]domain.BankQuestion, error) {
+	// The caller shuffles what it gets
+	return append([]domain.BankQuestion(nil), f.candidates[pool.ID]...), nil
+}
+
+func (f *fakeQuestionSource) EnsurePoolQuestion(question *domain.ExamQuestion) (*domain.ExamQuestion, error) {
+	drawn := *question
+	drawn.ID = 1000 + *question.BankQuestionID
+	f.ensured = append(f.ensured, drawn)
+	return &drawn, nil
+}
+
+func newFakeQuestionSource() *fakeQuestionSource {
+	f := &fakeQuestionSource{
+		fixed: []domain.ExamQuestion{{ID: 1}, {ID: 2}, {ID: 3}},
+		pools: []domain.ExamQuestionPool{
+			{ID: 10, DrawCount: 2, Points: 5},
+			{ID: 20, DrawCount: 3},
+		},
+		candidates: map[uint][]domain.BankQuestion{},
+	}
+	for id := uint(100); id < 110; id++ {
+		f.candidates[10] = append(f.candidates[10], domain.BankQuestion{ID: id, Points: 1})
+	}
+	for id := uint(200); id < 220; id++ {
+		f.candidates[20] = append(f.candidates[20], domain.BankQuestion{ID: id, Points: 2})
+	}
+	return f
+}
+
+func TestDrawQuestionsIsReproducible(t *testing.T) {
+	tests := []struct {
+		name      string
+		randomize bool
+	}{
+		{"fixed order", false},
+		{"randomized order", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exam := &domain.Exam{ID: 1, CourseID: 1, RandomizeQuestions: tt.randomize}
+			for _, seed := range []int64{0, 1, 42, 1 << 40} {
+				first, err := drawQuestions(newFakeQuestionSource(), exam, seed)
+				if err != nil {
+					t.Fatal(err)
+				}
+				again, err := drawQuestions(newFakeQuestionSource(), exam, seed)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !reflect.DeepEqual(first, again) {
+					t.Errorf("seed %d drew %v, then %v", seed, first, again)
+				}
+				if len(first) != 3+2+3 {
+					t.Errorf("seed %d drew %d questions, want 8", seed, len(first))
+				}
+				if !tt.randomize && !reflect.DeepEqual(first[:3], []uint{1, 2, 3}) {
+					t.Errorf("seed %d moved the fixed questions: %v", seed, first)
+				}
+			}
+		})
+	}
+}
+
+func TestDrawQuestionsVariesWithSeed(t *testing.T) {
+	exam := &domain.Exam{ID: 1, CourseID: 1, RandomizeQuestions: true}
+	draws := make(map[string]bool)
+	for seed := int64(0); seed < 20; seed++ {
+		ids, err := drawQuestions(newFakeQuestionSource(), exam, seed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		key, _ := json.Marshal(ids)
+		draws[string(key)] = true
+	}
+	if len(draws) < 10 {
+		t.Errorf("20 seeds gave only %d different draws", len(draws))
+	}
+}
+
+func TestDrawQuestionsFromPools(t *testing.T) {
+	source := newFakeQuestionSource()
+	exam := &domain.Exam{ID: 1, CourseID: 1}
+	ids, err := drawQuestions(source, exam, 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	seen := make(map[uint]bool)
+	for _, id := range ids {
+		if seen[id] {
+			t.Errorf("question %d drawn twice in %v", id, ids)
+		}
+		seen[id] = true
+	}
+	perPool := make(map[uint]int)
+	for _, question := range source.ensured {
+		perPool[*question.PoolID]++
+		switch *question.PoolID {
+		case 10:
+			if question.Points != 5 {
+				t.Errorf("pool 10 question worth %v points, want the pool's 5", question.Points)
+			}
+		case 20:
+			if question.Points != 2 {
+				t.Errorf("pool 20 question worth %v points, want the bank question's 2", question.Points)
+			}
+		}
+	}
+	if perPool[10] != 2 || perPool[20] != 3 {
+		t.Errorf("drew %v per pool, want 2 from pool 10 and 3 from pool 20", perPool)
+	}
+}
+
+func TestDrawQuestionsSmallPool(t *testing.T) {
+	source := newFakeQuestionSource()
+	source.pools = []domain.ExamQuestionPool{{ID: 10, DrawCount: 50}}
+	ids, err := drawQuestions(source, &domain.Exam{ID: 1, CourseID: 1}, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 3+10 {
+		t.Errorf("drew %d questions, want the 3 fixed ones and all 10 candidates", len(ids))
+	}
+}
+
+func TestShuffleOptions(t *testing.T) {
+	options := `["A","B","C","D","E","F"]`
+	tests := []struct {
+		name     string
+		question domain.ExamQuestion
+		shuffled bool
+	}{
+		{"multiple choice", domain.ExamQuestion{ID: 5, Type: domain.QuestionTypeMultipleChoice, Options: options}, true},
+		{"multi select", domain.ExamQuestion{ID: 6, Type: domain.QuestionTypeMultiSelect, Options: options}, true},
+		{"numeric", domain.ExamQuestion{ID: 7, Type: domain.QuestionTypeNumeric, Options: options}, false},
+		{"invalid options", domain.ExamQuestion{ID: 8, Type: domain.QuestionTypeMultipleChoice, Options: "A, B"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := shuffleOptions(&tt.question, 99)
+			if again := shuffleOptions(&tt.question, 99); got != again {
+				t.Errorf("same seed gave %s, then %s", got, again)
+			}
+			if !tt.shuffled {
+				if got != tt.question.Options {
+					t.Errorf("shuffleOptions = %s, want options unchanged", got)
+				}
+				return
+			}
+
+			var shuffled []string
+			if err := json.Unmarshal([]byte(got), &shuffled); err != nil {
+				t.Fatalf("shuffleOptions = %s: %v", got, err)
+			}
+			sort.Strings(shuffled)
+			if !reflect.DeepEqual(shuffled, []string{"A", "B", "C", "D", "E", "F"}) {
+				t.Errorf("shuffleOptions = %s, want the same options", got)
+			}
+
+			orders := make(map[string]bool)
+			for seed := int64(0); seed < 20; seed++ {
+				orders[shuffleOptions(&tt.question, seed)] = true
+			}
+			if len(orders) < 5 {
+				t.Errorf("20 seeds gave only %d option orders", len(orders))
+			}
+		})
+	}
+}
@@ -0,0 +1,433 @@
+package service
+
+import (
+	"backend/internal/domain"
+	"backend/internal/repository"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Exam delivery errors returned by ExamEngine
+var (
+	ErrExamNotYetAvailable = errors.New("the exam is not available yet")
+	ErrExamClosed          = errors.New("the exam is closed")
+	ErrExamHasNoQuestions  = errors.New("the exam has no questions")
+	ErrMaxAttemptsReached  = errors.New("maximum number of attempts reached")
+	ErrAttemptFinished     = errors.New("the attempt has already been submitted")
+	ErrAttemptExpired      = errors.New("the time limit has passed; the attempt was submitted automatically")
+	ErrQuestionNotInExam   = errors.New("question does not belong to this exam")
+	ErrAttemptNotFinished  = errors.New("the attempt has not been submitted yet")
+	ErrPointsExceedMaximum = errors.New("points exceed the question's maximum")
+)
+
+// answerGracePeriod tolerates autosaves and submissions that were sent just
+// before the deadline but arrive shortly after it
+const answerGracePeriod = 5 * time.Second
+
+// ExamEngine delivers exams: it starts attempts within the exam's
+// availability window and attempt limit, keeps the authoritative deadline,
+// autosaves answers and submits attempts, automatically once time is up.
+// Finished and graded attempts are passed on to the gradebook. Students
+// whose attendance is below their course's policy cannot start the exam
+// types it gates.
+type ExamEngine struct {
+	examRepo   *repository.ExamRepository
+	graders    *GraderRegistry
+	gradebook  *Gradebook
+	attendance *AttendancePolicyEngine
+	now        func() time.Time
+}
+
+// NewExamEngine creates a new exam engine
+func NewExamEngine(examRepo *repository.ExamRepository, graders *GraderRegistry, gradebook *Gradebook,
+	attendance *AttendancePolicyEngine) *ExamEngine {
+	return &ExamEngine{
+		examRepo:   examRepo,
+		graders:    graders,
+		gradebook:  gradebook,
+		attendance: attendance,
+		now:        time.Now,
+	}
+}
+
+// ValidateQuestion checks that a question's options and correct answer can
+// be graded by the grader of its type
+func (e *ExamEngine) ValidateQuestion(question *domain.ExamQuestion) error {
+	return e.graders.Validate(question)
+}
+
+// Start begins a new attempt at the exam for a student. An attempt that is
+// still running is returned instead, with resumed set, so that a reload of
+// the exam page does not use up another attempt.
+func (e *ExamEngine) Start(exam *domain.Exam, userID uint) (attempt *domain.ExamAttempt, resumed bool, err error) {
+	finished := false
+	err = e.examRepo.Transaction(func(tx *repository.ExamRepository) error {
+		if err := tx.LockStudentExam(exam.ID, userID); err != nil {
+			return err
+		}
+
+		attempts, err := tx.GetUserAttempts(exam.ID, userID)
+		if err != nil {
+			return err
+		}
+
+		now := e.now()
+		for i := range attempts {
+			if attempts[i].IsFinished() {
+				continue
+			}
+			if !e.isOverdue(&attempts[i], now) {
+				attempt, resumed = &attempts[i], true
+				return nil
+			}
+			if err := e.finish(tx, &attempts[i], domain.ExamAttemptStatusTimedOut); err != nil {
+				return err
+			}
+			finished = true
+		}
+
+		if !exam.AvailableFrom.IsZero() && now.Before(exam.AvailableFrom) {
+			return ErrExamNotYetAvailable
+		}
+		if !exam.AvailableTo.IsZero() && !now.Before(exam.AvailableTo) {
+			return ErrExamClosed
+		}
+		if exam.MaxAttempts > 0 && len(attempts) >= exam.MaxAttempts {
+			return ErrMaxAttemptsReached
+		}
+		if err := e.attendance.CheckExamEligibility(exam, userID); err != nil {
+			return err
+		}
+
+		seed, err := newAttemptSeed()
+		if err != nil {
+			return err
+		}
+		questionIDs, err := drawQuestions(tx, exam, seed)
+		if err != nil {
+			return err
+		}
+		if len(questionIDs) == 0 {
+			return ErrExamHasNoQuestions
+		}
+
+		duration, err := domain.ParseExamDuration(exam.Duration)
+		if err != nil {
+			return err
+		}
+		deadline := now.Add(duration)
+		if !exam.AvailableTo.IsZero() && exam.AvailableTo.Before(deadline) {
+			deadline = exam.AvailableTo
+		}
+
+		attempt = &domain.ExamAttempt{
+			ExamID:        exam.ID,
+			UserID:        userID,
+			StartTime:     now,
+			Deadline:      deadline,
+			Status:        domain.ExamAttemptStatusInProgress,
+			AttemptNumber: len(attempts) + 1,
+			Seed:          seed,
+		}
+		attempt.SetQuestionIDList(questionIDs)
+		return tx.CreateAttempt(attempt)
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	if finished {
+		e.gradebook.ResultChanged(domain.GradeItemExam, exam.ID, userID)
+	}
+	return attempt, resumed, nil
+}
+
+// Attempt retrieves an attempt with its answers, submitting it first when
+// its time is up
+func (e *ExamEngine) Attempt(attemptID uint) (*domain.ExamAttempt, error) {
+	attempt, err := e.examRepo.GetAttempt(attemptID)
+	if err != nil {
+		return nil, err
+	}
+	if attempt.IsFinished() || !e.isOverdue(attempt, e.now()) {
+		return attempt, nil
+	}
+
+	if err := e.Expire(attemptID); err != nil {
+		return nil, err
+	}
+	return e.examRepo.GetAttempt(attemptID)
+}
+
+// SaveAnswer stores a student's answer to one question of a running attempt.
+// Saving the same answer again leaves the attempt unchanged.
+func (e *ExamEngine) SaveAnswer(attemptID, questionID uint, answer string) (*domain.ExamAnswer, error) {
+	var saved *domain.ExamAnswer
+	var expired *domain.ExamAttempt
+	err := e.examRepo.Transaction(func(tx *repository.ExamRepository) error {
+		attempt, err := tx.LockAttempt(attemptID)
+		if err != nil {
+			return err
+		}
+		if attempt.IsFinished() {
+			return ErrAttemptFinished
+		}
+		if e.isOverdue(attempt, e.now()) {
+			expired = attempt
+			return e.finish(tx, attempt, domain.ExamAttemptStatusTimedOut)
+		}
+
+		questions, err := attemptQuestions(tx, attempt)
+		if err != nil {
+			return err
+		}
+		if findQuestion(questions, questionID) == nil {
+			return ErrQuestionNotInExam
+		}
+
+		saved = &domain.ExamAnswer{
+			ExamAttemptID: attempt.ID,
+			QuestionID:    questionID,
+			Answer:        answer,
+			UpdatedAt:     e.now(),
+		}
+		return tx.UpsertAnswer(saved)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if expired != nil {
+		e.gradebook.ResultChanged(domain.GradeItemExam, expired.ExamID, expired.UserID)
+		return nil, ErrAttemptExpired
+	}
+	return saved, nil
+}
+
+// Submit finishes an attempt and scores it. Submitting an attempt that is
+// already finished returns it unchanged.
+func (e *ExamEngine) Submit(attemptID uint) (*domain.ExamAttempt, error) {
+	var finished *domain.ExamAttempt
+	err := e.examRepo.Transaction(func(tx *repository.ExamRepository) error {
+		attempt, err := tx.LockAttempt(attemptID)
+		if err != nil {
+			return err
+		}
+		if attempt.IsFinished() {
+			return nil
+		}
+
+		status := domain.ExamAttemptStatusCompleted
+		if e.isOverdue(attempt, e.now()) {
+			status = domain.ExamAttemptStatusTimedOut
+		}
+		finished = attempt
+		return e.finish(tx, attempt, status)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if finished != nil {
+		e.gradebook.ResultChanged(domain.GradeItemExam, finished.ExamID, finished.UserID)
+	}
+	return e.examRepo.GetAttempt(attemptID)
+}
+
+// Expire submits an attempt whose time is up; other attempts are left alone
+func (e *ExamEngine) Expire(attemptID uint) error {
+	var finished *domain.ExamAttempt
+	err := e.examRepo.Transaction(func(tx *repository.ExamRepository) error {
+		attempt, err := tx.LockAttempt(attemptID)
+		if err != nil {
+			return err
+		}
+		if attempt.IsFinished() || !e.isOverdue(attempt, e.now()) {
+			return nil
+		}
+		finished = attempt
+		return e.finish(tx, attempt, domain.ExamAttemptStatusTimedOut)
+	})
+	if err != nil {
+		return err
+	}
+	if finished != nil {
+		e.gradebook.ResultChanged(domain.GradeItemExam, finished.ExamID, finished.UserID)
+	}
+	return nil
+}
+
+// ExpireOverdue submits up to limit running attempts whose time is up and
+// returns how many were submitted
+func (e *ExamEngine) ExpireOverdue(limit int) (int, error) {
+	ids, err := e.examRepo.FindExpiredAttempts(e.now().Add(-answerGracePeriod), limit)
+	if err != nil {
+		return 0, err
+	}
+
+	for i, id := range ids {
+		if err := e.Expire(id); err != nil {
+			return i, err
+		}
+	}
+	return len(ids), nil
+}
+
+func (e *ExamEngine) isOverdue(attempt *domain.ExamAttempt, now time.Time) bool {
+	return now.After(attempt.Deadline.Add(answerGracePeriod))
+}
+
+// finish grades the attempt's answers and closes it with the given status
+func (e *ExamEngine) finish(tx *repository.ExamRepository, attempt *domain.ExamAttempt, status string) error {
+	questions, err := attemptQuestions(tx, attempt)
+	if err != nil {
+		return err
+	}
+	answers, err := tx.GetAnswers(attempt.ID)
+	if err != nil {
+		return err
+	}
+
+	byID := make(map[uint]*domain.ExamQuestion, len(questions))
+	for i := range questions {
+		byID[questions[i].ID] = &questions[i]
+	}
+	for i := range answers {
+		answer := &answers[i]
+		result := GradeResult{}
+		if question, ok := byID[answer.QuestionID]; ok {
+			result = e.graders.Grade(question, answer.Answer)
+		}
+		answer.Points = result.Points
+		answer.IsCorrect = result.IsCorrect
+		answer.NeedsManualGrading = result.Manual
+	}
+	if err := tx.SaveAnswers(answers); err != nil {
+		return err
+	}
+
+	attempt.Score, attempt.GradingStatus = scoreAttempt(attempt, questions, answers)
+	if attempt.GradingStatus == domain.GradingStatusGraded {
+		// Nothing was left to staff
+		attempt.GradingStatus = domain.GradingStatusAutoGraded
+	}
+
+	endTime := e.now()
+	if endTime.After(attempt.Deadline) {
+		endTime = attempt.Deadline
+	}
+	attempt.EndTime = &endTime
+	attempt.Status = status
+	return tx.SaveAttempt(attempt)
+}
+
+// GradeAnswer records the points a grader awarded to one answer of a
+// finished attempt and rescores the attempt. The attempt counts as graded
+// once no answers are left waiting for manual grading. Attempts regraded
+// through a regrade request keep the score it set; a further request
+// changes it.
+func (e *ExamEngine) GradeAnswer(attemptID, questionID uint, points float64, feedback string, graderID uint) (*domain.ExamAttempt, error) {
+	var graded *domain.ExamAttempt
+	err := e.examRepo.Transaction(func(tx *repository.ExamRepository) error {
+		attempt, err := tx.LockAttempt(attemptID)
+		if err != nil {
+			return err
+		}
+		if !attempt.IsFinished() {
+			return ErrAttemptNotFinished
+		}
+
+		questions, err := attemptQuestions(tx, attempt)
+		if err != nil {
+			return err
+		}
+		question := findQuestion(questions, questionID)
+		if question == nil {
+			return ErrQuestionNotInExam
+		}
+		if points > question.Points {
+			return fmt.Errorf("%w (%g)", ErrPointsExceedMaximum, question.Points)
+		}
+
+		answer, err := tx.GetAnswer(attempt.ID, questionID)
+		if err != nil {
+			// Unanswered questions can still be graded, e.g. for an answer handed in on paper
+			answer = &domain.ExamAnswer{ExamAttemptID: attempt.ID, QuestionID: questionID}
+			if err := tx.UpsertAnswer(answer); err != nil {
+				return err
+			}
+		}
+
+		now := e.now()
+		answer.Points = points
+		answer.IsCorrect = points == question.Points
+		answer.NeedsManualGrading = false
+		answer.Feedback = feedback
+		answer.GradedBy = &graderID
+		answer.GradedAt = &now
+		if err := tx.SaveAnswers([]domain.ExamAnswer{*answer}); err != nil {
+			return err
+		}
+
+		answers, err := tx.GetAnswers(attempt.ID)
+		if err != nil {
+			return err
+		}
+		attempt.Score, attempt.GradingStatus = scoreAttempt(attempt, questions, answers)
+		graded = attempt
+		return tx.SaveAttempt(attempt)
+	})
+	if err != nil {
+		return nil, err
+	}
+	e.gradebook.ResultChanged(domain.GradeItemExam, graded.ExamID, graded.UserID)
+	return e.examRepo.GetAttempt(attemptID)
+}
+
+// scoreAttempt returns the attempt's score as a percentage of the exam's
+// total points, and whether answers are still waiting for manual grading.
+// A score set by an accepted regrade takes the place of the points.
+func scoreAttempt(attempt *domain.ExamAttempt, questions []domain.ExamQuestion, answers []domain.ExamAnswer) (float64, string) {
+	var total, earned float64
+	for _, question := range questions {
+		total += question.Points
+	}
+
+	status := domain.GradingStatusGraded
+	for _, answer := range answers {
+		earned += answer.Points
+		if answer.NeedsManualGrading {
+			status = domain.GradingStatusNeedsManualGrading
+		}
+	}
+
+	if attempt.ScoreOverride != nil {
+		return *attempt.ScoreOverride, status
+	}
+	if total == 0 {
+		return 0, status
+	}
+	return math.Round(earned/total*10000) / 100, status
+}
+
+// examHTTPError maps exam delivery errors to HTTP errors
+func examHTTPError(err error) error {
+	var ineligible *AttendanceIneligibleError
+	switch {
+	case errors.As(err, &ineligible):
+		return echo.NewHTTPError(http.StatusForbidden, err.Error())
+	case errors.Is(err, ErrExamNotYetAvailable), errors.Is(err, ErrExamClosed):
+		return echo.NewHTTPError(http.StatusForbidden, err.Error())
+	case errors.Is(err, ErrMaxAttemptsReached), errors.Is(err, ErrAttemptFinished), errors.Is(err, ErrAttemptExpired),
+		errors.Is(err, ErrAttemptNotFinished):
+		return echo.NewHTTPError(http.StatusConflict, err.Error())
+	case errors.Is(err, ErrExamHasNoQuestions), errors.Is(err, ErrQuestionNotInExam), errors.Is(err, ErrPointsExceedMaximum):
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	case err.Error() == "attempt not found":
+		return echo.NewHTTPError(http.StatusNotFound, "Attempt not found")
+	default:
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to process exam attempt")
+	}
+}
@@ -0,0 +1,337 @@
+package service
+
+import (
+	"backend/internal/domain"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// GradeResult is the outcome of grading one answer
+type GradeResult struct {
+	Points    float64
+	IsCorrect bool
+	// Manual is set when the answer has to be graded by course staff
+	Manual bool
+}
+
+// QuestionGrader validates the answer key of one question type and grades answers to it
+type QuestionGrader interface {
+	// Validate checks the question's Options and CorrectAnswer
+	Validate(question *domain.ExamQuestion) error
+	// Grade scores an answer out of question.Points
+	Grade(question *domain.ExamQuestion, answer string) GradeResult
+}
+
+// GraderRegistry maps question types to their graders
+type GraderRegistry struct {
+	graders map[string]QuestionGrader
+}
+
+// NewGraderRegistry creates a registry with the graders of all built-in question types
+func NewGraderRegistry() *GraderRegistry {
+	r := &GraderRegistry{graders: make(map[string]QuestionGrader)}
+	r.Register(domain.QuestionTypeMultipleChoice, multipleChoiceGrader{})
+	r.Register(domain.QuestionTypeMultiSelect, multiSelectGrader{})
+	r.Register(domain.QuestionTypeTrueFalse, trueFalseGrader{})
+	r.Register(domain.QuestionTypeNumeric, numericGrader{})
+	r.Register(domain.QuestionTypeShortAnswer, shortAnswerGrader{})
+	r.Register(domain.QuestionTypeEssay, manualGrader{})
+	return r
+}
+
+// Register sets the grader of a question type, replacing any earlier one
+func (r *GraderRegistry) Register(questionType string, grader QuestionGrader) {
+	r.graders[questionType] = grader
+}
+
+// Validate checks a question with the grader of its type
+func (r *GraderRegistry) Validate(question *domain.ExamQuestion) error {
+	grader, ok := r.graders[question.Type]
+	if !ok {
+		return fmt.Errorf("unsupported question type %q", question.Type)
+	}
+	return grader.Validate(question)
+}
+
+// Grade scores an answer with the grader of its question's type. Questions
+// of an unknown type are left for manual grading.
+func (r *GraderRegistry) Grade(question *domain.ExamQuestion, answer string) GradeResult {
+	grader, ok := r.graders[question.Type]
+	if !ok {
+		return GradeResult{Manual: true}
+	}
+	return grader.Grade(question, answer)
+}
+
+// fullOrNothing awards all points for a correct answer
+func fullOrNothing(question *domain.ExamQuestion, correct bool) GradeResult {
+	if correct {
+		return GradeResult{Points: question.Points, IsCorrect: true}
+	}
+	return GradeResult{}
+}
+
+// parseOptions decodes the question's Options, a JSON array of option texts
+func parseOptions(question *domain.ExamQuestion) ([]string, error) {
+	var options []string
+	if err := json.Unmarshal([]byte(question.Options), &options); err != nil {
+		return nil, errors.New("options must be a JSON array of strings")
+	}
+	if len(options) < 2 {
+		return nil, errors.New("at least two options are required")
+	}
+	return options, nil
+}
+
+// matchOption returns the option equal to value, ignoring case and surrounding spaces
+func matchOption(options []string, value string) (string, bool) {
+	for _, option := range options {
+		if strings.EqualFold(strings.TrimSpace(option), strings.TrimSpace(value)) {
+			return option, true
+		}
+	}
+	return "", false
+}
+
+// multipleChoiceGrader expects the text of the single correct option
+type multipleChoiceGrader struct{}
+
+func (multipleChoiceGrader) Validate(question *domain.ExamQuestion) error {
+	options, err := parseOptions(question)
+	if err != nil {
+		return err
+	}
+	if _, ok := matchOption(options, question.CorrectAnswer); !ok {
+		return errors.New("correct answer must be one of the options")
+	}
+	return nil
+}
+
+func (multipleChoiceGrader) Grade(question *domain.ExamQuestion, answer string) GradeResult {
+	return fullOrNothing(question, strings.TrimSpace(answer) != "" &&
+		strings.EqualFold(strings.TrimSpace(answer), strings.TrimSpace(question.CorrectAnswer)))
+}
+
+// multiSelectGrader expects a JSON array of the correct options and gives
+// partial credit: each correct choice earns its share of the points and each
+// wrong choice takes one share away, never going below zero
+type multiSelectGrader struct{}
+
+func (multiSelectGrader) Validate(question *domain.ExamQuestion) error {
+	options, err := parseOptions(question)
+	if err != nil {
+		return err
+	}
+	var correct []string
+	if err := json.Unmarshal([]byte(question.CorrectAnswer), &correct); err != nil || len(correct) == 0 {
+		return errors.New("correct answer must be a non-empty JSON array of options")
+	}
+	for _, value := range correct {
+		if _, ok := matchOption(options, value); !ok {
+			return fmt.Errorf("correct answer %q is not one of the options", value)
+		}
+	}
+	return nil
+}
+
+func (multiSelectGrader) Grade(question *domain.ExamQuestion, answer string) GradeResult {
+	var correct, selected []string
+	if err := json.Unmarshal([]byte(question.CorrectAnswer), &correct); err != nil || len(correct) == 0 {
+		return GradeResult{}
+	}
+	if err := json.Unmarshal([]byte(answer), &selected); err != nil {
+		return GradeResult{}
+	}
+
+	correctSet := normalizedSet(correct)
+	hits, misses := 0, 0
+	for value := range normalizedSet(selected) {
+		if correctSet[value] {
+			hits++
+		} else {
+			misses++
+		}
+	}
+
+	if hits == len(correctSet) && misses == 0 {
+		return GradeResult{Points: question.Points, IsCorrect: true}
+	}
+	share := float64(hits-misses) / float64(len(correctSet))
+	if share <= 0 {
+		return GradeResult{}
+	}
+	return GradeResult{Points: math.Round(question.Points*share*100) / 100}
+}
+
+func normalizedSet(values []string) map[string]bool {
+	set := make(map[string]bool, len(values))
+	for _, value := range values {
+		set[strings.ToLower(strings.TrimSpace(value))] = true
+	}
+	return set
+}
+
+// trueFalseGrader expects "true" or "false"
+type trueFalseGrader struct{}
+
+func (trueFalseGrader) Validate(question *domain.ExamQuestion) error {
+	if _, ok := parseTrueFalse(question.CorrectAnswer); !ok {
+		return errors.New(`correct answer must be "true" or "false"`)
+	}
+	return nil
+}
+
+func (trueFalseGrader) Grade(question *domain.ExamQuestion, answer string) GradeResult {
+	want, _ := parseTrueFalse(question.CorrectAnswer)
+	got, ok := parseTrueFalse(answer)
+	return fullOrNothing(question, ok && got == want)
+}
+
+func parseTrueFalse(value string) (bool, bool) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "true", "t", "yes":
+		return true, true
+	case "false", "f", "no":
+		return false, true
+	default:
+		return false, false
+	}
+}
+
+// numericGrader expects a number, or {"value": 9.81, "tolerance": 0.05}
+// to accept answers within an absolute tolerance
+type numericGrader struct{}
+
+type numericKey struct {
+	Value     *float64 `json:"value"`
+	Tolerance float64  `json:"tolerance"`
+}
+
+func (numericGrader) Validate(question *domain.ExamQuestion) error {
+	_, err := parseNumericKey(question.CorrectAnswer)
+	return err
+}
+
+func (numericGrader) Grade(question *domain.ExamQuestion, answer string) GradeResult {
+	key, err := parseNumericKey(question.CorrectAnswer)
+	if err != nil {
+		return GradeResult{}
+	}
+	value, err := strconv.ParseFloat(strings.ReplaceAll(strings.TrimSpace(answer), ",", "."), 64)
+	if err != nil {
+		return GradeResult{}
+	}
+	// Allow for floating point noise on exact answers
+	return fullOrNothing(question, math.Abs(value-*key.Value) <= key.Tolerance+1e-9)
+}
+
+func parseNumericKey(raw string) (numericKey, error) {
+	var key numericKey
+	if value, err := strconv.ParseFloat(strings.TrimSpace(raw), 64); err == nil {
+		key.Value = &value
+		return key, nil
+	}
+	if err := json.Unmarshal([]byte(raw), &key); err != nil || key.Value == nil {
+		return key, errors.New(`correct answer must be a number or {"value": number, "tolerance": number}`)
+	}
+	if key.Tolerance < 0 {
+		return key, errors.New("tolerance must not be negative")
+	}
+	return key, nil
+}
+
+// shortAnswerGrader expects an accepted answer, or
+// {"answers": [...], "caseSensitive": false} for several accepted answers, or
+// {"pattern": "..."} for a regular expression matched against the whole answer.
+// Answers are compared with surrounding and repeated whitespace removed.
+type shortAnswerGrader struct{}
+
+type shortAnswerKey struct {
+	Answers       []string `json:"answers,omitempty"`
+	Pattern       string   `json:"pattern,omitempty"`
+	CaseSensitive bool     `json:"caseSensitive,omitempty"`
+}
+
+func (shortAnswerGrader) Validate(question *domain.ExamQuestion) error {
+	_, err := parseShortAnswerKey(question.CorrectAnswer)
+	return err
+}
+
+func (shortAnswerGrader) Grade(question *domain.ExamQuestion, answer string) GradeResult {
+	key, err := parseShortAnswerKey(question.CorrectAnswer)
+	if err != nil {
+		return GradeResult{}
+	}
+
+	normalized := normalizeShortAnswer(answer, key.CaseSensitive)
+	if normalized == "" {
+		return GradeResult{}
+	}
+	if key.Pattern != "" {
+		pattern, err := compileShortAnswerPattern(key)
+		return fullOrNothing(question, err == nil && pattern.MatchString(normalized))
+	}
+	for _, accepted := range key.Answers {
+		if normalizeShortAnswer(accepted, key.CaseSensitive) == normalized {
+			return fullOrNothing(question, true)
+		}
+	}
+	return GradeResult{}
+}
+
+func parseShortAnswerKey(raw string) (shortAnswerKey, error) {
+	var key shortAnswerKey
+	if !strings.HasPrefix(strings.TrimSpace(raw), "{") {
+		if strings.TrimSpace(raw) == "" {
+			return key, errors.New("correct answer is required")
+		}
+		key.Answers = []string{raw}
+		return key, nil
+	}
+
+	if err := json.Unmarshal([]byte(raw), &key); err != nil {
+		return key, errors.New("correct answer must be text or a JSON object with answers or pattern")
+	}
+	if key.Pattern != "" {
+		if _, err := compileShortAnswerPattern(key); err != nil {
+			return key, fmt.Errorf("invalid pattern: %v", err)
+		}
+		return key, nil
+	}
+	if len(key.Answers) == 0 {
+		return key, errors.New("correct answer needs at least one accepted answer or a pattern")
+	}
+	return key, nil
+}
+
+func compileShortAnswerPattern(key shortAnswerKey) (*regexp.Regexp, error) {
+	pattern := "^(?:" + key.Pattern + ")$"
+	if !key.CaseSensitive {
+		pattern = "(?i)" + pattern
+	}
+	return regexp.Compile(pattern)
+}
+
+func normalizeShortAnswer(value string, caseSensitive bool) string {
+	value = strings.Join(strings.Fields(value), " ")
+	if !caseSensitive {
+		value = strings.ToLower(value)
+	}
+	return value
+}
+
+// manualGrader leaves answers to course staff
+type manualGrader struct{}
+
+func (manualGrader) Validate(question *domain.ExamQuestion) error {
+	return nil
+}
+
+func (manualGrader) Grade(question *domain.ExamQuestion, answer string) GradeResult {
+	return GradeResult{Manual: true}
+}
@@ -0,0 +1,179 @@
+package service
+
+import (
+	"backend/internal/domain"
+	"testing"
+)
+
+func TestMultiSelectPartialCredit(t *testing.T) {
+	question := &domain.ExamQuestion{
+		Type:          domain.QuestionTypeMultiSelect,
+		Points:        4,
+		Options:       `["A", "B", "C", "D", "E"]`,
+		CorrectAnswer: `["A", "B", "C", "D"]`,
+	}
+	tests := []struct {
+		name    string
+		answer  string
+		points  float64
+		correct bool
+	}{
+		{"all correct", `["A", "B", "C", "D"]`, 4, true},
+		{"order and case ignored", `["d", " c", "B", "a"]`, 4, true},
+		{"duplicates count once", `["A", "A", "B", "C", "D"]`, 4, true},
+		{"three of four", `["A", "B", "C"]`, 3, false},
+		{"one of four", `["A"]`, 1, false},
+		{"wrong choice takes a share away", `["A", "B", "C", "E"]`, 2, false},
+		{"all correct plus a wrong one", `["A", "B", "C", "D", "E"]`, 3, false},
+		{"never below zero", `["E"]`, 0, false},
+		{"as many wrong as right", `["A", "E"]`, 0, false},
+		{"nothing selected", `[]`, 0, false},
+		{"not JSON", `A, B`, 0, false},
+	}
+	registry := NewGraderRegistry()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := registry.Grade(question, tt.answer)
+			if got.Points != tt.points || got.IsCorrect != tt.correct || got.Manual {
+				t.Errorf("Grade(%s) = %+v, want %v points, correct %v", tt.answer, got, tt.points, tt.correct)
+			}
+		})
+	}
+}
+
+func TestMultiSelectPartialCreditRounding(t *testing.T) {
+	question := &domain.ExamQuestion{
+		Type:          domain.QuestionTypeMultiSelect,
+		Points:        1,
+		Options:       `["A", "B", "C"]`,
+		CorrectAnswer: `["A", "B", "C"]`,
+	}
+	got := NewGraderRegistry().Grade(question, `["A"]`)
+	if got.Points != 0.33 {
+		t.Errorf("Grade = %v points, want 0.33", got.Points)
+	}
+}
+
+func TestNumericTolerance(t *testing.T) {
+	tests := []struct {
+		name    string
+		key     string
+		answer  string
+		correct bool
+	}{
+		{"exact", "42", "42", true},
+		{"exact with decimals", "42", "42.0", true},
+		{"exact rejects near", "42", "42.001", false},
+		{"floating point noise", "0.3", "0.30000000000000004", true},
+		{"decimal comma", "3.5", "3,5", true},
+		{"surrounding spaces", "7", " 7 ", true},
+		{"within tolerance", `{"value": 9.81, "tolerance": 0.05}`, "9.85", true},
+		{"below within tolerance", `{"value": 9.81, "tolerance": 0.05}`, "9.76", true},
+		{"on the tolerance edge", `{"value": 10, "tolerance": 0.5}`, "10.5", true},
+		{"outside tolerance", `{"value": 9.81, "tolerance": 0.05}`, "9.87", false},
+		{"negative values", `{"value": -2, "tolerance": 0.1}`, "-2.05", true},
+		{"not a number", "42", "forty-two", false},
+		{"empty answer", "42", "", false},
+	}
+	registry := NewGraderRegistry()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			question := &domain.ExamQuestion{Type: domain.QuestionTypeNumeric, Points: 2, CorrectAnswer: tt.key}
+			if err := registry.Validate(question); err != nil {
+				t.Fatalf("Validate(%s): %v", tt.key, err)
+			}
+			got := registry.Grade(question, tt.answer)
+			if got.IsCorrect != tt.correct {
+				t.Errorf("Grade(%q) correct = %v, want %v", tt.answer, got.IsCorrect, tt.correct)
+			}
+			if tt.correct && got.Points != 2 {
+				t.Errorf("Grade(%q) = %v points, want 2", tt.answer, got.Points)
+			}
+		})
+	}
+}
+
+func TestNumericKeyValidation(t *testing.T) {
+	tests := []struct {
+		key   string
+		valid bool
+	}{
+		{"1.5", true},
+		{`{"value": 1, "tolerance": 0.1}`, true},
+		{`{"value": 0}`, true},
+		{`{"tolerance": 0.1}`, false},
+		{`{"value": 1, "tolerance": -0.1}`, false},
+		{"one", false},
+	}
+	for _, tt := range tests {
+		err := numericGrader{}.Validate(&domain.ExamQuestion{CorrectAnswer: tt.key})
+		if (err == nil) != tt.valid {
+			t.Errorf("Validate(%s) error = %v, want valid %v", tt.key, err, tt.valid)
+		}
+	}
+}
+
+func TestShortAnswer(t *testing.T) {
+	tests := []struct {
+		name    string
+		key     string
+		answer  string
+		correct bool
+	}{
+		{"plain text", "Paris", "paris", true},
+		{"repeated whitespace", "New York", "  new   york ", true},
+		{"other text", "Paris", "London", false},
+		{"empty answer", "Paris", " ", false},
+		{"one of several", `{"answers": ["colour", "color"]}`, "Color", true},
+		{"case sensitive", `{"answers": ["pH"], "caseSensitive": true}`, "PH", false},
+		{"case sensitive match", `{"answers": ["pH"], "caseSensitive": true}`, "pH", true},
+		{"pattern", `{"pattern": "colou?r"}`, "COLOR", true},
+		{"pattern anchored at the start", `{"pattern": "colou?r"}`, "watercolor", false},
+		{"pattern anchored at the end", `{"pattern": "colou?r"}`, "colors", false},
+		{"alternation anchored as a whole", `{"pattern": "cat|dog"}`, "cats and dogs", false},
+		{"alternation match", `{"pattern": "cat|dog"}`, "dog", true},
+		{"explicit anchors", `{"pattern": "^\\d+$"}`, "123", true},
+		{"case sensitive pattern", `{"pattern": "H2O", "caseSensitive": true}`, "h2o", false},
+	}
+	registry := NewGraderRegistry()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			question := &domain.ExamQuestion{Type: domain.QuestionTypeShortAnswer, Points: 1, CorrectAnswer: tt.key}
+			if err := registry.Validate(question); err != nil {
+				t.Fatalf("Validate(%s): %v", tt.key, err)
+			}
+			if got := registry.Grade(question, tt.answer); got.IsCorrect != tt.correct {
+				t.Errorf("Grade(%q) correct = %v, want %v", tt.answer, got.IsCorrect, tt.correct)
+			}
+		})
+	}
+}
+
+func TestShortAnswerKeyValidation(t *testing.T) {
+	tests := []struct {
+		key   string
+		valid bool
+	}{
+		{"answer", true},
+		{"", false},
+		{`{"answers": []}`, false},
+		{`{"pattern": "("}`, false},
+		{`{"pattern": "a+"}`, true},
+		{`{"answers": `, false},
+	}
+	for _, tt := range tests {
+		err := shortAnswerGrader{}.Validate(&domain.ExamQuestion{CorrectAnswer: tt.key})
+		if (err == nil) != tt.valid {
+			t.Errorf("Validate(%s) error = %v, want valid %v", tt.key, err, tt.valid)
+		}
+	}
+}
+
+func TestUnknownQuestionTypeIsManual(t *testing.T) {
+	registry := NewGraderRegistry()
+	for _, questionType := range []string{domain.QuestionTypeEssay, "drawing"} {
+		if got := registry.Grade(&domain.ExamQuestion{Type: questionType}, "anything"); !got.Manual {
+			t.Errorf("Grade of a %s question = %+v, want manual", questionType, got)
+		}
+	}
+}
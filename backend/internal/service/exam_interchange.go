@@ -0,0 +1,322 @@
+package service
+
+import (
+	"backend/internal/domain"
+	"backend/internal/repository"
+	"backend/pkg/questionformat"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Question interchange formats
+const (
+	questionFormatGIFT = "gift"
+	questionFormatQTI  = "qti"
+)
+
+// ImportQuestions adds questions to an exam from an uploaded QTI 2.1
+// package or GIFT file. Every item is checked first and reported on its
+// own; items that cannot be imported are skipped. With dry_run=true only
+// the report is returned.
+func (s *ExamService) ImportQuestions(c echo.Context) error {
+	exam, err := s.examFromParam(c)
+	if err != nil {
+		return err
+	}
+	if err := authorizeCourseStaff(c, s.courseRepo, exam.CourseID); err != nil {
+		return err
+	}
+
+	dryRun, _ := strconv.ParseBool(c.FormValue("dry_run"))
+	if !dryRun {
+		dryRun, _ = strconv.ParseBool(c.QueryParam("dry_run"))
+	}
+
+	file, err := c.FormFile("file")
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "No file uploaded")
+	}
+	format := questionFormat(c, file.Filename)
+	src, err := file.Open()
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to open uploaded file")
+	}
+	defer src.Close()
+
+	data, err := io.ReadAll(src)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to read uploaded file")
+	}
+
+	var parsed []questionformat.Parsed
+	switch format {
+	case questionFormatGIFT:
+		parsed = questionformat.ReadGIFT(data)
+	case questionFormatQTI:
+		if parsed, err = questionformat.ReadQTI(data); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+	default:
+		return echo.NewHTTPError(http.StatusBadRequest, "Unsupported format, expected gift or qti")
+	}
+	if len(parsed) == 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "The file contains no questions")
+	}
+
+	existing, err := s.examRepo.GetFixedQuestions(exam.ID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to get questions")
+	}
+	nextOrder := 1
+	for _, question := range existing {
+		if question.Order >= nextOrder {
+			nextOrder = question.Order + 1
+		}
+	}
+
+	result := domain.QuestionImportResult{
+		Format:     format,
+		DryRun:     dryRun,
+		TotalItems: len(parsed),
+		Items:      make([]domain.QuestionImportItem, 0, len(parsed)),
+	}
+	var questions []domain.ExamQuestion
+	var reportIndex []int
+	for _, p := range parsed {
+		report := domain.QuestionImportItem{
+			Item:       p.Position,
+			Identifier: p.Item.Identifier,
+			Title:      p.Item.Title,
+			Type:       p.Item.Type,
+			Status:     "valid",
+		}
+		question := itemToQuestion(&p.Item)
+		question.ExamID = exam.ID
+		question.Order = nextOrder + len(questions)
+
+		if p.Err != nil {
+			report.Errors = append(report.Errors, p.Err.Error())
+		} else if err := s.engine.ValidateQuestion(&question); err != nil {
+			report.Errors = append(report.Errors, err.Error())
+		}
+		if len(report.Errors) > 0 {
+			report.Status = "invalid"
+		} else {
+			questions = append(questions, question)
+			reportIndex = append(reportIndex, len(result.Items))
+		}
+		result.Items = append(result.Items, report)
+	}
+	result.ValidItems = len(questions)
+
+	if dryRun || len(questions) == 0 {
+		return c.JSON(http.StatusOK, result)
+	}
+
+	err = s.examRepo.Transaction(func(tx *repository.ExamRepository) error {
+		for i := range questions {
+			if err := tx.CreateQuestion(&questions[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to import questions")
+	}
+	for _, i := range reportIndex {
+		result.Items[i].Status = "imported"
+	}
+	result.Imported = len(questions)
+	s.syncQuestionsCount(exam)
+
+	return c.JSON(http.StatusOK, result)
+}
+
+// ExportQuestions downloads the fixed questions of an exam as a QTI 2.1
+// package or GIFT file. Questions the format cannot express are left out
+// and listed in the X-Skipped-Questions header.
+func (s *ExamService) ExportQuestions(c echo.Context) error {
+	exam, err := s.examFromParam(c)
+	if err != nil {
+		return err
+	}
+	if err := authorizeCourseStaff(c, s.courseRepo, exam.CourseID); err != nil {
+		return err
+	}
+
+	format := questionFormat(c, "")
+	if format != questionFormatGIFT && format != questionFormatQTI {
+		return echo.NewHTTPError(http.StatusBadRequest, "Unsupported format, expected gift or qti")
+	}
+
+	questions, err := s.examRepo.GetFixedQuestions(exam.ID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to get questions")
+	}
+	items := make([]questionformat.Item, 0, len(questions))
+	var skipped []string
+	for i := range questions {
+		item, err := questionToItem(&questions[i])
+		if err != nil {
+			skipped = append(skipped, fmt.Sprintf("%d: %v", questions[i].ID, err))
+			continue
+		}
+		items = append(items, item)
+	}
+
+	var buf bytes.Buffer
+	contentType, extension := "text/plain; charset=utf-8", "gift.txt"
+	if format == questionFormatQTI {
+		contentType, extension = "application/zip", "qti.zip"
+		err = questionformat.WriteQTI(&buf, items)
+	} else {
+		err = questionformat.WriteGIFT(&buf, items)
+	}
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to export questions")
+	}
+
+	if len(skipped) > 0 {
+		c.Response().Header().Set("X-Skipped-Questions", strings.Join(skipped, "; "))
+	}
+	filename := fmt.Sprintf("exam-%d-questions.%s", exam.ID, extension)
+	c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", filename))
+	return c.Blob(http.StatusOK, contentType, buf.Bytes())
+}
+
+// questionFormat reads the format parameter, falling back to the uploaded
+// file's extension
+func questionFormat(c echo.Context, filename string) string {
+	format := strings.ToLower(c.QueryParam("format"))
+	if format == "" {
+		format = strings.ToLower(c.FormValue("format"))
+	}
+	if format == "" {
+		switch strings.ToLower(filepath.Ext(filename)) {
+		case ".zip":
+			format = questionFormatQTI
+		case ".gift", ".txt":
+			format = questionFormatGIFT
+		}
+	}
+	return format
+}
+
+// questionToItem converts an exam question for export
+func questionToItem(question *domain.ExamQuestion) (questionformat.Item, error) {
+	item := questionformat.Item{
+		Identifier: fmt.Sprintf("Q%d", question.ID),
+		Type:       question.Type,
+		Text:       question.Question,
+		Points:     question.Points,
+	}
+
+	switch question.Type {
+	case domain.QuestionTypeMultipleChoice:
+		options, err := parseOptions(question)
+		if err != nil {
+			return item, err
+		}
+		correct, ok := matchOption(options, question.CorrectAnswer)
+		if !ok {
+			return item, errors.New("correct answer is not one of the options")
+		}
+		item.Options, item.Correct = options, []string{correct}
+	case domain.QuestionTypeMultiSelect:
+		options, err := parseOptions(question)
+		if err != nil {
+			return item, err
+		}
+		var values []string
+		if err := json.Unmarshal([]byte(question.CorrectAnswer), &values); err != nil {
+			return item, errors.New("correct answer is not a JSON array")
+		}
+		for _, value := range values {
+			correct, ok := matchOption(options, value)
+			if !ok {
+				return item, fmt.Errorf("correct answer %q is not one of the options", value)
+			}
+			item.Correct = append(item.Correct, correct)
+		}
+		item.Options = options
+	case domain.QuestionTypeTrueFalse:
+		value, ok := parseTrueFalse(question.CorrectAnswer)
+		if !ok {
+			return item, errors.New(`correct answer is not "true" or "false"`)
+		}
+		item.Correct = []string{strconv.FormatBool(value)}
+	case domain.QuestionTypeNumeric:
+		key, err := parseNumericKey(question.CorrectAnswer)
+		if err != nil {
+			return item, err
+		}
+		item.Value, item.Tolerance = *key.Value, key.Tolerance
+	case domain.QuestionTypeShortAnswer:
+		key, err := parseShortAnswerKey(question.CorrectAnswer)
+		if err != nil {
+			return item, err
+		}
+		if key.Pattern != "" {
+			return item, errors.New("answers given as a regular expression cannot be exported")
+		}
+		item.Correct, item.CaseSensitive = key.Answers, key.CaseSensitive
+	case domain.QuestionTypeEssay:
+	default:
+		return item, fmt.Errorf("unsupported question type %q", question.Type)
+	}
+	return item, nil
+}
+
+// itemToQuestion converts an imported item to an exam question, encoding
+// its answer key the way the graders expect. Items without points get one.
+func itemToQuestion(item *questionformat.Item) domain.ExamQuestion {
+	question := domain.ExamQuestion{
+		Type:     item.Type,
+		Question: item.Text,
+		Points:   item.Points,
+	}
+	if question.Points <= 0 {
+		question.Points = 1
+	}
+
+	switch item.Type {
+	case domain.QuestionTypeMultipleChoice, domain.QuestionTypeMultiSelect:
+		options, _ := json.Marshal(item.Options)
+		question.Options = string(options)
+		if item.Type == domain.QuestionTypeMultiSelect {
+			correct, _ := json.Marshal(item.Correct)
+			question.CorrectAnswer = string(correct)
+		} else if len(item.Correct) > 0 {
+			question.CorrectAnswer = item.Correct[0]
+		}
+	case domain.QuestionTypeTrueFalse:
+		if len(item.Correct) > 0 {
+			question.CorrectAnswer = item.Correct[0]
+		}
+	case domain.QuestionTypeNumeric:
+		question.CorrectAnswer = strconv.FormatFloat(item.Value, 'f', -1, 64)
+		if item.Tolerance > 0 {
+			value := item.Value
+			key, _ := json.Marshal(numericKey{Value: &value, Tolerance: item.Tolerance})
+			question.CorrectAnswer = string(key)
+		}
+	case domain.QuestionTypeShortAnswer:
+		if len(item.Correct) == 1 && !item.CaseSensitive && !strings.HasPrefix(strings.TrimSpace(item.Correct[0]), "{") {
+			question.CorrectAnswer = item.Correct[0]
+		} else {
+			key, _ := json.Marshal(shortAnswerKey{Answers: item.Correct, CaseSensitive: item.CaseSensitive})
+			question.CorrectAnswer = string(key)
+		}
+	}
+	return question
+}
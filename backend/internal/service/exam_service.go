@@ -1,65 +1,787 @@
 package service
 
-import "github.com/labstack/echo/v4"
+import (
+	"backend/internal/domain"
+	"backend/internal/repository"
+	"backend/pkg/middleware"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
-type ExamService struct{}
+	"github.com/labstack/echo/v4"
+)
 
+// ExamService handles exam management and exam delivery.
+// Students only ever receive questions through ExamQuestionResponse, so the
+// correct answers never leave the server for them.
+type ExamService struct {
+	examRepo   *repository.ExamRepository
+	courseRepo *repository.CourseRepository
+	engine     *ExamEngine
+}
+
+// NewExamService creates a new exam service
+func NewExamService(
+	examRepo *repository.ExamRepository,
+	courseRepo *repository.CourseRepository,
+	engine *ExamEngine,
+) *ExamService {
+	return &ExamService{
+		examRepo:   examRepo,
+		courseRepo: courseRepo,
+		engine:     engine,
+	}
+}
+
+// GetExams returns the exams visible to the current user, optionally for one course
 func (s *ExamService) GetExams(c echo.Context) error {
-	return c.JSON(200, map[string]string{"message": "dummy get exams"})
+	userID, err := middleware.GetUserIDFromToken(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Invalid token")
+	}
+	role, err := middleware.GetRoleFromToken(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Invalid token")
+	}
+
+	var filter repository.ExamFilter
+	if courseParam := c.QueryParam("courseId"); courseParam != "" {
+		courseID, err := strconv.ParseUint(courseParam, 10, 32)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid course ID")
+		}
+		filter.CourseID = uint(courseID)
+	}
+
+	// Scope the listing to the user's role
+	switch role {
+	case "admin":
+	case "instructor":
+		filter.InstructorID = userID
+	default:
+		filter.StudentID = userID
+	}
+
+	exams, err := s.examRepo.FindExams(filter)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to get exams")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"exams": exams,
+	})
 }
 
+// GetExam returns an exam
 func (s *ExamService) GetExam(c echo.Context) error {
-	return c.JSON(200, map[string]string{"message": "dummy get exam"})
+	exam, err := s.examFromParam(c)
+	if err != nil {
+		return err
+	}
+	if err := authorizeCourseAccess(c, s.courseRepo, exam.CourseID); err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, exam)
 }
 
+// CreateExam creates an exam in a course the current user teaches
 func (s *ExamService) CreateExam(c echo.Context) error {
-	return c.JSON(200, map[string]string{"message": "dummy create exam"})
+	var req domain.CreateExamRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+	if err := validateExamRequest(c, &req); err != nil {
+		return err
+	}
+
+	if err := authorizeCourseStaff(c, s.courseRepo, req.CourseID); err != nil {
+		return err
+	}
+
+	exam := &domain.Exam{CourseID: req.CourseID}
+	applyExamRequest(exam, &req)
+	if err := s.examRepo.Create(exam); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to create exam")
+	}
+
+	return c.JSON(http.StatusCreated, exam)
 }
 
+// UpdateExam updates an exam; its course cannot be changed
 func (s *ExamService) UpdateExam(c echo.Context) error {
-	return c.JSON(200, map[string]string{"message": "dummy update exam"})
+	exam, err := s.examFromParam(c)
+	if err != nil {
+		return err
+	}
+	if err := authorizeCourseStaff(c, s.courseRepo, exam.CourseID); err != nil {
+		return err
+	}
+
+	var req domain.CreateExamRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+	req.CourseID = exam.CourseID
+	if err := validateExamRequest(c, &req); err != nil {
+		return err
+	}
+
+	applyExamRequest(exam, &req)
+	if err := s.examRepo.Update(exam); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to update exam")
+	}
+
+	return c.JSON(http.StatusOK, exam)
 }
 
+// DeleteExam deletes an exam
 func (s *ExamService) DeleteExam(c echo.Context) error {
-	return c.JSON(200, map[string]string{"message": "dummy delete exam"})
+	exam, err := s.examFromParam(c)
+	if err != nil {
+		return err
+	}
+	if err := authorizeCourseStaff(c, s.courseRepo, exam.CourseID); err != nil {
+		return err
+	}
+
+	if err := s.examRepo.Delete(exam.ID); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to delete exam")
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"message": "Exam deleted successfully",
+	})
 }
 
+// GetQuestions returns the questions of an exam. Only course staff see
+// the questions with their correct answers; students see them once they
+// have started an attempt.
 func (s *ExamService) GetQuestions(c echo.Context) error {
-	return c.JSON(200, map[string]string{"message": "dummy get questions"})
+	exam, err := s.examFromParam(c)
+	if err != nil {
+		return err
+	}
+	if err := authorizeCourseStaff(c, s.courseRepo, exam.CourseID); err != nil {
+		return err
+	}
+
+	questions, err := s.examRepo.GetFixedQuestions(exam.ID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to get questions")
+	}
+	pools, err := s.poolResponses(exam)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"questions": questions,
+		"pools":     pools,
+	})
 }
 
+// AddQuestion adds a question to an exam
 func (s *ExamService) AddQuestion(c echo.Context) error {
-	return c.JSON(200, map[string]string{"message": "dummy add question"})
+	exam, err := s.examFromParam(c)
+	if err != nil {
+		return err
+	}
+	if err := authorizeCourseStaff(c, s.courseRepo, exam.CourseID); err != nil {
+		return err
+	}
+
+	var req domain.ExamQuestionRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+	if err := c.Validate(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	question := &domain.ExamQuestion{ExamID: exam.ID}
+	applyQuestionRequest(question, &req)
+	if err := s.engine.ValidateQuestion(question); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	if err := s.examRepo.CreateQuestion(question); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to add question")
+	}
+	s.syncQuestionsCount(exam)
+
+	return c.JSON(http.StatusCreated, question)
 }
 
+// UpdateQuestion updates a question of an exam
 func (s *ExamService) UpdateQuestion(c echo.Context) error {
-	return c.JSON(200, map[string]string{"message": "dummy update question"})
+	exam, err := s.examFromParam(c)
+	if err != nil {
+		return err
+	}
+	if err := authorizeCourseStaff(c, s.courseRepo, exam.CourseID); err != nil {
+		return err
+	}
+
+	questionID, err := parseIDParam(c, "questionId")
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid question ID")
+	}
+	question, err := s.examRepo.GetQuestion(exam.ID, questionID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, "Question not found")
+	}
+
+	var req domain.ExamQuestionRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+	if err := c.Validate(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	applyQuestionRequest(question, &req)
+	if err := s.engine.ValidateQuestion(question); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	if err := s.examRepo.UpdateQuestion(question); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to update question")
+	}
+
+	return c.JSON(http.StatusOK, question)
 }
 
+// DeleteQuestion removes a question from an exam
 func (s *ExamService) DeleteQuestion(c echo.Context) error {
-	return c.JSON(200, map[string]string{"message": "dummy delete question"})
+	exam, err := s.examFromParam(c)
+	if err != nil {
+		return err
+	}
+	if err := authorizeCourseStaff(c, s.courseRepo, exam.CourseID); err != nil {
+		return err
+	}
+
+	questionID, err := parseIDParam(c, "questionId")
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid question ID")
+	}
+	if _, err := s.examRepo.GetQuestion(exam.ID, questionID); err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, "Question not found")
+	}
+
+	if err := s.examRepo.DeleteQuestion(exam.ID, questionID); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to delete question")
+	}
+	s.syncQuestionsCount(exam)
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"message": "Question deleted successfully",
+	})
 }
 
-func (s *ExamService) StartExam(c echo.Context) error {
-	return c.JSON(200, map[string]string{"message": "dummy start exam"})
+// GetPools returns the question pools of an exam with the number of bank
+// questions each can currently draw from
+func (s *ExamService) GetPools(c echo.Context) error {
+	exam, err := s.examFromParam(c)
+	if err != nil {
+		return err
+	}
+	if err := authorizeCourseStaff(c, s.courseRepo, exam.CourseID); err != nil {
+		return err
+	}
+
+	pools, err := s.poolResponses(exam)
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"pools": pools,
+	})
 }
 
-func (s *ExamService) SubmitExam(c echo.Context) error {
-	return c.JSON(200, map[string]string{"message": "dummy submit exam"})
+// AddPool adds a question pool to an exam
+func (s *ExamService) AddPool(c echo.Context) error {
+	exam, err := s.examFromParam(c)
+	if err != nil {
+		return err
+	}
+	if err := authorizeCourseStaff(c, s.courseRepo, exam.CourseID); err != nil {
+		return err
+	}
+
+	var req domain.QuestionPoolRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+	if err := c.Validate(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	pool := &domain.ExamQuestionPool{ExamID: exam.ID}
+	applyPoolRequest(pool, &req)
+	if err := s.examRepo.CreatePool(pool); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to add pool")
+	}
+	s.syncQuestionsCount(exam)
+
+	return c.JSON(http.StatusCreated, pool)
 }
 
+// UpdatePool updates a question pool of an exam. Attempts already started
+// keep the questions they were given.
+func (s *ExamService) UpdatePool(c echo.Context) error {
+	exam, err := s.examFromParam(c)
+	if err != nil {
+		return err
+	}
+	if err := authorizeCourseStaff(c, s.courseRepo, exam.CourseID); err != nil {
+		return err
+	}
+
+	poolID, err := parseIDParam(c, "poolId")
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid pool ID")
+	}
+	pool, err := s.examRepo.GetPool(exam.ID, poolID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, "Pool not found")
+	}
+
+	var req domain.QuestionPoolRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+	if err := c.Validate(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	applyPoolRequest(pool, &req)
+	if err := s.examRepo.UpdatePool(pool); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to update pool")
+	}
+	s.syncQuestionsCount(exam)
+
+	return c.JSON(http.StatusOK, pool)
+}
+
+// DeletePool removes a question pool from an exam
+func (s *ExamService) DeletePool(c echo.Context) error {
+	exam, err := s.examFromParam(c)
+	if err != nil {
+		return err
+	}
+	if err := authorizeCourseStaff(c, s.courseRepo, exam.CourseID); err != nil {
+		return err
+	}
+
+	poolID, err := parseIDParam(c, "poolId")
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid pool ID")
+	}
+	if _, err := s.examRepo.GetPool(exam.ID, poolID); err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, "Pool not found")
+	}
+
+	if err := s.examRepo.DeletePool(exam.ID, poolID); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to delete pool")
+	}
+	s.syncQuestionsCount(exam)
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"message": "Pool deleted successfully",
+	})
+}
+
+// StartExam starts an attempt for the current student, or resumes the
+// attempt that is still running, and returns it with the questions
+func (s *ExamService) StartExam(c echo.Context) error {
+	userID, err := middleware.GetUserIDFromToken(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Invalid token")
+	}
+
+	exam, err := s.examFromParam(c)
+	if err != nil {
+		return err
+	}
+	if err := s.authorizeStudent(c, exam.CourseID); err != nil {
+		return err
+	}
+
+	attempt, resumed, err := s.engine.Start(exam, userID)
+	if err != nil {
+		return examHTTPError(err)
+	}
+
+	response, err := s.attemptResponse(attempt)
+	if err != nil {
+		return err
+	}
+
+	status := http.StatusCreated
+	if resumed {
+		status = http.StatusOK
+	}
+	return c.JSON(status, response)
+}
+
+// AnswerQuestion autosaves the answer to one question of the current student's attempt
 func (s *ExamService) AnswerQuestion(c echo.Context) error {
-	return c.JSON(200, map[string]string{"message": "dummy answer question"})
+	attempt, err := s.ownAttemptFromParam(c)
+	if err != nil {
+		return err
+	}
+
+	questionID, err := parseIDParam(c, "questionId")
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid question ID")
+	}
+
+	var req domain.SaveExamAnswerRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+
+	answer, err := s.engine.SaveAnswer(attempt.ID, questionID, req.Answer)
+	if err != nil {
+		return examHTTPError(err)
+	}
+
+	remaining := int64(time.Until(attempt.Deadline) / time.Second)
+	if remaining < 0 {
+		remaining = 0
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"questionId":       answer.QuestionID,
+		"answer":           answer.Answer,
+		"savedAt":          answer.UpdatedAt,
+		"remainingSeconds": remaining,
+	})
 }
 
+// SubmitExam submits the current student's attempt
+func (s *ExamService) SubmitExam(c echo.Context) error {
+	attempt, err := s.ownAttemptFromParam(c)
+	if err != nil {
+		return err
+	}
+
+	attempt, err = s.engine.Submit(attempt.ID)
+	if err != nil {
+		return examHTTPError(err)
+	}
+
+	response, err := s.attemptResponse(attempt)
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, response)
+}
+
+// GetAttempts returns every attempt at an exam for course staff
 func (s *ExamService) GetAttempts(c echo.Context) error {
-	return c.JSON(200, map[string]string{"message": "dummy get attempts"})
+	exam, err := s.examFromParam(c)
+	if err != nil {
+		return err
+	}
+	if err := authorizeCourseStaff(c, s.courseRepo, exam.CourseID); err != nil {
+		return err
+	}
+
+	attempts, err := s.examRepo.GetExamAttempts(exam.ID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to get attempts")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"attempts": attempts,
+	})
 }
 
+// GetMyAttempts returns the current student's attempts at an exam
 func (s *ExamService) GetMyAttempts(c echo.Context) error {
-	return c.JSON(200, map[string]string{"message": "dummy get my attempts"})
+	userID, err := middleware.GetUserIDFromToken(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Invalid token")
+	}
+
+	exam, err := s.examFromParam(c)
+	if err != nil {
+		return err
+	}
+	if err := authorizeCourseAccess(c, s.courseRepo, exam.CourseID); err != nil {
+		return err
+	}
+
+	attempts, err := s.examRepo.GetUserAttempts(exam.ID, userID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to get attempts")
+	}
+	released, err := resultsReleased(s.courseRepo, s.engine.gradebook, exam.CourseID, domain.GradeItemExam, exam.ID, userID)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	responses := make([]domain.ExamAttemptResponse, 0, len(attempts))
+	for i := range attempts {
+		attempt := &attempts[i]
+		if !attempt.IsFinished() {
+			if attempt, err = s.engine.Attempt(attempt.ID); err != nil {
+				return examHTTPError(err)
+			}
+		}
+		responses = append(responses, attempt.ToExamAttemptResponse(now, released))
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"attempts":    responses,
+		"maxAttempts": exam.MaxAttempts,
+	})
 }
 
+// GetAttempt returns an attempt. The student who made it gets the questions
+// without the answer keys; course staff get the full graded attempt.
 func (s *ExamService) GetAttempt(c echo.Context) error {
-	return c.JSON(200, map[string]string{"message": "dummy get attempt"})
+	userID, err := middleware.GetUserIDFromToken(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Invalid token")
+	}
+
+	attemptID, err := parseIDParam(c, "attemptId")
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid attempt ID")
+	}
+	attempt, err := s.engine.Attempt(attemptID)
+	if err != nil {
+		return examHTTPError(err)
+	}
+
+	if attempt.UserID == userID {
+		response, err := s.attemptResponse(attempt)
+		if err != nil {
+			return err
+		}
+		return c.JSON(http.StatusOK, response)
+	}
+
+	exam, err := s.examRepo.GetByID(attempt.ExamID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, "Exam not found")
+	}
+	if err := authorizeCourseStaff(c, s.courseRepo, exam.CourseID); err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, attempt)
+}
+
+// GradeAnswer records course staff's grade for one answer of a finished
+// attempt, typically an essay left for manual grading
+func (s *ExamService) GradeAnswer(c echo.Context) error {
+	graderID, err := middleware.GetUserIDFromToken(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Invalid token")
+	}
+
+	attemptID, err := parseIDParam(c, "attemptId")
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid attempt ID")
+	}
+	questionID, err := parseIDParam(c, "questionId")
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid question ID")
+	}
+
+	attempt, err := s.engine.Attempt(attemptID)
+	if err != nil {
+		return examHTTPError(err)
+	}
+	exam, err := s.examRepo.GetByID(attempt.ExamID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, "Exam not found")
+	}
+	if err := authorizeCourseStaff(c, s.courseRepo, exam.CourseID); err != nil {
+		return err
+	}
+
+	var req domain.GradeExamAnswerRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+	if err := c.Validate(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	attempt, err = s.engine.GradeAnswer(attempt.ID, questionID, req.Points, req.Feedback, graderID)
+	if err != nil {
+		return examHTTPError(err)
+	}
+	return c.JSON(http.StatusOK, attempt)
+}
+
+// examFromParam loads the exam named by ":id"
+func (s *ExamService) examFromParam(c echo.Context) (*domain.Exam, error) {
+	examID, err := parseIDParam(c, "id")
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid exam ID")
+	}
+	exam, err := s.examRepo.GetByID(examID)
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusNotFound, "Exam not found")
+	}
+	return exam, nil
+}
+
+// ownAttemptFromParam loads the attempt named by ":attemptId" and checks that
+// it belongs to the current user
+func (s *ExamService) ownAttemptFromParam(c echo.Context) (*domain.ExamAttempt, error) {
+	userID, err := middleware.GetUserIDFromToken(c)
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusUnauthorized, "Invalid token")
+	}
+
+	attemptID, err := parseIDParam(c, "attemptId")
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid attempt ID")
+	}
+	attempt, err := s.examRepo.GetAttempt(attemptID)
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusNotFound, "Attempt not found")
+	}
+	if attempt.UserID != userID {
+		return nil, echo.NewHTTPError(http.StatusForbidden, "This is not your attempt")
+	}
+	return attempt, nil
+}
+
+// authorizeStudent checks that the current user is a student enrolled in the course
+func (s *ExamService) authorizeStudent(c echo.Context, courseID uint) error {
+	role, err := middleware.GetRoleFromToken(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Invalid token")
+	}
+	if role != "student" {
+		return echo.NewHTTPError(http.StatusForbidden, "Only students can take exams")
+	}
+	return authorizeCourseAccess(c, s.courseRepo, courseID)
+}
+
+// attemptResponse builds the student's view of an attempt, with the
+// questions while it is running and with the results once finished and
+// released
+func (s *ExamService) attemptResponse(attempt *domain.ExamAttempt) (domain.ExamAttemptResponse, error) {
+	exam, err := s.examRepo.GetByID(attempt.ExamID)
+	if err != nil {
+		return domain.ExamAttemptResponse{}, echo.NewHTTPError(http.StatusNotFound, "Exam not found")
+	}
+	if attempt.IsFinished() {
+		released, err := resultsReleased(s.courseRepo, s.engine.gradebook, exam.CourseID, domain.GradeItemExam, exam.ID, attempt.UserID)
+		if err != nil {
+			return domain.ExamAttemptResponse{}, err
+		}
+		return attempt.ToExamAttemptResponse(time.Now(), released), nil
+	}
+
+	response := attempt.ToExamAttemptResponse(time.Now(), false)
+	questions, err := attemptQuestions(s.examRepo, attempt)
+	if err != nil {
+		return response, echo.NewHTTPError(http.StatusInternalServerError, "Failed to get questions")
+	}
+	response.Questions = make([]domain.ExamQuestionResponse, 0, len(questions))
+	for i := range questions {
+		question := questions[i].ToExamQuestionResponse()
+		if exam.RandomizeQuestions {
+			question.Options = shuffleOptions(&questions[i], attempt.Seed)
+		}
+		response.Questions = append(response.Questions, question)
+	}
+	return response, nil
+}
+
+// syncQuestionsCount keeps the exam's QuestionsCount in line with the number
+// of questions an attempt gets: its fixed questions plus the pools' draws
+func (s *ExamService) syncQuestionsCount(exam *domain.Exam) {
+	count, err := s.examRepo.CountQuestions(exam.ID)
+	if err != nil {
+		return
+	}
+	pools, err := s.examRepo.GetPools(exam.ID)
+	if err != nil {
+		return
+	}
+	for _, pool := range pools {
+		count += int64(pool.DrawCount)
+	}
+	exam.QuestionsCount = int(count)
+	_ = s.examRepo.Update(exam)
+}
+
+// poolResponses lists the exam's pools with the number of bank questions
+// each can currently draw from
+func (s *ExamService) poolResponses(exam *domain.Exam) ([]map[string]interface{}, error) {
+	pools, err := s.examRepo.GetPools(exam.ID)
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "Failed to get pools")
+	}
+	responses := make([]map[string]interface{}, 0, len(pools))
+	for i := range pools {
+		available, err := s.examRepo.CountPoolCandidates(exam.CourseID, &pools[i])
+		if err != nil {
+			return nil, echo.NewHTTPError(http.StatusInternalServerError, "Failed to get pools")
+		}
+		responses = append(responses, map[string]interface{}{
+			"pool":               pools[i],
+			"availableQuestions": available,
+		})
+	}
+	return responses, nil
+}
+
+// validateExamRequest applies the validator tags and checks the duration and window
+func validateExamRequest(c echo.Context, req *domain.CreateExamRequest) error {
+	if err := c.Validate(req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	if _, err := domain.ParseExamDuration(req.Duration); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid duration: "+err.Error())
+	}
+	if !req.AvailableFrom.IsZero() && !req.AvailableTo.IsZero() && !req.AvailableTo.After(req.AvailableFrom) {
+		return echo.NewHTTPError(http.StatusBadRequest, "availableTo must be after availableFrom")
+	}
+	return nil
+}
+
+func applyExamRequest(exam *domain.Exam, req *domain.CreateExamRequest) {
+	exam.Title = req.Title
+	exam.Type = req.Type
+	exam.Description = req.Description
+	exam.Duration = req.Duration
+	exam.AvailableFrom = req.AvailableFrom
+	exam.AvailableTo = req.AvailableTo
+	exam.PassingScore = req.PassingScore
+	exam.MaxAttempts = req.MaxAttempts
+	if exam.MaxAttempts == 0 {
+		exam.MaxAttempts = 1
+	}
+	exam.RandomizeQuestions = req.RandomizeQuestions
+	exam.Weight = req.Weight
+	exam.Prerequisites = req.Prerequisites
+}
+
+func applyQuestionRequest(question *domain.ExamQuestion, req *domain.ExamQuestionRequest) {
+	question.Type = req.Type
+	question.Question = req.Question
+	question.Options = req.Options
+	question.CorrectAnswer = req.CorrectAnswer
+	question.Points = req.Points
+	question.Order = req.Order
+}
+
+func applyPoolRequest(pool *domain.ExamQuestionPool, req *domain.QuestionPoolRequest) {
+	pool.Name = req.Name
+	pool.DrawCount = req.DrawCount
+	pool.Points = req.Points
+	pool.Tag = strings.ToLower(strings.TrimSpace(req.Tag))
+	pool.Difficulty = req.Difficulty
+	pool.LearningOutcomeID = req.LearningOutcomeID
 }
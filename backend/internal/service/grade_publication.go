@@ -0,0 +1,289 @@
+package service
+
+import (
+	"backend/internal/domain"
+	"backend/internal/repository"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+// Errors of the grade publication workflow
+var (
+	ErrNothingToModerate = errors.New("no grades are at the stage this step starts from")
+	ErrWeightsInvalid    = errors.New("the weights of the course's assessments and exams must sum to 100")
+	ErrNotPublished      = errors.New("only published grades can be amended")
+	ErrNoChange          = errors.New("the amendment does not change the grade")
+	ErrAmendItemNotFound = errors.New("the published grades have no such item")
+)
+
+// moderationStep is a transition of the grade publication workflow
+type moderationStep struct {
+	from []string
+	to   string
+}
+
+// moderationSteps are the transitions of the workflow by action. Grades can
+// be returned to draft from any stage before publication.
+var moderationSteps = map[string]moderationStep{
+	domain.ModerationSubmit:  {[]string{domain.PublicationDraft}, domain.PublicationSubmitted},
+	domain.ModerationApprove: {[]string{domain.PublicationSubmitted}, domain.PublicationApproved},
+	domain.ModerationReturn:  {[]string{domain.PublicationSubmitted, domain.PublicationApproved}, domain.PublicationDraft},
+	domain.ModerationPublish: {[]string{domain.PublicationApproved}, domain.PublicationPublished},
+}
+
+// Moderate moves the grades of a course, or of the given students, to the
+// next stage of the publication workflow and records the step. Grades are
+// recalculated one last time before they are submitted, which requires the
+// course's weights to sum to 100.
+func (g *Gradebook) Moderate(courseID uint, action string, userIDs []uint, actorID uint, comment string) (*domain.GradeModeration, error) {
+	step, ok := moderationSteps[action]
+	if !ok {
+		return nil, fmt.Errorf("unknown moderation action %q", action)
+	}
+
+	if action == domain.ModerationSubmit {
+		items, err := g.gradeRepo.GetItems(courseID)
+		if err != nil {
+			return nil, err
+		}
+		if _, valid := ValidateWeights(items); !valid {
+			return nil, ErrWeightsInvalid
+		}
+		if err := g.RecalculateCourse(courseID); err != nil {
+			return nil, err
+		}
+	}
+
+	now := g.now()
+	var publishedAt *time.Time
+	if step.to == domain.PublicationPublished {
+		publishedAt = &now
+	}
+
+	var moderation *domain.GradeModeration
+	var moved []uint
+	err := g.gradeRepo.Transaction(func(tx *repository.GradeRepository) error {
+		var err error
+		moved, err = tx.MovePublication(courseID, userIDs, step.from, step.to, publishedAt)
+		if err != nil {
+			return err
+		}
+		if len(moved) == 0 {
+			return ErrNothingToModerate
+		}
+
+		moderation = &domain.GradeModeration{
+			CourseID:   courseID,
+			Action:     action,
+			FromStatus: step.from[0],
+			ToStatus:   step.to,
+			Count:      len(moved),
+			ActorID:    actorID,
+			Comment:    comment,
+			CreatedAt:  now,
+		}
+		if len(step.from) > 1 {
+			moderation.FromStatus = ""
+		}
+		return tx.CreateModeration(moderation)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Published grades start counting toward GPAs
+	if step.to == domain.PublicationPublished {
+		for _, userID := range moved {
+			if err := g.SyncGPA(userID); err != nil {
+				log.Printf("Failed to sync GPA of user %d: %v", userID, err)
+			}
+		}
+	}
+	return moderation, nil
+}
+
+// Amend changes an item of a student's published gradebook, recomputes
+// their course grade from the published items and records the amendment
+// with the reason given
+func (g *Gradebook) Amend(courseID, userID, amendedBy uint, req domain.AmendGradeRequest) (*domain.StudentGradebook, *domain.GradeAmendment, error) {
+	items, err := g.gradeRepo.GetItems(courseID)
+	if err != nil {
+		return nil, nil, err
+	}
+	scale, err := g.Scale(courseID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var book *domain.StudentGradebook
+	var amendment *domain.GradeAmendment
+	err = g.gradeRepo.Transaction(func(tx *repository.GradeRepository) error {
+		if err := tx.LockStudentCourse(courseID, userID); err != nil {
+			return err
+		}
+		courseGrade, err := tx.GetCourseGrade(courseID, userID)
+		if err != nil {
+			if err.Error() == "course grade not found" {
+				return ErrNotPublished
+			}
+			return err
+		}
+		if courseGrade.PublicationStatus != domain.PublicationPublished {
+			return ErrNotPublished
+		}
+		grades, err := tx.GetGrades(courseID, userID)
+		if err != nil {
+			return err
+		}
+
+		key := gradeItemKey{req.Type, req.ID}
+		var grade *domain.Grade
+		for i := range grades {
+			if gradeKey(&grades[i]) == key {
+				grade = &grades[i]
+				break
+			}
+		}
+		if grade == nil {
+			return ErrAmendItemNotFound
+		}
+		old := *grade
+		oldCourseGrade := *courseGrade
+
+		if req.Excused != nil {
+			grade.Excused = *req.Excused
+		}
+		switch {
+		case grade.Excused:
+			grade.Status = domain.GradeStatusExcused
+			grade.Score = 0
+			grade.LetterGrade = ""
+		case req.Score != nil:
+			grade.Status = domain.GradeStatusGraded
+			grade.Score = *req.Score
+			grade.LetterGrade, _ = scale.Grade(*req.Score)
+		default:
+			// Take the item as the student's current results give it
+			results, err := studentResults(tx, courseID, userID)
+			if err != nil {
+				return err
+			}
+			computed, _ := g.computeGrades(courseID, userID, items, results, grades, scale)
+			found := false
+			for _, c := range computed {
+				if gradeKey(&c) == key {
+					grade.Status, grade.Score, grade.LetterGrade = c.Status, c.Score, c.LetterGrade
+					found = true
+					break
+				}
+			}
+			if !found {
+				// The item was deleted after publication
+				return ErrAmendItemNotFound
+			}
+		}
+		if grade.Status == old.Status && grade.Score == old.Score && grade.LetterGrade == old.LetterGrade &&
+			grade.Excused == old.Excused {
+			return ErrNoChange
+		}
+
+		now := g.now()
+		grade.LastUpdated = now
+		if err := tx.SaveGrade(grade); err != nil {
+			return err
+		}
+		applyCourseTotal(courseGrade, grades, scale, now)
+		if err := tx.SaveCourseGrade(courseGrade); err != nil {
+			return err
+		}
+
+		amendment = &domain.GradeAmendment{
+			GradeID:         grade.ID,
+			CourseGradeID:   courseGrade.ID,
+			CourseID:        courseID,
+			UserID:          userID,
+			OldStatus:       old.Status,
+			NewStatus:       grade.Status,
+			OldScore:        old.Score,
+			NewScore:        grade.Score,
+			OldLetterGrade:  old.LetterGrade,
+			NewLetterGrade:  grade.LetterGrade,
+			OldOverallScore: oldCourseGrade.OverallScore,
+			NewOverallScore: courseGrade.OverallScore,
+			OldCourseLetter: oldCourseGrade.LetterGrade,
+			NewCourseLetter: courseGrade.LetterGrade,
+			Reason:          req.Reason,
+			AmendedBy:       amendedBy,
+			CreatedAt:       now,
+		}
+		if err := tx.CreateAmendment(amendment); err != nil {
+			return err
+		}
+
+		book = &domain.StudentGradebook{CourseGrade: *courseGrade, Items: grades}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := g.SyncGPA(userID); err != nil {
+		log.Printf("Failed to sync GPA of user %d: %v", userID, err)
+	}
+	return book, amendment, nil
+}
+
+// Released reports whether students may see a course grade: it must be
+// published and the course's release date must have passed
+func (g *Gradebook) Released(course *domain.Course, courseGrade *domain.CourseGrade) bool {
+	return courseGrade.PublicationStatus == domain.PublicationPublished && g.releaseDue(course)
+}
+
+// ItemReleased reports whether a student may see their results for an
+// assessment or exam: the item's grade must be published and the course's
+// release date must have passed. Items without a grade are not released.
+func (g *Gradebook) ItemReleased(course *domain.Course, itemType string, itemID, userID uint) (bool, error) {
+	grade, err := g.gradeRepo.GetItemGrade(itemType, itemID, userID)
+	if err != nil {
+		if err.Error() == "grade not found" {
+			return false, nil
+		}
+		return false, err
+	}
+	return grade.PublicationStatus == domain.PublicationPublished && g.releaseDue(course), nil
+}
+
+// releaseDue reports whether a course's grade release date has passed
+func (g *Gradebook) releaseDue(course *domain.Course) bool {
+	return course.GradesReleaseAt == nil || !g.now().Before(*course.GradesReleaseAt)
+}
+
+// Regraded brings a student's gradebook up to date after a regrade changed
+// one of their results. Draft grades are recalculated and published grades
+// are amended with the given reason; grades under moderation keep their
+// values until they are returned to draft. The result is already stored,
+// so failures are only logged, like in ResultChanged.
+func (g *Gradebook) Regraded(itemType string, itemID, userID, actorID uint, reason string) {
+	if g == nil {
+		return
+	}
+	courseID, err := g.gradeRepo.GetItemCourseID(itemType, itemID)
+	if err != nil {
+		log.Printf("Failed to update gradebook after regrade of %s %d, user %d: %v", itemType, itemID, userID, err)
+		return
+	}
+
+	courseGrade, err := g.gradeRepo.GetCourseGrade(courseID, userID)
+	if err == nil && courseGrade.PublicationStatus == domain.PublicationPublished {
+		_, _, err = g.Amend(courseID, userID, actorID, domain.AmendGradeRequest{Type: itemType, ID: itemID, Reason: reason})
+		if errors.Is(err, ErrNoChange) {
+			err = nil
+		}
+	} else {
+		_, err = g.Recalculate(courseID, userID)
+	}
+	if err != nil {
+		log.Printf("Failed to update gradebook after regrade of %s %d, user %d: %v", itemType, itemID, userID, err)
+	}
+}
@@ -1,37 +1,723 @@
 package service
 
-import "github.com/labstack/echo/v4"
+import (
+	"backend/internal/domain"
+	"backend/internal/repository"
+	"backend/pkg/middleware"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
 
-type GradeService struct{}
+	"github.com/labstack/echo/v4"
+)
 
+// GradeService handles the gradebook of a course: item weights, grade
+// scales, excusals and the course grades computed by the Gradebook
+type GradeService struct {
+	gradeRepo  *repository.GradeRepository
+	courseRepo *repository.CourseRepository
+	gradebook  *Gradebook
+}
+
+// NewGradeService creates a new grade service
+func NewGradeService(
+	gradeRepo *repository.GradeRepository,
+	courseRepo *repository.CourseRepository,
+	gradebook *Gradebook,
+) *GradeService {
+	return &GradeService{
+		gradeRepo:  gradeRepo,
+		courseRepo: courseRepo,
+		gradebook:  gradebook,
+	}
+}
+
+// GetCourseGrades returns the course grades of every student of a course
 func (s *GradeService) GetCourseGrades(c echo.Context) error {
-	return c.JSON(200, map[string]string{"message": "dummy get course grades"})
+	courseID, err := s.courseFromParam(c)
+	if err != nil {
+		return err
+	}
+	if err := authorizeCourseStaff(c, s.courseRepo, courseID); err != nil {
+		return err
+	}
+
+	grades, err := s.gradeRepo.GetCourseGrades(courseID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to get course grades")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"items": grades,
+	})
 }
 
+// CalculateCourseGrade recalculates the course grades of every student of a course
+func (s *GradeService) CalculateCourseGrade(c echo.Context) error {
+	courseID, err := s.courseFromParam(c)
+	if err != nil {
+		return err
+	}
+	if err := authorizeCourseStaff(c, s.courseRepo, courseID); err != nil {
+		return err
+	}
+
+	if err := s.gradebook.RecalculateCourse(courseID); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to calculate course grades")
+	}
+	grades, err := s.gradeRepo.GetCourseGrades(courseID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to get course grades")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"items": grades,
+	})
+}
+
+// CalculateUserCourseGrade returns a student's up-to-date gradebook for a
+// course. Students see their own, course staff everyone's.
+func (s *GradeService) CalculateUserCourseGrade(c echo.Context) error {
+	courseID, userID, err := s.studentFromParam(c)
+	if err != nil {
+		return err
+	}
+
+	book, err := s.gradebook.Recalculate(courseID, userID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to calculate course grade")
+	}
+	if err := s.checkReleased(c, courseID, &book.CourseGrade); err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, book)
+}
+
+// ExcuseGrade excuses a student from an assessment or exam of a course, or
+// lifts the excusal, and recalculates their course grade
+func (s *GradeService) ExcuseGrade(c echo.Context) error {
+	courseID, userID, err := s.studentFromParam(c)
+	if err != nil {
+		return err
+	}
+	role, err := middleware.GetRoleFromToken(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Invalid token")
+	}
+	if role == "student" {
+		return echo.NewHTTPError(http.StatusForbidden, "Instructor access required")
+	}
+
+	var req domain.ExcuseGradeRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+	if err := c.Validate(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	// Recalculating first makes sure the student has a row for every item
+	book, err := s.gradebook.Recalculate(courseID, userID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to calculate course grade")
+	}
+	if frozen(&book.CourseGrade) {
+		return echo.NewHTTPError(http.StatusConflict, "The grades have been submitted for publication; published grades are changed by amending them")
+	}
+	var grade *domain.Grade
+	for i := range book.Items {
+		if gradeKey(&book.Items[i]) == (gradeItemKey{req.Type, req.ID}) {
+			grade = &book.Items[i]
+			break
+		}
+	}
+	if grade == nil {
+		return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("The course has no %s %d", req.Type, req.ID))
+	}
+
+	grade.Excused = req.Excused
+	if err := s.gradeRepo.SaveGrade(grade); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to excuse grade")
+	}
+	if book, err = s.gradebook.Recalculate(courseID, userID); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to calculate course grade")
+	}
+
+	return c.JSON(http.StatusOK, book)
+}
+
+// GetWeights returns the weighted items of a course and whether their
+// weights sum to 100
+func (s *GradeService) GetWeights(c echo.Context) error {
+	courseID, err := s.courseFromParam(c)
+	if err != nil {
+		return err
+	}
+	if err := authorizeCourseAccess(c, s.courseRepo, courseID); err != nil {
+		return err
+	}
+
+	items, err := s.gradeRepo.GetItems(courseID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to get gradebook items")
+	}
+	return c.JSON(http.StatusOK, weightsResponse(items))
+}
+
+// UpdateWeights sets the weights of a course's assessments and exams. Items
+// left out keep their weight; the resulting weights must sum to 100.
+func (s *GradeService) UpdateWeights(c echo.Context) error {
+	courseID, err := s.courseFromParam(c)
+	if err != nil {
+		return err
+	}
+	if err := authorizeCourseStaff(c, s.courseRepo, courseID); err != nil {
+		return err
+	}
+
+	var req domain.GradebookWeightsRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+	if err := c.Validate(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	items, err := s.gradeRepo.GetItems(courseID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to get gradebook items")
+	}
+	byKey := make(map[gradeItemKey]*domain.GradebookItem, len(items))
+	for i := range items {
+		byKey[gradeItemKey{items[i].Type, items[i].ID}] = &items[i]
+	}
+	for _, weight := range req.Items {
+		item, ok := byKey[gradeItemKey{weight.Type, weight.ID}]
+		if !ok {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("The course has no %s %d", weight.Type, weight.ID))
+		}
+		item.Weight = weight.Weight
+	}
+	if total, valid := ValidateWeights(items); !valid {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Weights must sum to 100, got %g", total))
+	}
+
+	err = s.gradeRepo.Transaction(func(tx *repository.GradeRepository) error {
+		for _, weight := range req.Items {
+			if err := tx.UpdateItemWeight(courseID, weight.Type, weight.ID, weight.Weight); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to update weights")
+	}
+	if err := s.gradebook.RecalculateCourse(courseID); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to calculate course grades")
+	}
+
+	return c.JSON(http.StatusOK, weightsResponse(items))
+}
+
+// GetMyGrades returns the current student's released course grades
 func (s *GradeService) GetMyGrades(c echo.Context) error {
-	return c.JSON(200, map[string]string{"message": "dummy get my grades"})
+	userID, err := middleware.GetUserIDFromToken(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Invalid token")
+	}
+
+	grades, err := s.gradeRepo.GetReleasedCourseGrades(userID, s.gradebook.now())
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to get grades")
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"items": grades,
+	})
 }
 
+// GetUserGrades returns a student's course grades in all courses
 func (s *GradeService) GetUserGrades(c echo.Context) error {
-	return c.JSON(200, map[string]string{"message": "dummy get user grades"})
+	userID, err := parseIDParam(c, "id")
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid user ID")
+	}
+	return s.userGrades(c, userID)
+}
+
+// GetMyTranscript returns the current student's transcript
+func (s *GradeService) GetMyTranscript(c echo.Context) error {
+	userID, err := middleware.GetUserIDFromToken(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Invalid token")
+	}
+	return s.transcript(c, userID)
+}
+
+// GetTranscript returns a student's transcript. Students may only view
+// their own; admins view everyone's.
+func (s *GradeService) GetTranscript(c echo.Context) error {
+	currentUserID, err := middleware.GetUserIDFromToken(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Invalid token")
+	}
+	role, err := middleware.GetRoleFromToken(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Invalid token")
+	}
+
+	userID, err := parseIDParam(c, "id")
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid user ID")
+	}
+	if role != "admin" && userID != currentUserID {
+		return echo.NewHTTPError(http.StatusForbidden, "You can only view your own transcript")
+	}
+	return s.transcript(c, userID)
 }
 
-func (s *GradeService) CreateGrade(c echo.Context) error {
-	return c.JSON(200, map[string]string{"message": "dummy create grade"})
+// GetPublication returns where a course's grades are in the publication
+// workflow, with the steps taken so far
+func (s *GradeService) GetPublication(c echo.Context) error {
+	courseID, err := s.courseFromParam(c)
+	if err != nil {
+		return err
+	}
+	if err := authorizeCourseStaff(c, s.courseRepo, courseID); err != nil {
+		return err
+	}
+	course, err := s.courseRepo.GetByID(courseID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, "Course not found")
+	}
+
+	counts, err := s.gradeRepo.CountPublication(courseID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to get grade publication")
+	}
+	history, err := s.gradeRepo.GetModerations(courseID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to get grade publication")
+	}
+
+	return c.JSON(http.StatusOK, domain.GradePublication{
+		CourseID:  courseID,
+		ReleaseAt: course.GradesReleaseAt,
+		Released:  s.gradebook.releaseDue(course),
+		Counts:    counts,
+		History:   history,
+	})
 }
 
-func (s *GradeService) UpdateGrade(c echo.Context) error {
-	return c.JSON(200, map[string]string{"message": "dummy update grade"})
+// SubmitGrades submits a course's draft grades for approval
+func (s *GradeService) SubmitGrades(c echo.Context) error {
+	return s.moderate(c, domain.ModerationSubmit)
 }
 
-func (s *GradeService) DeleteGrade(c echo.Context) error {
-	return c.JSON(200, map[string]string{"message": "dummy delete grade"})
+// ApproveGrades approves a course's submitted grades
+func (s *GradeService) ApproveGrades(c echo.Context) error {
+	return s.moderate(c, domain.ModerationApprove)
 }
 
-func (s *GradeService) CalculateCourseGrade(c echo.Context) error {
-	return c.JSON(200, map[string]string{"message": "dummy calculate course grade"})
+// ReturnGrades sends a course's submitted or approved grades back to draft
+// with a comment for course staff
+func (s *GradeService) ReturnGrades(c echo.Context) error {
+	return s.moderate(c, domain.ModerationReturn)
 }
 
-func (s *GradeService) CalculateUserCourseGrade(c echo.Context) error {
-	return c.JSON(200, map[string]string{"message": "dummy calculate user course grade"})
+// PublishGrades publishes a course's approved grades. Students see them
+// once the course's grade release date has passed.
+func (s *GradeService) PublishGrades(c echo.Context) error {
+	return s.moderate(c, domain.ModerationPublish)
+}
+
+// AmendGrade changes a published grade item of a student, giving a reason
+func (s *GradeService) AmendGrade(c echo.Context) error {
+	courseID, userID, err := s.studentFromParam(c)
+	if err != nil {
+		return err
+	}
+	amendedBy, err := middleware.GetUserIDFromToken(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Invalid token")
+	}
+	role, err := middleware.GetRoleFromToken(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Invalid token")
+	}
+	if role == "student" {
+		return echo.NewHTTPError(http.StatusForbidden, "Instructor access required")
+	}
+
+	var req domain.AmendGradeRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+	if err := c.Validate(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	req.Reason = strings.TrimSpace(req.Reason)
+	if req.Reason == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "A reason is required to amend a published grade")
+	}
+	if req.Score != nil && req.Excused != nil && *req.Excused {
+		return echo.NewHTTPError(http.StatusBadRequest, "An excused item cannot be given a score")
+	}
+
+	book, amendment, err := s.gradebook.Amend(courseID, userID, amendedBy, req)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrNotPublished):
+			return echo.NewHTTPError(http.StatusConflict, "Only published grades can be amended")
+		case errors.Is(err, ErrNoChange):
+			return echo.NewHTTPError(http.StatusBadRequest, "The amendment does not change the grade")
+		case errors.Is(err, ErrAmendItemNotFound):
+			return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("The published grades have no %s %d", req.Type, req.ID))
+		default:
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to amend grade")
+		}
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"gradebook": book,
+		"amendment": amendment,
+	})
+}
+
+// GetCourseAmendments returns the amendments of a course's published grades
+func (s *GradeService) GetCourseAmendments(c echo.Context) error {
+	courseID, err := s.courseFromParam(c)
+	if err != nil {
+		return err
+	}
+	if err := authorizeCourseStaff(c, s.courseRepo, courseID); err != nil {
+		return err
+	}
+	return s.amendments(c, courseID, 0)
+}
+
+// GetStudentAmendments returns the amendments of a student's published
+// grades in a course. Students see their own once the grades are released.
+func (s *GradeService) GetStudentAmendments(c echo.Context) error {
+	courseID, userID, err := s.studentFromParam(c)
+	if err != nil {
+		return err
+	}
+	courseGrade, err := s.gradeRepo.GetCourseGrade(courseID, userID)
+	if err != nil {
+		courseGrade = &domain.CourseGrade{PublicationStatus: domain.PublicationDraft}
+	}
+	if err := s.checkReleased(c, courseID, courseGrade); err != nil {
+		return err
+	}
+	return s.amendments(c, courseID, userID)
+}
+
+// GetCourseScale returns the grade scale that applies to a course
+func (s *GradeService) GetCourseScale(c echo.Context) error {
+	courseID, err := s.courseFromParam(c)
+	if err != nil {
+		return err
+	}
+	if err := authorizeCourseAccess(c, s.courseRepo, courseID); err != nil {
+		return err
+	}
+
+	scale, err := s.gradebook.Scale(courseID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to get grade scale")
+	}
+	return c.JSON(http.StatusOK, scale)
+}
+
+// UpdateCourseScale gives a course its own grade scale and recalculates
+// its course grades
+func (s *GradeService) UpdateCourseScale(c echo.Context) error {
+	courseID, err := s.courseFromParam(c)
+	if err != nil {
+		return err
+	}
+	if err := authorizeCourseStaff(c, s.courseRepo, courseID); err != nil {
+		return err
+	}
+
+	scale, err := s.gradeRepo.GetScale(&courseID)
+	if err != nil {
+		scale = &domain.GradeScale{CourseID: &courseID}
+	}
+	if err := applyGradeScaleRequest(c, scale); err != nil {
+		return err
+	}
+	if err := s.gradeRepo.SaveScale(scale); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to save grade scale")
+	}
+	if err := s.gradebook.RecalculateCourse(courseID); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to calculate course grades")
+	}
+
+	return c.JSON(http.StatusOK, scale)
+}
+
+// DeleteCourseScale returns a course to the institution's grade scale
+func (s *GradeService) DeleteCourseScale(c echo.Context) error {
+	courseID, err := s.courseFromParam(c)
+	if err != nil {
+		return err
+	}
+	if err := authorizeCourseStaff(c, s.courseRepo, courseID); err != nil {
+		return err
+	}
+
+	scale, err := s.gradeRepo.GetScale(&courseID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, "The course has no grade scale of its own")
+	}
+	if err := s.gradeRepo.DeleteScale(scale.ID); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to delete grade scale")
+	}
+	if err := s.gradebook.RecalculateCourse(courseID); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to calculate course grades")
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"message": "Grade scale deleted successfully",
+	})
+}
+
+// GetInstitutionScale returns the institution's grade scale
+func (s *GradeService) GetInstitutionScale(c echo.Context) error {
+	scale, err := s.gradebook.InstitutionScale()
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to get grade scale")
+	}
+	return c.JSON(http.StatusOK, scale)
+}
+
+// UpdateInstitutionScale sets the institution's grade scale and
+// recalculates the course grades of courses without a scale of their own
+func (s *GradeService) UpdateInstitutionScale(c echo.Context) error {
+	scale, err := s.gradeRepo.GetScale(nil)
+	if err != nil {
+		scale = &domain.GradeScale{}
+	}
+	if err := applyGradeScaleRequest(c, scale); err != nil {
+		return err
+	}
+	if err := s.gradeRepo.SaveScale(scale); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to save grade scale")
+	}
+
+	courseIDs, err := s.gradeRepo.GetGradedCourseIDs(true)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to get courses")
+	}
+	for _, courseID := range courseIDs {
+		if err := s.gradebook.RecalculateCourse(courseID); err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to calculate course grades")
+		}
+	}
+
+	return c.JSON(http.StatusOK, scale)
+}
+
+// userGrades responds with a student's course grades in all courses
+func (s *GradeService) userGrades(c echo.Context, userID uint) error {
+	grades, err := s.gradeRepo.GetUserCourseGrades(userID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to get grades")
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"items": grades,
+	})
+}
+
+// moderate takes a step of the grade publication workflow for the course
+// named by ":id"
+func (s *GradeService) moderate(c echo.Context, action string) error {
+	courseID, err := s.courseFromParam(c)
+	if err != nil {
+		return err
+	}
+	if err := authorizeCourseStaff(c, s.courseRepo, courseID); err != nil {
+		return err
+	}
+	actorID, err := middleware.GetUserIDFromToken(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Invalid token")
+	}
+
+	var req domain.GradeModerationRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+	if err := c.Validate(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	req.Comment = strings.TrimSpace(req.Comment)
+	if action == domain.ModerationReturn && req.Comment == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "A comment is required to return grades")
+	}
+
+	moderation, err := s.gradebook.Moderate(courseID, action, req.UserIDs, actorID, req.Comment)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrNothingToModerate):
+			return echo.NewHTTPError(http.StatusConflict, fmt.Sprintf("No grades are ready to %s", action))
+		case errors.Is(err, ErrWeightsInvalid):
+			return echo.NewHTTPError(http.StatusBadRequest, "Weights must sum to 100 before grades are submitted")
+		default:
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to update grade publication")
+		}
+	}
+	return c.JSON(http.StatusOK, moderation)
+}
+
+// checkReleased hides course grades from students until they are published
+// and the course's release date has passed; staff always see them
+func (s *GradeService) checkReleased(c echo.Context, courseID uint, courseGrade *domain.CourseGrade) error {
+	role, err := middleware.GetRoleFromToken(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Invalid token")
+	}
+	if role != "student" {
+		return nil
+	}
+	course, err := s.courseRepo.GetByID(courseID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, "Course not found")
+	}
+	if !s.gradebook.Released(course, courseGrade) {
+		return echo.NewHTTPError(http.StatusNotFound, "Grades for this course have not been released yet")
+	}
+	return nil
+}
+
+// amendments responds with the amendments of a course's grades
+func (s *GradeService) amendments(c echo.Context, courseID, userID uint) error {
+	amendments, err := s.gradeRepo.GetAmendments(courseID, userID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to get amendments")
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"items": amendments,
+	})
+}
+
+// transcript responds with a student's transcript
+func (s *GradeService) transcript(c echo.Context, userID uint) error {
+	transcript, err := s.gradebook.Transcript(userID)
+	if err != nil {
+		if err.Error() == "user not found" {
+			return echo.NewHTTPError(http.StatusNotFound, "User not found")
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to build transcript")
+	}
+	return c.JSON(http.StatusOK, transcript)
+}
+
+// courseFromParam parses the course named by ":id" and checks that it exists
+func (s *GradeService) courseFromParam(c echo.Context) (uint, error) {
+	courseID, err := parseIDParam(c, "id")
+	if err != nil {
+		return 0, echo.NewHTTPError(http.StatusBadRequest, "Invalid course ID")
+	}
+	if _, err := s.courseRepo.GetByID(courseID); err != nil {
+		return 0, echo.NewHTTPError(http.StatusNotFound, "Course not found")
+	}
+	return courseID, nil
+}
+
+// studentFromParam parses the course named by ":id" and the student named
+// by ":userId", checking that the current user is that student or course
+// staff and that the student is enrolled in the course
+func (s *GradeService) studentFromParam(c echo.Context) (uint, uint, error) {
+	courseID, err := s.courseFromParam(c)
+	if err != nil {
+		return 0, 0, err
+	}
+	userID, err := parseIDParam(c, "userId")
+	if err != nil {
+		return 0, 0, echo.NewHTTPError(http.StatusBadRequest, "Invalid user ID")
+	}
+
+	currentUserID, err := middleware.GetUserIDFromToken(c)
+	if err != nil {
+		return 0, 0, echo.NewHTTPError(http.StatusUnauthorized, "Invalid token")
+	}
+	if userID == currentUserID {
+		err = authorizeCourseAccess(c, s.courseRepo, courseID)
+	} else {
+		err = authorizeCourseStaff(c, s.courseRepo, courseID)
+	}
+	if err != nil {
+		return 0, 0, err
+	}
+
+	enrolled, err := s.courseRepo.IsStudentEnrolled(courseID, userID)
+	if err != nil {
+		return 0, 0, echo.NewHTTPError(http.StatusInternalServerError, "Failed to check enrollment")
+	}
+	if !enrolled {
+		return 0, 0, echo.NewHTTPError(http.StatusNotFound, "The student is not enrolled in this course")
+	}
+	return courseID, userID, nil
+}
+
+// applyGradeScaleRequest binds and validates a grade scale request and
+// copies it into the scale. Letters and minimum scores must be unique, grade
+// points must not rise as scores fall, and the lowest entry must start at 0
+// so that every score earns a letter.
+func applyGradeScaleRequest(c echo.Context, scale *domain.GradeScale) error {
+	var req domain.GradeScaleRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+	if err := c.Validate(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	entries := make([]domain.GradeScaleEntry, 0, len(req.Entries))
+	letters := make(map[string]bool, len(req.Entries))
+	for _, entry := range req.Entries {
+		letter := strings.TrimSpace(entry.Letter)
+		if letters[letter] {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Letter %q is used more than once", letter))
+		}
+		letters[letter] = true
+		entries = append(entries, domain.GradeScaleEntry{
+			Letter:      letter,
+			MinScore:    entry.MinScore,
+			GradePoints: entry.GradePoints,
+		})
+	}
+
+	scale.Name = strings.TrimSpace(req.Name)
+	scale.Entries = entries
+	scale.SortEntries()
+	for i := 1; i < len(scale.Entries); i++ {
+		prev, entry := scale.Entries[i-1], scale.Entries[i]
+		if entry.MinScore == prev.MinScore {
+			return echo.NewHTTPError(http.StatusBadRequest,
+				fmt.Sprintf("Letters %s and %s have the same minimum score", prev.Letter, entry.Letter))
+		}
+		if entry.GradePoints > prev.GradePoints {
+			return echo.NewHTTPError(http.StatusBadRequest,
+				fmt.Sprintf("%s must not be worth more grade points than %s", entry.Letter, prev.Letter))
+		}
+	}
+	if scale.Entries[len(scale.Entries)-1].MinScore != 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "The lowest letter must have a minimum score of 0")
+	}
+	return nil
+}
+
+// weightsResponse reports gradebook items with their total weight
+func weightsResponse(items []domain.GradebookItem) map[string]interface{} {
+	total, valid := ValidateWeights(items)
+	return map[string]interface{}{
+		"items":        items,
+		"totalWeight":  total,
+		"weightsValid": valid,
+	}
 }
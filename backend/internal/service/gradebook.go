@@ -0,0 +1,288 @@
+package service
+
+import (
+	"backend/internal/domain"
+	"backend/internal/repository"
+	"log"
+	"math"
+	"time"
+)
+
+// weightTolerance absorbs rounding when checking that weights sum to 100
+const weightTolerance = 0.01
+
+// Gradebook computes course grades from the weighted results of a course's
+// assessments and exams. Assessments count with the student's latest graded
+// submission and exams with their best graded attempt. Excused items are
+// left out and their weight is spread over the other items; items past due
+// without any work handed in count as zero; all other ungraded items are
+// left out until they are graded. Scores map to letter grades through the
+// course's grade scale, else the institution's, else DefaultGradeScale.
+// The student's GPA is resynced whenever a course grade changes.
+//
+// Only draft grades are recalculated. Once submitted for publication they
+// are kept as they are, and published grades change only through Amend.
+type Gradebook struct {
+	gradeRepo *repository.GradeRepository
+	settings  *SettingsStore
+	now       func() time.Time
+}
+
+// NewGradebook creates a new gradebook
+func NewGradebook(gradeRepo *repository.GradeRepository, settings *SettingsStore) *Gradebook {
+	return &Gradebook{
+		gradeRepo: gradeRepo,
+		settings:  settings,
+		now:       time.Now,
+	}
+}
+
+// gradeItemKey identifies a gradebook item within a course
+type gradeItemKey struct {
+	itemType string
+	id       uint
+}
+
+// Scale returns the grade scale that applies to a course
+func (g *Gradebook) Scale(courseID uint) (*domain.GradeScale, error) {
+	scale, err := g.gradeRepo.GetScale(&courseID)
+	if err == nil {
+		return scale, nil
+	}
+	if err.Error() != "grade scale not found" {
+		return nil, err
+	}
+	return g.InstitutionScale()
+}
+
+// InstitutionScale returns the institution's grade scale
+func (g *Gradebook) InstitutionScale() (*domain.GradeScale, error) {
+	scale, err := g.gradeRepo.GetScale(nil)
+	if err == nil {
+		return scale, nil
+	}
+	if err.Error() != "grade scale not found" {
+		return nil, err
+	}
+	defaults := domain.DefaultGradeScale()
+	return &defaults, nil
+}
+
+// Recalculate recomputes a student's gradebook items and course grade
+func (g *Gradebook) Recalculate(courseID, userID uint) (*domain.StudentGradebook, error) {
+	items, err := g.gradeRepo.GetItems(courseID)
+	if err != nil {
+		return nil, err
+	}
+	scale, err := g.Scale(courseID)
+	if err != nil {
+		return nil, err
+	}
+
+	var book *domain.StudentGradebook
+	err = g.gradeRepo.Transaction(func(tx *repository.GradeRepository) error {
+		if err := tx.LockStudentCourse(courseID, userID); err != nil {
+			return err
+		}
+		existing, err := tx.GetGrades(courseID, userID)
+		if err != nil {
+			return err
+		}
+		courseGrade, err := tx.GetCourseGrade(courseID, userID)
+		if err != nil {
+			if err.Error() != "course grade not found" {
+				return err
+			}
+			courseGrade = &domain.CourseGrade{CourseID: courseID, UserID: userID, PublicationStatus: domain.PublicationDraft}
+		}
+		if frozen(courseGrade) {
+			book = &domain.StudentGradebook{CourseGrade: *courseGrade, Items: existing}
+			return nil
+		}
+
+		results, err := studentResults(tx, courseID, userID)
+		if err != nil {
+			return err
+		}
+		grades, stale := g.computeGrades(courseID, userID, items, results, existing, scale)
+		for i := range grades {
+			if err := tx.SaveGrade(&grades[i]); err != nil {
+				return err
+			}
+		}
+		if err := tx.DeleteGrades(stale); err != nil {
+			return err
+		}
+
+		applyCourseTotal(courseGrade, grades, scale, g.now())
+		if err := tx.SaveCourseGrade(courseGrade); err != nil {
+			return err
+		}
+
+		book = &domain.StudentGradebook{CourseGrade: *courseGrade, Items: grades}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := g.SyncGPA(userID); err != nil {
+		log.Printf("Failed to sync GPA of user %d: %v", userID, err)
+	}
+	return book, nil
+}
+
+// RecalculateCourse recomputes the gradebooks of every student of a course
+func (g *Gradebook) RecalculateCourse(courseID uint) error {
+	userIDs, err := g.gradeRepo.GetStudentIDs(courseID)
+	if err != nil {
+		return err
+	}
+	for _, userID := range userIDs {
+		if _, err := g.Recalculate(courseID, userID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ResultChanged recalculates a student's gradebook after a result for an
+// assessment or exam changed. The result is already stored, so failures are
+// only logged; the next recalculation catches up.
+func (g *Gradebook) ResultChanged(itemType string, itemID, userID uint) {
+	if g == nil {
+		return
+	}
+	courseID, err := g.gradeRepo.GetItemCourseID(itemType, itemID)
+	if err == nil {
+		_, err = g.Recalculate(courseID, userID)
+	}
+	if err != nil {
+		log.Printf("Failed to recalculate gradebook for %s %d, user %d: %v", itemType, itemID, userID, err)
+	}
+}
+
+// ValidateWeights checks that the item weights of a course sum to 100
+func ValidateWeights(items []domain.GradebookItem) (total float64, valid bool) {
+	for _, item := range items {
+		total += item.Weight
+	}
+	total = math.Round(total*100) / 100
+	return total, math.Abs(total-100) <= weightTolerance
+}
+
+// studentResults loads a student's results for the items of a course
+func studentResults(tx *repository.GradeRepository, courseID, userID uint) (map[gradeItemKey]repository.ItemResult, error) {
+	submissions, err := tx.GetSubmissionResults(courseID, userID)
+	if err != nil {
+		return nil, err
+	}
+	exams, err := tx.GetExamResults(courseID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make(map[gradeItemKey]repository.ItemResult, len(submissions)+len(exams))
+	for _, result := range submissions {
+		results[gradeItemKey{domain.GradeItemAssessment, result.ItemID}] = result
+	}
+	for _, result := range exams {
+		results[gradeItemKey{domain.GradeItemExam, result.ItemID}] = result
+	}
+	return results, nil
+}
+
+// computeGrades derives a student's gradebook items from their results,
+// reusing the stored rows so that excusals are kept. It returns the IDs of
+// stored rows whose item no longer exists.
+func (g *Gradebook) computeGrades(courseID, userID uint, items []domain.GradebookItem, results map[gradeItemKey]repository.ItemResult,
+	existing []domain.Grade, scale *domain.GradeScale) ([]domain.Grade, []uint) {
+	stored := make(map[gradeItemKey]domain.Grade, len(existing))
+	for _, grade := range existing {
+		stored[gradeKey(&grade)] = grade
+	}
+
+	now := g.now()
+	grades := make([]domain.Grade, 0, len(items))
+	for _, item := range items {
+		key := gradeItemKey{item.Type, item.ID}
+		grade, ok := stored[key]
+		if ok {
+			delete(stored, key)
+		} else {
+			grade = domain.Grade{CourseID: courseID, UserID: userID, PublicationStatus: domain.PublicationDraft}
+			id := item.ID
+			if item.Type == domain.GradeItemExam {
+				grade.ExamID = &id
+			} else {
+				grade.AssessmentID = &id
+			}
+		}
+		grade.Title = item.Title
+		grade.Weight = item.Weight
+		grade.Score = 0
+		grade.LetterGrade = ""
+
+		result, handedIn := results[key]
+		switch {
+		case grade.Excused:
+			grade.Status = domain.GradeStatusExcused
+		case handedIn && result.Graded:
+			grade.Status = domain.GradeStatusGraded
+			grade.Score = result.Score
+			grade.LetterGrade, _ = scale.Grade(result.Score)
+		case !handedIn && item.DueDate != nil && now.After(*item.DueDate):
+			grade.Status = domain.GradeStatusMissing
+			grade.LetterGrade, _ = scale.Grade(0)
+		default:
+			grade.Status = domain.GradeStatusPending
+		}
+		grade.LastUpdated = now
+		grades = append(grades, grade)
+	}
+
+	stale := make([]uint, 0, len(stored))
+	for _, grade := range stored {
+		stale = append(stale, grade.ID)
+	}
+	return grades, stale
+}
+
+// applyCourseTotal sets the weighted course total of the graded and missing
+// items, scaled up to the weight they carry, and its letter grade
+func applyCourseTotal(courseGrade *domain.CourseGrade, grades []domain.Grade, scale *domain.GradeScale, now time.Time) {
+	var earned, counted float64
+	for _, grade := range grades {
+		if grade.Status != domain.GradeStatusGraded && grade.Status != domain.GradeStatusMissing {
+			continue
+		}
+		earned += grade.Weight * grade.Score
+		counted += grade.Weight
+	}
+
+	courseGrade.CountedWeight = math.Round(counted*100) / 100
+	courseGrade.OverallScore = 0
+	courseGrade.LetterGrade = ""
+	courseGrade.GradePoints = 0
+	if counted > 0 {
+		courseGrade.OverallScore = math.Round(earned/counted*100) / 100
+		courseGrade.LetterGrade, courseGrade.GradePoints = scale.Grade(courseGrade.OverallScore)
+	}
+	courseGrade.LastUpdated = now
+}
+
+// frozen reports whether a course grade has left the draft stage and is no
+// longer recalculated
+func frozen(courseGrade *domain.CourseGrade) bool {
+	return courseGrade.PublicationStatus != "" && courseGrade.PublicationStatus != domain.PublicationDraft
+}
+
+func gradeKey(grade *domain.Grade) gradeItemKey {
+	if grade.ExamID != nil {
+		return gradeItemKey{domain.GradeItemExam, *grade.ExamID}
+	}
+	if grade.AssessmentID != nil {
+		return gradeItemKey{domain.GradeItemAssessment, *grade.AssessmentID}
+	}
+	// Rows from before the gradebook tracked items are replaced
+	return gradeItemKey{"", grade.ID}
+}
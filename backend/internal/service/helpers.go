@@ -0,0 +1,75 @@
+package service
+
+import (
+	"backend/internal/repository"
+	"backend/pkg/middleware"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+)
+
+// parseIDParam parses a numeric path parameter such as ":id"
+func parseIDParam(c echo.Context, name string) (uint, error) {
+	id, err := strconv.ParseUint(c.Param(name), 10, 32)
+	if err != nil {
+		return 0, err
+	}
+	return uint(id), nil
+}
+
+// authorizeCourseAccess checks that the current user may view the course:
+// admins always, instructors when teaching it, students when enrolled
+func authorizeCourseAccess(c echo.Context, courseRepo *repository.CourseRepository, courseID uint) error {
+	userID, err := middleware.GetUserIDFromToken(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Invalid token")
+	}
+	role, err := middleware.GetRoleFromToken(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Invalid token")
+	}
+
+	var allowed bool
+	switch role {
+	case "admin":
+		return nil
+	case "instructor":
+		allowed, err = courseRepo.IsInstructor(courseID, userID)
+	default:
+		allowed, err = courseRepo.IsStudentEnrolled(courseID, userID)
+	}
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to check course access")
+	}
+	if !allowed {
+		return echo.NewHTTPError(http.StatusForbidden, "You do not have access to this course")
+	}
+	return nil
+}
+
+// resultsReleased reports whether a student may see their scores and
+// feedback for an assessment or exam of a course
+func resultsReleased(courseRepo *repository.CourseRepository, gradebook *Gradebook, courseID uint, itemType string, itemID, userID uint) (bool, error) {
+	course, err := courseRepo.GetByID(courseID)
+	if err != nil {
+		return false, echo.NewHTTPError(http.StatusNotFound, "Course not found")
+	}
+	released, err := gradebook.ItemReleased(course, itemType, itemID, userID)
+	if err != nil {
+		return false, echo.NewHTTPError(http.StatusInternalServerError, "Failed to check grade release")
+	}
+	return released, nil
+}
+
+// authorizeCourseStaff checks that the current user is an admin or teaches the course
+func authorizeCourseStaff(c echo.Context, courseRepo *repository.CourseRepository, courseID uint) error {
+	role, err := middleware.GetRoleFromToken(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Invalid token")
+	}
+	if role != "admin" && role != "instructor" {
+		return echo.NewHTTPError(http.StatusForbidden, "Instructor access required")
+	}
+	return authorizeCourseAccess(c, courseRepo, courseID)
+}
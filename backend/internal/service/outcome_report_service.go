@@ -0,0 +1,367 @@
+package service
+
+import (
+	"backend/internal/domain"
+	"backend/internal/repository"
+	"backend/pkg/middleware"
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// rubricLevelRanks orders the rubric levels for averaging
+var rubricLevelRanks = map[string]int{
+	domain.RubricLevelPoor:      1,
+	domain.RubricLevelAverage:   2,
+	domain.RubricLevelGood:      3,
+	domain.RubricLevelExcellent: 4,
+}
+
+// OutcomeReportService reports how students attain the learning outcomes of
+// their courses, from the rubric levels given on their latest graded
+// submissions. Reports are returned as JSON, or as CSV with format=csv.
+type OutcomeReportService struct {
+	reportRepo *repository.OutcomeReportRepository
+	courseRepo *repository.CourseRepository
+	userRepo   *repository.UserRepository
+	now        func() time.Time
+}
+
+// NewOutcomeReportService creates a new outcome report service
+func NewOutcomeReportService(
+	reportRepo *repository.OutcomeReportRepository,
+	courseRepo *repository.CourseRepository,
+	userRepo *repository.UserRepository,
+) *OutcomeReportService {
+	return &OutcomeReportService{
+		reportRepo: reportRepo,
+		courseRepo: courseRepo,
+		userRepo:   userRepo,
+		now:        time.Now,
+	}
+}
+
+// GetCourseReport returns, for every learning outcome of a course, the share
+// of assessed students at each rubric level and their mean attainment
+func (s *OutcomeReportService) GetCourseReport(c echo.Context) error {
+	courseID, err := parseIDParam(c, "id")
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid course ID")
+	}
+	course, err := s.courseRepo.GetByID(courseID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, "Course not found")
+	}
+	if err := authorizeCourseStaff(c, s.courseRepo, courseID); err != nil {
+		return err
+	}
+
+	outcomes, err := s.reportRepo.GetCourseOutcomes([]uint{courseID})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to get learning outcomes")
+	}
+	selections, err := s.reportRepo.GetCourseSelections(courseID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to get rubric grades")
+	}
+
+	report := domain.CourseOutcomeReport{
+		CourseID:    course.ID,
+		CourseCode:  course.Code,
+		CourseTitle: course.Title,
+		Outcomes:    courseAttainment(outcomes, selections),
+		GeneratedAt: s.now(),
+	}
+
+	if isCSVRequested(c) {
+		return sendCSV(c, fmt.Sprintf("outcomes-%s.csv", fileNamePart(course.Code)), courseReportCSV(&report))
+	}
+	return c.JSON(http.StatusOK, report)
+}
+
+// GetStudentProfile returns a student's attainment of the learning outcomes
+// of every course they are or were enrolled in. Students see their own
+// profile, instructors the courses they teach and admins everything.
+func (s *OutcomeReportService) GetStudentProfile(c echo.Context) error {
+	currentUserID, err := middleware.GetUserIDFromToken(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Invalid token")
+	}
+	role, err := middleware.GetRoleFromToken(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Invalid token")
+	}
+
+	userID, err := parseIDParam(c, "id")
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid user ID")
+	}
+	if role == "student" && userID != currentUserID {
+		return echo.NewHTTPError(http.StatusForbidden, "You can only view your own outcome profile")
+	}
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, "User not found")
+	}
+
+	selections, err := s.reportRepo.GetStudentSelections(userID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to get rubric grades")
+	}
+	courseIDs, err := s.reportRepo.GetStudentCourseIDs(userID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to get courses")
+	}
+	courseIDs = mergeCourseIDs(courseIDs, selections)
+
+	// Instructors only see the courses they teach
+	if role == "instructor" {
+		taught := courseIDs[:0]
+		for _, courseID := range courseIDs {
+			ok, err := s.courseRepo.IsInstructor(courseID, currentUserID)
+			if err != nil {
+				return echo.NewHTTPError(http.StatusInternalServerError, "Failed to check course access")
+			}
+			if ok {
+				taught = append(taught, courseID)
+			}
+		}
+		courseIDs = taught
+	}
+
+	outcomes, err := s.reportRepo.GetCourseOutcomes(courseIDs)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to get learning outcomes")
+	}
+
+	profile := domain.StudentOutcomeProfile{
+		UserID:      user.ID,
+		Name:        user.Name,
+		Courses:     make([]domain.StudentCourseOutcomes, 0, len(courseIDs)),
+		GeneratedAt: s.now(),
+	}
+	for _, courseID := range courseIDs {
+		course, err := s.courseRepo.GetByID(courseID)
+		if err != nil {
+			continue
+		}
+		profile.Courses = append(profile.Courses, domain.StudentCourseOutcomes{
+			CourseID:    course.ID,
+			CourseCode:  course.Code,
+			CourseTitle: course.Title,
+			Outcomes:    studentAttainment(courseID, outcomes, selections),
+		})
+	}
+
+	if isCSVRequested(c) {
+		return sendCSV(c, fmt.Sprintf("outcomes-%s.csv", fileNamePart(user.Username)), studentProfileCSV(&profile))
+	}
+	return c.JSON(http.StatusOK, profile)
+}
+
+// courseAttainment aggregates the selections of a course per learning outcome
+func courseAttainment(outcomes []repository.CourseOutcome, selections []repository.OutcomeSelection) []domain.OutcomeAttainment {
+	// Group the selections per outcome and student
+	perOutcome := make(map[uint]map[uint][]repository.OutcomeSelection)
+	for _, selection := range selections {
+		students := perOutcome[selection.LearningOutcomeID]
+		if students == nil {
+			students = make(map[uint][]repository.OutcomeSelection)
+			perOutcome[selection.LearningOutcomeID] = students
+		}
+		students[selection.UserID] = append(students[selection.UserID], selection)
+	}
+
+	report := make([]domain.OutcomeAttainment, 0, len(outcomes))
+	for _, outcome := range outcomes {
+		attainment := domain.OutcomeAttainment{
+			LearningOutcomeID: outcome.ID,
+			Code:              outcome.Code,
+			Description:       outcome.Knowledge,
+		}
+		counts := make(map[string]int, len(domain.RubricLevels))
+		var total float64
+		for _, studentSelections := range perOutcome[outcome.ID] {
+			share, level, ok := summarizeSelections(studentSelections)
+			if !ok {
+				continue
+			}
+			attainment.StudentsAssessed++
+			counts[level]++
+			total += share
+		}
+		if attainment.StudentsAssessed > 0 {
+			mean := roundPercent(total / float64(attainment.StudentsAssessed))
+			attainment.MeanAttainment = &mean
+		}
+		for _, level := range domain.RubricLevels {
+			share := domain.RubricLevelShare{Level: level, Students: counts[level]}
+			if attainment.StudentsAssessed > 0 {
+				share.Percent = roundPercent(float64(counts[level]) / float64(attainment.StudentsAssessed) * 100)
+			}
+			attainment.Levels = append(attainment.Levels, share)
+		}
+		report = append(report, attainment)
+	}
+	return report
+}
+
+// studentAttainment summarizes a student's selections for the outcomes of one course
+func studentAttainment(courseID uint, outcomes []repository.CourseOutcome, selections []repository.OutcomeSelection) []domain.StudentOutcome {
+	perOutcome := make(map[uint][]repository.OutcomeSelection)
+	for _, selection := range selections {
+		if selection.CourseID == courseID {
+			perOutcome[selection.LearningOutcomeID] = append(perOutcome[selection.LearningOutcomeID], selection)
+		}
+	}
+
+	result := make([]domain.StudentOutcome, 0)
+	for _, outcome := range outcomes {
+		if outcome.CourseID != courseID {
+			continue
+		}
+		studentOutcome := domain.StudentOutcome{
+			LearningOutcomeID: outcome.ID,
+			Code:              outcome.Code,
+			Description:       outcome.Knowledge,
+			AssessedItems:     len(perOutcome[outcome.ID]),
+		}
+		if share, level, ok := summarizeSelections(perOutcome[outcome.ID]); ok {
+			studentOutcome.Attainment = &share
+			studentOutcome.Level = level
+		}
+		result = append(result, studentOutcome)
+	}
+	return result
+}
+
+// summarizeSelections returns the share of rubric points earned, in
+// percent, and the rounded mean level of one student's selections
+func summarizeSelections(selections []repository.OutcomeSelection) (share float64, level string, ok bool) {
+	var points, maxPoints float64
+	var rankSum, ranked int
+	for _, selection := range selections {
+		points += selection.Points
+		maxPoints += selection.MaxPoints
+		if rank, known := rubricLevelRanks[selection.Level]; known {
+			rankSum += rank
+			ranked++
+		}
+	}
+	if maxPoints <= 0 || ranked == 0 {
+		return 0, "", false
+	}
+
+	// RubricLevels runs from excellent, rank 4, down to poor, rank 1
+	rank := int(math.Round(float64(rankSum) / float64(ranked)))
+	level = domain.RubricLevels[len(domain.RubricLevels)-rank]
+	return roundPercent(points / maxPoints * 100), level, true
+}
+
+// mergeCourseIDs adds the courses of the selections to the course IDs, in order
+func mergeCourseIDs(courseIDs []uint, selections []repository.OutcomeSelection) []uint {
+	seen := make(map[uint]bool, len(courseIDs))
+	for _, id := range courseIDs {
+		seen[id] = true
+	}
+	for _, selection := range selections {
+		if !seen[selection.CourseID] {
+			seen[selection.CourseID] = true
+			courseIDs = append(courseIDs, selection.CourseID)
+		}
+	}
+	return courseIDs
+}
+
+func roundPercent(value float64) float64 {
+	return math.Round(value*100) / 100
+}
+
+// courseReportCSV writes one row per learning outcome
+func courseReportCSV(report *domain.CourseOutcomeReport) [][]string {
+	header := []string{"course_code", "outcome_code", "outcome", "students_assessed", "mean_attainment"}
+	for _, level := range domain.RubricLevels {
+		header = append(header, level+"_percent")
+	}
+	rows := [][]string{header}
+	for _, outcome := range report.Outcomes {
+		row := []string{
+			report.CourseCode,
+			outcome.Code,
+			outcome.Description,
+			strconv.Itoa(outcome.StudentsAssessed),
+			formatOptionalPercent(outcome.MeanAttainment),
+		}
+		for _, share := range outcome.Levels {
+			row = append(row, strconv.FormatFloat(share.Percent, 'f', 2, 64))
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// studentProfileCSV writes one row per course and learning outcome
+func studentProfileCSV(profile *domain.StudentOutcomeProfile) [][]string {
+	rows := [][]string{{"user_id", "name", "course_code", "course_title", "outcome_code", "outcome", "assessed_items", "attainment", "level"}}
+	for _, course := range profile.Courses {
+		for _, outcome := range course.Outcomes {
+			rows = append(rows, []string{
+				strconv.FormatUint(uint64(profile.UserID), 10),
+				profile.Name,
+				course.CourseCode,
+				course.CourseTitle,
+				outcome.Code,
+				outcome.Description,
+				strconv.Itoa(outcome.AssessedItems),
+				formatOptionalPercent(outcome.Attainment),
+				outcome.Level,
+			})
+		}
+	}
+	return rows
+}
+
+func formatOptionalPercent(value *float64) string {
+	if value == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*value, 'f', 2, 64)
+}
+
+// isCSVRequested reports whether the client asked for CSV with format=csv
+func isCSVRequested(c echo.Context) bool {
+	return strings.EqualFold(c.QueryParam("format"), "csv")
+}
+
+// sendCSV sends rows as a CSV attachment
+func sendCSV(c echo.Context, filename string, rows [][]string) error {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	if err := w.WriteAll(rows); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to write CSV")
+	}
+	c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", filename))
+	return c.Blob(http.StatusOK, "text/csv; charset=utf-8", buf.Bytes())
+}
+
+// fileNamePart keeps the characters of value that are safe in a file name
+func fileNamePart(value string) string {
+	var b strings.Builder
+	for _, r := range value {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_':
+			b.WriteRune(r)
+		}
+	}
+	if b.Len() == 0 {
+		return "report"
+	}
+	return b.String()
+}
@@ -0,0 +1,190 @@
+package service
+
+import (
+	"backend/internal/domain"
+	"backend/internal/repository"
+	"backend/pkg/mail"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Password reset limits
+const (
+	passwordResetTTL          = time.Hour
+	passwordResetWindow       = time.Hour // period the request limits apply to
+	passwordResetEmailLimit   = 3         // requests per address per window
+	passwordResetClientLimit  = 10        // requests per client IP per window
+	passwordResetAttemptsKept = 24 * time.Hour
+)
+
+// ErrPasswordResetToken is returned for reset tokens that are unknown,
+// used or expired
+var ErrPasswordResetToken = errors.New("the password reset link is invalid or has expired")
+
+// RequestPasswordReset mails a single-use link to set a new password. The
+// answer is the same whether or not the address belongs to an account, and
+// requests are limited per address and per client.
+func (s *AuthService) RequestPasswordReset(c echo.Context) error {
+	var req domain.ForgotPasswordRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+
+	if err := c.Validate(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	email := normalizeEmail(req.Email)
+	ip := clientDetail(c.RealIP(), 64)
+	now := time.Now()
+
+	byEmail, byClient, err := s.passwordResetRepo.CountAttempts(email, ip, now.Add(-passwordResetWindow))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to request password reset")
+	}
+	if byEmail >= passwordResetEmailLimit || byClient >= passwordResetClientLimit {
+		c.Response().Header().Set("Retry-After", fmt.Sprint(int(passwordResetWindow.Seconds())))
+		return echo.NewHTTPError(http.StatusTooManyRequests, "Too many password reset requests; try again later")
+	}
+	attempt := &domain.PasswordResetAttempt{Email: email, IPAddress: ip, CreatedAt: now}
+	if err := s.passwordResetRepo.RecordAttempt(attempt); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to request password reset")
+	}
+
+	if user, err := s.userRepo.GetByEmail(email); err == nil {
+		if err := s.issuePasswordReset(user, ip, now); err != nil {
+			log.Printf("Failed to issue password reset for user %d: %v", user.ID, err)
+		}
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"message": "If an account uses this address, a link to reset its password has been sent to it.",
+	})
+}
+
+// ResetPassword sets a new password with the token of a reset link. The
+// token is used up, and every session of the user is signed out.
+func (s *AuthService) ResetPassword(c echo.Context) error {
+	var req domain.ResetPasswordRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+
+	if err := c.Validate(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	now := time.Now()
+	hash := hashToken(strings.TrimSpace(req.Token))
+	token, err := s.passwordResetRepo.GetByHash(hash)
+	if err != nil {
+		if err.Error() == "password reset token not found" {
+			return echo.NewHTTPError(http.StatusBadRequest, ErrPasswordResetToken.Error())
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to reset password")
+	}
+	if !token.Usable(now) {
+		return echo.NewHTTPError(http.StatusBadRequest, ErrPasswordResetToken.Error())
+	}
+
+	user, err := s.userRepo.GetByID(token.UserID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, ErrPasswordResetToken.Error())
+	}
+	if err := user.SetPassword(req.NewPassword); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to set new password")
+	}
+	user.UpdatedAt = now
+	// The link reached the address, which proves it
+	if user.EmailVerifiedAt == nil {
+		user.EmailVerifiedAt = &now
+	}
+
+	err = s.passwordResetRepo.Transaction(func(tx *repository.PasswordResetRepository) error {
+		token, err := tx.LockByHash(hash)
+		if err != nil {
+			return err
+		}
+		if !token.Usable(now) {
+			return ErrPasswordResetToken
+		}
+		token.UsedAt = &now
+		if err := tx.Save(token); err != nil {
+			return err
+		}
+		if err := tx.DeleteUnused(user.ID); err != nil {
+			return err
+		}
+		return tx.UpdateUser(user)
+	})
+	if err != nil {
+		if errors.Is(err, ErrPasswordResetToken) || err.Error() == "password reset token not found" {
+			return echo.NewHTTPError(http.StatusBadRequest, ErrPasswordResetToken.Error())
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to reset password")
+	}
+
+	// Whoever knew the old password is signed out too
+	if err := s.refreshTokenRepo.DeleteByUserID(user.ID); err != nil {
+		log.Printf("Failed to sign out user %d after password reset: %v", user.ID, err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"message": "Password has been reset. Sign in with your new password.",
+	})
+}
+
+// PrunePasswordResets deletes expired reset tokens and the requests that no
+// longer count toward the limits
+func (s *AuthService) PrunePasswordResets() error {
+	return s.passwordResetRepo.DeleteExpired(time.Now().Add(-passwordResetAttemptsKept))
+}
+
+// issuePasswordReset replaces a user's outstanding reset links with a new
+// one and mails it. The mail goes out in the background so that answering
+// for a known address takes no longer than for an unknown one.
+func (s *AuthService) issuePasswordReset(user *domain.User, ip string, now time.Time) error {
+	tokenString, err := randomHex(32)
+	if err != nil {
+		return err
+	}
+	token := &domain.PasswordResetToken{
+		UserID:      user.ID,
+		TokenHash:   hashToken(tokenString),
+		RequestedIP: ip,
+		ExpiresAt:   now.Add(passwordResetTTL),
+	}
+	err = s.passwordResetRepo.Transaction(func(tx *repository.PasswordResetRepository) error {
+		if err := tx.DeleteUnused(user.ID); err != nil {
+			return err
+		}
+		return tx.Create(token)
+	})
+	if err != nil {
+		return err
+	}
+
+	settings := s.settings.Current()
+	msg := mail.Message{
+		To:      user.Email,
+		Subject: fmt.Sprintf("Reset your %s password", settings.SystemName),
+		Body: fmt.Sprintf("Hello %s,\n\nSomeone, hopefully you, asked to reset the password of your account. "+
+			"Use this code to choose a new password:\n\n%s\n\n"+
+			"The code works once and expires in %d minutes. If you did not ask for it, ignore this message; "+
+			"your password stays the same.\n",
+			user.Name, tokenString, int(passwordResetTTL.Minutes())),
+	}
+	go func() {
+		if err := s.mailer.Send(context.Background(), msg); err != nil {
+			log.Printf("Failed to send password reset email to user %d: %v", user.ID, err)
+		}
+	}()
+	return nil
+}
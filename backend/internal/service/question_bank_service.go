@@ -0,0 +1,295 @@
+package service
+
+import (
+	"backend/internal/domain"
+	"backend/internal/repository"
+	"backend/pkg/middleware"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// QuestionBankService handles the question banks of courses. Only course
+// staff can see bank questions, since they carry their correct answers.
+type QuestionBankService struct {
+	bankRepo   *repository.QuestionBankRepository
+	courseRepo *repository.CourseRepository
+	graders    *GraderRegistry
+}
+
+// NewQuestionBankService creates a new question bank service
+func NewQuestionBankService(
+	bankRepo *repository.QuestionBankRepository,
+	courseRepo *repository.CourseRepository,
+	graders *GraderRegistry,
+) *QuestionBankService {
+	return &QuestionBankService{
+		bankRepo:   bankRepo,
+		courseRepo: courseRepo,
+		graders:    graders,
+	}
+}
+
+// GetQuestions returns the course's bank questions, optionally filtered by
+// type, tag, difficulty and learning outcome
+func (s *QuestionBankService) GetQuestions(c echo.Context) error {
+	courseID, err := s.courseFromParam(c)
+	if err != nil {
+		return err
+	}
+
+	filter := repository.BankQuestionFilter{
+		CourseID:   courseID,
+		Type:       c.QueryParam("type"),
+		Tag:        c.QueryParam("tag"),
+		Difficulty: c.QueryParam("difficulty"),
+	}
+	if outcomeParam := c.QueryParam("learningOutcomeId"); outcomeParam != "" {
+		outcomeID, err := strconv.ParseUint(outcomeParam, 10, 32)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid learning outcome ID")
+		}
+		filter.LearningOutcomeID = uint(outcomeID)
+	}
+
+	questions, err := s.bankRepo.Find(filter)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to get questions")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"questions": questions,
+	})
+}
+
+// GetQuestion returns a bank question
+func (s *QuestionBankService) GetQuestion(c echo.Context) error {
+	courseID, err := s.courseFromParam(c)
+	if err != nil {
+		return err
+	}
+	question, err := s.questionFromParam(c, courseID)
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, question)
+}
+
+// GetTags returns the tags of the course's question bank
+func (s *QuestionBankService) GetTags(c echo.Context) error {
+	courseID, err := s.courseFromParam(c)
+	if err != nil {
+		return err
+	}
+
+	tags, err := s.bankRepo.GetTags(courseID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to get tags")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"tags": tags,
+	})
+}
+
+// CreateQuestion adds a question to the course's question bank
+func (s *QuestionBankService) CreateQuestion(c echo.Context) error {
+	courseID, err := s.courseFromParam(c)
+	if err != nil {
+		return err
+	}
+	userID, err := middleware.GetUserIDFromToken(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Invalid token")
+	}
+
+	req, err := s.bindRequest(c)
+	if err != nil {
+		return err
+	}
+
+	question := &domain.BankQuestion{CourseID: courseID, CreatedBy: userID}
+	err = s.bankRepo.Transaction(func(tx *repository.QuestionBankRepository) error {
+		if err := s.applyRequest(tx, question, req); err != nil {
+			return err
+		}
+		return tx.Create(question)
+	})
+	if err != nil {
+		return bankHTTPError(err, "Failed to create question")
+	}
+
+	return c.JSON(http.StatusCreated, question)
+}
+
+// UpdateQuestion updates a bank question. Exams keep the copies they
+// already drew.
+func (s *QuestionBankService) UpdateQuestion(c echo.Context) error {
+	courseID, err := s.courseFromParam(c)
+	if err != nil {
+		return err
+	}
+	question, err := s.questionFromParam(c, courseID)
+	if err != nil {
+		return err
+	}
+
+	req, err := s.bindRequest(c)
+	if err != nil {
+		return err
+	}
+
+	err = s.bankRepo.Transaction(func(tx *repository.QuestionBankRepository) error {
+		if err := s.applyRequest(tx, question, req); err != nil {
+			return err
+		}
+		return tx.Update(question)
+	})
+	if err != nil {
+		return bankHTTPError(err, "Failed to update question")
+	}
+
+	return c.JSON(http.StatusOK, question)
+}
+
+// DeleteQuestion removes a question from the course's question bank
+func (s *QuestionBankService) DeleteQuestion(c echo.Context) error {
+	courseID, err := s.courseFromParam(c)
+	if err != nil {
+		return err
+	}
+	question, err := s.questionFromParam(c, courseID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.bankRepo.Delete(question.ID); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to delete question")
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"message": "Question deleted successfully",
+	})
+}
+
+// courseFromParam parses the course named by ":id" and checks that the
+// current user is on its staff
+func (s *QuestionBankService) courseFromParam(c echo.Context) (uint, error) {
+	courseID, err := parseIDParam(c, "id")
+	if err != nil {
+		return 0, echo.NewHTTPError(http.StatusBadRequest, "Invalid course ID")
+	}
+	if _, err := s.courseRepo.GetByID(courseID); err != nil {
+		return 0, echo.NewHTTPError(http.StatusNotFound, "Course not found")
+	}
+	if err := authorizeCourseStaff(c, s.courseRepo, courseID); err != nil {
+		return 0, err
+	}
+	return courseID, nil
+}
+
+// questionFromParam loads the bank question named by ":questionId"
+func (s *QuestionBankService) questionFromParam(c echo.Context, courseID uint) (*domain.BankQuestion, error) {
+	questionID, err := parseIDParam(c, "questionId")
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid question ID")
+	}
+	question, err := s.bankRepo.GetByID(questionID)
+	if err != nil || question.CourseID != courseID {
+		return nil, echo.NewHTTPError(http.StatusNotFound, "Question not found")
+	}
+	return question, nil
+}
+
+func (s *QuestionBankService) bindRequest(c echo.Context) (*domain.BankQuestionRequest, error) {
+	var req domain.BankQuestionRequest
+	if err := c.Bind(&req); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+	if err := c.Validate(&req); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	return &req, nil
+}
+
+// bankValidationError is a problem with a bank question request
+type bankValidationError struct {
+	message string
+}
+
+func (e *bankValidationError) Error() string {
+	return e.message
+}
+
+// applyRequest copies the request into the question, checking its answer key
+// and resolving its tags and learning outcomes within the course
+func (s *QuestionBankService) applyRequest(tx *repository.QuestionBankRepository, question *domain.BankQuestion, req *domain.BankQuestionRequest) error {
+	question.Type = req.Type
+	question.Question = req.Question
+	question.Options = req.Options
+	question.CorrectAnswer = req.CorrectAnswer
+	question.Points = req.Points
+	question.Difficulty = req.Difficulty
+	if question.Difficulty == "" {
+		question.Difficulty = domain.DifficultyMedium
+	}
+
+	examQuestion := question.ToExamQuestion(0)
+	if err := s.graders.Validate(&examQuestion); err != nil {
+		return &bankValidationError{err.Error()}
+	}
+
+	tags, err := tx.EnsureTags(question.CourseID, normalizeTags(req.Tags))
+	if err != nil {
+		return err
+	}
+	question.Tags = tags
+
+	outcomeIDs := uniqueIDs(req.LearningOutcomeIDs)
+	outcomes, err := tx.GetCourseLearningOutcomes(question.CourseID, outcomeIDs)
+	if err != nil {
+		return err
+	}
+	if len(outcomes) != len(outcomeIDs) {
+		return &bankValidationError{"learning outcomes must belong to the course's syllabus"}
+	}
+	question.LearningOutcomes = outcomes
+	return nil
+}
+
+// bankHTTPError maps question bank errors to HTTP errors
+func bankHTTPError(err error, message string) error {
+	if validationErr, ok := err.(*bankValidationError); ok {
+		return echo.NewHTTPError(http.StatusBadRequest, validationErr.Error())
+	}
+	return echo.NewHTTPError(http.StatusInternalServerError, message)
+}
+
+// normalizeTags lowercases tags and drops blanks and duplicates
+func normalizeTags(tags []string) []string {
+	seen := make(map[string]bool, len(tags))
+	normalized := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.Join(strings.Fields(tag), " "))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+	return normalized
+}
+
+func uniqueIDs(ids []uint) []uint {
+	seen := make(map[uint]bool, len(ids))
+	unique := make([]uint, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	return unique
+}
@@ -0,0 +1,447 @@
+package service
+
+import (
+	"backend/internal/domain"
+	"backend/internal/repository"
+	"backend/pkg/middleware"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Errors of regrade requests
+var (
+	ErrRegradeActive  = errors.New("the work already has a regrade request awaiting a decision")
+	ErrRegradeDecided = errors.New("the regrade request has already been decided")
+	ErrRegradeStatus  = errors.New("the regrade request is not in a state that allows this")
+)
+
+// RegradeService handles students' appeals against the scores of their
+// submissions and exam attempts. Requests are routed to the instructor who
+// graded the work, move from open through under review to accepted with a
+// new score or rejected, and keep every step in their history. Accepting a
+// request updates the score and the student's gradebook.
+type RegradeService struct {
+	regradeRepo *repository.RegradeRepository
+	courseRepo  *repository.CourseRepository
+	gradebook   *Gradebook
+	settings    *SettingsStore
+	now         func() time.Time
+}
+
+// NewRegradeService creates a new regrade service
+func NewRegradeService(
+	regradeRepo *repository.RegradeRepository,
+	courseRepo *repository.CourseRepository,
+	gradebook *Gradebook,
+	settings *SettingsStore,
+) *RegradeService {
+	return &RegradeService{
+		regradeRepo: regradeRepo,
+		courseRepo:  courseRepo,
+		gradebook:   gradebook,
+		settings:    settings,
+		now:         time.Now,
+	}
+}
+
+// CreateRegradeRequest files a student's appeal against the score of one
+// of their graded submissions or exam attempts. It must be filed within
+// the regrade window after the work was graded.
+func (s *RegradeService) CreateRegradeRequest(c echo.Context) error {
+	userID, err := middleware.GetUserIDFromToken(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Invalid token")
+	}
+
+	var req domain.CreateRegradeRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+	if err := c.Validate(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	if (req.SubmissionID == nil) == (req.ExamAttemptID == nil) {
+		return echo.NewHTTPError(http.StatusBadRequest, "Give either a submission or an exam attempt")
+	}
+	justification := strings.TrimSpace(req.Justification)
+	if justification == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "A justification is required")
+	}
+
+	window := s.settings.Current().RegradeWindow
+	if window == 0 {
+		return echo.NewHTTPError(http.StatusForbidden, "Regrade requests are not accepted")
+	}
+
+	request := &domain.RegradeRequest{
+		UserID:        userID,
+		SubmissionID:  req.SubmissionID,
+		ExamAttemptID: req.ExamAttemptID,
+		Status:        domain.RegradeStatusOpen,
+		Justification: justification,
+	}
+	gradedAt, err := s.describeItem(request)
+	if err != nil {
+		return err
+	}
+	if gradedAt != nil {
+		closes := gradedAt.AddDate(0, 0, window)
+		if s.now().After(closes) {
+			return echo.NewHTTPError(http.StatusBadRequest,
+				fmt.Sprintf("Regrades had to be requested by %s", closes.Format(time.RFC3339)))
+		}
+	}
+
+	now := s.now()
+	lockType, lockID := domain.GradeItemAssessment, request.SubmissionID
+	if request.ExamAttemptID != nil {
+		lockType, lockID = domain.GradeItemExam, request.ExamAttemptID
+	}
+	err = s.regradeRepo.Transaction(func(tx *repository.RegradeRepository) error {
+		if err := tx.LockItem(lockType, *lockID); err != nil {
+			return err
+		}
+		active, err := tx.HasActive(request.SubmissionID, request.ExamAttemptID)
+		if err != nil {
+			return err
+		}
+		if active {
+			return ErrRegradeActive
+		}
+
+		request.CreatedAt = now
+		if err := tx.Create(request); err != nil {
+			return err
+		}
+		return tx.AddEvent(&domain.RegradeEvent{
+			RequestID: request.ID,
+			ToStatus:  domain.RegradeStatusOpen,
+			ActorID:   userID,
+			Note:      justification,
+			CreatedAt: now,
+		})
+	})
+	if err != nil {
+		if errors.Is(err, ErrRegradeActive) {
+			return echo.NewHTTPError(http.StatusConflict, "This work already has a regrade request awaiting a decision")
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to create regrade request")
+	}
+
+	created, err := s.regradeRepo.GetByID(request.ID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to get regrade request")
+	}
+	return c.JSON(http.StatusCreated, created)
+}
+
+// GetMyRegradeRequests returns the current student's regrade requests
+func (s *RegradeService) GetMyRegradeRequests(c echo.Context) error {
+	userID, err := middleware.GetUserIDFromToken(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Invalid token")
+	}
+	return s.find(c, repository.RegradeFilter{UserID: userID, Status: c.QueryParam("status")})
+}
+
+// GetAssignedRegradeRequests returns the regrade requests routed to the
+// current instructor
+func (s *RegradeService) GetAssignedRegradeRequests(c echo.Context) error {
+	userID, err := middleware.GetUserIDFromToken(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Invalid token")
+	}
+	return s.find(c, repository.RegradeFilter{AssigneeID: userID, Status: c.QueryParam("status")})
+}
+
+// GetCourseRegradeRequests returns the regrade requests of a course
+func (s *RegradeService) GetCourseRegradeRequests(c echo.Context) error {
+	courseID, err := parseIDParam(c, "id")
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid course ID")
+	}
+	if _, err := s.courseRepo.GetByID(courseID); err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, "Course not found")
+	}
+	if err := authorizeCourseStaff(c, s.courseRepo, courseID); err != nil {
+		return err
+	}
+	return s.find(c, repository.RegradeFilter{CourseID: courseID, Status: c.QueryParam("status")})
+}
+
+// GetRegradeRequest returns a regrade request with its history. Students
+// see their own requests, course staff every request of their courses.
+func (s *RegradeService) GetRegradeRequest(c echo.Context) error {
+	request, err := s.requestFromParam(c)
+	if err != nil {
+		return err
+	}
+	userID, err := middleware.GetUserIDFromToken(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Invalid token")
+	}
+	if request.UserID != userID {
+		if err := authorizeCourseStaff(c, s.courseRepo, request.CourseID); err != nil {
+			return err
+		}
+	}
+	return c.JSON(http.StatusOK, request)
+}
+
+// ReviewRegradeRequest marks an open regrade request as under review
+func (s *RegradeService) ReviewRegradeRequest(c echo.Context) error {
+	var req domain.RegradeDecisionRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+	if err := c.Validate(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	return s.decide(c, domain.RegradeStatusUnderReview, strings.TrimSpace(req.Response), nil)
+}
+
+// AcceptRegradeRequest accepts a regrade request with the new score, which
+// replaces the score of the work and updates the student's gradebook
+func (s *RegradeService) AcceptRegradeRequest(c echo.Context) error {
+	var req domain.RegradeDecisionRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+	if err := c.Validate(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	if req.Score == nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "The new score is required to accept a regrade request")
+	}
+
+	return s.decide(c, domain.RegradeStatusAccepted, strings.TrimSpace(req.Response), req.Score)
+}
+
+// RejectRegradeRequest rejects a regrade request, explaining why
+func (s *RegradeService) RejectRegradeRequest(c echo.Context) error {
+	var req domain.RegradeDecisionRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+	if err := c.Validate(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	response := strings.TrimSpace(req.Response)
+	if response == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "A response is required to reject a regrade request")
+	}
+
+	return s.decide(c, domain.RegradeStatusRejected, response, nil)
+}
+
+// decide moves the regrade request named by ":id" to a new status on
+// behalf of course staff, applying the new score when it is accepted
+func (s *RegradeService) decide(c echo.Context, to, response string, score *float64) error {
+	request, err := s.requestFromParam(c)
+	if err != nil {
+		return err
+	}
+	if err := authorizeCourseStaff(c, s.courseRepo, request.CourseID); err != nil {
+		return err
+	}
+	actorID, err := middleware.GetUserIDFromToken(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Invalid token")
+	}
+
+	now := s.now()
+	err = s.regradeRepo.Transaction(func(tx *repository.RegradeRepository) error {
+		locked, err := tx.Lock(request.ID)
+		if err != nil {
+			return err
+		}
+		if !locked.Active() {
+			return ErrRegradeDecided
+		}
+		if to == domain.RegradeStatusUnderReview && locked.Status != domain.RegradeStatusOpen {
+			return ErrRegradeStatus
+		}
+
+		event := &domain.RegradeEvent{
+			RequestID:  locked.ID,
+			FromStatus: locked.Status,
+			ToStatus:   to,
+			ActorID:    actorID,
+			Note:       response,
+			CreatedAt:  now,
+		}
+		if to == domain.RegradeStatusAccepted {
+			newScore, err := applyRegrade(tx, locked, *score, actorID, now)
+			if err != nil {
+				return err
+			}
+			locked.NewScore = &newScore
+			event.Score = &newScore
+		}
+		if to != domain.RegradeStatusUnderReview {
+			locked.Response = response
+			locked.ResolvedBy = &actorID
+			locked.ResolvedAt = &now
+		}
+		locked.Status = to
+		if err := tx.Save(locked); err != nil {
+			return err
+		}
+		return tx.AddEvent(event)
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrRegradeDecided):
+			return echo.NewHTTPError(http.StatusConflict, "The regrade request has already been decided")
+		case errors.Is(err, ErrRegradeStatus):
+			return echo.NewHTTPError(http.StatusConflict, "The regrade request is already under review")
+		case errors.Is(err, ErrAttemptNotFinished):
+			return echo.NewHTTPError(http.StatusConflict, "The exam attempt is not finished")
+		default:
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to update regrade request")
+		}
+	}
+
+	if to == domain.RegradeStatusAccepted {
+		reason := fmt.Sprintf("Regrade request %d accepted", request.ID)
+		if response != "" {
+			reason += ": " + response
+		}
+		s.gradebook.Regraded(request.ItemType, request.ItemID, request.UserID, actorID, reason)
+	}
+
+	updated, err := s.regradeRepo.GetByID(request.ID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to get regrade request")
+	}
+	return c.JSON(http.StatusOK, updated)
+}
+
+// applyRegrade gives the appealed work its new score and returns the score
+// it ends up with; submissions keep their late penalty. Exam attempts keep
+// the score as an override, so that grading their answers again later
+// leaves it in place.
+func applyRegrade(tx *repository.RegradeRepository, request *domain.RegradeRequest, score float64, graderID uint, now time.Time) (float64, error) {
+	if request.SubmissionID != nil {
+		submission, err := tx.LockSubmission(*request.SubmissionID)
+		if err != nil {
+			return 0, err
+		}
+		submission.ApplyGrade(score)
+		submission.Status = domain.SubmissionStatusGraded
+		submission.GradedBy = graderID
+		submission.GradedAt = &now
+		if err := tx.SaveSubmission(submission); err != nil {
+			return 0, err
+		}
+		return submission.Score, nil
+	}
+
+	attempt, err := tx.LockAttempt(*request.ExamAttemptID)
+	if err != nil {
+		return 0, err
+	}
+	if !attempt.IsFinished() {
+		return 0, ErrAttemptNotFinished
+	}
+	attempt.ScoreOverride = &score
+	attempt.Score = score
+	attempt.GradingStatus = domain.GradingStatusGraded
+	if err := tx.SaveAttempt(attempt); err != nil {
+		return 0, err
+	}
+	return attempt.Score, nil
+}
+
+// describeItem loads the work a new request appeals against, checks that
+// it belongs to the student and is graded, and fills in the request's
+// course, item, score and assignee. It returns when the work was graded.
+func (s *RegradeService) describeItem(request *domain.RegradeRequest) (*time.Time, error) {
+	var gradedAt *time.Time
+	var graderID uint
+
+	if request.SubmissionID != nil {
+		submission, err := s.regradeRepo.GetSubmission(*request.SubmissionID)
+		if err != nil || submission.UserID != request.UserID {
+			return nil, echo.NewHTTPError(http.StatusNotFound, "Submission not found")
+		}
+		if submission.Status != domain.SubmissionStatusGraded {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, "Only graded work can be appealed")
+		}
+		request.CourseID = submission.Assessment.CourseID
+		request.ItemType = domain.GradeItemAssessment
+		request.ItemID = submission.AssessmentID
+		request.ItemTitle = submission.Assessment.Title
+		request.OriginalScore = submission.Score
+		graderID = submission.GradedBy
+		gradedAt = submission.GradedAt
+	} else {
+		attempt, err := s.regradeRepo.GetAttempt(*request.ExamAttemptID)
+		if err != nil || attempt.UserID != request.UserID {
+			return nil, echo.NewHTTPError(http.StatusNotFound, "Exam attempt not found")
+		}
+		if !attempt.IsFinished() ||
+			(attempt.GradingStatus != domain.GradingStatusAutoGraded && attempt.GradingStatus != domain.GradingStatusGraded) {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, "Only graded work can be appealed")
+		}
+		request.CourseID = attempt.Exam.CourseID
+		request.ItemType = domain.GradeItemExam
+		request.ItemID = attempt.ExamID
+		request.ItemTitle = attempt.Exam.Title
+		request.OriginalScore = attempt.Score
+
+		grader, lastGraded, err := s.regradeRepo.LastAnswerGrading(attempt.ID)
+		if err != nil {
+			return nil, echo.NewHTTPError(http.StatusInternalServerError, "Failed to create regrade request")
+		}
+		if grader != nil {
+			graderID = *grader
+		}
+		gradedAt = attempt.EndTime
+		if lastGraded != nil {
+			gradedAt = lastGraded
+		}
+	}
+
+	// Automatically graded work goes to the course's main instructor
+	if graderID == 0 {
+		if id, err := s.regradeRepo.GetMainInstructorID(request.CourseID); err == nil {
+			graderID = id
+		}
+	}
+	request.AssigneeID = graderID
+	return gradedAt, nil
+}
+
+// requestFromParam loads the regrade request named by ":id"
+func (s *RegradeService) requestFromParam(c echo.Context) (*domain.RegradeRequest, error) {
+	id, err := parseIDParam(c, "id")
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid regrade request ID")
+	}
+	request, err := s.regradeRepo.GetByID(id)
+	if err != nil {
+		if err.Error() == "regrade request not found" {
+			return nil, echo.NewHTTPError(http.StatusNotFound, "Regrade request not found")
+		}
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "Failed to get regrade request")
+	}
+	return request, nil
+}
+
+// find responds with the regrade requests matching a filter
+func (s *RegradeService) find(c echo.Context, filter repository.RegradeFilter) error {
+	requests, err := s.regradeRepo.Find(filter)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to get regrade requests")
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"items": requests,
+	})
+}
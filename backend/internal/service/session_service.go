@@ -1,27 +1,190 @@
 package service
 
-import "github.com/labstack/echo/v4"
+import (
+	"backend/internal/domain"
+	"backend/internal/repository"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// SessionService handles the sessions of a course
+type SessionService struct {
+	sessionRepo *repository.SessionRepository
+	courseRepo  *repository.CourseRepository
+}
 
-type SessionService struct{}
+// NewSessionService creates a new session service
+func NewSessionService(sessionRepo *repository.SessionRepository, courseRepo *repository.CourseRepository) *SessionService {
+	return &SessionService{
+		sessionRepo: sessionRepo,
+		courseRepo:  courseRepo,
+	}
+}
 
+// GetSessions returns the sessions of a course in order
 func (s *SessionService) GetSessions(c echo.Context) error {
-	return c.JSON(200, map[string]string{"message": "dummy get sessions"})
+	courseID, err := parseIDParam(c, "id")
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid course ID")
+	}
+	if _, err := s.courseRepo.GetByID(courseID); err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, "Course not found")
+	}
+	if err := authorizeCourseAccess(c, s.courseRepo, courseID); err != nil {
+		return err
+	}
+
+	sessions, err := s.sessionRepo.GetByCourse(courseID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to get sessions")
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{"items": sessions})
 }
 
+// GetSession returns a session
 func (s *SessionService) GetSession(c echo.Context) error {
-	return c.JSON(200, map[string]string{"message": "dummy get session"})
+	session, err := s.sessionFromParam(c)
+	if err != nil {
+		return err
+	}
+	if err := authorizeCourseAccess(c, s.courseRepo, session.CourseID); err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, session)
 }
 
+// CreateSession adds a session to a course; it is numbered after the
+// course's last session unless a number is given
 func (s *SessionService) CreateSession(c echo.Context) error {
-	return c.JSON(200, map[string]string{"message": "dummy create session"})
+	courseID, err := parseIDParam(c, "id")
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid course ID")
+	}
+	if _, err := s.courseRepo.GetByID(courseID); err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, "Course not found")
+	}
+	if err := authorizeCourseStaff(c, s.courseRepo, courseID); err != nil {
+		return err
+	}
+
+	var session domain.Session
+	if err := c.Bind(&session); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+	if session.Title == "" || session.Date.IsZero() {
+		return echo.NewHTTPError(http.StatusBadRequest, "Title and date are required")
+	}
+	if session.Number < 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "Number cannot be negative")
+	}
+	if session.Number == 0 {
+		if session.Number, err = s.sessionRepo.NextNumber(courseID); err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to number session")
+		}
+	}
+
+	session.ID = 0
+	session.CourseID = courseID
+	session.CreatedAt = time.Now()
+	session.UpdatedAt = time.Now()
+	if err := s.sessionRepo.Create(&session); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to create session")
+	}
+
+	return c.JSON(http.StatusCreated, session)
 }
 
+// UpdateSession updates the fields of a session that are provided
 func (s *SessionService) UpdateSession(c echo.Context) error {
-	return c.JSON(200, map[string]string{"message": "dummy update session"})
+	session, err := s.sessionFromParam(c)
+	if err != nil {
+		return err
+	}
+	if err := authorizeCourseStaff(c, s.courseRepo, session.CourseID); err != nil {
+		return err
+	}
+
+	var update domain.Session
+	if err := c.Bind(&update); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+	if update.Number < 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "Number cannot be negative")
+	}
+	if update.Number > 0 {
+		session.Number = update.Number
+	}
+	if update.Title != "" {
+		session.Title = update.Title
+	}
+	if update.Description != "" {
+		session.Description = update.Description
+	}
+	if !update.Date.IsZero() {
+		session.Date = update.Date
+	}
+	if update.StartTime != "" {
+		session.StartTime = update.StartTime
+	}
+	if update.EndTime != "" {
+		session.EndTime = update.EndTime
+	}
+	if update.Duration != "" {
+		session.Duration = update.Duration
+	}
+	if update.DeliveryMode != "" {
+		session.DeliveryMode = update.DeliveryMode
+	}
+	if update.Location != "" {
+		session.Location = update.Location
+	}
+	if update.ZoomLink != "" {
+		session.ZoomLink = update.ZoomLink
+	}
+
+	session.UpdatedAt = time.Now()
+	if err := s.sessionRepo.Update(session); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to update session")
+	}
+
+	return c.JSON(http.StatusOK, session)
 }
 
+// DeleteSession deletes a session
 func (s *SessionService) DeleteSession(c echo.Context) error {
-	return c.JSON(200, map[string]string{"message": "dummy delete session"})
+	session, err := s.sessionFromParam(c)
+	if err != nil {
+		return err
+	}
+	if err := authorizeCourseStaff(c, s.courseRepo, session.CourseID); err != nil {
+		return err
+	}
+
+	if err := s.sessionRepo.Delete(session.ID); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to delete session")
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"message": "Session deleted successfully",
+	})
+}
+
+// sessionFromParam loads the session named by the ":id" path parameter
+func (s *SessionService) sessionFromParam(c echo.Context) (*domain.Session, error) {
+	sessionID, err := parseIDParam(c, "id")
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid session ID")
+	}
+	session, err := s.sessionRepo.GetByID(sessionID)
+	if err != nil {
+		if err.Error() == "session not found" {
+			return nil, echo.NewHTTPError(http.StatusNotFound, "Session not found")
+		}
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "Failed to get session")
+	}
+	return session, nil
 }
 
 func (s *SessionService) GetSessionContents(c echo.Context) error {
@@ -0,0 +1,456 @@
+package service
+
+import (
+	"backend/internal/domain"
+	"backend/internal/repository"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/mail"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// settingsRefreshInterval bounds how stale the cached settings may get when
+// another replica changes them
+const settingsRefreshInterval = 15 * time.Second
+
+var (
+	academicYearPattern = regexp.MustCompile(`^(\d{4})-(\d{4})$`)
+	fileTypePattern     = regexp.MustCompile(`^\.[a-z0-9]{1,10}$`)
+	emailDomainPattern  = regexp.MustCompile(`^([a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?\.)+[a-z]{2,63}$`)
+	validSemesters      = []string{"Spring", "Summer", "Fall", "Winter", "Odd", "Even"}
+)
+
+// SettingValidationError lists the rejected keys of a settings update
+type SettingValidationError struct {
+	Errors map[string]string
+}
+
+func (e *SettingValidationError) Error() string {
+	keys := make([]string, 0, len(e.Errors))
+	for key := range e.Errors {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(keys))
+	for _, key := range keys {
+		parts = append(parts, key+": "+e.Errors[key])
+	}
+	return "invalid settings: " + strings.Join(parts, "; ")
+}
+
+// settingDefinition decodes and validates one setting key into SystemSettings
+type settingDefinition func(raw json.RawMessage, s *domain.SystemSettings) error
+
+var settingDefinitions = map[string]settingDefinition{
+	domain.SettingSystemName: func(raw json.RawMessage, s *domain.SystemSettings) error {
+		return decodeText(raw, 100, &s.SystemName)
+	},
+	domain.SettingInstitutionName: func(raw json.RawMessage, s *domain.SystemSettings) error {
+		return decodeText(raw, 150, &s.InstitutionName)
+	},
+	domain.SettingAdminEmail: func(raw json.RawMessage, s *domain.SystemSettings) error {
+		var email string
+		if err := json.Unmarshal(raw, &email); err != nil {
+			return fmt.Errorf("must be a string")
+		}
+		if _, err := mail.ParseAddress(email); err != nil {
+			return fmt.Errorf("must be a valid email address")
+		}
+		s.AdminEmail = email
+		return nil
+	},
+	domain.SettingMaintenanceMode: func(raw json.RawMessage, s *domain.SystemSettings) error {
+		if err := json.Unmarshal(raw, &s.MaintenanceMode); err != nil {
+			return fmt.Errorf("must be a boolean")
+		}
+		return nil
+	},
+	domain.SettingMaintenanceMsg: func(raw json.RawMessage, s *domain.SystemSettings) error {
+		return decodeText(raw, 500, &s.MaintenanceMsg)
+	},
+	domain.SettingMaintenanceStart: func(raw json.RawMessage, s *domain.SystemSettings) error {
+		return decodeTime(raw, &s.MaintenanceStart)
+	},
+	domain.SettingMaintenanceEnd: func(raw json.RawMessage, s *domain.SystemSettings) error {
+		return decodeTime(raw, &s.MaintenanceEnd)
+	},
+	domain.SettingAcademicYear: func(raw json.RawMessage, s *domain.SystemSettings) error {
+		var year string
+		if err := json.Unmarshal(raw, &year); err != nil {
+			return fmt.Errorf("must be a string")
+		}
+		match := academicYearPattern.FindStringSubmatch(year)
+		if match == nil {
+			return fmt.Errorf("must look like 2024-2025")
+		}
+		start, _ := strconv.Atoi(match[1])
+		end, _ := strconv.Atoi(match[2])
+		if end != start+1 {
+			return fmt.Errorf("must span two consecutive years")
+		}
+		s.AcademicYear = year
+		return nil
+	},
+	domain.SettingSemester: func(raw json.RawMessage, s *domain.SystemSettings) error {
+		var semester string
+		if err := json.Unmarshal(raw, &semester); err != nil {
+			return fmt.Errorf("must be a string")
+		}
+		for _, valid := range validSemesters {
+			if strings.EqualFold(semester, valid) {
+				s.Semester = valid
+				return nil
+			}
+		}
+		return fmt.Errorf("must be one of %s", strings.Join(validSemesters, ", "))
+	},
+	domain.SettingMaxFileSize: func(raw json.RawMessage, s *domain.SystemSettings) error {
+		var size int64
+		if err := json.Unmarshal(raw, &size); err != nil {
+			return fmt.Errorf("must be an integer number of bytes")
+		}
+		if size < 1024 || size > 512<<20 {
+			return fmt.Errorf("must be between 1KB and 512MB")
+		}
+		s.MaxFileSize = size
+		return nil
+	},
+	domain.SettingAllowedFileTypes: func(raw json.RawMessage, s *domain.SystemSettings) error {
+		var types []string
+		if err := json.Unmarshal(raw, &types); err != nil {
+			return fmt.Errorf("must be a list of file extensions")
+		}
+		if len(types) == 0 {
+			return fmt.Errorf("must not be empty")
+		}
+		normalized := make([]string, 0, len(types))
+		for _, t := range types {
+			t = strings.ToLower(strings.TrimSpace(t))
+			if !strings.HasPrefix(t, ".") {
+				t = "." + t
+			}
+			if !fileTypePattern.MatchString(t) {
+				return fmt.Errorf("%q is not a valid file extension", t)
+			}
+			normalized = append(normalized, t)
+		}
+		s.AllowedFileTypes = normalized
+		return nil
+	},
+	domain.SettingGPARepeatPolicy: func(raw json.RawMessage, s *domain.SystemSettings) error {
+		var policy string
+		if err := json.Unmarshal(raw, &policy); err != nil {
+			return fmt.Errorf("must be a string")
+		}
+		policy = strings.ToLower(strings.TrimSpace(policy))
+		if policy != domain.RepeatPolicyBest && policy != domain.RepeatPolicyLatest {
+			return fmt.Errorf("must be %s or %s", domain.RepeatPolicyBest, domain.RepeatPolicyLatest)
+		}
+		s.GPARepeatPolicy = policy
+		return nil
+	},
+	domain.SettingRegradeWindow: func(raw json.RawMessage, s *domain.SystemSettings) error {
+		var days int
+		if err := json.Unmarshal(raw, &days); err != nil {
+			return fmt.Errorf("must be an integer number of days")
+		}
+		if days < 0 || days > 365 {
+			return fmt.Errorf("must be between 0 and 365")
+		}
+		s.RegradeWindow = days
+		return nil
+	},
+	domain.SettingAttendanceLate: func(raw json.RawMessage, s *domain.SystemSettings) error {
+		var minutes int
+		if err := json.Unmarshal(raw, &minutes); err != nil {
+			return fmt.Errorf("must be an integer number of minutes")
+		}
+		if minutes < 0 || minutes > 240 {
+			return fmt.Errorf("must be between 0 and 240")
+		}
+		s.AttendanceLate = minutes
+		return nil
+	},
+	domain.SettingRegistration: func(raw json.RawMessage, s *domain.SystemSettings) error {
+		if err := json.Unmarshal(raw, &s.Registration); err != nil {
+			return fmt.Errorf("must be a boolean")
+		}
+		return nil
+	},
+	domain.SettingRegistrationMail: func(raw json.RawMessage, s *domain.SystemSettings) error {
+		var domains []string
+		if err := json.Unmarshal(raw, &domains); err != nil {
+			return fmt.Errorf("must be a list of email domains")
+		}
+		normalized := make([]string, 0, len(domains))
+		for _, d := range domains {
+			d = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(d)), "@")
+			if !emailDomainPattern.MatchString(d) {
+				return fmt.Errorf("%q is not a valid email domain", d)
+			}
+			normalized = append(normalized, d)
+		}
+		s.RegistrationMail = normalized
+		return nil
+	},
+	domain.SettingMFARequired: func(raw json.RawMessage, s *domain.SystemSettings) error {
+		var roles []string
+		if err := json.Unmarshal(raw, &roles); err != nil {
+			return fmt.Errorf("must be a list of roles")
+		}
+		normalized := make([]string, 0, len(roles))
+		seen := make(map[string]bool)
+		for _, role := range roles {
+			role = strings.ToLower(strings.TrimSpace(role))
+			if role != "admin" && role != "instructor" && role != "student" {
+				return fmt.Errorf("%q is not a role; use admin, instructor or student", role)
+			}
+			if !seen[role] {
+				seen[role] = true
+				normalized = append(normalized, role)
+			}
+		}
+		s.MFARequired = normalized
+		return nil
+	},
+}
+
+func decodeText(raw json.RawMessage, maxLen int, dst *string) error {
+	var value string
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return fmt.Errorf("must be a string")
+	}
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return fmt.Errorf("must not be empty")
+	}
+	if len(value) > maxLen {
+		return fmt.Errorf("must be at most %d characters", maxLen)
+	}
+	*dst = value
+	return nil
+}
+
+// decodeTime decodes an RFC 3339 timestamp, or null to clear it
+func decodeTime(raw json.RawMessage, dst **time.Time) error {
+	var value *time.Time
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return fmt.Errorf("must be an RFC 3339 timestamp or null")
+	}
+	if value != nil {
+		utc := value.UTC()
+		value = &utc
+	}
+	*dst = value
+	return nil
+}
+
+// validateSettings checks the rules that span several keys
+func validateSettings(s domain.SystemSettings) map[string]string {
+	invalid := make(map[string]string)
+	if s.MaintenanceStart != nil && s.MaintenanceEnd != nil && !s.MaintenanceEnd.After(*s.MaintenanceStart) {
+		invalid[domain.SettingMaintenanceEnd] = "must be after maintenance_start"
+	}
+	return invalid
+}
+
+// SettingsStore is the typed, database-backed system settings store.
+// Settings that were never saved fall back to the defaults it was created with.
+// Reads are served from a cache that is refreshed periodically.
+type SettingsStore struct {
+	settingRepo *repository.SettingRepository
+	defaults    domain.SystemSettings
+
+	mu        sync.RWMutex
+	current   domain.SystemSettings
+	loadedAt  time.Time
+	listeners map[string][]func(domain.SystemSettings)
+}
+
+// DefaultSettings returns the settings used until an admin changes them
+func DefaultSettings(maxUploadSize int64) domain.SystemSettings {
+	if maxUploadSize <= 0 {
+		maxUploadSize = 5 << 20
+	}
+	return domain.SystemSettings{
+		SystemName:       "Learning Management System",
+		InstitutionName:  "Your University",
+		AdminEmail:       "admin@example.com",
+		MaintenanceMode:  false,
+		MaintenanceMsg:   "The system is undergoing maintenance. Please try again later.",
+		AcademicYear:     "2024-2025",
+		Semester:         "Spring",
+		MaxFileSize:      maxUploadSize,
+		AllowedFileTypes: []string{".pdf", ".doc", ".docx", ".jpg", ".jpeg", ".png"},
+		GPARepeatPolicy:  domain.RepeatPolicyBest,
+		RegradeWindow:    7,
+		AttendanceLate:   10,
+		Registration:     true,
+		RegistrationMail: []string{},
+		MFARequired:      []string{},
+	}
+}
+
+// NewSettingsStore creates a new settings store
+func NewSettingsStore(settingRepo *repository.SettingRepository, defaults domain.SystemSettings) *SettingsStore {
+	return &SettingsStore{
+		settingRepo: settingRepo,
+		defaults:    defaults,
+		current:     defaults,
+	}
+}
+
+// Current returns the current settings
+func (s *SettingsStore) Current() domain.SystemSettings {
+	s.mu.RLock()
+	fresh := time.Since(s.loadedAt) < settingsRefreshInterval
+	current := s.current
+	s.mu.RUnlock()
+
+	if fresh {
+		return current
+	}
+	if err := s.Reload(); err != nil {
+		// Keep serving the last known settings rather than failing requests
+		log.Printf("Failed to reload system settings: %v", err)
+		s.mu.Lock()
+		s.loadedAt = time.Now()
+		s.mu.Unlock()
+		return current
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.current
+}
+
+// Reload reads all settings from the database
+func (s *SettingsStore) Reload() error {
+	stored, err := s.settingRepo.GetAll()
+	if err != nil {
+		return err
+	}
+
+	settings := s.defaultsCopy()
+	for _, setting := range stored {
+		define, ok := settingDefinitions[setting.Key]
+		if !ok {
+			continue
+		}
+		if err := define(json.RawMessage(setting.Value), &settings); err != nil {
+			log.Printf("Ignoring invalid stored setting %s: %v", setting.Key, err)
+		}
+	}
+
+	s.mu.Lock()
+	s.current = settings
+	s.loadedAt = time.Now()
+	s.mu.Unlock()
+	return nil
+}
+
+// Update validates and persists the given settings. Either every key is
+// applied or, when any key is unknown or invalid, none is.
+func (s *SettingsStore) Update(changes map[string]json.RawMessage, changedBy uint) (domain.SystemSettings, error) {
+	if err := s.Reload(); err != nil {
+		return domain.SystemSettings{}, err
+	}
+	previous := s.Current()
+	updated := previous
+	updated.AllowedFileTypes = append([]string(nil), previous.AllowedFileTypes...)
+	updated.RegistrationMail = append([]string{}, previous.RegistrationMail...)
+	updated.MFARequired = append([]string{}, previous.MFARequired...)
+
+	invalid := make(map[string]string)
+	for key, raw := range changes {
+		define, ok := settingDefinitions[key]
+		if !ok {
+			invalid[key] = "unknown setting"
+			continue
+		}
+		if err := define(raw, &updated); err != nil {
+			invalid[key] = err.Error()
+		}
+	}
+	if len(invalid) == 0 {
+		invalid = validateSettings(updated)
+	}
+	if len(invalid) > 0 {
+		return domain.SystemSettings{}, &SettingValidationError{Errors: invalid}
+	}
+
+	oldValues := encodeSettings(previous)
+	newValues := encodeSettings(updated)
+
+	var rows []domain.SystemSetting
+	for key := range changes {
+		if oldValues[key] == newValues[key] {
+			continue
+		}
+		rows = append(rows, domain.SystemSetting{Key: key, Value: newValues[key]})
+	}
+	if len(rows) == 0 {
+		return previous, nil
+	}
+	if err := s.settingRepo.SaveAll(rows, oldValues, changedBy); err != nil {
+		return domain.SystemSettings{}, err
+	}
+
+	s.mu.Lock()
+	s.current = updated
+	s.loadedAt = time.Now()
+	var notify []func(domain.SystemSettings)
+	for _, row := range rows {
+		notify = append(notify, s.listeners[row.Key]...)
+	}
+	s.mu.Unlock()
+
+	for _, fn := range notify {
+		fn(updated)
+	}
+	return updated, nil
+}
+
+// OnChange registers fn to be called with the new settings whenever an
+// update through this store changes the given key
+func (s *SettingsStore) OnChange(key string, fn func(domain.SystemSettings)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.listeners == nil {
+		s.listeners = make(map[string][]func(domain.SystemSettings))
+	}
+	s.listeners[key] = append(s.listeners[key], fn)
+}
+
+// History returns the change history of the settings, newest first
+func (s *SettingsStore) History(key string, limit, offset int) ([]domain.SettingChange, error) {
+	return s.settingRepo.GetHistory(key, limit, offset)
+}
+
+func (s *SettingsStore) defaultsCopy() domain.SystemSettings {
+	settings := s.defaults
+	settings.AllowedFileTypes = append([]string(nil), s.defaults.AllowedFileTypes...)
+	settings.RegistrationMail = append([]string{}, s.defaults.RegistrationMail...)
+	settings.MFARequired = append([]string{}, s.defaults.MFARequired...)
+	return settings
+}
+
+// encodeSettings returns the JSON value of every setting key
+func encodeSettings(settings domain.SystemSettings) map[string]string {
+	data, _ := json.Marshal(settings)
+	var fields map[string]json.RawMessage
+	_ = json.Unmarshal(data, &fields)
+
+	values := make(map[string]string, len(fields))
+	for key, raw := range fields {
+		values[key] = string(raw)
+	}
+	return values
+}
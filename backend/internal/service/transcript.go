@@ -0,0 +1,263 @@
+package service
+
+import (
+	"backend/internal/domain"
+	"backend/internal/repository"
+	"errors"
+	"log"
+	"math"
+	"sort"
+)
+
+// ErrSemesterNotFound is returned for grade reports of semesters in which
+// the student took no course
+var ErrSemesterNotFound = errors.New("the student took no course in that semester")
+
+// semesterOrder ranks the semesters of a year in calendar order. Odd
+// semesters start an academic year and Even semesters follow them.
+var semesterOrder = map[string]int{
+	"Winter": 1,
+	"Odd":    1,
+	"Spring": 2,
+	"Even":   2,
+	"Summer": 3,
+	"Fall":   4,
+}
+
+// Transcript builds a student's transcript from their course grades,
+// counting repeated courses by the configured repeat policy
+func (g *Gradebook) Transcript(userID uint) (*domain.Transcript, error) {
+	user, err := g.gradeRepo.GetStudent(userID)
+	if err != nil {
+		return nil, err
+	}
+	records, err := g.gradeRepo.GetTranscriptRecords(userID, g.now())
+	if err != nil {
+		return nil, err
+	}
+
+	transcript := buildTranscript(records, g.repeatPolicy())
+	g.setHolder(transcript, user)
+	return transcript, nil
+}
+
+// GradeReport builds a student's transcript up to and including the given
+// semester, so that its cumulative GPA is the one as of that semester. The
+// report's last semester is the requested one.
+func (g *Gradebook) GradeReport(userID uint, semester string, year int) (*domain.Transcript, error) {
+	user, err := g.gradeRepo.GetStudent(userID)
+	if err != nil {
+		return nil, err
+	}
+	records, err := g.gradeRepo.GetTranscriptRecords(userID, g.now())
+	if err != nil {
+		return nil, err
+	}
+
+	target := semesterKey{year, semesterRank(semester), semester}
+	found := false
+	through := records[:0]
+	for _, record := range records {
+		key := semesterKey{record.Year, semesterRank(record.Semester), record.Semester}
+		if key == target {
+			found = true
+		}
+		if !target.before(key) {
+			through = append(through, record)
+		}
+	}
+	if !found {
+		return nil, ErrSemesterNotFound
+	}
+
+	report := buildTranscript(through, g.repeatPolicy())
+	g.setHolder(report, user)
+	return report, nil
+}
+
+func (g *Gradebook) setHolder(transcript *domain.Transcript, user *domain.User) {
+	transcript.UserID = user.ID
+	transcript.StudentID = user.StudentInfo.StudentID
+	transcript.Name = user.Name
+	transcript.Major = user.StudentInfo.Major
+	transcript.GeneratedAt = g.now()
+}
+
+// SyncGPA stores a student's cumulative GPA on their student info
+func (g *Gradebook) SyncGPA(userID uint) error {
+	records, err := g.gradeRepo.GetTranscriptRecords(userID, g.now())
+	if err != nil {
+		return err
+	}
+	transcript := buildTranscript(records, g.repeatPolicy())
+	return g.gradeRepo.UpdateStudentGPA(userID, float32(transcript.CumulativeGPA))
+}
+
+// EnrollmentChanged resyncs a student's GPA after their enrollment in a
+// course changed. Failures are only logged, like in ResultChanged.
+func (g *Gradebook) EnrollmentChanged(userID uint) {
+	if g == nil {
+		return
+	}
+	if err := g.SyncGPA(userID); err != nil {
+		log.Printf("Failed to sync GPA of user %d: %v", userID, err)
+	}
+}
+
+// SyncCourseGPAs resyncs the GPAs of every student of a course, e.g. after
+// its credits changed
+func (g *Gradebook) SyncCourseGPAs(courseID uint) error {
+	userIDs, err := g.gradeRepo.GetStudentIDs(courseID)
+	if err != nil {
+		return err
+	}
+	for _, userID := range userIDs {
+		if err := g.SyncGPA(userID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SyncAllGPAs resyncs the GPA of every student with a course grade, e.g.
+// after the repeat policy changed
+func (g *Gradebook) SyncAllGPAs() error {
+	userIDs, err := g.gradeRepo.GetGradedStudentIDs()
+	if err != nil {
+		return err
+	}
+	for _, userID := range userIDs {
+		if err := g.SyncGPA(userID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (g *Gradebook) repeatPolicy() string {
+	if g.settings == nil {
+		return domain.RepeatPolicyBest
+	}
+	return g.settings.Current().GPARepeatPolicy
+}
+
+// buildTranscript groups the records by semester in calendar order and
+// computes the semester and cumulative GPAs, weighting grade points by
+// credits. Semester GPAs include every attempt; the cumulative GPA counts
+// one attempt per course code, chosen by the repeat policy.
+func buildTranscript(records []repository.TranscriptRecord, policy string) *domain.Transcript {
+	sorted := append([]repository.TranscriptRecord(nil), records...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, b := sorted[i], sorted[j]
+		ka := semesterKey{a.Year, semesterRank(a.Semester), a.Semester}
+		kb := semesterKey{b.Year, semesterRank(b.Semester), b.Semester}
+		if ka != kb {
+			return ka.before(kb)
+		}
+		if !a.EnrolledAt.Equal(b.EnrolledAt) {
+			return a.EnrolledAt.Before(b.EnrolledAt)
+		}
+		return a.CourseID < b.CourseID
+	})
+
+	// Pick the attempt of every course code that counts cumulatively
+	attempts := make(map[string]int, len(sorted))
+	chosen := make(map[string]int, len(sorted))
+	for i, record := range sorted {
+		attempts[record.Code]++
+		if !countsTowardGPA(&record) {
+			continue
+		}
+		prev, ok := chosen[record.Code]
+		if !ok || policy == domain.RepeatPolicyLatest || record.GradePoints >= sorted[prev].GradePoints {
+			chosen[record.Code] = i
+		}
+	}
+
+	transcript := &domain.Transcript{
+		RepeatPolicy: policy,
+		Semesters:    []domain.TranscriptSemester{},
+	}
+	var cumulativePoints, semesterPoints float64
+	for i, record := range sorted {
+		if n := len(transcript.Semesters); n == 0 ||
+			transcript.Semesters[n-1].Year != record.Year || transcript.Semesters[n-1].Semester != record.Semester {
+			transcript.Semesters = append(transcript.Semesters, domain.TranscriptSemester{
+				Semester: record.Semester,
+				Year:     record.Year,
+			})
+			semesterPoints = 0
+		}
+		semester := &transcript.Semesters[len(transcript.Semesters)-1]
+
+		course := domain.TranscriptCourse{
+			CourseID:     record.CourseID,
+			Code:         record.Code,
+			Title:        record.Title,
+			Credits:      record.Credits,
+			Status:       record.EnrollmentStatus,
+			OverallScore: record.OverallScore,
+			LetterGrade:  record.LetterGrade,
+			GradePoints:  record.GradePoints,
+			Repeated:     attempts[record.Code] > 1,
+		}
+		if record.EnrollmentStatus == domain.EnrollmentStatusWithdrawn {
+			course.LetterGrade, course.GradePoints = "W", 0
+		}
+		if countsTowardGPA(&record) {
+			semesterPoints += record.GradePoints * record.Credits
+			semester.Credits += record.Credits
+			semester.GPA = roundGPA(semesterPoints / semester.Credits)
+
+			if chosen[record.Code] == i {
+				course.Cumulative = true
+				cumulativePoints += record.GradePoints * record.Credits
+				transcript.AttemptedCredits += record.Credits
+				if record.GradePoints > 0 {
+					transcript.EarnedCredits += record.Credits
+				}
+			}
+		}
+		semester.Courses = append(semester.Courses, course)
+	}
+	if transcript.AttemptedCredits > 0 {
+		transcript.CumulativeGPA = roundGPA(cumulativePoints / transcript.AttemptedCredits)
+	}
+	return transcript
+}
+
+// countsTowardGPA reports whether a course has a grade and credits and was
+// not withdrawn from
+func countsTowardGPA(record *repository.TranscriptRecord) bool {
+	return record.EnrollmentStatus != domain.EnrollmentStatusWithdrawn &&
+		record.LetterGrade != "" && record.Credits > 0
+}
+
+// semesterKey orders semesters like buildTranscript does
+type semesterKey struct {
+	year int
+	rank int
+	name string
+}
+
+func (k semesterKey) before(other semesterKey) bool {
+	if k.year != other.year {
+		return k.year < other.year
+	}
+	if k.rank != other.rank {
+		return k.rank < other.rank
+	}
+	return k.name < other.name
+}
+
+// semesterRank orders known semesters before unknown ones
+func semesterRank(semester string) int {
+	if rank, ok := semesterOrder[semester]; ok {
+		return rank
+	}
+	return len(semesterOrder) + 1
+}
+
+func roundGPA(gpa float64) float64 {
+	return math.Round(gpa*100) / 100
+}
@@ -4,12 +4,14 @@ import (
 	"backend/internal/domain"
 	"backend/internal/repository"
 	"backend/pkg/middleware"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/labstack/echo/v4"
@@ -19,13 +21,15 @@ import (
 type UserService struct {
 	userRepo        *repository.UserRepository
 	uploadDirectory string
+	settings        *SettingsStore
 }
 
 // NewUserService creates a new user service
-func NewUserService(userRepo *repository.UserRepository, uploadDir string) *UserService {
+func NewUserService(userRepo *repository.UserRepository, uploadDir string, settings *SettingsStore) *UserService {
 	return &UserService{
 		userRepo:        userRepo,
 		uploadDirectory: uploadDir,
+		settings:        settings,
 	}
 }
 
@@ -137,7 +141,7 @@ func (s *UserService) UpdateInstructor(c echo.Context) error {
 
 // Helper function to validate image file types
 func isValidImageType(filename string) bool {
-	ext := filepath.Ext(filename)
+	ext := strings.ToLower(filepath.Ext(filename))
 	validExtensions := map[string]bool{
 		".jpg":  true,
 		".jpeg": true,
@@ -146,6 +150,17 @@ func isValidImageType(filename string) bool {
 	return validExtensions[ext]
 }
 
+// allowedImageTypes returns the image extensions that are also allowed by the system settings
+func allowedImageTypes(settings domain.SystemSettings) []string {
+	var allowed []string
+	for _, ext := range settings.AllowedFileTypes {
+		if isValidImageType(ext) {
+			allowed = append(allowed, strings.TrimPrefix(ext, "."))
+		}
+	}
+	return allowed
+}
+
 // GetUser returns a user by ID
 func (s *UserService) GetUser(c echo.Context) error {
 	// Parse user ID
@@ -308,12 +323,23 @@ func (s *UserService) UploadProfilePhoto(c echo.Context) error {
 	// Get file from request
 	file, err := c.FormFile("photo")
 	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return echo.NewHTTPError(http.StatusRequestEntityTooLarge, "File is too large")
+		}
 		return echo.NewHTTPError(http.StatusBadRequest, "No file uploaded")
 	}
 
-	// Validate file type
-	if !isValidImageType(file.Filename) {
-		return echo.NewHTTPError(http.StatusBadRequest, "Invalid file type. Allowed types: jpg, jpeg, png")
+	// Validate file type and size against the system settings
+	settings := s.settings.Current()
+	ext := strings.ToLower(filepath.Ext(file.Filename))
+	if !isValidImageType(ext) || !settings.IsFileTypeAllowed(ext) {
+		return echo.NewHTTPError(http.StatusBadRequest,
+			"Invalid file type. Allowed types: "+strings.Join(allowedImageTypes(settings), ", "))
+	}
+	if file.Size > settings.MaxFileSize {
+		return echo.NewHTTPError(http.StatusRequestEntityTooLarge,
+			fmt.Sprintf("File is too large. Maximum size is %d bytes", settings.MaxFileSize))
 	}
 
 	// Create profile photos directory if it doesn't exist
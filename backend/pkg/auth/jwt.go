@@ -6,13 +6,18 @@ import (
 	"github.com/golang-jwt/jwt/v4"
 )
 
-// GenerateToken generates a JWT token with role information
-func GenerateToken(userID uint, role string, secret string, expiration time.Duration) (string, error) {
+// GenerateToken generates a JWT token with role information. sessionID
+// names the signed-in session the token was issued for and is left out
+// when empty.
+func GenerateToken(userID uint, role string, sessionID string, secret string, expiration time.Duration) (string, error) {
 	claims := jwt.MapClaims{
 		"user_id": userID,
 		"role":    role,
 		"exp":     time.Now().Add(expiration).Unix(),
 	}
+	if sessionID != "" {
+		claims["sid"] = sessionID
+	}
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	return token.SignedString([]byte(secret))
 }
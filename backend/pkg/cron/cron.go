@@ -0,0 +1,195 @@
+// Package cron parses cron schedules: the five standard fields (minute,
+// hour, day of month, month, day of week) with lists, ranges and steps, the
+// @hourly, @daily, @weekly, @monthly and @yearly shorthands, and fixed
+// intervals written as "@every 90s"
+package cron
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule tells when a job runs next
+type Schedule interface {
+	// Next returns the first time after t the schedule fires
+	Next(t time.Time) time.Time
+}
+
+// shorthands maps the @ shorthands to their expressions
+var shorthands = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// field is the range of values of one field of an expression
+type field struct {
+	name     string
+	min, max int
+}
+
+var fields = [5]field{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 7}, // 0 and 7 are both Sunday
+}
+
+// Parse parses a cron expression or shorthand
+func Parse(spec string) (Schedule, error) {
+	spec = strings.TrimSpace(spec)
+	if strings.HasPrefix(spec, "@every ") {
+		interval, err := time.ParseDuration(strings.TrimSpace(strings.TrimPrefix(spec, "@every ")))
+		if err != nil {
+			return nil, fmt.Errorf("invalid interval in %q: %w", spec, err)
+		}
+		if interval < time.Second {
+			return nil, fmt.Errorf("interval in %q must be at least one second", spec)
+		}
+		return every(interval), nil
+	}
+	if expression, ok := shorthands[spec]; ok {
+		spec = expression
+	}
+
+	parts := strings.Fields(spec)
+	if len(parts) != len(fields) {
+		return nil, fmt.Errorf("expected %d fields in %q, got %d", len(fields), spec, len(parts))
+	}
+	var s expression
+	var sets [5]uint64
+	for i, part := range parts {
+		set, err := parseField(part, fields[i])
+		if err != nil {
+			return nil, err
+		}
+		sets[i] = set
+	}
+	s.minute, s.hour, s.dom, s.month, s.dow = sets[0], sets[1], sets[2], sets[3], sets[4]
+	if s.dow&(1<<7) != 0 {
+		s.dow |= 1 << 0
+	}
+	s.anyDom = parts[2] == "*"
+	s.anyDow = parts[4] == "*"
+	return s, nil
+}
+
+// MustParse is like Parse but panics on invalid expressions; it is meant
+// for schedules written into the code
+func MustParse(spec string) Schedule {
+	schedule, err := Parse(spec)
+	if err != nil {
+		panic(err)
+	}
+	return schedule
+}
+
+// parseField parses a comma separated list of values, ranges and steps
+// into a bit set of the values it matches
+func parseField(value string, f field) (uint64, error) {
+	var set uint64
+	for _, item := range strings.Split(value, ",") {
+		rangePart, step := item, 1
+		if i := strings.Index(item, "/"); i >= 0 {
+			var err error
+			rangePart = item[:i]
+			step, err = strconv.Atoi(item[i+1:])
+			if err != nil || step <= 0 {
+				return 0, fmt.Errorf("invalid step in %s field %q", f.name, item)
+			}
+		}
+
+		low, high := f.min, f.max
+		switch {
+		case rangePart == "*":
+		case strings.Contains(rangePart, "-"):
+			bounds := strings.SplitN(rangePart, "-", 2)
+			var errLow, errHigh error
+			low, errLow = strconv.Atoi(bounds[0])
+			high, errHigh = strconv.Atoi(bounds[1])
+			if errLow != nil || errHigh != nil {
+				return 0, fmt.Errorf("invalid range in %s field %q", f.name, item)
+			}
+		default:
+			n, err := strconv.Atoi(rangePart)
+			if err != nil {
+				return 0, fmt.Errorf("invalid value in %s field %q", f.name, item)
+			}
+			low, high = n, n
+			if step > 1 {
+				// "5/15" runs from 5 to the end of the range
+				high = f.max
+			}
+		}
+		if low < f.min || high > f.max || low > high {
+			return 0, fmt.Errorf("%s field %q is outside %d-%d", f.name, item, f.min, f.max)
+		}
+		for n := low; n <= high; n += step {
+			set |= 1 << uint(n)
+		}
+	}
+	return set, nil
+}
+
+// expression is a parsed five field cron expression
+type expression struct {
+	minute, hour, dom, month, dow uint64
+	anyDom, anyDow                bool
+}
+
+// Next returns the first minute after t that matches the expression, in
+// t's location
+func (s expression) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	// Every expression that parses matches within a few years, the longest
+	// being February 29 on a given weekday
+	limit := t.AddDate(30, 0, 0)
+
+	for t.Before(limit) {
+		if s.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !s.matchDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if s.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if s.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+// matchDay reports whether t's day matches. As in cron, a day matches
+// either restricted day field when both are restricted.
+func (s expression) matchDay(t time.Time) bool {
+	dom := s.dom&(1<<uint(t.Day())) != 0
+	dow := s.dow&(1<<uint(t.Weekday())) != 0
+	if s.anyDom || s.anyDow {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+// every fires at a fixed interval
+type every time.Duration
+
+// Next returns the next multiple of the interval since the zero time, so
+// that every process running the schedule fires at the same times
+func (e every) Next(t time.Time) time.Time {
+	return t.Truncate(time.Duration(e)).Add(time.Duration(e))
+}
@@ -0,0 +1,101 @@
+package cron
+
+import (
+	"testing"
+	"time"
+)
+
+func date(year int, month time.Month, day, hour, minute, second int) time.Time {
+	return time.Date(year, month, day, hour, minute, second, 0, time.UTC)
+}
+
+func TestNext(t *testing.T) {
+	// 2024-01-01 is a Monday
+	from := date(2024, 1, 1, 10, 17, 30)
+	tests := []struct {
+		spec string
+		from time.Time
+		want time.Time
+	}{
+		{"* * * * *", from, date(2024, 1, 1, 10, 18, 0)},
+		{"* * * * *", date(2024, 1, 1, 10, 17, 0), date(2024, 1, 1, 10, 18, 0)},
+		{"*/5 * * * *", from, date(2024, 1, 1, 10, 20, 0)},
+		{"5/15 * * * *", from, date(2024, 1, 1, 10, 20, 0)},
+		{"0 * * * *", from, date(2024, 1, 1, 11, 0, 0)},
+		{"30 3 * * *", from, date(2024, 1, 2, 3, 30, 0)},
+		{"0 9-17/4 * * *", from, date(2024, 1, 1, 13, 0, 0)},
+		{"15,45 * * * *", from, date(2024, 1, 1, 10, 45, 0)},
+		{"59 23 31 12 *", from, date(2024, 12, 31, 23, 59, 0)},
+		{"0 0 1 * *", from, date(2024, 2, 1, 0, 0, 0)},
+		{"0 0 * * 0", from, date(2024, 1, 7, 0, 0, 0)},
+		{"0 0 * * 7", from, date(2024, 1, 7, 0, 0, 0)},
+		{"0 0 * * 1-5", date(2024, 1, 5, 12, 0, 0), date(2024, 1, 8, 0, 0, 0)},
+		// Both day fields restricted: either one matches
+		{"0 0 15 * 3", from, date(2024, 1, 3, 0, 0, 0)},
+		{"0 0 29 2 *", from, date(2024, 2, 29, 0, 0, 0)},
+		{"0 0 29 2 *", date(2024, 3, 1, 0, 0, 0), date(2028, 2, 29, 0, 0, 0)},
+		{"@hourly", from, date(2024, 1, 1, 11, 0, 0)},
+		{"@daily", from, date(2024, 1, 2, 0, 0, 0)},
+		{"@weekly", from, date(2024, 1, 7, 0, 0, 0)},
+		{"@monthly", from, date(2024, 2, 1, 0, 0, 0)},
+		{"@yearly", from, date(2025, 1, 1, 0, 0, 0)},
+		{"@every 90s", date(2024, 1, 1, 10, 0, 10), date(2024, 1, 1, 10, 1, 30)},
+		{"@every 1h", from, date(2024, 1, 1, 11, 0, 0)},
+		{"@every 1h", date(2024, 1, 1, 11, 0, 0), date(2024, 1, 1, 12, 0, 0)},
+		{"0 0 30 2 *", from, time.Time{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			schedule, err := Parse(tt.spec)
+			if err != nil {
+				t.Fatalf("Parse(%q): %v", tt.spec, err)
+			}
+			if got := schedule.Next(tt.from); !got.Equal(tt.want) {
+				t.Errorf("Next(%s) = %s, want %s", tt.from, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNextKeepsLocation(t *testing.T) {
+	jakarta := time.FixedZone("WIB", 7*60*60)
+	got := MustParse("30 3 * * *").Next(time.Date(2024, 1, 1, 10, 0, 0, 0, jakarta))
+	want := time.Date(2024, 1, 2, 3, 30, 0, 0, jakarta)
+	if !got.Equal(want) || got.Location() != jakarta {
+		t.Errorf("Next = %s, want %s", got, want)
+	}
+}
+
+func TestEveryFiresAtTheSameTimesEverywhere(t *testing.T) {
+	schedule := MustParse("@every 5m")
+	base := date(2024, 1, 1, 10, 0, 0)
+	for _, offset := range []time.Duration{0, time.Second, 2 * time.Minute, 4*time.Minute + 59*time.Second} {
+		if got := schedule.Next(base.Add(offset)); !got.Equal(base.Add(5 * time.Minute)) {
+			t.Errorf("Next(%s) = %s, want %s", base.Add(offset), got, base.Add(5*time.Minute))
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, spec := range []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"a * * * *",
+		"@every",
+		"@every 500ms",
+		"@every soon",
+		"@fortnightly",
+	} {
+		if _, err := Parse(spec); err == nil {
+			t.Errorf("Parse(%q) succeeded, want an error", spec)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+// Package filetype identifies uploaded files by their content rather than
+// by the extension of their name
+package filetype
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+)
+
+// sniffLength is how much of a file is inspected for its signature
+const sniffLength = 512
+
+// Type is the detected kind of a file and the extensions it may carry
+type Type struct {
+	MIME       string
+	Extensions []string
+}
+
+// Matches reports whether ext, such as ".pdf", is an extension of the type
+func (t Type) Matches(ext string) bool {
+	ext = strings.ToLower(ext)
+	for _, e := range t.Extensions {
+		if e == ext {
+			return true
+		}
+	}
+	return false
+}
+
+// textExtensions are the plain-text files accepted when their content is text
+var textExtensions = []string{
+	".txt", ".md", ".csv", ".tsv", ".json", ".xml", ".html", ".htm", ".css", ".sql", ".tex",
+	".c", ".h", ".cpp", ".hpp", ".cs", ".go", ".java", ".js", ".ts", ".py", ".rb", ".php", ".sh", ".kt", ".swift", ".rs",
+}
+
+// signatures are the magic numbers of binary formats, checked in order
+var signatures = []struct {
+	prefix []byte
+	typ    Type
+}{
+	{[]byte("%PDF-"), Type{"application/pdf", []string{".pdf"}}},
+	{[]byte("\x89PNG\r\n\x1a\n"), Type{"image/png", []string{".png"}}},
+	{[]byte("\xff\xd8\xff"), Type{"image/jpeg", []string{".jpg", ".jpeg"}}},
+	{[]byte("GIF87a"), Type{"image/gif", []string{".gif"}}},
+	{[]byte("GIF89a"), Type{"image/gif", []string{".gif"}}},
+	{[]byte("{\\rtf"), Type{"application/rtf", []string{".rtf"}}},
+	{[]byte("Rar!\x1a\x07"), Type{"application/vnd.rar", []string{".rar"}}},
+	{[]byte("7z\xbc\xaf\x27\x1c"), Type{"application/x-7z-compressed", []string{".7z"}}},
+	{[]byte("\x1f\x8b"), Type{"application/gzip", []string{".gz", ".tgz"}}},
+	// Compound files hold the legacy Office formats, which cannot be told apart cheaply
+	{[]byte("\xd0\xcf\x11\xe0\xa1\xb1\x1a\xe1"), Type{"application/x-ole-storage", []string{".doc", ".xls", ".ppt"}}},
+}
+
+// officeParts identify Office Open XML documents by their main part
+var officeParts = []struct {
+	prefix string
+	typ    Type
+}{
+	{"word/", Type{"application/vnd.openxmlformats-officedocument.wordprocessingml.document", []string{".docx"}}},
+	{"xl/", Type{"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", []string{".xlsx"}}},
+	{"ppt/", Type{"application/vnd.openxmlformats-officedocument.presentationml.presentation", []string{".pptx"}}},
+}
+
+// openDocumentTypes are the OpenDocument formats by their mimetype entry
+var openDocumentTypes = map[string][]string{
+	"application/vnd.oasis.opendocument.text":         {".odt"},
+	"application/vnd.oasis.opendocument.spreadsheet":  {".ods"},
+	"application/vnd.oasis.opendocument.presentation": {".odp"},
+}
+
+// Detect identifies a file from its content. Zip archives are opened to
+// recognise the Office and OpenDocument formats built on them. Files that
+// are not recognised are reported with the MIME type net/http sniffs and no
+// extensions.
+func Detect(r io.ReaderAt, size int64) (Type, error) {
+	head := make([]byte, sniffLength)
+	n, err := r.ReadAt(head, 0)
+	if err != nil && err != io.EOF {
+		return Type{}, err
+	}
+	head = head[:n]
+
+	for _, sig := range signatures {
+		if bytes.HasPrefix(head, sig.prefix) {
+			return sig.typ, nil
+		}
+	}
+	if bytes.HasPrefix(head, []byte("PK\x03\x04")) {
+		return detectZip(r, size), nil
+	}
+	if isText(head) {
+		return Type{"text/plain; charset=utf-8", textExtensions}, nil
+	}
+	return Type{MIME: http.DetectContentType(head)}, nil
+}
+
+// detectZip tells the formats packaged as zip archives apart
+func detectZip(r io.ReaderAt, size int64) Type {
+	archive := Type{"application/zip", []string{".zip"}}
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		// A damaged archive is not trusted to be anything
+		return Type{MIME: "application/octet-stream"}
+	}
+
+	hasContentTypes := false
+	for _, f := range zr.File {
+		if f.Name == "[Content_Types].xml" {
+			hasContentTypes = true
+			break
+		}
+	}
+	for _, f := range zr.File {
+		if f.Name == "mimetype" {
+			if exts, ok := openDocumentTypes[readSmall(f)]; ok {
+				return Type{readSmall(f), exts}
+			}
+		}
+		if !hasContentTypes {
+			continue
+		}
+		for _, part := range officeParts {
+			if strings.HasPrefix(f.Name, part.prefix) {
+				return part.typ
+			}
+		}
+	}
+	return archive
+}
+
+// readSmall returns the trimmed content of a short archive entry
+func readSmall(f *zip.File) string {
+	rc, err := f.Open()
+	if err != nil {
+		return ""
+	}
+	defer rc.Close()
+	data, _ := io.ReadAll(io.LimitReader(rc, 128))
+	return strings.TrimSpace(string(data))
+}
+
+// isText reports whether the head of a file looks like UTF-8 text
+func isText(head []byte) bool {
+	if len(head) == 0 || bytes.IndexByte(head, 0) >= 0 {
+		return false
+	}
+	// The head may end in the middle of a multi-byte character
+	for i := 0; i < utf8.UTFMax && len(head) > 0 && !utf8.Valid(head); i++ {
+		head = head[:len(head)-1]
+	}
+	if !utf8.Valid(head) {
+		return false
+	}
+	for _, r := range string(head) {
+		if r < 0x20 && r != '\n' && r != '\r' && r != '\t' && r != '\f' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,68 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// FileMailer drops every message as an .eml file into a directory, where
+// mail clients can open it
+type FileMailer struct {
+	Dir  string
+	From string
+}
+
+// Send writes the message to a new file
+func (m *FileMailer) Send(ctx context.Context, msg Message) error {
+	now := time.Now()
+	data, err := compose(m.From, msg, now)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(m.Dir, 0o755); err != nil {
+		return err
+	}
+	unique, err := randomHex(4)
+	if err != nil {
+		return err
+	}
+	// Names sort in the order the messages were sent
+	name := fmt.Sprintf("%s-%s.eml", now.UTC().Format("20060102T150405.000000000Z"), unique)
+	return os.WriteFile(filepath.Join(m.Dir, name), data, 0o644)
+}
+
+// MemoryMailer keeps sent messages in memory, for tests and local runs that
+// inspect what would have been delivered
+type MemoryMailer struct {
+	mu       sync.Mutex
+	messages []Message
+}
+
+// Send records the message
+func (m *MemoryMailer) Send(ctx context.Context, msg Message) error {
+	if _, err := compose("memory@localhost", msg, time.Now()); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.messages = append(m.messages, msg)
+	return nil
+}
+
+// Messages returns the messages sent so far, oldest first
+func (m *MemoryMailer) Messages() []Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]Message(nil), m.messages...)
+}
+
+// Reset forgets the messages sent so far
+func (m *MemoryMailer) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.messages = nil
+}
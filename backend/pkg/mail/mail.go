@@ -0,0 +1,109 @@
+// Package mail sends plain text email through pluggable transports: an SMTP
+// server, a directory of .eml files, memory, or the process log
+package mail
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"mime"
+	"mime/quotedprintable"
+	"net/mail"
+	"strings"
+	"time"
+)
+
+// Message is a plain text email
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers messages
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// ErrInvalidHeader is returned for recipients and subjects that would break
+// out of their header
+var ErrInvalidHeader = errors.New("mail header contains a line break")
+
+// LogMailer notes messages in the process log instead of delivering them.
+// It is meant for development, where nobody should receive real mail. The
+// body is left out because it carries sign-in links and codes.
+type LogMailer struct{}
+
+// Send logs the recipient and subject of the message
+func (LogMailer) Send(ctx context.Context, msg Message) error {
+	log.Printf("Mail to %s: %s", msg.To, msg.Subject)
+	return nil
+}
+
+// compose renders a message as RFC 5322 text with CRLF line endings
+func compose(from string, msg Message, date time.Time) ([]byte, error) {
+	if strings.ContainsAny(msg.To, "\r\n") || strings.ContainsAny(msg.Subject, "\r\n") || strings.ContainsAny(from, "\r\n") {
+		return nil, ErrInvalidHeader
+	}
+	if _, err := mail.ParseAddress(msg.To); err != nil {
+		return nil, fmt.Errorf("invalid recipient %q: %w", msg.To, err)
+	}
+	sender, err := mail.ParseAddress(from)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sender %q: %w", from, err)
+	}
+	id, err := messageID(sender.Address)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	header := func(name, value string) {
+		buf.WriteString(name + ": " + value + "\r\n")
+	}
+	header("From", from)
+	header("To", msg.To)
+	header("Subject", mime.QEncoding.Encode("utf-8", msg.Subject))
+	header("Date", date.Format(time.RFC1123Z))
+	header("Message-ID", id)
+	header("MIME-Version", "1.0")
+	header("Content-Type", "text/plain; charset=utf-8")
+	header("Content-Transfer-Encoding", "quoted-printable")
+	buf.WriteString("\r\n")
+
+	body := quotedprintable.NewWriter(&buf)
+	normalized := strings.ReplaceAll(strings.ReplaceAll(msg.Body, "\r\n", "\n"), "\n", "\r\n")
+	if _, err := body.Write([]byte(normalized)); err != nil {
+		return nil, err
+	}
+	if err := body.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// messageID returns a unique Message-ID on the sender's domain
+func messageID(sender string) (string, error) {
+	domain := "localhost"
+	if at := strings.LastIndex(sender, "@"); at >= 0 {
+		domain = sender[at+1:]
+	}
+	unique, err := randomHex(12)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("<%d.%s@%s>", time.Now().UnixNano(), unique, domain), nil
+}
+
+// randomHex returns n random bytes, hex encoded
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
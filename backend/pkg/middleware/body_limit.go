@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// multipartOverhead leaves room for multipart boundaries and form fields
+// around an uploaded file of the maximum size
+const multipartOverhead = 64 << 10
+
+// BodyLimit rejects request bodies larger than the limit returned by limitFn.
+// Unlike echo's BodyLimit the limit is read on every request, so it follows
+// runtime changes to the upload settings.
+func BodyLimit(limitFn func() int64) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			limit := limitFn()
+			if limit <= 0 {
+				return next(c)
+			}
+			limit += multipartOverhead
+
+			req := c.Request()
+			if req.ContentLength > limit {
+				return echo.NewHTTPError(http.StatusRequestEntityTooLarge,
+					fmt.Sprintf("Request body exceeds the maximum upload size of %d bytes", limit-multipartOverhead))
+			}
+			req.Body = http.MaxBytesReader(c.Response(), req.Body, limit)
+
+			return next(c)
+		}
+	}
+}
@@ -62,6 +62,11 @@ func JWT(secret string) echo.MiddlewareFunc {
 			c.Set("user_id", uint(userID))
 			c.Set("role", role)
 
+			// The signed-in session the token was issued for, if any
+			if sessionID, ok := claims["sid"].(string); ok {
+				c.Set("session_id", sessionID)
+			}
+
 			return next(c)
 		}
 	}
@@ -76,6 +81,22 @@ func GetUserIDFromToken(c echo.Context) (uint, error) {
 	return userID, nil
 }
 
+// GetRoleFromToken extracts the user role from context
+func GetRoleFromToken(c echo.Context) (string, error) {
+	role, ok := c.Get("role").(string)
+	if !ok || role == "" {
+		return "", echo.NewHTTPError(http.StatusUnauthorized, "Role not found in token")
+	}
+	return role, nil
+}
+
+// GetSessionIDFromToken returns the signed-in session the token was issued
+// for, or "" for tokens that do not name one
+func GetSessionIDFromToken(c echo.Context) string {
+	sessionID, _ := c.Get("session_id").(string)
+	return sessionID
+}
+
 // RequireAdmin middleware checks if the user has admin role
 func RequireAdmin() echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
@@ -0,0 +1,88 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/labstack/echo/v4"
+)
+
+// defaultRetryAfter is advertised when a maintenance window has no end time
+const defaultRetryAfter = 5 * time.Minute
+
+// MaintenanceStatus describes the maintenance state at the time of a request
+type MaintenanceStatus struct {
+	Active  bool
+	Message string
+	Until   *time.Time
+}
+
+// Maintenance answers requests with 503 Service Unavailable while the status
+// reports maintenance. Requests carrying a valid admin JWT and requests to the
+// exempt paths (e.g. health checks and login) are passed through.
+func Maintenance(secret string, status func() MaintenanceStatus, exemptPaths ...string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			if req.Method == http.MethodOptions {
+				return next(c)
+			}
+			for _, path := range exemptPaths {
+				if req.URL.Path == path {
+					return next(c)
+				}
+			}
+
+			current := status()
+			if !current.Active || isAdminRequest(req, secret) {
+				return next(c)
+			}
+
+			retryAfter := defaultRetryAfter
+			if current.Until != nil {
+				if remaining := time.Until(*current.Until); remaining > 0 {
+					retryAfter = remaining
+				}
+			}
+			seconds := int(retryAfter.Round(time.Second) / time.Second)
+			if seconds < 1 {
+				seconds = 1
+			}
+			c.Response().Header().Set("Retry-After", strconv.Itoa(seconds))
+
+			return c.JSON(http.StatusServiceUnavailable, map[string]interface{}{
+				"message":     current.Message,
+				"maintenance": true,
+				"until":       current.Until,
+			})
+		}
+	}
+}
+
+// isAdminRequest reports whether the request carries a valid JWT with the admin role
+func isAdminRequest(req *http.Request, secret string) bool {
+	parts := strings.Split(req.Header.Get("Authorization"), " ")
+	if len(parts) != 2 || parts[0] != "Bearer" {
+		return false
+	}
+
+	token, err := jwt.Parse(parts[1], func(t *jwt.Token) (interface{}, error) {
+		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, jwt.ErrSignatureInvalid
+		}
+		return []byte(secret), nil
+	})
+	if err != nil || !token.Valid {
+		return false
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return false
+	}
+	role, _ := claims["role"].(string)
+	return role == "admin"
+}
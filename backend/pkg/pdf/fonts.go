@@ -0,0 +1,142 @@
+package pdf
+
+import (
+	_ "embed"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf16"
+)
+
+// The bundled DejaVu Sans Condensed faces; see fonts/LICENSE
+var (
+	//go:embed fonts/DejaVuSansCondensed.ttf
+	sansData []byte
+	//go:embed fonts/DejaVuSansCondensed-Bold.ttf
+	sansBoldData []byte
+)
+
+// fontDef describes a font: an embedded TrueType face, or one of the
+// standard Type1 fonts by name
+type fontDef struct {
+	name string
+	face *face
+}
+
+var fontDefs = [...]fontDef{
+	Sans:        {face: mustParseTrueType(sansData)},
+	SansBold:    {face: mustParseTrueType(sansBoldData)},
+	Courier:     {name: "Courier"},
+	CourierBold: {name: "Courier-Bold"},
+}
+
+func mustParseTrueType(data []byte) *face {
+	f, err := parseTrueType(data)
+	if err != nil {
+		panic(fmt.Sprintf("pdf: bundled font: %v", err))
+	}
+	return f
+}
+
+// fontUse records what a document draws with an embedded font: the glyphs
+// and the text each stands for
+type fontUse struct {
+	glyphs map[uint16][]rune
+}
+
+// glyphFor returns the glyph a face draws a run with. Characters shaped
+// into a form the face lacks fall back to the character itself.
+func glyphFor(f *face, run glyphRun) uint16 {
+	if !f.has(run.r) && len(run.source) == 1 {
+		return f.glyph(run.source[0])
+	}
+	return f.glyph(run.r)
+}
+
+// sortedGlyphs returns the glyphs a document uses from a font in order
+func (u *fontUse) sortedGlyphs() []uint16 {
+	gids := make([]uint16, 0, len(u.glyphs))
+	for gid := range u.glyphs {
+		gids = append(gids, gid)
+	}
+	sort.Slice(gids, func(i, j int) bool { return gids[i] < gids[j] })
+	return gids
+}
+
+// embeddedFontObjects writes the objects of an embedded font, numbered
+// from first: the Type0 font, its CIDFontType2 descendant, the font
+// descriptor, the subset font file and the ToUnicode map
+func embeddedFontObjects(w *writer, first int, f *face, use *fontUse) {
+	gids := use.sortedGlyphs()
+	name := subsetTag(gids) + "+" + f.name
+	fontFile := f.subset(gids)
+
+	w.object(first, fmt.Sprintf("<< /Type /Font /Subtype /Type0 /BaseFont /%s /Encoding /Identity-H /DescendantFonts [%d 0 R] /ToUnicode %d 0 R >>",
+		name, first+1, first+4))
+	w.object(first+1, fmt.Sprintf("<< /Type /Font /Subtype /CIDFontType2 /BaseFont /%s "+
+		"/CIDSystemInfo << /Registry (Adobe) /Ordering (Identity) /Supplement 0 >> "+
+		"/FontDescriptor %d 0 R /DW %d /W [%s] /CIDToGIDMap /Identity >>",
+		name, first+2, f.advance(0), glyphWidths(f, gids)))
+	w.object(first+2, fmt.Sprintf("<< /Type /FontDescriptor /FontName /%s /Flags 32 /FontBBox [%d %d %d %d] "+
+		"/ItalicAngle %s /Ascent %d /Descent %d /CapHeight %d /StemV %d /FontFile2 %d 0 R >>",
+		name, f.scale(f.bbox[0]), f.scale(f.bbox[1]), f.scale(f.bbox[2]), f.scale(f.bbox[3]),
+		num(f.italicAngle), f.scale(f.ascent), f.scale(f.descent), f.scale(f.capHeight), f.stemV, first+3))
+	w.stream(first+3, fmt.Sprintf("/Length1 %d /Filter /FlateDecode", len(fontFile)), deflate(fontFile))
+	w.stream(first+4, "/Filter /FlateDecode", deflate(toUnicode(gids, use.glyphs)))
+}
+
+// glyphWidths returns the /W array of a CID font, in runs of consecutive glyphs
+func glyphWidths(f *face, gids []uint16) string {
+	var b strings.Builder
+	for i := 0; i < len(gids); {
+		j := i + 1
+		for j < len(gids) && gids[j] == gids[j-1]+1 {
+			j++
+		}
+		fmt.Fprintf(&b, "%d [", gids[i])
+		for k := i; k < j; k++ {
+			if k > i {
+				b.WriteByte(' ')
+			}
+			fmt.Fprintf(&b, "%d", f.advance(gids[k]))
+		}
+		b.WriteString("] ")
+		i = j
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// toUnicode returns the CMap that maps glyphs back to text for copying and
+// searching
+func toUnicode(gids []uint16, text map[uint16][]rune) []byte {
+	var b strings.Builder
+	b.WriteString("/CIDInit /ProcSet findresource begin\n12 dict begin\nbegincmap\n" +
+		"/CIDSystemInfo << /Registry (Adobe) /Ordering (UCS) /Supplement 0 >> def\n" +
+		"/CMapName /Adobe-Identity-UCS def\n/CMapType 2 def\n" +
+		"1 begincodespacerange\n<0000> <FFFF>\nendcodespacerange\n")
+	var mapped []uint16
+	for _, gid := range gids {
+		if gid != 0 && len(text[gid]) > 0 {
+			mapped = append(mapped, gid)
+		}
+	}
+	// At most 100 entries per block
+	for len(mapped) > 0 {
+		n := len(mapped)
+		if n > 100 {
+			n = 100
+		}
+		fmt.Fprintf(&b, "%d beginbfchar\n", n)
+		for _, gid := range mapped[:n] {
+			fmt.Fprintf(&b, "<%04X> <", gid)
+			for _, unit := range utf16.Encode(text[gid]) {
+				fmt.Fprintf(&b, "%04X", unit)
+			}
+			b.WriteString(">\n")
+		}
+		b.WriteString("endbfchar\n")
+		mapped = mapped[n:]
+	}
+	b.WriteString("endcmap\nCMapName currentdict /CMap defineresource pop\nend\nend\n")
+	return []byte(b.String())
+}
@@ -0,0 +1,36 @@
+package pdf
+
+// courierWidth is the width of every Courier glyph in thousandths of the
+// font size
+const courierWidth = 600
+
+// winAnsi maps the characters of WinAnsiEncoding above Latin-1's control
+// range that differ from their Unicode code point
+var winAnsi = map[rune]byte{
+	'€': 0x80, '‚': 0x82, 'ƒ': 0x83, '„': 0x84, '…': 0x85, '†': 0x86, '‡': 0x87,
+	'ˆ': 0x88, '‰': 0x89, 'Š': 0x8a, '‹': 0x8b, 'Œ': 0x8c, 'Ž': 0x8e,
+	'‘': 0x91, '’': 0x92, '“': 0x93, '”': 0x94, '•': 0x95, '–': 0x96, '—': 0x97,
+	'˜': 0x98, '™': 0x99, 'š': 0x9a, '›': 0x9b, 'œ': 0x9c, 'ž': 0x9e, 'Ÿ': 0x9f,
+}
+
+// encode converts text to WinAnsiEncoding, the encoding of the standard
+// Courier fonts. Characters it cannot represent become '?'; the embedded
+// fonts are used for everything but ASCII codes.
+func encode(text string) []byte {
+	out := make([]byte, 0, len(text))
+	for _, r := range text {
+		switch {
+		case r == '\t' || r == '\n' || r == '\r':
+			out = append(out, ' ')
+		case r >= 32 && r < 127, r >= 0xa0 && r <= 0xff:
+			out = append(out, byte(r))
+		default:
+			if c, ok := winAnsi[r]; ok {
+				out = append(out, c)
+			} else {
+				out = append(out, '?')
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,424 @@
+// Package pdf writes simple PDF documents: text, lines, rectangles and
+// raster images. Text is set in the bundled DejaVu Sans Condensed faces,
+// which cover Latin (including Vietnamese), Greek, Cyrillic, Hebrew and
+// Arabic; each document embeds the subset of glyphs it draws, so it can be
+// produced fully offline and shows the same everywhere. Arabic letters are
+// joined and right-to-left runs are put in visual order, but there is no
+// further shaping. Courier, one of the standard Type1 fonts built into
+// every PDF viewer, is kept for ASCII codes.
+//
+// Coordinates are in points (1/72 inch) from the top-left corner of the
+// page, with y growing downwards; text is positioned by its baseline.
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"crypto/md5"
+	"fmt"
+	"image"
+	"io"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf16"
+)
+
+// Page sizes in points
+const (
+	A4Width  = 595.28
+	A4Height = 841.89
+
+	// ID-1 card size used by ID and bank cards, 85.60 x 53.98 mm
+	CardWidth  = 242.65
+	CardHeight = 153.01
+)
+
+// Font is one of the fonts a document can use
+type Font int
+
+// The fonts a document can use
+const (
+	Sans Font = iota
+	SansBold
+	Courier
+	CourierBold
+)
+
+// Info is the document information dictionary
+type Info struct {
+	Title        string
+	Author       string
+	Subject      string
+	Keywords     string
+	Creator      string
+	CreationDate time.Time
+}
+
+// Document is a PDF document under construction
+type Document struct {
+	width, height float64
+	info          Info
+	pages         []*Page
+	images        []*Image
+	fonts         map[Font]*fontUse
+}
+
+// Page is a page of a document
+type Page struct {
+	doc     *Document
+	content bytes.Buffer
+	images  []*Image
+}
+
+// Image is a raster image added to a document; it can be drawn on any
+// number of pages
+type Image struct {
+	index         int
+	width, height int
+	data          []byte // zlib compressed 8-bit RGB samples
+}
+
+// New creates an empty document whose pages have the given size
+func New(width, height float64) *Document {
+	return &Document{width: width, height: height, fonts: make(map[Font]*fontUse)}
+}
+
+// SetInfo sets the document information dictionary
+func (d *Document) SetInfo(info Info) {
+	d.info = info
+}
+
+// AddPage appends a page to the document
+func (d *Document) AddPage() *Page {
+	page := &Page{doc: d}
+	d.pages = append(d.pages, page)
+	return page
+}
+
+// Pages returns the pages of the document in order
+func (d *Document) Pages() []*Page {
+	return d.pages
+}
+
+// AddImage adds a raster image to the document
+func (d *Document) AddImage(img image.Image) *Image {
+	bounds := img.Bounds()
+	var raw bytes.Buffer
+	raw.Grow(bounds.Dx() * bounds.Dy() * 3)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			r, g, b, a := img.At(x, y).RGBA()
+			// Blend transparent pixels onto white
+			r, g, b = r+0xffff-a, g+0xffff-a, b+0xffff-a
+			raw.Write([]byte{byte(r >> 8), byte(g >> 8), byte(b >> 8)})
+		}
+	}
+
+	added := &Image{
+		index:  len(d.images),
+		width:  bounds.Dx(),
+		height: bounds.Dy(),
+		data:   deflate(raw.Bytes()),
+	}
+	d.images = append(d.images, added)
+	return added
+}
+
+// Width returns the width of text set in the given font and size
+func Width(font Font, size float64, text string) float64 {
+	total := 0
+	if f := fontDefs[font].face; f != nil {
+		for _, run := range shape(text) {
+			total += f.advance(glyphFor(f, run))
+		}
+	} else {
+		total = len(encode(text)) * courierWidth
+	}
+	return float64(total) * size / 1000
+}
+
+// Wrap breaks text into lines no wider than maxWidth, breaking at spaces.
+// Words longer than a line are kept whole.
+func Wrap(font Font, size float64, text string, maxWidth float64) []string {
+	var lines []string
+	for _, paragraph := range strings.Split(text, "\n") {
+		words := strings.Fields(paragraph)
+		if len(words) == 0 {
+			lines = append(lines, "")
+			continue
+		}
+		line := words[0]
+		for _, word := range words[1:] {
+			if Width(font, size, line+" "+word) > maxWidth {
+				lines = append(lines, line)
+				line = word
+				continue
+			}
+			line += " " + word
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// Text draws text with its baseline starting at (x, y)
+func (p *Page) Text(x, y float64, font Font, size float64, text string) {
+	fmt.Fprintf(&p.content, "BT /F%d %s Tf %s %s Td %s Tj ET\n",
+		int(font)+1, num(size), num(x), num(p.doc.height-y), p.doc.showText(font, text))
+}
+
+// showText returns text as a string operand for a font and records the
+// glyphs the document must embed. Embedded fonts are addressed by glyph
+// ID, two bytes each.
+func (d *Document) showText(font Font, text string) string {
+	use := d.fonts[font]
+	if use == nil {
+		use = &fontUse{glyphs: make(map[uint16][]rune)}
+		d.fonts[font] = use
+	}
+	f := fontDefs[font].face
+	if f == nil {
+		return "(" + escape(encode(text)) + ")"
+	}
+
+	var b strings.Builder
+	b.WriteByte('<')
+	for _, run := range shape(text) {
+		gid := glyphFor(f, run)
+		if _, ok := use.glyphs[gid]; !ok {
+			use.glyphs[gid] = run.source
+		}
+		fmt.Fprintf(&b, "%04X", gid)
+	}
+	b.WriteByte('>')
+	return b.String()
+}
+
+// TextRight draws text ending at x
+func (p *Page) TextRight(x, y float64, font Font, size float64, text string) {
+	p.Text(x-Width(font, size, text), y, font, size, text)
+}
+
+// TextCenter draws text centered on x
+func (p *Page) TextCenter(x, y float64, font Font, size float64, text string) {
+	p.Text(x-Width(font, size, text)/2, y, font, size, text)
+}
+
+// Line draws a black line of the given width
+func (p *Page) Line(x1, y1, x2, y2, width float64) {
+	fmt.Fprintf(&p.content, "%s w %s %s m %s %s l S\n",
+		num(width), num(x1), num(p.doc.height-y1), num(x2), num(p.doc.height-y2))
+}
+
+// StrokeRect draws the outline of a rectangle whose top-left corner is (x, y)
+func (p *Page) StrokeRect(x, y, w, h, lineWidth float64) {
+	fmt.Fprintf(&p.content, "%s w %s %s %s %s re S\n",
+		num(lineWidth), num(x), num(p.doc.height-y-h), num(w), num(h))
+}
+
+// FillRect fills a rectangle whose top-left corner is (x, y) with a shade
+// of gray, from 0 for black to 1 for white
+func (p *Page) FillRect(x, y, w, h, gray float64) {
+	fmt.Fprintf(&p.content, "q %s g %s %s %s %s re f Q\n",
+		num(gray), num(x), num(p.doc.height-y-h), num(w), num(h))
+}
+
+// DrawImage draws an image scaled into the rectangle whose top-left corner is (x, y)
+func (p *Page) DrawImage(img *Image, x, y, w, h float64) {
+	p.images = append(p.images, img)
+	fmt.Fprintf(&p.content, "q %s 0 0 %s %s %s cm /Im%d Do Q\n",
+		num(w), num(h), num(x), num(p.doc.height-y-h), img.index+1)
+}
+
+// Bytes renders the document
+func (d *Document) Bytes() []byte {
+	w := &writer{}
+	w.write("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	// Object numbers: catalog, page tree, info, the fonts in use (five
+	// objects for an embedded font, one for a standard one), images, then a
+	// page object and its content stream for every page
+	const catalogObj, pagesObj, infoObj = 1, 2, 3
+	used := make([]Font, 0, len(d.fonts))
+	for font := range d.fonts {
+		used = append(used, font)
+	}
+	sort.Slice(used, func(i, j int) bool { return used[i] < used[j] })
+	fontObj := make(map[Font]int, len(used))
+	next := 4
+	for _, font := range used {
+		fontObj[font] = next
+		if fontDefs[font].face != nil {
+			next += 5
+		} else {
+			next++
+		}
+	}
+	imageObj := func(index int) int { return next + index }
+	pageObj := func(index int) int { return next + len(d.images) + 2*index }
+
+	w.object(catalogObj, fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R >>", pagesObj))
+
+	kids := make([]string, len(d.pages))
+	for i := range d.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", pageObj(i))
+	}
+	w.object(pagesObj, fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d /MediaBox [0 0 %s %s] >>",
+		strings.Join(kids, " "), len(d.pages), num(d.width), num(d.height)))
+
+	w.object(infoObj, d.infoDict())
+
+	for _, font := range used {
+		if f := fontDefs[font].face; f != nil {
+			embeddedFontObjects(w, fontObj[font], f, d.fonts[font])
+			continue
+		}
+		w.object(fontObj[font], fmt.Sprintf("<< /Type /Font /Subtype /Type1 /BaseFont /%s /Encoding /WinAnsiEncoding >>",
+			fontDefs[font].name))
+	}
+	for _, img := range d.images {
+		w.stream(imageObj(img.index), fmt.Sprintf(
+			"/Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace /DeviceRGB /BitsPerComponent 8 /Filter /FlateDecode",
+			img.width, img.height), img.data)
+	}
+
+	fonts := make([]string, len(used))
+	for i, font := range used {
+		fonts[i] = fmt.Sprintf("/F%d %d 0 R", int(font)+1, fontObj[font])
+	}
+	for i, page := range d.pages {
+		var xobjects []string
+		seen := make(map[int]bool)
+		for _, img := range page.images {
+			if !seen[img.index] {
+				seen[img.index] = true
+				xobjects = append(xobjects, fmt.Sprintf("/Im%d %d 0 R", img.index+1, imageObj(img.index)))
+			}
+		}
+		resources := fmt.Sprintf("/Font << %s >>", strings.Join(fonts, " "))
+		if len(xobjects) > 0 {
+			resources += fmt.Sprintf(" /XObject << %s >>", strings.Join(xobjects, " "))
+		}
+		w.object(pageObj(i), fmt.Sprintf("<< /Type /Page /Parent %d 0 R /Resources << %s >> /Contents %d 0 R >>",
+			pagesObj, resources, pageObj(i)+1))
+		w.stream(pageObj(i)+1, "/Filter /FlateDecode", deflate(page.content.Bytes()))
+	}
+
+	// The file ID is derived from the content so that equal documents are
+	// byte for byte equal
+	id := md5.Sum(w.buf.Bytes())
+	xref := w.buf.Len()
+	fmt.Fprintf(&w.buf, "xref\n0 %d\n0000000000 65535 f \n", len(w.offsets)+1)
+	for _, offset := range w.offsets {
+		fmt.Fprintf(&w.buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&w.buf, "trailer\n<< /Size %d /Root %d 0 R /Info %d 0 R /ID [<%x> <%x>] >>\nstartxref\n%d\n%%%%EOF\n",
+		len(w.offsets)+1, catalogObj, infoObj, id, id, xref)
+	return w.buf.Bytes()
+}
+
+// WriteTo writes the rendered document to out
+func (d *Document) WriteTo(out io.Writer) (int64, error) {
+	n, err := out.Write(d.Bytes())
+	return int64(n), err
+}
+
+func (d *Document) infoDict() string {
+	var b strings.Builder
+	b.WriteString("<<")
+	entries := []struct{ key, value string }{
+		{"Title", d.info.Title},
+		{"Author", d.info.Author},
+		{"Subject", d.info.Subject},
+		{"Keywords", d.info.Keywords},
+		{"Creator", d.info.Creator},
+		{"Producer", d.info.Creator},
+	}
+	for _, entry := range entries {
+		if entry.value != "" {
+			fmt.Fprintf(&b, " /%s %s", entry.key, textString(entry.value))
+		}
+	}
+	if !d.info.CreationDate.IsZero() {
+		fmt.Fprintf(&b, " /CreationDate (D:%s)", d.info.CreationDate.UTC().Format("20060102150405Z"))
+	}
+	b.WriteString(" >>")
+	return b.String()
+}
+
+// writer tracks the byte offset of every object for the cross-reference table
+type writer struct {
+	buf     bytes.Buffer
+	offsets []int
+}
+
+func (w *writer) write(s string) {
+	w.buf.WriteString(s)
+}
+
+// object writes an object; objects must be written in numbering order
+func (w *writer) object(number int, body string) {
+	w.offsets = append(w.offsets, w.buf.Len())
+	fmt.Fprintf(&w.buf, "%d 0 obj\n%s\nendobj\n", number, body)
+}
+
+func (w *writer) stream(number int, dict string, data []byte) {
+	w.offsets = append(w.offsets, w.buf.Len())
+	fmt.Fprintf(&w.buf, "%d 0 obj\n<< %s /Length %d >>\nstream\n", number, dict, len(data))
+	w.buf.Write(data)
+	w.buf.WriteString("\nendstream\nendobj\n")
+}
+
+func deflate(data []byte) []byte {
+	var buf bytes.Buffer
+	zw, _ := zlib.NewWriterLevel(&buf, zlib.BestCompression)
+	zw.Write(data)
+	zw.Close()
+	return buf.Bytes()
+}
+
+// escape escapes the delimiters of a PDF literal string
+func escape(text []byte) string {
+	var b strings.Builder
+	for _, c := range text {
+		switch c {
+		case '(', ')', '\\':
+			b.WriteByte('\\')
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
+
+// textString encodes text for the document information dictionary: as a
+// literal string when it is ASCII, else as UTF-16 with a byte order mark
+func textString(text string) string {
+	ascii := true
+	for _, r := range text {
+		if r >= 0x80 {
+			ascii = false
+			break
+		}
+	}
+	if ascii {
+		return "(" + escape([]byte(text)) + ")"
+	}
+	var b strings.Builder
+	b.WriteString("<FEFF")
+	for _, unit := range utf16.Encode([]rune(text)) {
+		fmt.Fprintf(&b, "%04X", unit)
+	}
+	b.WriteByte('>')
+	return b.String()
+}
+
+// num formats a number compactly with at most two decimals
+func num(v float64) string {
+	s := fmt.Sprintf("%.2f", v)
+	s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	if s == "-0" || s == "" {
+		return "0"
+	}
+	return s
+}
@@ -0,0 +1,92 @@
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+func TestShape(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []rune
+	}{
+		{"latin", "Ánh", []rune("Ánh")},
+		{"line break", "a\nb", []rune("a b")},
+		{"hebrew reversed", "שלום", []rune("םולש")},
+		{"hebrew words", "שלום עולם", []rune("םלוע םולש")},
+		{"mixed", "ab שלום 12", []rune("ab םולש 12")},
+		// beh initial, beh final; reversed into visual order
+		{"arabic joining", "بب", []rune{0xfe90, 0xfe91}},
+		{"arabic isolated", "ا", []rune{0xfe8d}},
+		// lam alef ligature, isolated
+		{"lam alef", "لا", []rune{0xfefb}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runs := shape(tt.text)
+			got := make([]rune, len(runs))
+			for i, run := range runs {
+				got[i] = run.r
+			}
+			if string(got) != string(tt.want) {
+				t.Errorf("shape(%q) = %U, want %U", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEmbeddedFontText(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+	}{
+		{"vietnamese", "Nguyễn Thị Ánh"},
+		{"polish", "Łukasz Żółć"},
+		{"cyrillic", "Дмитрий"},
+		{"greek", "Αλέξανδρος"},
+		{"arabic", "محمد"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := New(A4Width, A4Height)
+			d.AddPage().Text(50, 50, Sans, 12, tt.text)
+			out := d.Bytes()
+
+			for _, want := range []string{"/Subtype /Type0", "/Subtype /CIDFontType2", "/FontFile2", "/ToUnicode"} {
+				if !bytes.Contains(out, []byte(want)) {
+					t.Errorf("document has no %s", want)
+				}
+			}
+			f := fontDefs[Sans].face
+			for _, run := range shape(tt.text) {
+				if glyphFor(f, run) == 0 {
+					t.Errorf("no glyph for %q", run.source)
+				}
+			}
+			use := d.fonts[Sans]
+			cmap := toUnicode(use.sortedGlyphs(), use.glyphs)
+			for _, r := range tt.text {
+				if r == ' ' || r >= 0x0600 {
+					continue
+				}
+				if !bytes.Contains(cmap, []byte(fmt.Sprintf("<%04X>\n", r))) {
+					t.Errorf("ToUnicode map has no entry for %q", r)
+				}
+			}
+		})
+	}
+}
+
+func TestWidthUsesFontMetrics(t *testing.T) {
+	if w := Width(Courier, 10, "abc"); w != 18 {
+		t.Errorf("Width(Courier) = %v, want 18", w)
+	}
+	if Width(Sans, 10, "Дмитрий") <= 0 {
+		t.Error("Width(Sans) of Cyrillic text is not positive")
+	}
+	if Width(SansBold, 10, "Dmitri") <= Width(Sans, 10, "Dmitri") {
+		t.Error("bold text is not wider than regular text")
+	}
+}
@@ -0,0 +1,177 @@
+package pdf
+
+import (
+	"unicode"
+)
+
+// glyphRun is a character as drawn, with the text it stands for so that
+// copying text out of the document gives the original characters
+type glyphRun struct {
+	r      rune
+	source []rune
+}
+
+// arabicForms maps the Arabic letters to their first presentation form:
+// the isolated form, followed by the final form for letters that join the
+// letter before them and the initial and medial forms for letters that
+// also join the letter after them
+var arabicForms = map[rune]struct {
+	first rune
+	forms int
+}{
+	0x0621: {0xfe80, 1}, 0x0622: {0xfe81, 2}, 0x0623: {0xfe83, 2}, 0x0624: {0xfe85, 2},
+	0x0625: {0xfe87, 2}, 0x0626: {0xfe89, 4}, 0x0627: {0xfe8d, 2}, 0x0628: {0xfe8f, 4},
+	0x0629: {0xfe93, 2}, 0x062a: {0xfe95, 4}, 0x062b: {0xfe99, 4}, 0x062c: {0xfe9d, 4},
+	0x062d: {0xfea1, 4}, 0x062e: {0xfea5, 4}, 0x062f: {0xfea9, 2}, 0x0630: {0xfeab, 2},
+	0x0631: {0xfead, 2}, 0x0632: {0xfeaf, 2}, 0x0633: {0xfeb1, 4}, 0x0634: {0xfeb5, 4},
+	0x0635: {0xfeb9, 4}, 0x0636: {0xfebd, 4}, 0x0637: {0xfec1, 4}, 0x0638: {0xfec5, 4},
+	0x0639: {0xfec9, 4}, 0x063a: {0xfecd, 4}, 0x0641: {0xfed1, 4}, 0x0642: {0xfed5, 4},
+	0x0643: {0xfed9, 4}, 0x0644: {0xfedd, 4}, 0x0645: {0xfee1, 4}, 0x0646: {0xfee5, 4},
+	0x0647: {0xfee9, 4}, 0x0648: {0xfeed, 2}, 0x0649: {0xfeef, 2}, 0x064a: {0xfef1, 4},
+}
+
+// lamAlef maps the alefs that form a mandatory ligature with a preceding
+// lam to the ligature's isolated form; its final form follows it
+var lamAlef = map[rune]rune{0x0622: 0xfef5, 0x0623: 0xfef7, 0x0625: 0xfef9, 0x0627: 0xfefb}
+
+const (
+	lam     = 0x0644
+	tatweel = 0x0640
+)
+
+// shape prepares text for drawing left to right: Arabic letters take the
+// form that joins them to their neighbours, and runs of right-to-left
+// script are reversed into visual order. Line breaks and tabs become spaces.
+func shape(text string) []glyphRun {
+	runes := []rune(text)
+	runs := make([]glyphRun, 0, len(runes))
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case r == '\t' || r == '\n' || r == '\r':
+			runs = append(runs, glyphRun{' ', []rune{r}})
+		case r == lam && lamAlef[nextLetter(runes, i)] != 0:
+			j := nextLetterIndex(runes, i)
+			ligature := lamAlef[runes[j]]
+			if joinsBefore(runes, i) {
+				ligature++
+			}
+			runs = append(runs, glyphRun{ligature, runes[i : j+1]})
+			// Marks between lam and alef are dropped with the alef
+			i = j
+		case arabicForms[r].forms > 0:
+			runs = append(runs, glyphRun{arabicForm(runes, i), []rune{r}})
+		default:
+			runs = append(runs, glyphRun{r, []rune{r}})
+		}
+	}
+	return visualOrder(runs)
+}
+
+// arabicForm returns the presentation form of the Arabic letter at i
+func arabicForm(runes []rune, i int) rune {
+	letter := arabicForms[runes[i]]
+	before := joinsBefore(runes, i)
+	after := letter.forms == 4 && canJoinBefore(nextLetter(runes, i))
+	switch {
+	case before && after:
+		return letter.first + 3
+	case after:
+		return letter.first + 2
+	case before && letter.forms >= 2:
+		return letter.first + 1
+	}
+	return letter.first
+}
+
+// joinsBefore reports whether the letter at i joins the letter before it
+func joinsBefore(runes []rune, i int) bool {
+	if !canJoinBefore(runes[i]) {
+		return false
+	}
+	for j := i - 1; j >= 0; j-- {
+		if unicode.Is(unicode.Mn, runes[j]) {
+			continue
+		}
+		return runes[j] == tatweel || arabicForms[runes[j]].forms == 4
+	}
+	return false
+}
+
+// canJoinBefore reports whether a letter can join the letter before it
+func canJoinBefore(r rune) bool {
+	return r == tatweel || arabicForms[r].forms >= 2
+}
+
+// nextLetterIndex returns the index of the next character after i that
+// is not a combining mark, or -1
+func nextLetterIndex(runes []rune, i int) int {
+	for j := i + 1; j < len(runes); j++ {
+		if !unicode.Is(unicode.Mn, runes[j]) {
+			return j
+		}
+	}
+	return -1
+}
+
+// nextLetter returns the next character after i that is not a combining
+// mark, or 0
+func nextLetter(runes []rune, i int) rune {
+	if j := nextLetterIndex(runes, i); j >= 0 {
+		return runes[j]
+	}
+	return 0
+}
+
+// isRTL reports whether a character belongs to a right-to-left script
+func isRTL(r rune) bool {
+	return unicode.In(r, unicode.Hebrew, unicode.Arabic) && !unicode.IsDigit(r)
+}
+
+// visualOrder reverses the runs of right-to-left characters, with the
+// spaces and marks inside them, keeping each combining mark after the
+// character it belongs to. The line itself reads left to right.
+func visualOrder(runs []glyphRun) []glyphRun {
+	for start := 0; start < len(runs); {
+		if !isRTL(runs[start].r) {
+			start++
+			continue
+		}
+		end := start + 1
+		for end < len(runs) {
+			r := runs[end].r
+			if isRTL(r) || unicode.Is(unicode.Mn, r) {
+				end++
+				continue
+			}
+			// A space stays in the run only when more of the script follows
+			next := end
+			for next < len(runs) && runs[next].r == ' ' {
+				next++
+			}
+			if r == ' ' && next < len(runs) && isRTL(runs[next].r) {
+				end = next
+				continue
+			}
+			break
+		}
+		reverseClusters(runs[start:end])
+		start = end
+	}
+	return runs
+}
+
+// reverseClusters reverses characters in place, moving combining marks
+// along with the character before them
+func reverseClusters(runs []glyphRun) {
+	reversed := make([]glyphRun, 0, len(runs))
+	for end := len(runs); end > 0; {
+		start := end - 1
+		for start > 0 && unicode.Is(unicode.Mn, runs[start].r) {
+			start--
+		}
+		reversed = append(reversed, runs[start:end]...)
+		end = start
+	}
+	copy(runs, reversed)
+}
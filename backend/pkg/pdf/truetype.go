@@ -0,0 +1,461 @@
+package pdf
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+	"unicode/utf16"
+)
+
+// face is a parsed TrueType font: what text layout needs, and the tables
+// an embedded subset is built from
+type face struct {
+	name        string // PostScript name
+	unitsPerEm  int
+	bbox        [4]int
+	ascent      int
+	descent     int
+	capHeight   int
+	italicAngle float64
+	stemV       int
+	advances    []int // advance width per glyph, in font units
+	cmap        map[rune]uint16
+	tables      map[string][]byte
+	loca        []uint32
+}
+
+// errNotTrueType is returned for font files without TrueType outlines
+var errNotTrueType = errors.New("not a TrueType font")
+
+// parseTrueType parses a TrueType font file
+func parseTrueType(data []byte) (*face, error) {
+	if len(data) < 12 || binary.BigEndian.Uint32(data) != 0x00010000 {
+		return nil, errNotTrueType
+	}
+	f := &face{tables: make(map[string][]byte)}
+	numTables := int(binary.BigEndian.Uint16(data[4:]))
+	if len(data) < 12+16*numTables {
+		return nil, errors.New("truncated table directory")
+	}
+	for i := 0; i < numTables; i++ {
+		entry := data[12+16*i:]
+		tag := string(entry[:4])
+		offset, length := binary.BigEndian.Uint32(entry[8:]), binary.BigEndian.Uint32(entry[12:])
+		if uint64(offset)+uint64(length) > uint64(len(data)) {
+			return nil, fmt.Errorf("table %q out of bounds", tag)
+		}
+		f.tables[tag] = data[offset : offset+length]
+	}
+	for _, tag := range []string{"head", "hhea", "maxp", "hmtx", "loca", "glyf", "cmap"} {
+		if f.tables[tag] == nil {
+			return nil, fmt.Errorf("missing %q table", tag)
+		}
+	}
+
+	head, hhea, maxp := f.tables["head"], f.tables["hhea"], f.tables["maxp"]
+	if len(head) < 54 || len(hhea) < 36 || len(maxp) < 6 {
+		return nil, errors.New("truncated header tables")
+	}
+	f.unitsPerEm = int(binary.BigEndian.Uint16(head[18:]))
+	if f.unitsPerEm == 0 {
+		return nil, errors.New("zero units per em")
+	}
+	for i := range f.bbox {
+		f.bbox[i] = int(int16(binary.BigEndian.Uint16(head[36+2*i:])))
+	}
+	f.ascent = int(int16(binary.BigEndian.Uint16(hhea[4:])))
+	f.descent = int(int16(binary.BigEndian.Uint16(hhea[6:])))
+	f.capHeight = f.ascent
+	f.stemV = 80
+	if os2 := f.tables["OS/2"]; len(os2) >= 6 {
+		weight := float64(binary.BigEndian.Uint16(os2[4:]))
+		f.stemV = int(50 + (weight/65)*(weight/65))
+		if version := binary.BigEndian.Uint16(os2); version >= 2 && len(os2) >= 90 {
+			f.capHeight = int(int16(binary.BigEndian.Uint16(os2[88:])))
+		}
+	}
+	if post := f.tables["post"]; len(post) >= 8 {
+		f.italicAngle = float64(int32(binary.BigEndian.Uint32(post[4:]))) / 65536
+	}
+
+	numGlyphs := int(binary.BigEndian.Uint16(maxp[4:]))
+	if err := f.parseMetrics(numGlyphs, int(binary.BigEndian.Uint16(hhea[34:]))); err != nil {
+		return nil, err
+	}
+	if err := f.parseLoca(numGlyphs, binary.BigEndian.Uint16(head[50:]) == 1); err != nil {
+		return nil, err
+	}
+	if err := f.parseCmap(); err != nil {
+		return nil, err
+	}
+	f.name = f.postScriptName()
+	return f, nil
+}
+
+// parseMetrics reads the advance width of every glyph
+func (f *face) parseMetrics(numGlyphs, numMetrics int) error {
+	hmtx := f.tables["hmtx"]
+	if numMetrics == 0 || len(hmtx) < 4*numMetrics {
+		return errors.New("truncated hmtx table")
+	}
+	f.advances = make([]int, numGlyphs)
+	for gid := range f.advances {
+		// Glyphs past the last metric share its advance
+		i := gid
+		if i >= numMetrics {
+			i = numMetrics - 1
+		}
+		f.advances[gid] = int(binary.BigEndian.Uint16(hmtx[4*i:]))
+	}
+	return nil
+}
+
+// parseLoca reads where each glyph's outline starts in the glyf table
+func (f *face) parseLoca(numGlyphs int, long bool) error {
+	loca, glyf := f.tables["loca"], f.tables["glyf"]
+	f.loca = make([]uint32, numGlyphs+1)
+	for i := range f.loca {
+		if long {
+			if len(loca) < 4*(i+1) {
+				return errors.New("truncated loca table")
+			}
+			f.loca[i] = binary.BigEndian.Uint32(loca[4*i:])
+		} else {
+			if len(loca) < 2*(i+1) {
+				return errors.New("truncated loca table")
+			}
+			f.loca[i] = 2 * uint32(binary.BigEndian.Uint16(loca[2*i:]))
+		}
+		if f.loca[i] > uint32(len(glyf)) || (i > 0 && f.loca[i] < f.loca[i-1]) {
+			return errors.New("invalid loca table")
+		}
+	}
+	return nil
+}
+
+// parseCmap reads the Unicode character map, preferring the full
+// repertoire subtable (format 12) over the basic plane one (format 4)
+func (f *face) parseCmap() error {
+	cmap := f.tables["cmap"]
+	if len(cmap) < 4 {
+		return errors.New("truncated cmap table")
+	}
+	var basic, full []byte
+	numSubtables := int(binary.BigEndian.Uint16(cmap[2:]))
+	for i := 0; i < numSubtables && len(cmap) >= 12+8*i; i++ {
+		entry := cmap[4+8*i:]
+		platform, encoding := binary.BigEndian.Uint16(entry), binary.BigEndian.Uint16(entry[2:])
+		offset := binary.BigEndian.Uint32(entry[4:])
+		if uint64(offset)+4 > uint64(len(cmap)) {
+			continue
+		}
+		subtable := cmap[offset:]
+		format := binary.BigEndian.Uint16(subtable)
+		unicode := platform == 0 || (platform == 3 && (encoding == 1 || encoding == 10))
+		switch {
+		case unicode && format == 12:
+			full = subtable
+		case unicode && format == 4:
+			basic = subtable
+		}
+	}
+
+	f.cmap = make(map[rune]uint16)
+	switch {
+	case full != nil:
+		return f.parseCmap12(full)
+	case basic != nil:
+		return f.parseCmap4(basic)
+	}
+	return errors.New("no Unicode cmap subtable")
+}
+
+func (f *face) parseCmap4(t []byte) error {
+	if len(t) < 14 {
+		return errors.New("truncated cmap subtable")
+	}
+	segments := int(binary.BigEndian.Uint16(t[6:])) / 2
+	ends := 14
+	starts := ends + 2*segments + 2
+	deltas := starts + 2*segments
+	rangeOffsets := deltas + 2*segments
+	if len(t) < rangeOffsets+2*segments {
+		return errors.New("truncated cmap subtable")
+	}
+	for i := 0; i < segments; i++ {
+		end := int(binary.BigEndian.Uint16(t[ends+2*i:]))
+		start := int(binary.BigEndian.Uint16(t[starts+2*i:]))
+		delta := int(binary.BigEndian.Uint16(t[deltas+2*i:]))
+		rangeOffset := int(binary.BigEndian.Uint16(t[rangeOffsets+2*i:]))
+		for c := start; c <= end && c != 0xffff; c++ {
+			gid := 0
+			if rangeOffset == 0 {
+				gid = (c + delta) & 0xffff
+			} else {
+				at := rangeOffsets + 2*i + rangeOffset + 2*(c-start)
+				if at+2 > len(t) {
+					continue
+				}
+				if gid = int(binary.BigEndian.Uint16(t[at:])); gid != 0 {
+					gid = (gid + delta) & 0xffff
+				}
+			}
+			if gid != 0 && gid < len(f.advances) {
+				f.cmap[rune(c)] = uint16(gid)
+			}
+		}
+	}
+	return nil
+}
+
+func (f *face) parseCmap12(t []byte) error {
+	if len(t) < 16 {
+		return errors.New("truncated cmap subtable")
+	}
+	groups := int(binary.BigEndian.Uint32(t[12:]))
+	if len(t) < 16+12*groups {
+		return errors.New("truncated cmap subtable")
+	}
+	for i := 0; i < groups; i++ {
+		group := t[16+12*i:]
+		start, end := binary.BigEndian.Uint32(group), binary.BigEndian.Uint32(group[4:])
+		gid := binary.BigEndian.Uint32(group[8:])
+		if end < start || end > 0x10ffff {
+			continue
+		}
+		for c := start; c <= end; c++ {
+			if g := gid + c - start; g != 0 && g < uint32(len(f.advances)) {
+				f.cmap[rune(c)] = uint16(g)
+			}
+		}
+	}
+	return nil
+}
+
+// postScriptName returns the font's PostScript name from its name table
+func (f *face) postScriptName() string {
+	name := f.tables["name"]
+	if len(name) < 6 {
+		return "Embedded"
+	}
+	count, storage := int(binary.BigEndian.Uint16(name[2:])), int(binary.BigEndian.Uint16(name[4:]))
+	for i := 0; i < count && len(name) >= 6+12*(i+1); i++ {
+		record := name[6+12*i:]
+		platform, nameID := binary.BigEndian.Uint16(record), binary.BigEndian.Uint16(record[6:])
+		length, offset := int(binary.BigEndian.Uint16(record[8:])), int(binary.BigEndian.Uint16(record[10:]))
+		if nameID != 6 || storage+offset+length > len(name) {
+			continue
+		}
+		raw := name[storage+offset : storage+offset+length]
+		switch platform {
+		case 1:
+			return string(raw)
+		case 0, 3:
+			units := make([]uint16, len(raw)/2)
+			for j := range units {
+				units[j] = binary.BigEndian.Uint16(raw[2*j:])
+			}
+			return string(utf16.Decode(units))
+		}
+	}
+	return "Embedded"
+}
+
+// glyph returns the glyph of a character, 0 (.notdef) when the font has none
+func (f *face) glyph(r rune) uint16 {
+	return f.cmap[r]
+}
+
+// has reports whether the font has a glyph for a character
+func (f *face) has(r rune) bool {
+	_, ok := f.cmap[r]
+	return ok
+}
+
+// advance returns a glyph's advance width in thousandths of the font size
+func (f *face) advance(gid uint16) int {
+	if int(gid) >= len(f.advances) {
+		return 0
+	}
+	return f.advances[gid] * 1000 / f.unitsPerEm
+}
+
+// scale converts font units to thousandths of the font size
+func (f *face) scale(v int) int {
+	return v * 1000 / f.unitsPerEm
+}
+
+// subset returns a font file with the outlines of the given glyphs and
+// of the glyphs they are composed of. Glyph IDs stay the same, so the
+// other glyphs remain in place with empty outlines.
+func (f *face) subset(gids []uint16) []byte {
+	keep := make(map[uint16]bool)
+	pending := append([]uint16{0}, gids...)
+	for len(pending) > 0 {
+		gid := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+		if keep[gid] || int(gid) >= len(f.loca)-1 {
+			continue
+		}
+		keep[gid] = true
+		pending = append(pending, f.components(gid)...)
+	}
+
+	glyf := f.tables["glyf"]
+	var newGlyf bytes.Buffer
+	newLoca := make([]byte, 4*len(f.loca))
+	for gid := 0; gid < len(f.loca)-1; gid++ {
+		binary.BigEndian.PutUint32(newLoca[4*gid:], uint32(newGlyf.Len()))
+		if keep[uint16(gid)] {
+			newGlyf.Write(glyf[f.loca[gid]:f.loca[gid+1]])
+			for newGlyf.Len()%4 != 0 {
+				newGlyf.WriteByte(0)
+			}
+		}
+	}
+	binary.BigEndian.PutUint32(newLoca[4*(len(f.loca)-1):], uint32(newGlyf.Len()))
+
+	head := append([]byte{}, f.tables["head"]...)
+	binary.BigEndian.PutUint32(head[8:], 0)  // checkSumAdjustment, set below
+	binary.BigEndian.PutUint16(head[50:], 1) // long loca offsets
+
+	tables := map[string][]byte{
+		"glyf": newGlyf.Bytes(),
+		"head": head,
+		"hhea": f.tables["hhea"],
+		"hmtx": f.tables["hmtx"],
+		"loca": newLoca,
+		"maxp": f.tables["maxp"],
+	}
+	// Hinting programs, which the outlines may refer to
+	for _, tag := range []string{"cvt ", "fpgm", "prep"} {
+		if data := f.tables[tag]; data != nil {
+			tables[tag] = data
+		}
+	}
+
+	font := writeSFNT(tables)
+	adjustment := 0xb1b0afba - checksum(font)
+	binary.BigEndian.PutUint32(font[headOffset(font):][8:], adjustment)
+	return font
+}
+
+// components returns the glyphs a composite glyph is built from
+func (f *face) components(gid uint16) []uint16 {
+	glyph := f.tables["glyf"][f.loca[gid]:f.loca[gid+1]]
+	if len(glyph) < 10 || int16(binary.BigEndian.Uint16(glyph)) >= 0 {
+		return nil
+	}
+	const (
+		argsAreWords   = 0x0001
+		haveScale      = 0x0008
+		moreComponents = 0x0020
+		haveXYScale    = 0x0040
+		haveTwoByTwo   = 0x0080
+	)
+	var parts []uint16
+	for at := 10; at+4 <= len(glyph); {
+		flags := binary.BigEndian.Uint16(glyph[at:])
+		parts = append(parts, binary.BigEndian.Uint16(glyph[at+2:]))
+		at += 4
+		if flags&argsAreWords != 0 {
+			at += 4
+		} else {
+			at += 2
+		}
+		switch {
+		case flags&haveScale != 0:
+			at += 2
+		case flags&haveXYScale != 0:
+			at += 4
+		case flags&haveTwoByTwo != 0:
+			at += 8
+		}
+		if flags&moreComponents == 0 {
+			break
+		}
+	}
+	return parts
+}
+
+// writeSFNT assembles a font file from its tables
+func writeSFNT(tables map[string][]byte) []byte {
+	tags := make([]string, 0, len(tables))
+	for tag := range tables {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+
+	n := len(tags)
+	searchRange, selector := 1, 0
+	for searchRange*2 <= n {
+		searchRange *= 2
+		selector++
+	}
+	var out bytes.Buffer
+	header := make([]byte, 12+16*n)
+	binary.BigEndian.PutUint32(header, 0x00010000)
+	binary.BigEndian.PutUint16(header[4:], uint16(n))
+	binary.BigEndian.PutUint16(header[6:], uint16(searchRange*16))
+	binary.BigEndian.PutUint16(header[8:], uint16(selector))
+	binary.BigEndian.PutUint16(header[10:], uint16(n*16-searchRange*16))
+
+	offset := len(header)
+	for i, tag := range tags {
+		data := tables[tag]
+		entry := header[12+16*i:]
+		copy(entry, tag)
+		binary.BigEndian.PutUint32(entry[4:], checksum(data))
+		binary.BigEndian.PutUint32(entry[8:], uint32(offset))
+		binary.BigEndian.PutUint32(entry[12:], uint32(len(data)))
+		offset += (len(data) + 3) &^ 3
+	}
+	out.Write(header)
+	for _, tag := range tags {
+		data := tables[tag]
+		out.Write(data)
+		out.Write(make([]byte, ((len(data)+3)&^3)-len(data)))
+	}
+	return out.Bytes()
+}
+
+// headOffset returns where the head table starts in a font file
+func headOffset(font []byte) int {
+	n := int(binary.BigEndian.Uint16(font[4:]))
+	for i := 0; i < n; i++ {
+		entry := font[12+16*i:]
+		if string(entry[:4]) == "head" {
+			return int(binary.BigEndian.Uint32(entry[8:]))
+		}
+	}
+	return 0
+}
+
+// checksum is the TrueType table checksum: the sum of big-endian words
+func checksum(data []byte) uint32 {
+	var sum uint32
+	for i := 0; i < len(data); i += 4 {
+		var word [4]byte
+		copy(word[:], data[i:])
+		sum += binary.BigEndian.Uint32(word[:])
+	}
+	return sum
+}
+
+// subsetTag returns the six letter prefix that marks a subset font's name,
+// derived from its glyphs so that equal documents stay byte for byte equal
+func subsetTag(gids []uint16) string {
+	h := sha1.New()
+	for _, gid := range gids {
+		binary.Write(h, binary.BigEndian, gid)
+	}
+	sum := h.Sum(nil)
+	tag := make([]byte, 6)
+	for i := range tag {
+		tag[i] = 'A' + sum[i]%26
+	}
+	return string(tag)
+}
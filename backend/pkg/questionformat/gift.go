@@ -0,0 +1,443 @@
+package questionformat
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// giftPointsComment carries an item's points through GIFT, which has no
+// notation for them; other tools ignore it as a comment
+const giftPointsComment = "// points:"
+
+// ReadGIFT parses Moodle GIFT text. Questions are separated by blank lines;
+// each is read independently so that one unsupported question does not
+// prevent importing the others. Supported are multiple choice, multiple
+// answers (weighted choices), true/false, numerical, short answer and
+// essay questions. Matching questions, descriptions and partial credit
+// other than for multiple answers are reported as errors.
+func ReadGIFT(data []byte) []Parsed {
+	text := string(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")))
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	text = strings.ReplaceAll(text, "\r", "\n")
+
+	var parsed []Parsed
+	var block []string
+	var points float64
+	flush := func() {
+		raw := strings.TrimSpace(strings.Join(block, "\n"))
+		if raw != "" {
+			item, err := parseGIFTQuestion(raw)
+			if item.Points == 0 {
+				item.Points = points
+			}
+			parsed = append(parsed, Parsed{Position: len(parsed) + 1, Item: item, Err: err})
+		}
+		block, points = nil, 0
+	}
+
+	for _, line := range strings.Split(text, "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(trimmed, giftPointsComment):
+			points, _ = strconv.ParseFloat(strings.TrimSpace(strings.TrimPrefix(trimmed, giftPointsComment)), 64)
+		case strings.HasPrefix(trimmed, "//"), strings.HasPrefix(trimmed, "$CATEGORY:"):
+		case trimmed == "" && !giftBraceOpen(block):
+			flush()
+		default:
+			block = append(block, line)
+		}
+	}
+	flush()
+	return parsed
+}
+
+// WriteGIFT writes items as GIFT text
+func WriteGIFT(w io.Writer, items []Item) error {
+	out := bufio.NewWriter(w)
+	for i, item := range items {
+		answers, err := giftAnswerBlock(&item)
+		if err != nil {
+			return fmt.Errorf("item %d: %w", i+1, err)
+		}
+
+		if i > 0 {
+			out.WriteString("\n")
+		}
+		if item.Identifier != "" {
+			fmt.Fprintf(out, "// question: %s\n", item.Identifier)
+		}
+		if item.Points > 0 {
+			fmt.Fprintf(out, "%s %s\n", giftPointsComment, formatNumber(item.Points))
+		}
+		title := item.Title
+		if title == "" {
+			title = item.Identifier
+		}
+		if title != "" {
+			fmt.Fprintf(out, "::%s::", escapeGIFT(title))
+		}
+		fmt.Fprintf(out, "%s %s\n", escapeGIFT(item.Text), answers)
+	}
+	return out.Flush()
+}
+
+func giftAnswerBlock(item *Item) (string, error) {
+	var b strings.Builder
+	switch item.Type {
+	case TypeMultipleChoice:
+		b.WriteString("{\n")
+		for _, option := range item.Options {
+			marker := "~"
+			if contains(item.Correct, option) {
+				marker = "="
+			}
+			fmt.Fprintf(&b, "\t%s%s\n", marker, escapeGIFT(option))
+		}
+		b.WriteString("}")
+	case TypeMultiSelect:
+		if len(item.Correct) == 0 {
+			return "", errors.New("no correct option")
+		}
+		// A wrong choice cancels a correct one, as in our own grading
+		share := formatNumber(100 / float64(len(item.Correct)))
+		b.WriteString("{\n")
+		for _, option := range item.Options {
+			weight := "-" + share
+			if contains(item.Correct, option) {
+				weight = share
+			}
+			fmt.Fprintf(&b, "\t~%%%s%%%s\n", weight, escapeGIFT(option))
+		}
+		b.WriteString("}")
+	case TypeTrueFalse:
+		if len(item.Correct) == 1 && item.Correct[0] == "true" {
+			b.WriteString("{TRUE}")
+		} else {
+			b.WriteString("{FALSE}")
+		}
+	case TypeNumeric:
+		b.WriteString("{#" + formatNumber(item.Value))
+		if item.Tolerance > 0 {
+			b.WriteString(":" + formatNumber(item.Tolerance))
+		}
+		b.WriteString("}")
+	case TypeShortAnswer:
+		b.WriteString("{\n")
+		for _, answer := range item.Correct {
+			fmt.Fprintf(&b, "\t=%s\n", escapeGIFT(answer))
+		}
+		b.WriteString("}")
+	case TypeEssay:
+		b.WriteString("{}")
+	default:
+		return "", fmt.Errorf("unsupported question type %q", item.Type)
+	}
+	return b.String(), nil
+}
+
+func parseGIFTQuestion(raw string) (Item, error) {
+	var item Item
+	rest := raw
+	if strings.HasPrefix(rest, "::") {
+		end := indexUnescaped(rest, "::", 2)
+		if end < 0 {
+			return item, errors.New("unterminated question title")
+		}
+		item.Title = collapseSpace(unescapeGIFT(rest[2:end]))
+		rest = rest[end+2:]
+	}
+	rest = stripFormatMarker(strings.TrimSpace(rest))
+
+	open := indexUnescaped(rest, "{", 0)
+	if open < 0 {
+		return item, errors.New("descriptions without answers are not supported")
+	}
+	end := indexUnescaped(rest, "}", open+1)
+	if end < 0 {
+		return item, errors.New("unterminated answer block")
+	}
+	before, answers, after := rest[:open], rest[open+1:end], rest[end+1:]
+	if indexUnescaped(after, "{", 0) >= 0 {
+		return item, errors.New("questions with several answer blocks are not supported")
+	}
+
+	item.Text = strings.TrimSpace(unescapeGIFT(before))
+	if strings.TrimSpace(after) != "" {
+		// Missing word format: the answer block stands for a gap in the text
+		item.Text = strings.TrimSpace(item.Text + " _____ " + strings.TrimSpace(unescapeGIFT(after)))
+	}
+	if item.Text == "" || item.Text == "_____" {
+		return item, errors.New("question text is missing")
+	}
+	return item, parseGIFTAnswers(&item, answers)
+}
+
+// giftAnswer is one answer of an answer block
+type giftAnswer struct {
+	marker byte // '=' or '~'
+	weight *float64
+	text   string
+}
+
+func parseGIFTAnswers(item *Item, block string) error {
+	if i := indexUnescaped(block, "####", 0); i >= 0 {
+		block = block[:i]
+	}
+	block = strings.TrimSpace(block)
+
+	if block == "" {
+		item.Type = TypeEssay
+		return nil
+	}
+	if strings.HasPrefix(block, "#") {
+		return parseGIFTNumeric(item, block[1:])
+	}
+	switch strings.ToUpper(strings.TrimSpace(stripGIFTFeedback(block))) {
+	case "T", "TRUE":
+		item.Type, item.Correct = TypeTrueFalse, []string{"true"}
+		return nil
+	case "F", "FALSE":
+		item.Type, item.Correct = TypeTrueFalse, []string{"false"}
+		return nil
+	}
+
+	answers, err := splitGIFTAnswers(block)
+	if err != nil {
+		return err
+	}
+
+	wrongChoices := false
+	for _, answer := range answers {
+		if answer.marker == '~' {
+			wrongChoices = true
+		}
+	}
+	if !wrongChoices {
+		item.Type = TypeShortAnswer
+		for _, answer := range answers {
+			if answer.weight != nil && *answer.weight != 100 {
+				return errors.New("partial credit for short answers is not supported")
+			}
+			item.Correct = append(item.Correct, answer.text)
+		}
+		return nil
+	}
+
+	equals, weighted := 0, false
+	for _, answer := range answers {
+		item.Options = append(item.Options, answer.text)
+		switch {
+		case answer.marker == '=':
+			equals++
+			item.Correct = append(item.Correct, answer.text)
+		case answer.weight != nil && *answer.weight > 0:
+			weighted = true
+			item.Correct = append(item.Correct, answer.text)
+		}
+	}
+	switch {
+	case len(item.Correct) == 0:
+		return errors.New("no correct answer")
+	case equals == 1 && !weighted:
+		item.Type = TypeMultipleChoice
+	case equals == 0 || !weighted:
+		item.Type = TypeMultiSelect
+	default:
+		return errors.New("partial credit for single answer questions is not supported")
+	}
+	return nil
+}
+
+func parseGIFTNumeric(item *Item, block string) error {
+	item.Type = TypeNumeric
+	block = strings.TrimSpace(block)
+	if !strings.HasPrefix(block, "=") {
+		value, tolerance, err := parseGIFTNumber(stripGIFTFeedback(block))
+		item.Value, item.Tolerance = value, tolerance
+		return err
+	}
+
+	answers, err := splitGIFTAnswers(block)
+	if err != nil {
+		return err
+	}
+	var full []giftAnswer
+	for _, answer := range answers {
+		if answer.weight == nil || *answer.weight == 100 {
+			full = append(full, answer)
+		}
+	}
+	if len(full) != 1 || len(answers) != 1 {
+		return errors.New("numerical questions with several answers or partial credit are not supported")
+	}
+	value, tolerance, err := parseGIFTNumber(full[0].text)
+	item.Value, item.Tolerance = value, tolerance
+	return err
+}
+
+// parseGIFTNumber reads "value", "value:tolerance" or "min..max"
+func parseGIFTNumber(text string) (float64, float64, error) {
+	text = strings.TrimSpace(text)
+	invalid := fmt.Errorf("invalid numerical answer %q", text)
+
+	if low, high, ok := strings.Cut(text, ".."); ok {
+		min, errMin := strconv.ParseFloat(strings.TrimSpace(low), 64)
+		max, errMax := strconv.ParseFloat(strings.TrimSpace(high), 64)
+		if errMin != nil || errMax != nil || max < min {
+			return 0, 0, invalid
+		}
+		return (min + max) / 2, (max - min) / 2, nil
+	}
+	valueText, toleranceText, hasTolerance := strings.Cut(text, ":")
+	value, err := strconv.ParseFloat(strings.TrimSpace(valueText), 64)
+	if err != nil {
+		return 0, 0, invalid
+	}
+	var tolerance float64
+	if hasTolerance {
+		tolerance, err = strconv.ParseFloat(strings.TrimSpace(toleranceText), 64)
+		if err != nil || tolerance < 0 {
+			return 0, 0, invalid
+		}
+	}
+	return value, tolerance, nil
+}
+
+// splitGIFTAnswers splits an answer block at its unescaped = and ~ markers
+func splitGIFTAnswers(block string) ([]giftAnswer, error) {
+	var starts []int
+	for i := 0; i < len(block); i++ {
+		switch block[i] {
+		case '\\':
+			i++
+		case '=', '~':
+			starts = append(starts, i)
+		}
+	}
+	if len(starts) == 0 || strings.TrimSpace(block[:starts[0]]) != "" {
+		return nil, errors.New("answers must start with = or ~")
+	}
+
+	answers := make([]giftAnswer, 0, len(starts))
+	for n, start := range starts {
+		end := len(block)
+		if n+1 < len(starts) {
+			end = starts[n+1]
+		}
+		answer := giftAnswer{marker: block[start]}
+		text := strings.TrimSpace(stripGIFTFeedback(block[start+1 : end]))
+
+		if strings.HasPrefix(text, "%") {
+			closing := strings.Index(text[1:], "%")
+			if closing < 0 {
+				return nil, fmt.Errorf("invalid answer weight in %q", text)
+			}
+			weight, err := strconv.ParseFloat(text[1:closing+1], 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid answer weight in %q", text)
+			}
+			answer.weight = &weight
+			text = strings.TrimSpace(text[closing+2:])
+		}
+		if indexUnescaped(text, "->", 0) >= 0 {
+			return nil, errors.New("matching questions are not supported")
+		}
+
+		answer.text = strings.TrimSpace(unescapeGIFT(text))
+		if answer.text == "" {
+			return nil, errors.New("empty answer")
+		}
+		answers = append(answers, answer)
+	}
+	return answers, nil
+}
+
+// stripGIFTFeedback removes the feedback that follows an unescaped #
+func stripGIFTFeedback(text string) string {
+	if i := indexUnescaped(text, "#", 0); i >= 0 {
+		return text[:i]
+	}
+	return text
+}
+
+// stripFormatMarker removes a leading text format such as [html]
+func stripFormatMarker(text string) string {
+	for _, marker := range []string{"[html]", "[moodle]", "[plain]", "[markdown]"} {
+		if strings.HasPrefix(strings.ToLower(text), marker) {
+			return strings.TrimSpace(text[len(marker):])
+		}
+	}
+	return text
+}
+
+// giftBraceOpen reports whether the lines leave an answer block open, in
+// which case a blank line does not end the question
+func giftBraceOpen(lines []string) bool {
+	text := strings.Join(lines, "\n")
+	depth := 0
+	for i := 0; i < len(text); i++ {
+		switch text[i] {
+		case '\\':
+			i++
+		case '{':
+			depth++
+		case '}':
+			depth--
+		}
+	}
+	return depth > 0
+}
+
+// indexUnescaped returns the index of the first occurrence of sub at or
+// after from that is not preceded by a backslash, or -1
+func indexUnescaped(text, sub string, from int) int {
+	for i := from; i < len(text); i++ {
+		if text[i] == '\\' {
+			i++
+			continue
+		}
+		if strings.HasPrefix(text[i:], sub) {
+			return i
+		}
+	}
+	return -1
+}
+
+func unescapeGIFT(text string) string {
+	var b strings.Builder
+	for i := 0; i < len(text); i++ {
+		if text[i] == '\\' && i+1 < len(text) {
+			i++
+			if text[i] == 'n' {
+				b.WriteByte('\n')
+			} else {
+				b.WriteByte(text[i])
+			}
+			continue
+		}
+		b.WriteByte(text[i])
+	}
+	return b.String()
+}
+
+func escapeGIFT(text string) string {
+	var b strings.Builder
+	for _, r := range text {
+		switch r {
+		case '~', '=', '#', '{', '}', ':', '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
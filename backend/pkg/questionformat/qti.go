@@ -0,0 +1,741 @@
+package questionformat
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	qtiNamespace      = "http://www.imsglobal.org/xsd/imsqti_v2p1"
+	qtiSchemaLocation = "http://www.imsglobal.org/xsd/imsqti_v2p1 http://www.imsglobal.org/xsd/qti/qtiv2p1/imsqti_v2p1.xsd"
+	imscpNamespace    = "http://www.imsglobal.org/xsd/imscp_v1p1"
+	qtiItemType       = "imsqti_item_xmlv2p1"
+	qtiMatchCorrect   = "http://www.imsglobal.org/question/qti_v2p1/rptemplates/match_correct"
+	qtiMapResponse    = "http://www.imsglobal.org/question/qti_v2p1/rptemplates/map_response"
+
+	// maxQTIFileSize bounds every file read from a package
+	maxQTIFileSize = 10 << 20
+)
+
+// ErrInvalidPackage is returned for uploads that are not a QTI zip package
+var ErrInvalidPackage = errors.New("invalid QTI package, expected a zip file")
+
+// ReadQTI reads the assessment items of an IMS QTI 2.1 content package.
+// Items are found through imsmanifest.xml, or by scanning the package when
+// it has no manifest. Items with choice, text entry or extended text
+// interactions are supported; any other interaction, or an item with
+// several interactions, is reported as an error for that item.
+func ReadQTI(data []byte) ([]Parsed, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, ErrInvalidPackage
+	}
+
+	files := make(map[string]*zip.File, len(archive.File))
+	for _, f := range archive.File {
+		files[path.Clean(f.Name)] = f
+	}
+
+	hrefs, err := qtiItemHrefs(files)
+	if err != nil {
+		return nil, err
+	}
+
+	parsed := make([]Parsed, 0, len(hrefs))
+	for i, href := range hrefs {
+		result := Parsed{Position: i + 1}
+		content, err := readZipFile(files[href])
+		if err != nil {
+			result.Err = fmt.Errorf("%s: %v", href, err)
+		} else {
+			result.Item, result.Err = parseQTIItem(content)
+		}
+		if result.Item.Identifier == "" {
+			result.Item.Identifier = href
+		}
+		parsed = append(parsed, result)
+	}
+	return parsed, nil
+}
+
+// WriteQTI writes items as an IMS QTI 2.1 content package with one
+// assessment item per question and a manifest listing them
+func WriteQTI(w io.Writer, items []Item) error {
+	archive := zip.NewWriter(w)
+	manifest := imsManifest{
+		Xmlns:      imscpNamespace,
+		Identifier: "MANIFEST-1",
+		Metadata:   imsMetadata{Schema: "QTIv2.1 Package", SchemaVersion: "1.0.0"},
+	}
+
+	used := make(map[string]bool, len(items))
+	for i, item := range items {
+		identifier := qtiIdentifier(item.Identifier, i+1)
+		for used[identifier] {
+			identifier += "_"
+		}
+		used[identifier] = true
+
+		assessmentItem, err := buildQTIItem(identifier, &item)
+		if err != nil {
+			return fmt.Errorf("item %d: %w", i+1, err)
+		}
+		href := "items/" + identifier + ".xml"
+		if err := writeZipXML(archive, href, assessmentItem); err != nil {
+			return err
+		}
+		manifest.Resources = append(manifest.Resources, imsResource{
+			Identifier: identifier,
+			Type:       qtiItemType,
+			Href:       href,
+			Files:      []imsFile{{Href: href}},
+		})
+	}
+
+	if err := writeZipXML(archive, "imsmanifest.xml", manifest); err != nil {
+		return err
+	}
+	return archive.Close()
+}
+
+// Manifest of a content package
+
+type imsManifest struct {
+	XMLName       xml.Name      `xml:"manifest"`
+	Xmlns         string        `xml:"xmlns,attr,omitempty"`
+	Identifier    string        `xml:"identifier,attr"`
+	Metadata      imsMetadata   `xml:"metadata"`
+	Organizations struct{}      `xml:"organizations"`
+	Resources     []imsResource `xml:"resources>resource"`
+}
+
+type imsMetadata struct {
+	Schema        string `xml:"schema"`
+	SchemaVersion string `xml:"schemaversion"`
+}
+
+type imsResource struct {
+	Identifier string    `xml:"identifier,attr"`
+	Type       string    `xml:"type,attr"`
+	Href       string    `xml:"href,attr"`
+	Files      []imsFile `xml:"file"`
+}
+
+type imsFile struct {
+	Href string `xml:"href,attr"`
+}
+
+// Assessment items
+
+type qtiAssessmentItem struct {
+	XMLName        xml.Name                 `xml:"assessmentItem"`
+	Xmlns          string                   `xml:"xmlns,attr,omitempty"`
+	Xsi            string                   `xml:"xmlns:xsi,attr,omitempty"`
+	SchemaLocation string                   `xml:"xsi:schemaLocation,attr,omitempty"`
+	Identifier     string                   `xml:"identifier,attr"`
+	Title          string                   `xml:"title,attr"`
+	Adaptive       bool                     `xml:"adaptive,attr"`
+	TimeDependent  bool                     `xml:"timeDependent,attr"`
+	Responses      []qtiResponseDeclaration `xml:"responseDeclaration"`
+	Outcomes       []qtiOutcomeDeclaration  `xml:"outcomeDeclaration"`
+	Body           qtiInner                 `xml:"itemBody"`
+	Processing     *qtiResponseProcessing   `xml:"responseProcessing"`
+}
+
+type qtiResponseDeclaration struct {
+	Identifier  string      `xml:"identifier,attr"`
+	Cardinality string      `xml:"cardinality,attr"`
+	BaseType    string      `xml:"baseType,attr"`
+	Correct     *qtiValues  `xml:"correctResponse"`
+	Mapping     *qtiMapping `xml:"mapping"`
+}
+
+type qtiOutcomeDeclaration struct {
+	Identifier    string     `xml:"identifier,attr"`
+	Cardinality   string     `xml:"cardinality,attr"`
+	BaseType      string     `xml:"baseType,attr"`
+	NormalMaximum *float64   `xml:"normalMaximum,attr,omitempty"`
+	Default       *qtiValues `xml:"defaultValue"`
+}
+
+type qtiValues struct {
+	Values []string `xml:"value"`
+}
+
+type qtiMapping struct {
+	LowerBound   *float64      `xml:"lowerBound,attr,omitempty"`
+	UpperBound   *float64      `xml:"upperBound,attr,omitempty"`
+	DefaultValue float64       `xml:"defaultValue,attr"`
+	Entries      []qtiMapEntry `xml:"mapEntry"`
+}
+
+type qtiMapEntry struct {
+	MapKey        string  `xml:"mapKey,attr"`
+	MappedValue   float64 `xml:"mappedValue,attr"`
+	CaseSensitive *bool   `xml:"caseSensitive,attr,omitempty"`
+}
+
+type qtiResponseProcessing struct {
+	Template string `xml:"template,attr,omitempty"`
+	Inner    string `xml:",innerxml"`
+}
+
+// qtiInner keeps an element's content as raw XML
+type qtiInner struct {
+	Inner string `xml:",innerxml"`
+}
+
+type qtiChoiceInteraction struct {
+	ResponseIdentifier string      `xml:"responseIdentifier,attr"`
+	Shuffle            bool        `xml:"shuffle,attr"`
+	MaxChoices         int         `xml:"maxChoices,attr"`
+	Prompt             *qtiInner   `xml:"prompt"`
+	Choices            []qtiChoice `xml:"simpleChoice"`
+}
+
+type qtiChoice struct {
+	Identifier string `xml:"identifier,attr"`
+	Inner      string `xml:",innerxml"`
+}
+
+type qtiTextEntryInteraction struct {
+	ResponseIdentifier string `xml:"responseIdentifier,attr"`
+	ExpectedLength     int    `xml:"expectedLength,attr,omitempty"`
+}
+
+type qtiExtendedTextInteraction struct {
+	ResponseIdentifier string    `xml:"responseIdentifier,attr"`
+	Prompt             *qtiInner `xml:"prompt"`
+}
+
+// qtiItemHrefs lists the item files of a package in manifest order
+func qtiItemHrefs(files map[string]*zip.File) ([]string, error) {
+	if manifestFile, ok := files["imsmanifest.xml"]; ok {
+		content, err := readZipFile(manifestFile)
+		if err != nil {
+			return nil, err
+		}
+		var manifest imsManifest
+		if err := xml.Unmarshal(content, &manifest); err != nil {
+			return nil, fmt.Errorf("invalid imsmanifest.xml: %v", err)
+		}
+
+		var hrefs []string
+		for _, resource := range manifest.Resources {
+			if !strings.HasPrefix(resource.Type, qtiItemType) {
+				continue
+			}
+			// A missing file is reported for its item by ReadQTI
+			hrefs = append(hrefs, path.Clean(resource.Href))
+		}
+		return hrefs, nil
+	}
+
+	// Without a manifest every XML file holding an assessment item is read
+	var hrefs []string
+	for name, f := range files {
+		if strings.HasSuffix(strings.ToLower(name), ".xml") && !f.FileInfo().IsDir() {
+			content, err := readZipFile(f)
+			if err == nil && bytes.Contains(content, []byte("assessmentItem")) {
+				hrefs = append(hrefs, name)
+			}
+		}
+	}
+	sort.Strings(hrefs)
+	return hrefs, nil
+}
+
+func parseQTIItem(content []byte) (Item, error) {
+	var source qtiAssessmentItem
+	if err := xml.Unmarshal(content, &source); err != nil {
+		return Item{}, fmt.Errorf("invalid assessment item: %v", err)
+	}
+	item := Item{
+		Identifier: source.Identifier,
+		Title:      collapseSpace(source.Title),
+		Points:     qtiPoints(&source),
+	}
+
+	body, err := walkQTIBody(source.Body.Inner)
+	if err != nil {
+		return item, err
+	}
+	if len(body.interactions) == 0 {
+		return item, errors.New("the item has no interaction")
+	}
+	if len(body.interactions) > 1 {
+		return item, errors.New("items with several interactions are not supported")
+	}
+	if body.unsupported != "" {
+		return item, fmt.Errorf("%s is not supported", body.unsupported)
+	}
+
+	var response *qtiResponseDeclaration
+	interaction := body.interactions[0]
+	for i := range source.Responses {
+		if source.Responses[i].Identifier == interaction.responseIdentifier {
+			response = &source.Responses[i]
+		}
+	}
+	if response == nil {
+		return item, fmt.Errorf("no response declaration for %s", interaction.responseIdentifier)
+	}
+
+	// A text entry inside the text stands for a gap; at its end it is just the answer field
+	text := strings.Join(append(body.text, interaction.prompt), " ")
+	if before, after, ok := strings.Cut(text, qtiGap); ok && strings.TrimSpace(after) == "" {
+		text = before
+	}
+	item.Text = collapseSpace(strings.ReplaceAll(text, qtiGap, "_____"))
+	if item.Text == "" {
+		return item, errors.New("question text is missing")
+	}
+
+	switch {
+	case interaction.choice != nil:
+		err = qtiChoiceItem(&item, interaction.choice, response)
+	case interaction.textEntry:
+		err = qtiTextEntryItem(&item, response, source.Processing)
+	default:
+		item.Type = TypeEssay
+	}
+	return item, err
+}
+
+func qtiChoiceItem(item *Item, interaction *qtiChoiceInteraction, response *qtiResponseDeclaration) error {
+	texts := make(map[string]string, len(interaction.Choices))
+	for _, choice := range interaction.Choices {
+		text := collapseSpace(xmlText(choice.Inner))
+		if text == "" {
+			return fmt.Errorf("choice %s has no text", choice.Identifier)
+		}
+		texts[choice.Identifier] = text
+		item.Options = append(item.Options, text)
+	}
+
+	var correctIDs []string
+	if response.Correct != nil {
+		correctIDs = response.Correct.Values
+	} else if response.Mapping != nil {
+		for _, entry := range response.Mapping.Entries {
+			if entry.MappedValue > 0 {
+				correctIDs = append(correctIDs, entry.MapKey)
+			}
+		}
+	}
+	for _, id := range correctIDs {
+		text, ok := texts[strings.TrimSpace(id)]
+		if !ok {
+			return fmt.Errorf("correct response %s is not a choice", id)
+		}
+		item.Correct = append(item.Correct, text)
+	}
+	if len(item.Correct) == 0 {
+		return errors.New("no correct response")
+	}
+
+	if interaction.MaxChoices == 1 && response.Cardinality != "multiple" {
+		if len(item.Correct) != 1 {
+			return errors.New("single choice items need exactly one correct response")
+		}
+		if isTrueFalse(item.Options) {
+			item.Type = TypeTrueFalse
+			item.Correct = []string{strings.ToLower(item.Correct[0])}
+			item.Options = nil
+			return nil
+		}
+		item.Type = TypeMultipleChoice
+		return nil
+	}
+	item.Type = TypeMultiSelect
+	return nil
+}
+
+func qtiTextEntryItem(item *Item, response *qtiResponseDeclaration, processing *qtiResponseProcessing) error {
+	var accepted []string
+	if response.Correct != nil {
+		accepted = append(accepted, response.Correct.Values...)
+	}
+	if response.Mapping != nil {
+		for _, entry := range response.Mapping.Entries {
+			if entry.MappedValue > 0 && !contains(accepted, entry.MapKey) {
+				accepted = append(accepted, entry.MapKey)
+			}
+			if entry.CaseSensitive != nil && *entry.CaseSensitive {
+				item.CaseSensitive = true
+			}
+		}
+	}
+	if len(accepted) == 0 {
+		return errors.New("no correct response")
+	}
+
+	switch response.BaseType {
+	case "float", "integer":
+		item.Type = TypeNumeric
+		value, err := strconv.ParseFloat(strings.TrimSpace(accepted[0]), 64)
+		if err != nil {
+			return fmt.Errorf("invalid numerical response %q", accepted[0])
+		}
+		item.Value = value
+		if processing != nil {
+			item.Tolerance = qtiTolerance(processing.Inner, value)
+		}
+	case "string":
+		item.Type = TypeShortAnswer
+		for _, answer := range accepted {
+			if answer = strings.TrimSpace(answer); answer != "" {
+				item.Correct = append(item.Correct, answer)
+			}
+		}
+	default:
+		return fmt.Errorf("text entry with base type %s is not supported", response.BaseType)
+	}
+	return nil
+}
+
+// qtiPoints reads an item's maximum score
+func qtiPoints(source *qtiAssessmentItem) float64 {
+	for _, outcome := range source.Outcomes {
+		if outcome.Identifier == "MAXSCORE" && outcome.Default != nil && len(outcome.Default.Values) > 0 {
+			if points, err := strconv.ParseFloat(strings.TrimSpace(outcome.Default.Values[0]), 64); err == nil {
+				return points
+			}
+		}
+	}
+	for _, outcome := range source.Outcomes {
+		if outcome.Identifier == "SCORE" && outcome.NormalMaximum != nil {
+			return *outcome.NormalMaximum
+		}
+	}
+	return 0
+}
+
+// qtiTolerance reads the tolerance of an equal operator in custom response
+// processing as an absolute deviation
+func qtiTolerance(processing string, value float64) float64 {
+	decoder := xml.NewDecoder(strings.NewReader(processing))
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return 0
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok || start.Name.Local != "equal" {
+			continue
+		}
+
+		mode, tolerance := "exact", ""
+		for _, attr := range start.Attr {
+			switch attr.Name.Local {
+			case "toleranceMode":
+				mode = attr.Value
+			case "tolerance":
+				tolerance = attr.Value
+			}
+		}
+		fields := strings.Fields(tolerance)
+		if mode == "exact" || len(fields) == 0 {
+			return 0
+		}
+		t, err := strconv.ParseFloat(fields[0], 64)
+		if err != nil {
+			return 0
+		}
+		if mode == "relative" {
+			return math.Abs(value) * t / 100
+		}
+		return t
+	}
+}
+
+// qtiGap marks the position of a text entry interaction in an item's text
+const qtiGap = "\x00"
+
+// qtiBody is what an item body holds
+type qtiBody struct {
+	text         []string
+	interactions []qtiInteraction
+	unsupported  string
+}
+
+type qtiInteraction struct {
+	responseIdentifier string
+	prompt             string
+	choice             *qtiChoiceInteraction
+	textEntry          bool
+}
+
+// walkQTIBody collects the text of an item body and decodes its interactions
+func walkQTIBody(inner string) (qtiBody, error) {
+	var body qtiBody
+	decoder := xml.NewDecoder(strings.NewReader("<itemBody>" + inner + "</itemBody>"))
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return body, nil
+		}
+		if err != nil {
+			return body, fmt.Errorf("invalid item body: %v", err)
+		}
+
+		switch t := token.(type) {
+		case xml.CharData:
+			body.text = append(body.text, string(t))
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "choiceInteraction":
+				var choice qtiChoiceInteraction
+				if err := decoder.DecodeElement(&choice, &t); err != nil {
+					return body, fmt.Errorf("invalid choice interaction: %v", err)
+				}
+				interaction := qtiInteraction{responseIdentifier: choice.ResponseIdentifier, choice: &choice}
+				if choice.Prompt != nil {
+					interaction.prompt = xmlText(choice.Prompt.Inner)
+				}
+				body.interactions = append(body.interactions, interaction)
+			case "textEntryInteraction":
+				var entry qtiTextEntryInteraction
+				if err := decoder.DecodeElement(&entry, &t); err != nil {
+					return body, fmt.Errorf("invalid text entry interaction: %v", err)
+				}
+				body.text = append(body.text, qtiGap)
+				body.interactions = append(body.interactions, qtiInteraction{responseIdentifier: entry.ResponseIdentifier, textEntry: true})
+			case "extendedTextInteraction":
+				var extended qtiExtendedTextInteraction
+				if err := decoder.DecodeElement(&extended, &t); err != nil {
+					return body, fmt.Errorf("invalid extended text interaction: %v", err)
+				}
+				interaction := qtiInteraction{responseIdentifier: extended.ResponseIdentifier}
+				if extended.Prompt != nil {
+					interaction.prompt = xmlText(extended.Prompt.Inner)
+				}
+				body.interactions = append(body.interactions, interaction)
+			default:
+				if strings.HasSuffix(t.Name.Local, "Interaction") {
+					if err := decoder.Skip(); err != nil {
+						return body, fmt.Errorf("invalid item body: %v", err)
+					}
+					body.interactions = append(body.interactions, qtiInteraction{})
+					if body.unsupported == "" {
+						body.unsupported = t.Name.Local
+					}
+				}
+			}
+		}
+	}
+}
+
+// xmlText returns the text content of an XML fragment
+func xmlText(fragment string) string {
+	decoder := xml.NewDecoder(strings.NewReader("<t>" + fragment + "</t>"))
+	var b strings.Builder
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return b.String()
+		}
+		switch t := token.(type) {
+		case xml.CharData:
+			b.Write(t)
+		case xml.StartElement:
+			// Keep words of adjacent block elements apart
+			b.WriteByte(' ')
+		}
+	}
+}
+
+func isTrueFalse(options []string) bool {
+	if len(options) != 2 {
+		return false
+	}
+	first, second := strings.ToLower(options[0]), strings.ToLower(options[1])
+	return (first == "true" && second == "false") || (first == "false" && second == "true")
+}
+
+// buildQTIItem converts an item to a QTI assessment item
+func buildQTIItem(identifier string, item *Item) (*qtiAssessmentItem, error) {
+	points := item.Points
+	assessmentItem := &qtiAssessmentItem{
+		Xmlns:          qtiNamespace,
+		Xsi:            "http://www.w3.org/2001/XMLSchema-instance",
+		SchemaLocation: qtiSchemaLocation,
+		Identifier:     identifier,
+		Title:          item.Title,
+		Outcomes: []qtiOutcomeDeclaration{
+			{Identifier: "SCORE", Cardinality: "single", BaseType: "float", NormalMaximum: &points},
+			{Identifier: "MAXSCORE", Cardinality: "single", BaseType: "float", Default: &qtiValues{Values: []string{formatNumber(points)}}},
+		},
+	}
+	if assessmentItem.Title == "" {
+		assessmentItem.Title = identifier
+	}
+
+	response := qtiResponseDeclaration{Identifier: "RESPONSE", Cardinality: "single"}
+	var interaction interface{}
+	prompt := "<p>" + xmlEscape(item.Text) + "</p>"
+
+	switch item.Type {
+	case TypeMultipleChoice, TypeMultiSelect, TypeTrueFalse:
+		options, correct := item.Options, item.Correct
+		if item.Type == TypeTrueFalse {
+			options = []string{"True", "False"}
+			correct = []string{"False"}
+			if len(item.Correct) == 1 && item.Correct[0] == "true" {
+				correct = []string{"True"}
+			}
+		}
+		if len(correct) == 0 {
+			return nil, errors.New("no correct option")
+		}
+
+		choice := qtiChoiceInteraction{ResponseIdentifier: "RESPONSE", MaxChoices: 1}
+		response.BaseType = "identifier"
+		response.Correct = &qtiValues{}
+		var entries []qtiMapEntry
+		share := points / float64(len(correct))
+		for i, option := range options {
+			id := fmt.Sprintf("choice%d", i+1)
+			choice.Choices = append(choice.Choices, qtiChoice{Identifier: id, Inner: xmlEscape(option)})
+			mapped := -share
+			if contains(correct, option) {
+				response.Correct.Values = append(response.Correct.Values, id)
+				mapped = share
+			}
+			entries = append(entries, qtiMapEntry{MapKey: id, MappedValue: mapped})
+		}
+
+		assessmentItem.Processing = &qtiResponseProcessing{Template: qtiMatchCorrect}
+		if item.Type == TypeMultiSelect {
+			// Partial credit as in our own grading: a wrong choice cancels a correct one
+			lower := 0.0
+			choice.MaxChoices = 0
+			response.Cardinality = "multiple"
+			response.Mapping = &qtiMapping{LowerBound: &lower, UpperBound: &points, Entries: entries}
+			assessmentItem.Processing = &qtiResponseProcessing{Template: qtiMapResponse}
+		}
+		interaction = choice
+	case TypeNumeric:
+		response.BaseType = "float"
+		response.Correct = &qtiValues{Values: []string{formatNumber(item.Value)}}
+		assessmentItem.Processing = &qtiResponseProcessing{Template: qtiMatchCorrect}
+		if item.Tolerance > 0 {
+			assessmentItem.Processing = &qtiResponseProcessing{Inner: qtiToleranceProcessing(item.Tolerance)}
+		}
+		prompt = "<p>" + xmlEscape(item.Text) + " " + `<textEntryInteraction responseIdentifier="RESPONSE"/></p>`
+	case TypeShortAnswer:
+		if len(item.Correct) == 0 {
+			return nil, errors.New("no accepted answer")
+		}
+		caseSensitive := item.CaseSensitive
+		response.BaseType = "string"
+		response.Correct = &qtiValues{Values: []string{item.Correct[0]}}
+		response.Mapping = &qtiMapping{}
+		for _, answer := range item.Correct {
+			response.Mapping.Entries = append(response.Mapping.Entries, qtiMapEntry{MapKey: answer, MappedValue: points, CaseSensitive: &caseSensitive})
+		}
+		response.Mapping.UpperBound = &points
+		assessmentItem.Processing = &qtiResponseProcessing{Template: qtiMapResponse}
+		prompt = "<p>" + xmlEscape(item.Text) + " " + `<textEntryInteraction responseIdentifier="RESPONSE"/></p>`
+	case TypeEssay:
+		response.BaseType = "string"
+		interaction = qtiExtendedTextInteraction{ResponseIdentifier: "RESPONSE"}
+	default:
+		return nil, fmt.Errorf("unsupported question type %q", item.Type)
+	}
+	assessmentItem.Responses = []qtiResponseDeclaration{response}
+
+	body := prompt
+	if interaction != nil {
+		var buf bytes.Buffer
+		name := "choiceInteraction"
+		if _, ok := interaction.(qtiExtendedTextInteraction); ok {
+			name = "extendedTextInteraction"
+		}
+		encoder := xml.NewEncoder(&buf)
+		if err := encoder.EncodeElement(interaction, xml.StartElement{Name: xml.Name{Local: name}}); err != nil {
+			return nil, err
+		}
+		body += buf.String()
+	}
+	assessmentItem.Body = qtiInner{Inner: body}
+	return assessmentItem, nil
+}
+
+// qtiToleranceProcessing awards MAXSCORE to a numeric response within an
+// absolute tolerance of the correct response
+func qtiToleranceProcessing(tolerance float64) string {
+	t := formatNumber(tolerance)
+	return `<responseCondition><responseIf>` +
+		`<equal toleranceMode="absolute" tolerance="` + t + ` ` + t + `"><variable identifier="RESPONSE"/><correct identifier="RESPONSE"/></equal>` +
+		`<setOutcomeValue identifier="SCORE"><variable identifier="MAXSCORE"/></setOutcomeValue>` +
+		`</responseIf><responseElse>` +
+		`<setOutcomeValue identifier="SCORE"><baseValue baseType="float">0</baseValue></setOutcomeValue>` +
+		`</responseElse></responseCondition>`
+}
+
+// qtiIdentifier makes an identifier valid as an XML name and file name
+func qtiIdentifier(identifier string, position int) string {
+	var b strings.Builder
+	for _, r := range identifier {
+		if r == '-' || r == '_' || r == '.' || (r >= '0' && r <= '9') || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') {
+			b.WriteRune(r)
+		}
+	}
+	id := b.String()
+	if id == "" || !(id[0] == '_' || (id[0] >= 'a' && id[0] <= 'z') || (id[0] >= 'A' && id[0] <= 'Z')) {
+		id = fmt.Sprintf("item%d%s", position, id)
+	}
+	return id
+}
+
+func xmlEscape(text string) string {
+	var buf bytes.Buffer
+	xml.EscapeText(&buf, []byte(text))
+	return buf.String()
+}
+
+func writeZipXML(archive *zip.Writer, name string, v interface{}) error {
+	f, err := archive.Create(name)
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(f, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(f)
+	encoder.Indent("", "  ")
+	return encoder.Encode(v)
+}
+
+func readZipFile(f *zip.File) ([]byte, error) {
+	if f == nil {
+		return nil, errors.New("missing file")
+	}
+	if f.UncompressedSize64 > maxQTIFileSize {
+		return nil, fmt.Errorf("%s is too large", f.Name)
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	content, err := io.ReadAll(io.LimitReader(rc, maxQTIFileSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(content) > maxQTIFileSize {
+		return nil, fmt.Errorf("%s is too large", f.Name)
+	}
+	return content, nil
+}
@@ -0,0 +1,67 @@
+// Package questionformat reads and writes exam questions in interchange
+// formats: IMS QTI 2.1 content packages and Moodle GIFT text
+package questionformat
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Question types, matching the exam question types of the API
+const (
+	TypeMultipleChoice = "multiple_choice"
+	TypeMultiSelect    = "multi_select"
+	TypeTrueFalse      = "true_false"
+	TypeNumeric        = "numeric"
+	TypeShortAnswer    = "short_answer"
+	TypeEssay          = "essay"
+)
+
+// Item is a question in a format-neutral form
+type Item struct {
+	Identifier string
+	Title      string
+	Type       string
+	Text       string
+	// Options are the choices of multiple_choice and multi_select questions
+	Options []string
+	// Correct holds the correct options, the accepted short answers, or
+	// "true" or "false" for true_false questions
+	Correct []string
+	// Value and Tolerance are the answer of numeric questions and the
+	// absolute deviation accepted from it
+	Value     float64
+	Tolerance float64
+	// CaseSensitive marks short answers that must match case
+	CaseSensitive bool
+	// Points is zero when the file does not give the item's points
+	Points float64
+}
+
+// Parsed is an item read from a file, or the reason it could not be read
+type Parsed struct {
+	Position int // 1-based position of the item in the file
+	Item     Item
+	Err      error
+}
+
+// collapseSpace trims text and collapses runs of whitespace to single spaces
+func collapseSpace(text string) string {
+	return strings.Join(strings.Fields(text), " ")
+}
+
+// formatNumber writes a number without exponent or trailing zeros
+func formatNumber(value float64) string {
+	return strconv.FormatFloat(math.Round(value*1e6)/1e6, 'f', -1, 64)
+}
+
+// contains reports whether values holds value
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
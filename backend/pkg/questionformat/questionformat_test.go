@@ -0,0 +1,252 @@
+package questionformat
+
+import (
+	"archive/zip"
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// sampleItems has one item of every type, with text that needs escaping
+// in both formats
+var sampleItems = []Item{
+	{
+		Identifier: "q1", Title: "Capitals", Type: TypeMultipleChoice,
+		Text:    "Which city is the capital of France? {pick one}",
+		Options: []string{"Paris", "Lyon", "Marseille = 2nd"},
+		Correct: []string{"Paris"}, Points: 2,
+	},
+	{
+		Identifier: "q2", Title: "Primes", Type: TypeMultiSelect,
+		Text:    "Which numbers are prime? Note: 1 is not",
+		Options: []string{"2", "3", "4", "5"},
+		Correct: []string{"2", "3", "5"}, Points: 3,
+	},
+	{
+		Identifier: "q3", Title: "Water", Type: TypeTrueFalse,
+		Text: "Water boils at 100 °C at sea level & 1 atm", Correct: []string{"true"}, Points: 1,
+	},
+	{
+		Identifier: "q4", Title: "Gravity", Type: TypeNumeric,
+		Text: "Acceleration due to gravity in m/s²", Value: 9.81, Tolerance: 0.05, Points: 1.5,
+	},
+	{
+		Identifier: "q5", Title: "Answer", Type: TypeNumeric,
+		Text: "What is 6 × 7 < 50?", Value: 42, Points: 1,
+	},
+	{
+		Identifier: "q6", Title: "Colour", Type: TypeShortAnswer,
+		Text: "Spell the colour of the sky #1", Correct: []string{"blue", "Blue sky"}, Points: 1,
+	},
+	{
+		Identifier: "q7", Title: "Essay", Type: TypeEssay,
+		Text: "Discuss the causes of the French Revolution", Points: 10,
+	},
+}
+
+func TestGIFTRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteGIFT(&buf, sampleItems); err != nil {
+		t.Fatal(err)
+	}
+	parsed := ReadGIFT(buf.Bytes())
+	if len(parsed) != len(sampleItems) {
+		t.Fatalf("read %d items, want %d:\n%s", len(parsed), len(sampleItems), buf.String())
+	}
+	for i, want := range sampleItems {
+		t.Run(want.Type+"/"+want.Identifier, func(t *testing.T) {
+			got := parsed[i]
+			if got.Err != nil {
+				t.Fatalf("item %d: %v", got.Position, got.Err)
+			}
+			// GIFT keeps identifiers only in comments
+			want.Identifier = ""
+			if !reflect.DeepEqual(got.Item, want) {
+				t.Errorf("round trip gave\n%+v\nwant\n%+v", got.Item, want)
+			}
+		})
+	}
+}
+
+func TestQTIRoundTrip(t *testing.T) {
+	items := append([]Item(nil), sampleItems...)
+	items[5].CaseSensitive = true
+
+	var buf bytes.Buffer
+	if err := WriteQTI(&buf, items); err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := ReadQTI(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(parsed) != len(items) {
+		t.Fatalf("read %d items, want %d", len(parsed), len(items))
+	}
+	for i, want := range items {
+		t.Run(want.Type+"/"+want.Identifier, func(t *testing.T) {
+			got := parsed[i]
+			if got.Err != nil {
+				t.Fatalf("item %d: %v", got.Position, got.Err)
+			}
+			if !reflect.DeepEqual(got.Item, want) {
+				t.Errorf("round trip gave\n%+v\nwant\n%+v", got.Item, want)
+			}
+		})
+	}
+}
+
+func TestQTIIdentifiers(t *testing.T) {
+	items := []Item{
+		{Identifier: "7 wonders", Type: TypeEssay, Text: "One"},
+		{Identifier: "7 wonders", Type: TypeEssay, Text: "Two"},
+		{Type: TypeEssay, Text: "Three"},
+	}
+	var buf bytes.Buffer
+	if err := WriteQTI(&buf, items); err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := ReadQTI(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	seen := make(map[string]bool)
+	for _, p := range parsed {
+		if p.Err != nil {
+			t.Fatal(p.Err)
+		}
+		if seen[p.Item.Identifier] {
+			t.Errorf("identifier %q used twice", p.Item.Identifier)
+		}
+		seen[p.Item.Identifier] = true
+	}
+	if len(seen) != 3 {
+		t.Errorf("read identifiers %v, want three", seen)
+	}
+}
+
+func TestReadGIFT(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want Item
+		err  string
+	}{
+		{
+			name: "multiple choice with feedback",
+			text: "::Q1:: Pick one {=right#yes ~wrong#no}",
+			want: Item{Title: "Q1", Type: TypeMultipleChoice, Text: "Pick one", Options: []string{"right", "wrong"}, Correct: []string{"right"}},
+		},
+		{
+			name: "short answer",
+			text: "Who wrote Hamlet? {=Shakespeare =William Shakespeare}",
+			want: Item{Type: TypeShortAnswer, Text: "Who wrote Hamlet?", Correct: []string{"Shakespeare", "William Shakespeare"}},
+		},
+		{
+			name: "missing word",
+			text: "The sky is {=blue ~green} today.",
+			want: Item{Type: TypeMultipleChoice, Text: "The sky is _____ today.", Options: []string{"blue", "green"}, Correct: []string{"blue"}},
+		},
+		{
+			name: "true false shorthand",
+			text: "The earth is flat. {F}",
+			want: Item{Type: TypeTrueFalse, Text: "The earth is flat.", Correct: []string{"false"}},
+		},
+		{
+			name: "numeric range",
+			text: "Pick a number from 1 to 3 {#1..3}",
+			want: Item{Type: TypeNumeric, Text: "Pick a number from 1 to 3", Value: 2, Tolerance: 1},
+		},
+		{
+			name: "points comment",
+			text: "// points: 4\nWrite about it {}",
+			want: Item{Type: TypeEssay, Text: "Write about it", Points: 4},
+		},
+		{name: "matching", text: "Match {=a -> 1 =b -> 2}", err: "matching"},
+		{name: "description", text: "Just some text", err: "descriptions"},
+		{name: "partial credit short answer", text: "Q {=%50%half}", err: "partial credit"},
+		{name: "unterminated", text: "Q {=a", err: "unterminated"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parsed := ReadGIFT([]byte(tt.text))
+			if len(parsed) != 1 {
+				t.Fatalf("read %d items, want 1", len(parsed))
+			}
+			got := parsed[0]
+			if tt.err != "" {
+				if got.Err == nil || !strings.Contains(got.Err.Error(), tt.err) {
+					t.Errorf("error = %v, want one about %q", got.Err, tt.err)
+				}
+				return
+			}
+			if got.Err != nil {
+				t.Fatal(got.Err)
+			}
+			if !reflect.DeepEqual(got.Item, tt.want) {
+				t.Errorf("ReadGIFT gave\n%+v\nwant\n%+v", got.Item, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadGIFTKeepsGoingAfterErrors(t *testing.T) {
+	text := "\xef\xbb\xbf$CATEGORY: quiz\r\n\r\nMatch {=a -> 1}\r\n\r\nFine {\r\n=yes\r\n\r\n~no\r\n}\r\n"
+	parsed := ReadGIFT([]byte(text))
+	if len(parsed) != 2 {
+		t.Fatalf("read %d items, want 2", len(parsed))
+	}
+	if parsed[0].Err == nil || parsed[0].Position != 1 {
+		t.Errorf("first item = %+v, want an error at position 1", parsed[0])
+	}
+	if parsed[1].Err != nil || parsed[1].Position != 2 || parsed[1].Item.Type != TypeMultipleChoice {
+		t.Errorf("second item = %+v, want a multiple choice question at position 2", parsed[1])
+	}
+}
+
+func TestReadQTIRejectsOtherFiles(t *testing.T) {
+	if _, err := ReadQTI([]byte("not a zip")); err != ErrInvalidPackage {
+		t.Errorf("ReadQTI error = %v, want ErrInvalidPackage", err)
+	}
+}
+
+func TestReadQTIWithoutManifest(t *testing.T) {
+	var qti bytes.Buffer
+	if err := WriteQTI(&qti, sampleItems[:1]); err != nil {
+		t.Fatal(err)
+	}
+	source, err := zip.NewReader(bytes.NewReader(qti.Bytes()), int64(qti.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Repack the item alone
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	for _, f := range source.File {
+		if f.Name == "imsmanifest.xml" {
+			continue
+		}
+		content, err := readZipFile(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w, err := archive.Create(f.Name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write(content)
+	}
+	if err := archive.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	parsed, err := ReadQTI(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(parsed) != 1 || parsed[0].Err != nil || !reflect.DeepEqual(parsed[0].Item, sampleItems[0]) {
+		t.Errorf("ReadQTI without a manifest = %+v", parsed)
+	}
+}
@@ -0,0 +1,243 @@
+// Package spreadsheet reads tabular uploads (CSV and XLSX) into rows of strings
+package spreadsheet
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrUnsupportedFormat is returned for files that are neither CSV nor XLSX
+var ErrUnsupportedFormat = errors.New("unsupported file format, expected .csv or .xlsx")
+
+// Read parses a CSV or XLSX file, chosen by the file name's extension,
+// and returns its rows. For XLSX only the first worksheet is read.
+func Read(filename string, data []byte) ([][]string, error) {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".csv":
+		return ReadCSV(bytes.NewReader(data))
+	case ".xlsx":
+		return ReadXLSX(data)
+	default:
+		return nil, ErrUnsupportedFormat
+	}
+}
+
+// ReadCSV parses comma separated rows, tolerating a UTF-8 byte order mark
+// and rows with a varying number of fields
+func ReadCSV(r io.Reader) ([][]string, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	return reader.ReadAll()
+}
+
+// ReadXLSX parses the first worksheet of an Office Open XML workbook
+func ReadXLSX(data []byte) ([][]string, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid xlsx file: %w", err)
+	}
+
+	files := make(map[string]*zip.File, len(archive.File))
+	for _, f := range archive.File {
+		files[f.Name] = f
+	}
+
+	sheetPath, err := firstSheetPath(files)
+	if err != nil {
+		return nil, err
+	}
+
+	var sharedStrings []string
+	if f, ok := files["xl/sharedStrings.xml"]; ok {
+		if sharedStrings, err = readSharedStrings(f); err != nil {
+			return nil, err
+		}
+	}
+
+	sheet, ok := files[sheetPath]
+	if !ok {
+		return nil, fmt.Errorf("invalid xlsx file: missing %s", sheetPath)
+	}
+	return readSheet(sheet, sharedStrings)
+}
+
+type xlsxWorkbook struct {
+	Sheets []struct {
+		RelID string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+	} `xml:"sheets>sheet"`
+}
+
+type xlsxRelationships struct {
+	Relationships []struct {
+		ID     string `xml:"Id,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+type xlsxSharedStrings struct {
+	Items []xlsxRichText `xml:"si"`
+}
+
+type xlsxRichText struct {
+	Text string `xml:"t"`
+	Runs []struct {
+		Text string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (t xlsxRichText) String() string {
+	if len(t.Runs) == 0 {
+		return t.Text
+	}
+	var b strings.Builder
+	for _, run := range t.Runs {
+		b.WriteString(run.Text)
+	}
+	return b.String()
+}
+
+type xlsxSheet struct {
+	Rows []struct {
+		Cells []struct {
+			Ref       string       `xml:"r,attr"`
+			Type      string       `xml:"t,attr"`
+			Value     string       `xml:"v"`
+			InlineStr xlsxRichText `xml:"is"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+func firstSheetPath(files map[string]*zip.File) (string, error) {
+	var workbook xlsxWorkbook
+	if err := decodeXML(files["xl/workbook.xml"], &workbook); err != nil {
+		return "", err
+	}
+	if len(workbook.Sheets) == 0 {
+		return "", errors.New("invalid xlsx file: workbook has no sheets")
+	}
+
+	var rels xlsxRelationships
+	if err := decodeXML(files["xl/_rels/workbook.xml.rels"], &rels); err != nil {
+		return "", err
+	}
+	for _, rel := range rels.Relationships {
+		if rel.ID != workbook.Sheets[0].RelID {
+			continue
+		}
+		if strings.HasPrefix(rel.Target, "/") {
+			return strings.TrimPrefix(rel.Target, "/"), nil
+		}
+		return path.Join("xl", rel.Target), nil
+	}
+	return "", errors.New("invalid xlsx file: first sheet not found")
+}
+
+func readSharedStrings(f *zip.File) ([]string, error) {
+	var sst xlsxSharedStrings
+	if err := decodeXML(f, &sst); err != nil {
+		return nil, err
+	}
+	values := make([]string, len(sst.Items))
+	for i, item := range sst.Items {
+		values[i] = item.String()
+	}
+	return values, nil
+}
+
+func readSheet(f *zip.File, sharedStrings []string) ([][]string, error) {
+	var sheet xlsxSheet
+	if err := decodeXML(f, &sheet); err != nil {
+		return nil, err
+	}
+
+	rows := make([][]string, 0, len(sheet.Rows))
+	for _, row := range sheet.Rows {
+		var values []string
+		for i, cell := range row.Cells {
+			col := i
+			if cell.Ref != "" {
+				col = columnIndex(cell.Ref)
+			}
+			for len(values) <= col {
+				values = append(values, "")
+			}
+
+			switch cell.Type {
+			case "s":
+				idx, err := strconv.Atoi(cell.Value)
+				if err != nil || idx < 0 || idx >= len(sharedStrings) {
+					return nil, fmt.Errorf("invalid xlsx file: bad shared string in cell %s", cell.Ref)
+				}
+				values[col] = sharedStrings[idx]
+			case "inlineStr":
+				values[col] = cell.InlineStr.String()
+			case "b":
+				values[col] = map[string]string{"1": "TRUE", "0": "FALSE"}[cell.Value]
+			default:
+				values[col] = cell.Value
+			}
+		}
+		rows = append(rows, values)
+	}
+	return rows, nil
+}
+
+// columnIndex converts the column letters of a cell reference such as "AB12" to a zero-based index
+func columnIndex(ref string) int {
+	col := 0
+	for _, r := range ref {
+		if r < 'A' || r > 'Z' {
+			break
+		}
+		col = col*26 + int(r-'A'+1)
+	}
+	return col - 1
+}
+
+func decodeXML(f *zip.File, v interface{}) error {
+	if f == nil {
+		return errors.New("invalid xlsx file: missing workbook part")
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	if err := xml.NewDecoder(rc).Decode(v); err != nil {
+		return fmt.Errorf("invalid xlsx file: %w", err)
+	}
+	return nil
+}
+
+// ParseDate parses a date cell written as YYYY-MM-DD, DD/MM/YYYY or as an
+// Excel serial day number
+func ParseDate(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	for _, layout := range []string{"2006-01-02", "02/01/2006", time.RFC3339} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	if serial, err := strconv.ParseFloat(value, 64); err == nil && serial > 0 {
+		epoch := time.Date(1899, time.December, 30, 0, 0, 0, 0, time.UTC)
+		return epoch.AddDate(0, 0, int(serial)), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q", value)
+}
@@ -0,0 +1,126 @@
+package totp
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfcSecret is the SHA-1 key of the RFC 6238 test vectors,
+// "12345678901234567890", base32 encoded
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+// The RFC 6238 appendix B vectors for SHA-1. The RFC lists eight digit
+// codes; six digit codes are their last six digits.
+var rfcVectors = []struct {
+	unix int64
+	code string
+}{
+	{59, "287082"},
+	{1111111109, "081804"},
+	{1111111111, "050471"},
+	{1234567890, "005924"},
+	{2000000000, "279037"},
+	{20000000000, "353130"},
+}
+
+func TestCodeRFC6238(t *testing.T) {
+	for _, tt := range rfcVectors {
+		at := time.Unix(tt.unix, 0).UTC()
+		t.Run(at.Format(time.RFC3339), func(t *testing.T) {
+			got, err := Code(rfcSecret, Step(at))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.code {
+				t.Errorf("Code at %d = %s, want %s", tt.unix, got, tt.code)
+			}
+		})
+	}
+}
+
+func TestCodeAcceptsSecretsAsTyped(t *testing.T) {
+	for _, secret := range []string{strings.ToLower(rfcSecret), " " + rfcSecret + " "} {
+		got, err := Code(secret, Step(time.Unix(59, 0)))
+		if err != nil || got != "287082" {
+			t.Errorf("Code(%q) = %s, %v, want 287082", secret, got, err)
+		}
+	}
+	if _, err := Code("not base32!", 1); err == nil {
+		t.Error("Code with an invalid secret succeeded")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	current := Step(now)
+	code := func(step int64) string {
+		c, err := Code(rfcSecret, step)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+	tests := []struct {
+		name     string
+		code     string
+		skew     int64
+		wantStep int64
+		ok       bool
+	}{
+		{"current step", code(current), 0, current, true},
+		{"with spaces", code(current)[:3] + " " + code(current)[3:], 0, current, true},
+		{"previous step within skew", code(current - 1), 1, current - 1, true},
+		{"next step within skew", code(current + 1), 1, current + 1, true},
+		{"previous step without skew", code(current - 1), 0, 0, false},
+		{"two steps back", code(current - 2), 1, 0, false},
+		{"too short", "12345", 1, 0, false},
+		{"too long", "1234567", 1, 0, false},
+		{"wrong code", "000000", 1, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, ok := Validate(rfcSecret, tt.code, now, tt.skew)
+			if ok != tt.ok || step != tt.wantStep {
+				t.Errorf("Validate(%q) = %d, %v, want %d, %v", tt.code, step, ok, tt.wantStep, tt.ok)
+			}
+		})
+	}
+}
+
+func TestGenerateSecret(t *testing.T) {
+	a, err := GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(a) != 32 || a == b {
+		t.Errorf("GenerateSecret = %q, %q, want two different 32 character secrets", a, b)
+	}
+	if _, err := Code(a, 1); err != nil {
+		t.Errorf("generated secret does not decode: %v", err)
+	}
+}
+
+func TestProvisioningURI(t *testing.T) {
+	uri := ProvisioningURI("Campus LMS", "ana@example.com", rfcSecret)
+	u, err := url.Parse(uri)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Scheme != "otpauth" || u.Host != "totp" || u.Path != "/Campus LMS:ana@example.com" {
+		t.Errorf("ProvisioningURI = %s", uri)
+	}
+	query := u.Query()
+	for key, want := range map[string]string{
+		"secret": rfcSecret, "issuer": "Campus LMS", "algorithm": "SHA1", "digits": "6", "period": "30",
+	} {
+		if got := query.Get(key); got != want {
+			t.Errorf("%s = %q, want %q", key, got, want)
+		}
+	}
+}